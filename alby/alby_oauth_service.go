@@ -565,6 +565,30 @@ func (svc *albyOAuthService) ConsumeEvent(ctx context.Context, event *events.Eve
 		}
 	}
 
+	if event.Event == "nwc_rebalance_succeeded" || event.Event == "nwc_rebalance_failed" {
+		transaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			logger.Logger.WithField("event", event).Error("Failed to cast event")
+			return
+		}
+
+		type rebalanceEventProperties struct {
+			Id         uint   `json:"id"`
+			AmountMsat uint64 `json:"amount_msat"`
+			FeeMsat    uint64 `json:"fee_msat"`
+		}
+
+		// pass a new custom event without the invoice and preimage
+		event = &events.Event{
+			Event: event.Event,
+			Properties: &rebalanceEventProperties{
+				Id:         transaction.ID,
+				AmountMsat: transaction.AmountMsat,
+				FeeMsat:    transaction.FeeMsat,
+			},
+		}
+	}
+
 	token, err := svc.fetchUserToken(ctx)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to fetch user token")
@@ -9,10 +9,12 @@ import (
 	"net/http"
 	"net/url"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tyler-smith/go-bip39"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/notifications"
 	permissions "github.com/getAlby/hub/nip47/permissions"
 	"github.com/getAlby/hub/service"
 	"github.com/getAlby/hub/service/keys"
@@ -71,6 +74,11 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 		}
 	}
 
+	notificationTypes, err := api.parseNotificationTypes(createAppRequest.NotificationTypes)
+	if err != nil {
+		return nil, err
+	}
+
 	app, pairingSecretKey, err := api.dbSvc.CreateApp(
 		createAppRequest.Name,
 		createAppRequest.Pubkey,
@@ -85,6 +93,22 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 		return nil, err
 	}
 
+	if notificationTypes != "" {
+		err = api.db.Model(app).Update("notification_types", notificationTypes).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to save notification types: %v", err)
+		}
+	}
+
+	walletPubkey := api.keys.GetNostrPublicKey()
+	if createAppRequest.UniqueWalletPubkey {
+		err = api.svc.GetNip47Service().CreateAppWalletKey(app)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create app wallet key: %v", err)
+		}
+		walletPubkey = *app.WalletPubkey
+	}
+
 	relayUrl := api.cfg.GetRelayUrl()
 
 	responseBody := &CreateAppResponse{}
@@ -92,6 +116,7 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 	responseBody.Name = createAppRequest.Name
 	responseBody.Pubkey = app.NostrPubkey
 	responseBody.PairingSecret = pairingSecretKey
+	responseBody.WalletPubkey = walletPubkey
 
 	lightningAddress, err := api.albyOAuthSvc.GetLightningAddress()
 	if err != nil {
@@ -103,7 +128,7 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 		if err == nil {
 			query := returnToUrl.Query()
 			query.Add("relay", relayUrl)
-			query.Add("pubkey", api.keys.GetNostrPublicKey())
+			query.Add("pubkey", walletPubkey)
 			if lightningAddress != "" && !app.Isolated {
 				query.Add("lud16", lightningAddress)
 			}
@@ -116,7 +141,7 @@ func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppRespons
 	if lightningAddress != "" && !app.Isolated {
 		lud16 = fmt.Sprintf("&lud16=%s", lightningAddress)
 	}
-	responseBody.PairingUri = fmt.Sprintf("nostr+walletconnect://%s?relay=%s&secret=%s%s", api.keys.GetNostrPublicKey(), relayUrl, pairingSecretKey, lud16)
+	responseBody.PairingUri = fmt.Sprintf("nostr+walletconnect://%s?relay=%s&secret=%s%s", walletPubkey, relayUrl, pairingSecretKey, lud16)
 	return responseBody, nil
 }
 
@@ -140,6 +165,14 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 		return fmt.Errorf("invalid expiresAt: %v", err)
 	}
 
+	var notificationTypes string
+	if updateAppRequest.NotificationTypes != nil {
+		notificationTypes, err = api.parseNotificationTypes(updateAppRequest.NotificationTypes)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = api.db.Transaction(func(tx *gorm.DB) error {
 		// Update app name if it is not the same
 		if name != userApp.Name {
@@ -163,6 +196,13 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 			}
 		}
 
+		if updateAppRequest.NotificationTypes != nil {
+			err := tx.Model(&db.App{}).Where("id", userApp.ID).Update("notification_types", notificationTypes).Error
+			if err != nil {
+				return err
+			}
+		}
+
 		// Update existing permissions with new budget and expiry
 		err := tx.Model(&db.AppPermission{}).Where("app_id", userApp.ID).Updates(map[string]interface{}{
 			"ExpiresAt":     expiresAt,
@@ -215,7 +255,25 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 }
 
 func (api *api) DeleteApp(userApp *db.App) error {
-	return api.db.Delete(userApp).Error
+	err := api.db.Delete(userApp).Error
+	if err != nil {
+		return err
+	}
+	if userApp.WalletPubkey != nil {
+		// stop handling requests sent to the wallet service key of the app
+		api.svc.GetNip47Service().RefreshAppWalletKeys()
+	}
+	return nil
+}
+
+// RotateAppWalletKey replaces the wallet service key of a connection. The app has to be
+// connected again with the new wallet pubkey, requests sent to the previous key are not handled.
+func (api *api) RotateAppWalletKey(userApp *db.App) (*App, error) {
+	err := api.svc.GetNip47Service().RotateAppWalletKey(userApp)
+	if err != nil {
+		return nil, err
+	}
+	return api.GetApp(userApp), nil
 }
 
 func (api *api) GetApp(dbApp *db.App) *App {
@@ -267,6 +325,9 @@ func (api *api) GetApp(dbApp *db.App) *App {
 		BudgetRenewal: paySpecificPermission.BudgetRenewal,
 		Isolated:      dbApp.Isolated,
 		Metadata:      metadata,
+		WalletPubkey:  dbApp.WalletPubkey,
+
+		NotificationTypes: notifications.GetAppNotificationTypes(dbApp),
 	}
 
 	if dbApp.Isolated {
@@ -305,13 +366,16 @@ func (api *api) ListApps() ([]App, error) {
 	apiApps := []App{}
 	for _, dbApp := range dbApps {
 		apiApp := App{
-			ID:          dbApp.ID,
-			Name:        dbApp.Name,
-			Description: dbApp.Description,
-			CreatedAt:   dbApp.CreatedAt,
-			UpdatedAt:   dbApp.UpdatedAt,
-			NostrPubkey: dbApp.NostrPubkey,
-			Isolated:    dbApp.Isolated,
+			ID:           dbApp.ID,
+			Name:         dbApp.Name,
+			Description:  dbApp.Description,
+			CreatedAt:    dbApp.CreatedAt,
+			UpdatedAt:    dbApp.UpdatedAt,
+			NostrPubkey:  dbApp.NostrPubkey,
+			Isolated:     dbApp.Isolated,
+			WalletPubkey: dbApp.WalletPubkey,
+
+			NotificationTypes: notifications.GetAppNotificationTypes(&dbApp),
 		}
 
 		if dbApp.Isolated {
@@ -381,6 +445,11 @@ func (api *api) ListChannels(ctx context.Context) ([]Channel, error) {
 			Confirmations:                            channel.Confirmations,
 			ConfirmationsRequired:                    channel.ConfirmationsRequired,
 			ForwardingFeeBaseMsat:                    channel.ForwardingFeeBaseMsat,
+			ForwardingFeeProportionalMillionths:      channel.ForwardingFeeProportionalMillionths,
+			CltvExpiryDelta:                          channel.CltvExpiryDelta,
+			HtlcMinimumMsat:                          channel.HtlcMinimumMsat,
+			HtlcMaximumMsat:                          channel.HtlcMaximumMsat,
+			ForceCloseAvoidanceMaxFeeSat:             channel.ForceCloseAvoidanceMaxFeeSat,
 			UnspendablePunishmentReserve:             channel.UnspendablePunishmentReserve,
 			CounterpartyUnspendablePunishmentReserve: channel.CounterpartyUnspendablePunishmentReserve,
 			Error:                                    channel.Error,
@@ -477,6 +546,10 @@ func (api *api) OpenChannel(ctx context.Context, openChannelRequest *OpenChannel
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
 	}
+	err := validateOpenChannelRequest(openChannelRequest)
+	if err != nil {
+		return nil, err
+	}
 	return api.svc.GetLNClient().OpenChannel(ctx, openChannelRequest)
 }
 
@@ -513,9 +586,59 @@ func (api *api) UpdateChannel(ctx context.Context, updateChannelRequest *UpdateC
 	logger.Logger.WithFields(logrus.Fields{
 		"request": updateChannelRequest,
 	}).Info("updating channel")
+	err := updateChannelRequest.Validate()
+	if err != nil {
+		return err
+	}
 	return api.svc.GetLNClient().UpdateChannel(ctx, updateChannelRequest)
 }
 
+func (api *api) UpdateChannels(ctx context.Context, updateChannelsRequest *UpdateChannelsRequest) (*UpdateChannelsResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"request": updateChannelsRequest,
+	}).Info("updating all channels")
+
+	err := updateChannelsRequest.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a failure to update one channel should not stop the others from being updated
+	results := []UpdateChannelResult{}
+	for _, channel := range channels {
+		result := UpdateChannelResult{
+			ChannelId: channel.Id,
+			NodeId:    channel.RemotePubkey,
+		}
+		err := lnClient.UpdateChannel(ctx, &UpdateChannelRequest{
+			ChannelId:           channel.Id,
+			NodeId:              channel.RemotePubkey,
+			ChannelPolicyUpdate: *updateChannelsRequest,
+		})
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"channel_id": channel.Id,
+			}).Error("Failed to update channel")
+			errorMessage := err.Error()
+			result.Error = &errorMessage
+		}
+		results = append(results, result)
+	}
+
+	return &UpdateChannelsResponse{
+		Channels: results,
+	}, nil
+}
+
 func (api *api) GetNewOnchainAddress(ctx context.Context) (string, error) {
 	if api.svc.GetLNClient() == nil {
 		return "", errors.New("LNClient not started")
@@ -583,19 +706,6 @@ func (api *api) SignMessage(ctx context.Context, message string) (*SignMessageRe
 	}, nil
 }
 
-func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*RedeemOnchainFundsResponse, error) {
-	if api.svc.GetLNClient() == nil {
-		return nil, errors.New("LNClient not started")
-	}
-	txId, err := api.svc.GetLNClient().RedeemOnchainFunds(ctx, toAddress, amount, sendAll)
-	if err != nil {
-		return nil, err
-	}
-	return &RedeemOnchainFundsResponse{
-		TxId: txId,
-	}, nil
-}
-
 func (api *api) GetBalances(ctx context.Context) (*BalancesResponse, error) {
 	if api.svc.GetLNClient() == nil {
 		return nil, errors.New("LNClient not started")
@@ -791,6 +901,21 @@ func (api *api) Setup(ctx context.Context, setupRequest *SetupRequest) error {
 		api.cfg.SetUpdate("CashuMintUrl", setupRequest.CashuMintUrl, setupRequest.UnlockPassword)
 	}
 
+	// cashu proofs are derived from the mnemonic so the wallet can be restored from the mint
+	if setupRequest.LNBackendType == config.CashuBackendType && setupRequest.Mnemonic == "" {
+		entropy, err := bip39.NewEntropy(128)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to generate entropy for cashu mnemonic")
+			return err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to generate cashu mnemonic")
+			return err
+		}
+		api.cfg.SetUpdate("Mnemonic", mnemonic, setupRequest.UnlockPassword)
+	}
+
 	return nil
 }
 
@@ -887,6 +1012,23 @@ func (api *api) GetLogOutput(ctx context.Context, logType string, getLogRequest
 	return &GetLogOutputResponse{Log: string(logData)}, nil
 }
 
+// parseNotificationTypes validates the notification types an app opts in to
+// and returns them in the format they are stored in
+func (api *api) parseNotificationTypes(notificationTypes []string) (string, error) {
+	if len(notificationTypes) == 0 {
+		return "", nil
+	}
+
+	// apps can be created while the node is not running
+	for _, notificationType := range notificationTypes {
+		if !slices.Contains(notifications.AllNotificationTypes, notificationType) {
+			return "", fmt.Errorf("did not recognize requested notification type: %s", notificationType)
+		}
+	}
+
+	return strings.Join(notificationTypes, " "), nil
+}
+
 func (api *api) parseExpiresAt(expiresAtString string) (*time.Time, error) {
 	var expiresAt *time.Time
 	if expiresAtString != "" {
@@ -900,3 +1042,15 @@ func (api *api) parseExpiresAt(expiresAtString string) (*time.Time, error) {
 	}
 	return expiresAt, nil
 }
+
+func (api *api) GetRelayStatus() *RelayStatus {
+	relayStatus := api.svc.GetRelayStatus()
+	return &RelayStatus{
+		Url:           relayStatus.Url,
+		Connected:     relayStatus.Connected,
+		AuthRequired:  relayStatus.AuthRequired,
+		Authenticated: relayStatus.Authenticated,
+		AuthError:     relayStatus.AuthError,
+		UpdatedAt:     relayStatus.UpdatedAt,
+	}
+}
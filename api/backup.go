@@ -220,6 +220,16 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 			}
 		}
 
+		// cashu proofs are not part of the backup. Move any existing cashu wallet aside
+		// so the wallet is restored from the mnemonic in the backup on the next start
+		cashuDir := filepath.Join(workDir, "cashu")
+		if _, err := os.Stat(cashuDir); err == nil {
+			err = os.Rename(cashuDir, fmt.Sprintf("%s-%d.bak", cashuDir, time.Now().Unix()))
+			if err != nil {
+				logger.Logger.WithError(err).Error("failed to move old cashu wallet before restore")
+			}
+		}
+
 		// schedule node shutdown after a few seconds to ensure frontend updates
 		time.Sleep(5 * time.Second)
 		os.Exit(0)
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+func (api *api) OpenChannels(ctx context.Context, batchOpenChannelRequest *BatchOpenChannelRequest) (*BatchOpenChannelResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	batchChannelOpener, ok := lnClient.(lnclient.BatchChannelOpener)
+	if !ok {
+		return nil, errors.New("batch channel opens are not supported by this backend")
+	}
+	if len(batchOpenChannelRequest.Channels) == 0 {
+		return nil, errors.New("no channels to open")
+	}
+
+	pubkeys := map[string]struct{}{}
+	for _, openChannelRequest := range batchOpenChannelRequest.Channels {
+		if _, ok := pubkeys[openChannelRequest.Pubkey]; ok {
+			return nil, fmt.Errorf("duplicate peer: %s", openChannelRequest.Pubkey)
+		}
+		pubkeys[openChannelRequest.Pubkey] = struct{}{}
+
+		err := validateOpenChannelRequest(&openChannelRequest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"channels": len(batchOpenChannelRequest.Channels),
+		"fee_rate": batchOpenChannelRequest.FeeRate,
+	}).Info("Opening channels in batch")
+
+	return batchChannelOpener.OpenChannels(ctx, batchOpenChannelRequest)
+}
+
+// ListFundingTransactionChannels returns the channels funded by the given transaction,
+// which allows to follow the channels of a batch open until they are confirmed
+func (api *api) ListFundingTransactionChannels(ctx context.Context, fundingTxId string) ([]Channel, error) {
+	channels, err := api.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fundingTransactionChannels := []Channel{}
+	for _, channel := range channels {
+		if channel.FundingTxId == fundingTxId {
+			fundingTransactionChannels = append(fundingTransactionChannels, channel)
+		}
+	}
+	return fundingTransactionChannels, nil
+}
+
+func validateOpenChannelRequest(openChannelRequest *OpenChannelRequest) error {
+	if openChannelRequest.Amount <= 0 {
+		return fmt.Errorf("invalid channel amount for %s", openChannelRequest.Pubkey)
+	}
+	if openChannelRequest.PushAmount < 0 || openChannelRequest.PushAmount >= openChannelRequest.Amount {
+		return fmt.Errorf("push amount must be less than the channel amount for %s", openChannelRequest.Pubkey)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47"
+	"github.com/getAlby/hub/nip47/permissions"
+	"gorm.io/gorm"
+)
+
+// pending connection requests are deleted after this time, the app can request the connection again
+const connectionRequestTTL = 24 * time.Hour
+
+// CreateConnectionRequest stores a connection requested by an app with a nostr+walletauth URI,
+// which the user pasted into the hub. Requests are not received over nostr. Once approved, the app
+// learns about the connection from the info event (kind 13194) of its wallet service key, which is tagged with the app pubkey.
+func (api *api) CreateConnectionRequest(createConnectionRequestRequest *CreateConnectionRequestRequest) (*ConnectionRequest, error) {
+	walletAuthRequest, err := nip47.ParseWalletAuthUri(createConnectionRequestRequest.Uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if walletAuthRequest.ExpiresAt != nil && !walletAuthRequest.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("the requested connection is already expired")
+	}
+
+	// the app will only find the hub on the relay it is connected to
+	if walletAuthRequest.RelayUrl != "" && walletAuthRequest.RelayUrl != api.cfg.GetRelayUrl() {
+		return nil, fmt.Errorf("unsupported relay: %s", walletAuthRequest.RelayUrl)
+	}
+
+	scopes := []string{}
+	requestMethods := []string{}
+	for _, requestMethod := range walletAuthRequest.RequestMethods {
+		if requestMethod == "notifications" {
+			scopes = append(scopes, constants.NOTIFICATIONS_SCOPE)
+			continue
+		}
+		requestMethods = append(requestMethods, requestMethod)
+	}
+	requestMethodScopes, err := permissions.RequestMethodsToScopes(requestMethods)
+	if err != nil {
+		return nil, err
+	}
+	scopes = append(scopes, requestMethodScopes...)
+
+	var existingAppCount int64
+	err = api.db.Model(&db.App{}).Where("nostr_pubkey = ?", walletAuthRequest.Pubkey).Count(&existingAppCount).Error
+	if err != nil {
+		return nil, err
+	}
+	if existingAppCount > 0 {
+		return nil, errors.New("a connection for this public key already exists")
+	}
+
+	// an expired request does not prevent the app from requesting the connection again
+	err = api.deleteExpiredConnectionRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	name := walletAuthRequest.Name
+	if name == "" {
+		name = fmt.Sprintf("App %s", walletAuthRequest.Pubkey[:8])
+	}
+
+	connectionRequest := &db.ConnectionRequest{
+		Name:          name,
+		NostrPubkey:   walletAuthRequest.Pubkey,
+		RelayUrl:      walletAuthRequest.RelayUrl,
+		Scopes:        strings.Join(scopes, ","),
+		MaxAmountSat:  walletAuthRequest.MaxAmountSat,
+		BudgetRenewal: walletAuthRequest.BudgetRenewal,
+		ExpiresAt:     walletAuthRequest.ExpiresAt,
+		Isolated:      walletAuthRequest.Isolated,
+		ReturnTo:      walletAuthRequest.ReturnTo,
+	}
+	err = api.db.Create(connectionRequest).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New("a connection request for this public key already exists")
+		}
+		logger.Logger.WithError(err).Error("Failed to save connection request")
+		return nil, err
+	}
+
+	return toApiConnectionRequest(connectionRequest), nil
+}
+
+func (api *api) ListConnectionRequests() ([]ConnectionRequest, error) {
+	err := api.deleteExpiredConnectionRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	connectionRequests := []db.ConnectionRequest{}
+	err = api.db.Order("created_at desc").Find(&connectionRequests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	apiConnectionRequests := []ConnectionRequest{}
+	for _, connectionRequest := range connectionRequests {
+		apiConnectionRequests = append(apiConnectionRequests, *toApiConnectionRequest(&connectionRequest))
+	}
+	return apiConnectionRequests, nil
+}
+
+// ApproveConnectionRequest creates the requested connection. The app gets its own wallet
+// service key, and is notified through the info event published for that key.
+// Apps which provided a return_to URL can also be opened with the returned URL.
+func (api *api) ApproveConnectionRequest(id uint) (*ApproveConnectionRequestResponse, error) {
+	err := api.deleteExpiredConnectionRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	connectionRequest := db.ConnectionRequest{}
+	err = api.db.First(&connectionRequest, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("connection request not found or expired")
+		}
+		return nil, err
+	}
+	if connectionRequest.ExpiresAt != nil && !connectionRequest.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("the requested connection is already expired")
+	}
+
+	var expiresAt string
+	if connectionRequest.ExpiresAt != nil {
+		expiresAt = connectionRequest.ExpiresAt.Format(time.RFC3339)
+	}
+
+	createAppResponse, err := api.CreateApp(&CreateAppRequest{
+		Name:               connectionRequest.Name,
+		Pubkey:             connectionRequest.NostrPubkey,
+		MaxAmountSat:       connectionRequest.MaxAmountSat,
+		BudgetRenewal:      connectionRequest.BudgetRenewal,
+		ExpiresAt:          expiresAt,
+		Scopes:             strings.Split(connectionRequest.Scopes, ","),
+		Isolated:           connectionRequest.Isolated,
+		ReturnTo:           connectionRequest.ReturnTo,
+		UniqueWalletPubkey: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.db.Delete(&connectionRequest).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("id", id).Error("Failed to delete approved connection request")
+	}
+
+	app := db.App{}
+	err = api.db.First(&app, createAppResponse.Id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ApproveConnectionRequestResponse{
+		App:      *api.GetApp(&app),
+		ReturnTo: createAppResponse.ReturnTo,
+	}, nil
+}
+
+func (api *api) RejectConnectionRequest(id uint) error {
+	result := api.db.Delete(&db.ConnectionRequest{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("connection request not found")
+	}
+	return nil
+}
+
+func (api *api) deleteExpiredConnectionRequests() error {
+	err := api.db.Where("created_at < ?", time.Now().Add(-connectionRequestTTL)).Delete(&db.ConnectionRequest{}).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to delete expired connection requests")
+	}
+	return err
+}
+
+func toApiConnectionRequest(connectionRequest *db.ConnectionRequest) *ConnectionRequest {
+	return &ConnectionRequest{
+		ID:            connectionRequest.ID,
+		Name:          connectionRequest.Name,
+		Pubkey:        connectionRequest.NostrPubkey,
+		Scopes:        strings.Split(connectionRequest.Scopes, ","),
+		MaxAmountSat:  connectionRequest.MaxAmountSat,
+		BudgetRenewal: connectionRequest.BudgetRenewal,
+		ExpiresAt:     connectionRequest.ExpiresAt,
+		Isolated:      connectionRequest.Isolated,
+		ReturnTo:      connectionRequest.ReturnTo,
+		CreatedAt:     connectionRequest.CreatedAt,
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCreateConnectionRequest_Expired(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	api := &api{db: svc.DB, cfg: svc.Cfg}
+
+	pubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	assert.NoError(t, err)
+
+	_, err = api.CreateConnectionRequest(&CreateConnectionRequestRequest{
+		Uri: fmt.Sprintf("nostr+walletauth://%s?required_commands=get_balance&expires_at=%d", pubkey, time.Now().Add(-time.Hour).Unix()),
+	})
+	assert.EqualError(t, err, "the requested connection is already expired")
+}
+
+func TestListConnectionRequests_DeletesExpired(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	api := &api{db: svc.DB, cfg: svc.Cfg}
+
+	pubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	assert.NoError(t, err)
+	uri := fmt.Sprintf("nostr+walletauth://%s?required_commands=get_balance", pubkey)
+
+	connectionRequest, err := api.CreateConnectionRequest(&CreateConnectionRequestRequest{Uri: uri})
+	assert.NoError(t, err)
+
+	connectionRequests, err := api.ListConnectionRequests()
+	assert.NoError(t, err)
+	assert.Len(t, connectionRequests, 1)
+
+	err = svc.DB.Model(&db.ConnectionRequest{}).Where("id = ?", connectionRequest.ID).Update("created_at", time.Now().Add(-connectionRequestTTL-time.Minute)).Error
+	assert.NoError(t, err)
+
+	connectionRequests, err = api.ListConnectionRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, connectionRequests)
+
+	// the app can request the connection again
+	_, err = api.CreateConnectionRequest(&CreateConnectionRequestRequest{Uri: uri})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+func (api *api) SendEcashToken(ctx context.Context, sendEcashTokenRequest *SendEcashTokenRequest) (*SendEcashTokenResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transaction, err := api.svc.GetTransactionsService().SendEcashToken(ctx, sendEcashTokenRequest.Amount, sendEcashTokenRequest.MintUrl, api.svc.GetLNClient(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SendEcashTokenResponse{
+		Token:       *transaction.EcashToken,
+		Transaction: *toApiTransaction(transaction),
+	}, nil
+}
+
+func (api *api) ReceiveEcashToken(ctx context.Context, receiveEcashTokenRequest *ReceiveEcashTokenRequest) (*ReceiveEcashTokenResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transaction, err := api.svc.GetTransactionsService().ReceiveEcashToken(ctx, receiveEcashTokenRequest.Token, receiveEcashTokenRequest.SwapToDefaultMint, api.svc.GetLNClient(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toApiTransaction(transaction), nil
+}
+
+func (api *api) SwapEcashToDefaultMint(ctx context.Context, swapEcashRequest *SwapEcashRequest) error {
+	if api.svc.GetLNClient() == nil {
+		return errors.New("LNClient not started")
+	}
+	ecashClient, ok := api.svc.GetLNClient().(lnclient.EcashClient)
+	if !ok {
+		return errors.New("this wallet does not support ecash tokens")
+	}
+	return ecashClient.SwapToDefaultMint(ctx, swapEcashRequest.FromMintUrl, swapEcashRequest.Amount)
+}
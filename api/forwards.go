@@ -0,0 +1,18 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/config"
+)
+
+func (api *api) GetForwardingStats(ctx context.Context, from, until, interval uint64) (*ForwardingStatsResponse, error) {
+	// forwards are only recorded from nwc_lnclient_forward events, which only LND publishes.
+	// ldk-node-go does not expose the PaymentForwarded event yet.
+	backendType, _ := api.cfg.Get("LNBackendType", "")
+	if backendType != config.LNDBackendType {
+		return nil, errors.New("forwarding stats are not supported by this backend")
+	}
+	return api.svc.GetForwardsService().GetForwardingStats(ctx, from, until, interval)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+const (
+	HISTORY_ITEM_TYPE_LIGHTNING = "lightning"
+	HISTORY_ITEM_TYPE_ONCHAIN   = "onchain"
+)
+
+// ListHistory returns settled Lightning transactions and onchain transactions, newest first
+func (api *api) ListHistory(ctx context.Context, limit uint64, offset uint64) ([]HistoryItem, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	// onchain transactions are not paginated, so all Lightning transactions up to the page end are needed
+	var lightningLimit uint64
+	if limit > 0 {
+		lightningLimit = offset + limit
+	}
+	transactions, err := api.svc.GetTransactionsService().ListTransactions(ctx, 0, 0, lightningLimit, 0, false, nil, lnClient, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	onchainTransactions, err := api.ListOnchainTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	historyItems := []HistoryItem{}
+	for _, transaction := range transactions {
+		apiTransaction := toApiTransaction(&transaction)
+		timestamp := apiTransaction.CreatedAt
+		if apiTransaction.SettledAt != nil {
+			timestamp = *apiTransaction.SettledAt
+		}
+		historyItems = append(historyItems, HistoryItem{
+			Type:      HISTORY_ITEM_TYPE_LIGHTNING,
+			Timestamp: timestamp,
+			Lightning: apiTransaction,
+		})
+	}
+	for _, onchainTransaction := range onchainTransactions {
+		onchainTransaction := onchainTransaction
+		historyItems = append(historyItems, HistoryItem{
+			Type:      HISTORY_ITEM_TYPE_ONCHAIN,
+			Timestamp: onchainTransaction.CreatedAt,
+			Onchain:   &onchainTransaction,
+		})
+	}
+
+	sort.SliceStable(historyItems, func(i, j int) bool {
+		return parseTime(historyItems[i].Timestamp).After(parseTime(historyItems[j].Timestamp))
+	})
+
+	if offset >= uint64(len(historyItems)) {
+		return []HistoryItem{}, nil
+	}
+	historyItems = historyItems[offset:]
+	if limit > 0 && limit < uint64(len(historyItems)) {
+		historyItems = historyItems[:limit]
+	}
+	return historyItems, nil
+}
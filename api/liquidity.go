@@ -0,0 +1,34 @@
+package api
+
+func (api *api) GetLiquiditySettings() (*LiquiditySettings, error) {
+	return api.svc.GetLiquidityService().GetSettings()
+}
+
+func (api *api) UpdateLiquiditySettings(settings *LiquiditySettings) error {
+	return api.svc.GetLiquidityService().UpdateSettings(settings)
+}
+
+func (api *api) ListLiquidityDecisions(limit uint64, offset uint64) ([]LiquidityDecision, error) {
+	decisions, err := api.svc.GetLiquidityService().ListDecisions(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	apiDecisions := []LiquidityDecision{}
+	for _, decision := range decisions {
+		apiDecisions = append(apiDecisions, LiquidityDecision{
+			ID:            decision.ID,
+			Action:        decision.Action,
+			Reason:        decision.Reason,
+			ReceivableSat: decision.ReceivableSat,
+			ThresholdSat:  decision.ThresholdSat,
+			AmountSat:     decision.AmountSat,
+			FeeSat:        decision.FeeSat,
+			Provider:      decision.Provider,
+			Invoice:       decision.Invoice,
+			DryRun:        decision.DryRun,
+			CreatedAt:     decision.CreatedAt,
+		})
+	}
+	return apiDecisions, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListLSPs() ([]LSP, error) {
+	lsps, err := api.svc.GetLSPService().ListLSPs()
+	if err != nil {
+		return nil, err
+	}
+
+	apiLSPs := []LSP{}
+	for _, lsp := range lsps {
+		apiLSPs = append(apiLSPs, *toApiLSP(&lsp))
+	}
+	return apiLSPs, nil
+}
+
+func (api *api) AddLSP(addLSPRequest *AddLSPRequest) (*LSP, error) {
+	lsp, err := api.svc.GetLSPService().AddLSP(addLSPRequest.Name, addLSPRequest.Url, addLSPRequest.Pubkey, addLSPRequest.Protocols, addLSPRequest.Network)
+	if err != nil {
+		return nil, err
+	}
+	return toApiLSP(lsp), nil
+}
+
+func (api *api) DeleteLSP(id uint) error {
+	return api.svc.GetLSPService().DeleteLSP(id)
+}
+
+func (api *api) ListLSPOrders(limit uint64, offset uint64) ([]LSPOrder, error) {
+	orders, err := api.svc.GetLSPService().ListOrders(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	apiOrders := []LSPOrder{}
+	for _, order := range orders {
+		apiOrder := LSPOrder{
+			ID:                   order.ID,
+			LSPId:                order.LSPId,
+			LSPUrl:               order.LSPUrl,
+			LSPType:              order.LSPType,
+			OrderId:              order.OrderId,
+			State:                order.State,
+			PaymentState:         order.PaymentState,
+			Invoice:              order.Invoice,
+			FeeSat:               order.FeeSat,
+			LSPBalanceSat:        order.LSPBalanceSat,
+			Public:               order.Public,
+			RefundOnchainAddress: order.RefundOnchainAddress,
+			FundingOutpoint:      order.FundingOutpoint,
+			RefundState:          order.RefundState,
+			CreatedAt:            order.CreatedAt,
+			UpdatedAt:            order.UpdatedAt,
+		}
+		if order.LSP != nil {
+			apiOrder.LSPName = order.LSP.Name
+		}
+		apiOrders = append(apiOrders, apiOrder)
+	}
+	return apiOrders, nil
+}
+
+func toApiLSP(lsp *db.LSP) *LSP {
+	// LSPs seeded by the migration have no known protocols
+	protocols := []string{}
+	if lsp.Protocols != "" {
+		protocols = strings.Split(lsp.Protocols, ",")
+	}
+	return &LSP{
+		ID:        lsp.ID,
+		Name:      lsp.Name,
+		Url:       lsp.Url,
+		Pubkey:    lsp.Pubkey,
+		Protocols: protocols,
+		Network:   lsp.Network,
+		CreatedAt: lsp.CreatedAt,
+	}
+}
@@ -2,18 +2,24 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"time"
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/forwards"
+	"github.com/getAlby/hub/liquidity"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/swaps"
+	"github.com/nbd-wtf/go-nostr"
 )
 
 type API interface {
 	CreateApp(createAppRequest *CreateAppRequest) (*CreateAppResponse, error)
 	UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) error
 	DeleteApp(userApp *db.App) error
+	RotateAppWalletKey(userApp *db.App) (*App, error)
 	GetApp(userApp *db.App) *App
 	ListApps() ([]App, error)
 	ListChannels(ctx context.Context) ([]Channel, error)
@@ -27,12 +33,30 @@ type API interface {
 	ConnectPeer(ctx context.Context, connectPeerRequest *ConnectPeerRequest) error
 	DisconnectPeer(ctx context.Context, peerId string) error
 	OpenChannel(ctx context.Context, openChannelRequest *OpenChannelRequest) (*OpenChannelResponse, error)
+	OpenChannels(ctx context.Context, batchOpenChannelRequest *BatchOpenChannelRequest) (*BatchOpenChannelResponse, error)
+	ListFundingTransactionChannels(ctx context.Context, fundingTxId string) ([]Channel, error)
 	CloseChannel(ctx context.Context, peerId, channelId string, force bool) (*CloseChannelResponse, error)
+	StartPsbtChannelOpen(ctx context.Context, openChannelRequest *OpenChannelRequest) (*PsbtChannelOpenResponse, error)
+	VerifyPsbtChannelFunding(ctx context.Context, pendingChannelId string, verifyPsbtChannelFundingRequest *VerifyPsbtChannelFundingRequest) error
+	FinalizePsbtChannelOpen(ctx context.Context, pendingChannelId string, finalizePsbtChannelOpenRequest *FinalizePsbtChannelOpenRequest) (*OpenChannelResponse, error)
+	CancelPsbtChannelOpen(ctx context.Context, pendingChannelId string) error
 	UpdateChannel(ctx context.Context, updateChannelRequest *UpdateChannelRequest) error
+	UpdateChannels(ctx context.Context, updateChannelsRequest *UpdateChannelsRequest) (*UpdateChannelsResponse, error)
+	GetForwardingStats(ctx context.Context, from, until, interval uint64) (*ForwardingStatsResponse, error)
+	Rebalance(ctx context.Context, rebalanceRequest *RebalanceRequest) (*RebalanceResponse, error)
+	ListRebalances(ctx context.Context, limit uint64, offset uint64) (*ListTransactionsResponse, error)
 	GetNewOnchainAddress(ctx context.Context) (string, error)
 	GetUnusedOnchainAddress(ctx context.Context) (string, error)
 	SignMessage(ctx context.Context, message string) (*SignMessageResponse, error)
 	RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*RedeemOnchainFundsResponse, error)
+	EstimateOnchainFees(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*OnchainFeeEstimate, error)
+	ListOnchainTransactions(ctx context.Context) ([]OnchainTransaction, error)
+	ListUtxos(ctx context.Context) ([]Utxo, error)
+	SendOnchain(ctx context.Context, sendOnchainRequest *SendOnchainRequest) (*RedeemOnchainFundsResponse, error)
+	BumpOnchainFee(ctx context.Context, bumpOnchainFeeRequest *BumpOnchainFeeRequest) (*RedeemOnchainFundsResponse, error)
+	ListOnchainLabels(ctx context.Context, labelType string) ([]OnchainLabel, error)
+	SetOnchainLabel(ctx context.Context, setOnchainLabelRequest *SetOnchainLabelRequest) error
+	ListHistory(ctx context.Context, limit uint64, offset uint64) ([]HistoryItem, error)
 	GetBalances(ctx context.Context) (*BalancesResponse, error)
 	ListTransactions(ctx context.Context, limit uint64, offset uint64) (*ListTransactionsResponse, error)
 	SendPayment(ctx context.Context, invoice string) (*SendPaymentResponse, error)
@@ -50,9 +74,32 @@ type API interface {
 	SyncWallet() error
 	GetLogOutput(ctx context.Context, logType string, getLogRequest *GetLogOutputRequest) (*GetLogOutputResponse, error)
 	RequestLSPOrder(ctx context.Context, request *LSPOrderRequest) (*LSPOrderResponse, error)
+	ListLSPOrders(limit uint64, offset uint64) ([]LSPOrder, error)
+	ListLSPs() ([]LSP, error)
+	AddLSP(addLSPRequest *AddLSPRequest) (*LSP, error)
+	DeleteLSP(id uint) error
+	GetLiquiditySettings() (*LiquiditySettings, error)
+	UpdateLiquiditySettings(settings *LiquiditySettings) error
+	ListLiquidityDecisions(limit uint64, offset uint64) ([]LiquidityDecision, error)
+	GetSwapInfo(ctx context.Context) (*SwapInfo, error)
+	SwapIn(ctx context.Context, swapInRequest *SwapInRequest) (*Swap, error)
+	SwapOut(ctx context.Context, swapOutRequest *SwapOutRequest) (*Swap, error)
+	ListSwaps(limit uint64, offset uint64) ([]Swap, error)
+	GetSwap(swapId string) (*Swap, error)
+	GetNip47QueueStats() (*Nip47QueueStats, error)
+	GetRelayStatus() *RelayStatus
+	ListAppRequests(appPubkey string, filters *ListAppRequestsFilters, limit uint64, offset uint64) (*ListAppRequestsResponse, error)
+	CreateConnectionRequest(createConnectionRequestRequest *CreateConnectionRequestRequest) (*ConnectionRequest, error)
+	ListConnectionRequests() ([]ConnectionRequest, error)
+	ApproveConnectionRequest(id uint) (*ApproveConnectionRequestResponse, error)
+	RejectConnectionRequest(id uint) error
+	HandleNip47Request(ctx context.Context, event *nostr.Event) (*Nip47HttpResponse, error)
 	CreateBackup(unlockPassword string, w io.Writer) error
 	RestoreBackup(unlockPassword string, r io.Reader) error
 	GetWalletCapabilities(ctx context.Context) (*WalletCapabilitiesResponse, error)
+	SendEcashToken(ctx context.Context, sendEcashTokenRequest *SendEcashTokenRequest) (*SendEcashTokenResponse, error)
+	ReceiveEcashToken(ctx context.Context, receiveEcashTokenRequest *ReceiveEcashTokenRequest) (*ReceiveEcashTokenResponse, error)
+	SwapEcashToDefaultMint(ctx context.Context, swapEcashRequest *SwapEcashRequest) error
 }
 
 type App struct {
@@ -71,6 +118,9 @@ type App struct {
 	Isolated      bool       `json:"isolated"`
 	Balance       uint64     `json:"balance"`
 	Metadata      Metadata   `json:"metadata,omitempty"`
+	WalletPubkey  *string    `json:"walletPubkey"`
+	// NIP-47 notification types sent to the app
+	NotificationTypes []string `json:"notificationTypes"`
 }
 
 type ListAppsResponse struct {
@@ -84,6 +134,8 @@ type UpdateAppRequest struct {
 	ExpiresAt     string   `json:"expiresAt"`
 	Scopes        []string `json:"scopes"`
 	Metadata      Metadata `json:"metadata,omitempty"`
+	// omit to keep the current notification types, empty to reset to the defaults
+	NotificationTypes []string `json:"notificationTypes"`
 }
 
 type CreateAppRequest struct {
@@ -96,6 +148,34 @@ type CreateAppRequest struct {
 	ReturnTo      string   `json:"returnTo"`
 	Isolated      bool     `json:"isolated"`
 	Metadata      Metadata `json:"metadata,omitempty"`
+	// give the connection its own wallet service key instead of the hub's shared key
+	UniqueWalletPubkey bool `json:"uniqueWalletPubkey"`
+	// NIP-47 notification types the app opts in to, omit for the defaults
+	NotificationTypes []string `json:"notificationTypes"`
+}
+
+type CreateConnectionRequestRequest struct {
+	// nostr+walletauth URI presented by the app
+	Uri string `json:"uri"`
+}
+
+type ConnectionRequest struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	Pubkey        string     `json:"pubkey"`
+	Scopes        []string   `json:"scopes"`
+	MaxAmountSat  uint64     `json:"maxAmount"`
+	BudgetRenewal string     `json:"budgetRenewal"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	Isolated      bool       `json:"isolated"`
+	ReturnTo      string     `json:"returnTo"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type ApproveConnectionRequestResponse struct {
+	App
+	// return_to URL of the app with the relay and wallet pubkey of the connection, empty if none was provided
+	ReturnTo string `json:"returnTo"`
 }
 
 type StartRequest struct {
@@ -115,7 +195,7 @@ type SetupRequest struct {
 	LNBackendType  string `json:"backendType"`
 	UnlockPassword string `json:"unlockPassword"`
 
-	// Breez / Greenlight
+	// Breez / Greenlight / Cashu
 	Mnemonic             string `json:"mnemonic"`
 	GreenlightInviteCode string `json:"greenlightInviteCode"`
 	NextBackupReminder   string `json:"nextBackupReminder"`
@@ -142,6 +222,7 @@ type CreateAppResponse struct {
 	PairingUri    string `json:"pairingUri"`
 	PairingSecret string `json:"pairingSecretKey"`
 	Pubkey        string `json:"pairingPublicKey"`
+	WalletPubkey  string `json:"walletPubkey"`
 	Id            uint   `json:"id"`
 	Name          string `json:"name"`
 	ReturnTo      string `json:"returnTo"`
@@ -184,8 +265,44 @@ type ChangeUnlockPasswordRequest struct {
 type ConnectPeerRequest = lnclient.ConnectPeerRequest
 type OpenChannelRequest = lnclient.OpenChannelRequest
 type OpenChannelResponse = lnclient.OpenChannelResponse
+type BatchOpenChannelRequest = lnclient.BatchOpenChannelRequest
+type BatchOpenChannelResponse = lnclient.BatchOpenChannelResponse
 type CloseChannelResponse = lnclient.CloseChannelResponse
+type PsbtChannelOpenResponse = lnclient.PsbtChannelOpen
+
+type VerifyPsbtChannelFundingRequest struct {
+	Psbt string `json:"psbt"`
+}
+
+// either the signed PSBT (base64) or the raw transaction (hex) must be provided
+type FinalizePsbtChannelOpenRequest struct {
+	SignedPsbt string `json:"signedPsbt"`
+	RawTx      string `json:"rawTx"`
+}
 type UpdateChannelRequest = lnclient.UpdateChannelRequest
+type ChannelPolicyUpdate = lnclient.ChannelPolicyUpdate
+
+// UpdateChannelsRequest applies the same policy to all channels
+type UpdateChannelsRequest = ChannelPolicyUpdate
+
+type UpdateChannelsResponse struct {
+	Channels []UpdateChannelResult `json:"channels"`
+}
+
+type UpdateChannelResult struct {
+	ChannelId string  `json:"channelId"`
+	NodeId    string  `json:"nodeId"`
+	Error     *string `json:"error"`
+}
+
+type RebalanceRequest struct {
+	OutgoingChannelId string `json:"outgoingChannelId"`
+	IncomingChannelId string `json:"incomingChannelId"`
+	AmountMsat        uint64 `json:"amountMsat"`
+	MaxFeeMsat        uint64 `json:"maxFeeMsat"`
+}
+
+type RebalanceResponse = Transaction
 
 type RedeemOnchainFundsRequest struct {
 	ToAddress string `json:"toAddress"`
@@ -195,9 +312,64 @@ type RedeemOnchainFundsRequest struct {
 
 type RedeemOnchainFundsResponse struct {
 	TxId string `json:"txId"`
+	// set instead of the transaction ID for sends made via a swap
+	SwapId string `json:"swapId,omitempty"`
+}
+
+type OnchainFeeEstimate = lnclient.OnchainFeeEstimate
+
+type Utxo = lnclient.Utxo
+
+// fees are paid at feeRate (sat/vB) if set, otherwise at the rate
+// recommended by the mempool API to confirm within targetConfirmations blocks
+type SendOnchainRequest struct {
+	Outputs             []lnclient.OnchainOutput `json:"outputs"`
+	Inputs              []lnclient.OutPoint      `json:"inputs"`
+	FeeRate             uint64                   `json:"feeRate"`
+	TargetConfirmations uint32                   `json:"targetConfirmations"`
+}
+
+type BumpOnchainFeeRequest struct {
+	TxId                string `json:"txId"`
+	FeeRate             uint64 `json:"feeRate"`
+	TargetConfirmations uint32 `json:"targetConfirmations"`
+}
+
+type OnchainTransaction struct {
+	TxId          string  `json:"txId"`
+	Type          string  `json:"type"`
+	State         string  `json:"state"`
+	Address       string  `json:"address"`
+	AmountSat     uint64  `json:"amountSat"`
+	FeeSat        *uint64 `json:"feeSat"`
+	SendAll       bool    `json:"sendAll"`
+	Confirmations uint32  `json:"confirmations"`
+	ChannelId     string  `json:"channelId,omitempty"`
+	ChannelAction string  `json:"channelAction,omitempty"`
+	Label         string  `json:"label,omitempty"`
+	// empty if not known
+	CreatedAt string `json:"createdAt"`
+}
+
+type OnchainLabel struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+type SetOnchainLabelRequest = OnchainLabel
+
+// HistoryItem is either a Lightning or an onchain transaction
+type HistoryItem struct {
+	Type      string              `json:"type"`
+	Timestamp string              `json:"timestamp"`
+	Lightning *Transaction        `json:"lightning,omitempty"`
+	Onchain   *OnchainTransaction `json:"onchain,omitempty"`
 }
 
 type OnchainBalanceResponse = lnclient.OnchainBalanceResponse
+
+type ForwardingStatsResponse = forwards.ForwardingStats
 type BalancesResponse = lnclient.BalancesResponse
 
 type SendPaymentResponse = Transaction
@@ -286,6 +458,28 @@ type MakeInvoiceRequest struct {
 	Description string `json:"description"`
 }
 
+type SendEcashTokenRequest struct {
+	Amount  uint64 `json:"amount"`
+	MintUrl string `json:"mintUrl"`
+}
+
+type SendEcashTokenResponse struct {
+	Token       string      `json:"token"`
+	Transaction Transaction `json:"transaction"`
+}
+
+type ReceiveEcashTokenRequest struct {
+	Token             string `json:"token"`
+	SwapToDefaultMint bool   `json:"swapToDefaultMint"`
+}
+
+type ReceiveEcashTokenResponse = Transaction
+
+type SwapEcashRequest struct {
+	FromMintUrl string `json:"fromMintUrl"`
+	Amount      uint64 `json:"amount"`
+}
+
 type ResetRouterRequest struct {
 	Key string `json:"key"`
 }
@@ -305,6 +499,8 @@ type LSPOrderRequest struct {
 	LSPType string `json:"lspType"`
 	LSPUrl  string `json:"lspUrl"`
 	Public  bool   `json:"public"`
+	// optional, a registered LSP to use instead of the type and url
+	LSPId uint `json:"lspId"`
 }
 
 type LSPOrderResponse struct {
@@ -315,6 +511,164 @@ type LSPOrderResponse struct {
 	OutgoingLiquidity uint64 `json:"outgoingLiquidity"`
 }
 
+type LSPOrder struct {
+	ID                   uint      `json:"id"`
+	LSPId                *uint     `json:"lspId"`
+	LSPName              string    `json:"lspName"`
+	LSPUrl               string    `json:"lspUrl"`
+	LSPType              string    `json:"lspType"`
+	OrderId              string    `json:"orderId"`
+	State                string    `json:"state"`
+	PaymentState         string    `json:"paymentState"`
+	Invoice              string    `json:"invoice"`
+	FeeSat               uint64    `json:"feeSat"`
+	LSPBalanceSat        uint64    `json:"lspBalanceSat"`
+	Public               bool      `json:"public"`
+	RefundOnchainAddress string    `json:"refundOnchainAddress"`
+	FundingOutpoint      string    `json:"fundingOutpoint"`
+	RefundState          string    `json:"refundState"`
+	CreatedAt            time.Time `json:"createdAt"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+type LSP struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Url       string    `json:"url"`
+	Pubkey    string    `json:"pubkey"`
+	Protocols []string  `json:"protocols"`
+	Network   string    `json:"network"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AddLSPRequest struct {
+	Name      string   `json:"name"`
+	Url       string   `json:"url"`
+	Pubkey    string   `json:"pubkey"`
+	Protocols []string `json:"protocols"`
+	Network   string   `json:"network"`
+}
+
+type LiquiditySettings = liquidity.Settings
+
+type LiquidityDecision struct {
+	ID            uint      `json:"id"`
+	Action        string    `json:"action"`
+	Reason        string    `json:"reason"`
+	ReceivableSat uint64    `json:"receivableSat"`
+	ThresholdSat  uint64    `json:"thresholdSat"`
+	AmountSat     uint64    `json:"amountSat"`
+	FeeSat        uint64    `json:"feeSat"`
+	Provider      string    `json:"provider"`
+	Invoice       string    `json:"invoice"`
+	DryRun        bool      `json:"dryRun"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type SwapInfo = swaps.SwapInfo
+
+type SwapInRequest struct {
+	Amount uint64 `json:"amount"`
+}
+
+type SwapOutRequest struct {
+	Amount uint64 `json:"amount"`
+	// optional, defaults to a new address of the onchain wallet
+	Destination string `json:"destination"`
+}
+
+type Swap struct {
+	SwapId             string    `json:"swapId"`
+	Type               string    `json:"type"`
+	State              string    `json:"state"`
+	ProviderStatus     string    `json:"providerStatus"`
+	AmountSat          uint64    `json:"amountSat"`
+	OnchainAmountSat   uint64    `json:"onchainAmountSat"`
+	PaymentHash        string    `json:"paymentHash"`
+	Invoice            string    `json:"invoice"`
+	LockupAddress      string    `json:"lockupAddress"`
+	TimeoutBlockHeight uint32    `json:"timeoutBlockHeight"`
+	LockupTxId         string    `json:"lockupTxId"`
+	ClaimTxId          string    `json:"claimTxId"`
+	DestinationAddress string    `json:"destinationAddress"`
+	FailureReason      string    `json:"failureReason"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+type ListAppRequestsFilters struct {
+	Method string
+	State  string
+	From   *time.Time
+	Until  *time.Time
+}
+
+type ListAppRequestsResponse struct {
+	Requests   []AppRequest `json:"requests"`
+	TotalCount int64        `json:"totalCount"`
+}
+
+type AppRequest struct {
+	ID           uint                    `json:"id"`
+	NostrId      string                  `json:"nostrId"`
+	Method       string                  `json:"method"`
+	State        string                  `json:"state"`
+	Params       json.RawMessage         `json:"params,omitempty"`
+	CreatedAt    time.Time               `json:"createdAt"`
+	UpdatedAt    time.Time               `json:"updatedAt"`
+	Responses    []AppRequestResponse    `json:"responses"`
+	Transactions []AppRequestTransaction `json:"transactions"`
+}
+
+type AppRequestResponse struct {
+	NostrId    string    `json:"nostrId"`
+	State      string    `json:"state"`
+	RepliedAt  time.Time `json:"repliedAt"`
+	RetryCount int       `json:"retryCount"`
+}
+
+type AppRequestTransaction struct {
+	ID          uint   `json:"id"`
+	Type        string `json:"type"`
+	State       string `json:"state"`
+	PaymentHash string `json:"paymentHash"`
+	AmountMsat  uint64 `json:"amount"`
+	FeeMsat     uint64 `json:"fee"`
+}
+
+type RelayStatus struct {
+	Url           string    `json:"url"`
+	Connected     bool      `json:"connected"`
+	AuthRequired  bool      `json:"authRequired"`
+	Authenticated bool      `json:"authenticated"`
+	AuthError     string    `json:"authError"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type Nip47HttpResponse struct {
+	// NIP-47 response events, multi_* requests have one per payment
+	Events []*nostr.Event `json:"events"`
+	// the events contain the RATE_LIMITED error response
+	RateLimited bool `json:"-"`
+}
+
+type Nip47QueueStats struct {
+	MaxWorkers        int                  `json:"maxWorkers"`
+	MaxPaymentWorkers int                  `json:"maxPaymentWorkers"`
+	Running           int                  `json:"running"`
+	RunningPayments   int                  `json:"runningPayments"`
+	Queued            int                  `json:"queued"`
+	Apps              []Nip47AppQueueStats `json:"apps"`
+}
+
+type Nip47AppQueueStats struct {
+	AppId     *uint  `json:"appId"`
+	AppName   string `json:"appName"`
+	AppPubkey string `json:"appPubkey"`
+	Queued    int    `json:"queued"`
+	Running   int    `json:"running"`
+}
+
 type WalletCapabilitiesResponse struct {
 	Scopes            []string `json:"scopes"`
 	Methods           []string `json:"methods"`
@@ -334,6 +688,11 @@ type Channel struct {
 	Confirmations                            *uint32     `json:"confirmations"`
 	ConfirmationsRequired                    *uint32     `json:"confirmationsRequired"`
 	ForwardingFeeBaseMsat                    uint32      `json:"forwardingFeeBaseMsat"`
+	ForwardingFeeProportionalMillionths      uint32      `json:"forwardingFeeProportionalMillionths"`
+	CltvExpiryDelta                          uint16      `json:"cltvExpiryDelta"`
+	HtlcMinimumMsat                          *uint64     `json:"htlcMinimumMsat"`
+	HtlcMaximumMsat                          *uint64     `json:"htlcMaximumMsat"`
+	ForceCloseAvoidanceMaxFeeSat             *uint64     `json:"forceCloseAvoidanceMaxFeeSat,omitempty"`
 	UnspendablePunishmentReserve             uint64      `json:"unspendablePunishmentReserve"`
 	CounterpartyUnspendablePunishmentReserve uint64      `json:"counterpartyUnspendablePunishmentReserve"`
 	Error                                    *string     `json:"error"`
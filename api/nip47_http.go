@@ -0,0 +1,27 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// HandleNip47Request handles a NIP-47 request event sent over HTTP by clients
+// which cannot keep a relay connection open
+func (api *api) HandleNip47Request(ctx context.Context, event *nostr.Event) (*Nip47HttpResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	result, err := api.svc.GetNip47Service().HandleHttpEvent(ctx, event, lnClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Nip47HttpResponse{
+		Events:      result.Events,
+		RateLimited: result.RateLimited,
+	}, nil
+}
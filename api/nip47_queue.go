@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) GetNip47QueueStats() (*Nip47QueueStats, error) {
+	stats := api.svc.GetNip47Service().GetRequestSchedulerStats()
+
+	queueStats := &Nip47QueueStats{
+		MaxWorkers:        stats.MaxWorkers,
+		MaxPaymentWorkers: stats.MaxPaymentWorkers,
+		Running:           stats.Running,
+		RunningPayments:   stats.RunningPayments,
+		Queued:            stats.Queued,
+		Apps:              []Nip47AppQueueStats{},
+	}
+	for _, appStats := range stats.Apps {
+		appQueueStats := Nip47AppQueueStats{
+			AppPubkey: appStats.AppPubkey,
+			Queued:    appStats.Queued,
+			Running:   appStats.Running,
+		}
+		// the connection can be deleted while its requests are queued
+		app := db.App{}
+		result := api.db.Limit(1).Find(&app, &db.App{NostrPubkey: appStats.AppPubkey})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			appQueueStats.AppId = &app.ID
+			appQueueStats.AppName = app.Name
+		}
+		queueStats.Apps = append(queueStats.Apps, appQueueStats)
+	}
+	return queueStats, nil
+}
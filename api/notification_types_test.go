@@ -0,0 +1,23 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotificationTypes(t *testing.T) {
+	// the node does not have to be running
+	api := &api{}
+
+	notificationTypes, err := api.parseNotificationTypes([]string{"payment_received", "budget_warning"})
+	assert.NoError(t, err)
+	assert.Equal(t, "payment_received budget_warning", notificationTypes)
+
+	notificationTypes, err = api.parseNotificationTypes(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, notificationTypes)
+
+	_, err = api.parseNotificationTypes([]string{"hold_invoice_accepted"})
+	assert.EqualError(t, err, "did not recognize requested notification type: hold_invoice_accepted")
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*RedeemOnchainFundsResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	// the estimate is only used for the history, the send should not fail because of it
+	var feeSat *uint64
+	if feeEstimator, ok := lnClient.(lnclient.OnchainFeeEstimator); ok {
+		feeEstimate, err := feeEstimator.EstimateOnchainFees(ctx, toAddress, amount, sendAll)
+		if err != nil {
+			logger.Logger.WithError(err).Warn("Failed to estimate onchain fees")
+		} else {
+			feeSat = &feeEstimate.Fee
+			if sendAll {
+				amount = feeEstimate.Amount
+			}
+		}
+	}
+
+	// the transaction ID of a send made via a swap is resolved later by the onchain service
+	var txId, swapId string
+	var err error
+	if swapSender, ok := lnClient.(lnclient.OnchainSwapSender); ok && !sendAll {
+		swapId, err = swapSender.PayOnchainViaSwap(ctx, toAddress, amount)
+	} else {
+		txId, err = lnClient.RedeemOnchainFunds(ctx, toAddress, amount, sendAll)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.db.Create(&db.OnchainTransaction{
+		TxId:      txId,
+		SwapId:    swapId,
+		Type:      constants.TRANSACTION_TYPE_OUTGOING,
+		State:     constants.TRANSACTION_STATE_PENDING,
+		Address:   toAddress,
+		AmountSat: amount,
+		FeeSat:    feeSat,
+		SendAll:   sendAll,
+	}).Error
+	if err != nil {
+		// the funds were already sent so only log the error
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"txId":   txId,
+			"swapId": swapId,
+		}).Error("Failed to save onchain transaction")
+	}
+
+	return &RedeemOnchainFundsResponse{
+		TxId:   txId,
+		SwapId: swapId,
+	}, nil
+}
+
+func (api *api) EstimateOnchainFees(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*OnchainFeeEstimate, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	feeEstimator, ok := lnClient.(lnclient.OnchainFeeEstimator)
+	if !ok {
+		return nil, errors.New("fee estimation is not supported by this backend")
+	}
+	return feeEstimator.EstimateOnchainFees(ctx, toAddress, amount, sendAll)
+}
+
+// ListOnchainTransactions merges the transactions known by the backend with the sends made
+// through the hub, which may not be visible to the backend yet (or at all, e.g. swaps).
+// The states of the stored sends are updated in the background by the onchain service.
+func (api *api) ListOnchainTransactions(ctx context.Context) ([]OnchainTransaction, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	backendTransactions, err := lnClient.ListOnchainTransactions(ctx)
+	if err != nil {
+		// the sends made through the hub can still be listed
+		logger.Logger.WithError(err).Error("Failed to list onchain transactions from backend")
+		backendTransactions = nil
+	}
+
+	dbOnchainTransactions := []db.OnchainTransaction{}
+	err = api.db.Order("created_at desc").Find(&dbOnchainTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := api.getOnchainLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeOnchainTransactions(backendTransactions, dbOnchainTransactions, labels), nil
+}
+
+func mergeOnchainTransactions(backendTransactions []lnclient.OnchainTransaction, dbOnchainTransactions []db.OnchainTransaction, labels onchainLabels) []OnchainTransaction {
+	dbOnchainTransactionsByTxId := map[string][]db.OnchainTransaction{}
+	for _, dbOnchainTransaction := range dbOnchainTransactions {
+		dbOnchainTransactionsByTxId[dbOnchainTransaction.TxId] = append(dbOnchainTransactionsByTxId[dbOnchainTransaction.TxId], dbOnchainTransaction)
+	}
+
+	onchainTransactions := []OnchainTransaction{}
+	for _, backendTransaction := range backendTransactions {
+		state := constants.TRANSACTION_STATE_PENDING
+		if backendTransaction.Confirmations > 0 {
+			state = constants.TRANSACTION_STATE_SETTLED
+		}
+
+		onchainTransaction := OnchainTransaction{
+			TxId:          backendTransaction.TxId,
+			Type:          backendTransaction.Type,
+			State:         state,
+			Address:       backendTransaction.Address,
+			AmountSat:     backendTransaction.AmountSat,
+			Confirmations: backendTransaction.Confirmations,
+			ChannelId:     backendTransaction.ChannelId,
+			ChannelAction: backendTransaction.ChannelAction,
+		}
+		if backendTransaction.FeeSat > 0 {
+			feeSat := backendTransaction.FeeSat
+			onchainTransaction.FeeSat = &feeSat
+		}
+		if backendTransaction.Timestamp > 0 {
+			onchainTransaction.CreatedAt = time.Unix(backendTransaction.Timestamp, 0).Format(time.RFC3339)
+		}
+
+		if dbOnchainTransactions, ok := dbOnchainTransactionsByTxId[backendTransaction.TxId]; ok && backendTransaction.TxId != "" {
+			delete(dbOnchainTransactionsByTxId, backendTransaction.TxId)
+			onchainTransaction.Address = dbOnchainTransactions[0].Address
+			onchainTransaction.SendAll = dbOnchainTransactions[0].SendAll
+			if onchainTransaction.FeeSat == nil {
+				onchainTransaction.FeeSat = dbOnchainTransactions[0].FeeSat
+			}
+			if onchainTransaction.CreatedAt == "" {
+				onchainTransaction.CreatedAt = dbOnchainTransactions[0].CreatedAt.Format(time.RFC3339)
+			}
+		}
+
+		onchainTransaction.Label = labels.get(onchainTransaction.TxId, onchainTransaction.Address)
+		if onchainTransaction.Label == "" {
+			onchainTransaction.Label = backendTransaction.Label
+		}
+		onchainTransactions = append(onchainTransactions, onchainTransaction)
+	}
+
+	for _, dbOnchainTransactions := range dbOnchainTransactionsByTxId {
+		for _, dbOnchainTransaction := range dbOnchainTransactions {
+			onchainTransactions = append(onchainTransactions, OnchainTransaction{
+				TxId:      dbOnchainTransaction.TxId,
+				Type:      dbOnchainTransaction.Type,
+				State:     dbOnchainTransaction.State,
+				Address:   dbOnchainTransaction.Address,
+				AmountSat: dbOnchainTransaction.AmountSat,
+				FeeSat:    dbOnchainTransaction.FeeSat,
+				SendAll:   dbOnchainTransaction.SendAll,
+				CreatedAt: dbOnchainTransaction.CreatedAt.Format(time.RFC3339),
+				Label:     labels.get(dbOnchainTransaction.TxId, dbOnchainTransaction.Address),
+			})
+		}
+	}
+
+	// newest first, transactions without a known time last
+	sort.SliceStable(onchainTransactions, func(i, j int) bool {
+		return parseTime(onchainTransactions[i].CreatedAt).After(parseTime(onchainTransactions[j].CreatedAt))
+	})
+
+	return onchainTransactions
+}
+
+func (api *api) ListOnchainLabels(ctx context.Context, labelType string) ([]OnchainLabel, error) {
+	dbOnchainLabels := []db.OnchainLabel{}
+	tx := api.db.Order("label asc")
+	if labelType != "" {
+		tx = tx.Where("type = ?", labelType)
+	}
+	err := tx.Find(&dbOnchainLabels).Error
+	if err != nil {
+		return nil, err
+	}
+
+	onchainLabels := []OnchainLabel{}
+	for _, dbOnchainLabel := range dbOnchainLabels {
+		onchainLabels = append(onchainLabels, OnchainLabel{
+			Type:  dbOnchainLabel.Type,
+			Value: dbOnchainLabel.Value,
+			Label: dbOnchainLabel.Label,
+		})
+	}
+	return onchainLabels, nil
+}
+
+// SetOnchainLabel creates or updates a label, an empty label removes it
+func (api *api) SetOnchainLabel(ctx context.Context, setOnchainLabelRequest *SetOnchainLabelRequest) error {
+	if setOnchainLabelRequest.Type != db.ONCHAIN_LABEL_TYPE_TRANSACTION && setOnchainLabelRequest.Type != db.ONCHAIN_LABEL_TYPE_ADDRESS {
+		return fmt.Errorf("invalid label type: %s", setOnchainLabelRequest.Type)
+	}
+	if setOnchainLabelRequest.Value == "" {
+		return errors.New("no transaction ID or address")
+	}
+
+	if setOnchainLabelRequest.Label == "" {
+		return api.db.Where("type = ? AND value = ?", setOnchainLabelRequest.Type, setOnchainLabelRequest.Value).Delete(&db.OnchainLabel{}).Error
+	}
+
+	return api.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "value"}},
+		DoUpdates: clause.AssignmentColumns([]string{"label", "updated_at"}),
+	}).Create(&db.OnchainLabel{
+		Type:  setOnchainLabelRequest.Type,
+		Value: setOnchainLabelRequest.Value,
+		Label: setOnchainLabelRequest.Label,
+	}).Error
+}
+
+type onchainLabels map[string]map[string]string
+
+// transaction labels take precedence over address labels
+func (labels onchainLabels) get(txId string, address string) string {
+	if label, ok := labels[db.ONCHAIN_LABEL_TYPE_TRANSACTION][txId]; ok {
+		return label
+	}
+	return labels[db.ONCHAIN_LABEL_TYPE_ADDRESS][address]
+}
+
+func (api *api) getOnchainLabels() (onchainLabels, error) {
+	dbOnchainLabels := []db.OnchainLabel{}
+	err := api.db.Find(&dbOnchainLabels).Error
+	if err != nil {
+		return nil, err
+	}
+	labels := onchainLabels{
+		db.ONCHAIN_LABEL_TYPE_TRANSACTION: {},
+		db.ONCHAIN_LABEL_TYPE_ADDRESS:     {},
+	}
+	for _, dbOnchainLabel := range dbOnchainLabels {
+		if _, ok := labels[dbOnchainLabel.Type]; ok {
+			labels[dbOnchainLabel.Type][dbOnchainLabel.Value] = dbOnchainLabel.Label
+		}
+	}
+	return labels, nil
+}
+
+func (api *api) ListUtxos(ctx context.Context) ([]Utxo, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	coinController, ok := lnClient.(lnclient.OnchainCoinController)
+	if !ok {
+		return nil, errors.New("coin control is not supported by this backend")
+	}
+	return coinController.ListUtxos(ctx)
+}
+
+func (api *api) SendOnchain(ctx context.Context, sendOnchainRequest *SendOnchainRequest) (*RedeemOnchainFundsResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	coinController, ok := lnClient.(lnclient.OnchainCoinController)
+	if !ok {
+		return nil, errors.New("coin control is not supported by this backend")
+	}
+
+	feeRate, err := api.getOnchainFeeRate(sendOnchainRequest.FeeRate, sendOnchainRequest.TargetConfirmations)
+	if err != nil {
+		return nil, err
+	}
+
+	txId, err := coinController.SendOnchain(ctx, &lnclient.SendOnchainRequest{
+		Outputs: sendOnchainRequest.Outputs,
+		Inputs:  sendOnchainRequest.Inputs,
+		FeeRate: feeRate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// one entry per output, the fee is not known upfront
+	for _, output := range sendOnchainRequest.Outputs {
+		err = api.db.Create(&db.OnchainTransaction{
+			TxId:      txId,
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			State:     constants.TRANSACTION_STATE_PENDING,
+			Address:   output.Address,
+			AmountSat: output.AmountSat,
+		}).Error
+		if err != nil {
+			// the funds were already sent so only log the error
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"txId": txId,
+			}).Error("Failed to save onchain transaction")
+		}
+	}
+
+	return &RedeemOnchainFundsResponse{
+		TxId: txId,
+	}, nil
+}
+
+func (api *api) BumpOnchainFee(ctx context.Context, bumpOnchainFeeRequest *BumpOnchainFeeRequest) (*RedeemOnchainFundsResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	coinController, ok := lnClient.(lnclient.OnchainCoinController)
+	if !ok {
+		return nil, errors.New("coin control is not supported by this backend")
+	}
+
+	feeRate, err := api.getOnchainFeeRate(bumpOnchainFeeRequest.FeeRate, bumpOnchainFeeRequest.TargetConfirmations)
+	if err != nil {
+		return nil, err
+	}
+
+	txId, err := coinController.BumpOnchainFee(ctx, bumpOnchainFeeRequest.TxId, feeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if txId != bumpOnchainFeeRequest.TxId {
+		// the transaction was replaced, the sends and labels now belong to the replacement
+		err = api.db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&db.OnchainTransaction{}).Where("tx_id = ?", bumpOnchainFeeRequest.TxId).Update("tx_id", txId).Error
+			if err != nil {
+				return err
+			}
+			return tx.Model(&db.OnchainLabel{}).Where("type = ? AND value = ?", db.ONCHAIN_LABEL_TYPE_TRANSACTION, bumpOnchainFeeRequest.TxId).Update("value", txId).Error
+		})
+		if err != nil {
+			// the replacement was already published so only log the error
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"txId":            bumpOnchainFeeRequest.TxId,
+				"replacementTxId": txId,
+			}).Error("Failed to update replaced onchain transaction")
+		}
+	}
+
+	return &RedeemOnchainFundsResponse{
+		TxId: txId,
+	}, nil
+}
+
+// getOnchainFeeRate returns the fee rate in sat/vB, either as given or
+// recommended by the mempool API for the target number of confirmations
+func (api *api) getOnchainFeeRate(feeRate uint64, targetConfirmations uint32) (uint64, error) {
+	if feeRate > 0 {
+		return feeRate, nil
+	}
+	if targetConfirmations == 0 {
+		return 0, errors.New("either a fee rate or target confirmations must be set")
+	}
+
+	response, err := api.RequestMempoolApi("/v1/fees/recommended")
+	if err != nil {
+		return 0, err
+	}
+	recommendedFees, ok := response.(map[string]interface{})
+	if !ok {
+		return 0, errors.New("unexpected recommended fees response")
+	}
+
+	// mempool recommends fees for the next block, ~30 minutes, ~1 hour and no priority
+	feeKey := "economyFee"
+	switch {
+	case targetConfirmations <= 1:
+		feeKey = "fastestFee"
+	case targetConfirmations <= 3:
+		feeKey = "halfHourFee"
+	case targetConfirmations <= 6:
+		feeKey = "hourFee"
+	}
+
+	recommendedFeeRate, ok := recommendedFees[feeKey].(float64)
+	if !ok || recommendedFeeRate <= 0 {
+		return 0, fmt.Errorf("no recommended fee rate for %d confirmations", targetConfirmations)
+	}
+	return uint64(recommendedFeeRate), nil
+}
+
+// parseTime returns the zero time for empty or invalid RFC3339 timestamps
+func parseTime(timestamp string) time.Time {
+	parsedTime, _ := time.Parse(time.RFC3339, timestamp)
+	return parsedTime
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+)
+
+func TestMergeOnchainTransactions(t *testing.T) {
+	feeSat := uint64(150)
+	createdAt := time.Date(2024, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	backendTransactions := []lnclient.OnchainTransaction{
+		{
+			TxId:          "deposit",
+			Type:          constants.TRANSACTION_TYPE_INCOMING,
+			AmountSat:     100_000,
+			Confirmations: 3,
+			Timestamp:     createdAt.Add(-time.Hour).Unix(),
+			Address:       "bc1qreceive",
+		},
+		{
+			TxId:      "send",
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			AmountSat: 50_150,
+			Timestamp: createdAt.Unix(),
+			Address:   "bc1qbackenddestination",
+			Label:     "backend label",
+		},
+	}
+	dbOnchainTransactions := []db.OnchainTransaction{
+		{
+			TxId:      "send",
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			State:     constants.TRANSACTION_STATE_PENDING,
+			Address:   "bc1qdestination",
+			AmountSat: 50_000,
+			FeeSat:    &feeSat,
+			CreatedAt: createdAt,
+		},
+		{
+			SwapId:    "swap",
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			State:     constants.TRANSACTION_STATE_PENDING,
+			Address:   "bc1qswapdestination",
+			AmountSat: 20_000,
+			CreatedAt: createdAt.Add(time.Hour),
+		},
+	}
+	labels := onchainLabels{
+		db.ONCHAIN_LABEL_TYPE_TRANSACTION: {},
+		db.ONCHAIN_LABEL_TYPE_ADDRESS:     {"bc1qreceive": "savings"},
+	}
+
+	onchainTransactions := mergeOnchainTransactions(backendTransactions, dbOnchainTransactions, labels)
+	assert.Equal(t, 3, len(onchainTransactions))
+
+	// the swap is not known by the backend yet
+	assert.Equal(t, "", onchainTransactions[0].TxId)
+	assert.Equal(t, "bc1qswapdestination", onchainTransactions[0].Address)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, onchainTransactions[0].State)
+
+	assert.Equal(t, "send", onchainTransactions[1].TxId)
+	assert.Equal(t, "bc1qdestination", onchainTransactions[1].Address)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, onchainTransactions[1].State)
+	assert.Equal(t, &feeSat, onchainTransactions[1].FeeSat)
+	assert.Equal(t, "backend label", onchainTransactions[1].Label)
+
+	assert.Equal(t, "deposit", onchainTransactions[2].TxId)
+	assert.Equal(t, "bc1qreceive", onchainTransactions[2].Address)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, onchainTransactions[2].State)
+	assert.Equal(t, "savings", onchainTransactions[2].Label)
+}
+
+func TestMergeOnchainTransactions_UserLabelTakesPrecedence(t *testing.T) {
+	backendTransactions := []lnclient.OnchainTransaction{
+		{TxId: "send", Type: constants.TRANSACTION_TYPE_OUTGOING, Label: "backend label"},
+	}
+	labels := onchainLabels{
+		db.ONCHAIN_LABEL_TYPE_TRANSACTION: {"send": "rent"},
+		db.ONCHAIN_LABEL_TYPE_ADDRESS:     {},
+	}
+
+	onchainTransactions := mergeOnchainTransactions(backendTransactions, nil, labels)
+	assert.Equal(t, 1, len(onchainTransactions))
+	assert.Equal(t, "rent", onchainTransactions[0].Label)
+}
+
+func TestMergeOnchainTransactions_NoBackendTransactions(t *testing.T) {
+	// e.g. the backend failed to list its transactions
+	dbOnchainTransactions := []db.OnchainTransaction{
+		{
+			TxId:      "send",
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			State:     constants.TRANSACTION_STATE_SETTLED,
+			Address:   "bc1qdestination",
+			AmountSat: 50_000,
+			CreatedAt: time.Now(),
+		},
+	}
+	labels := onchainLabels{
+		db.ONCHAIN_LABEL_TYPE_TRANSACTION: {},
+		db.ONCHAIN_LABEL_TYPE_ADDRESS:     {},
+	}
+
+	onchainTransactions := mergeOnchainTransactions(nil, dbOnchainTransactions, labels)
+	assert.Equal(t, 1, len(onchainTransactions))
+	assert.Equal(t, "send", onchainTransactions[0].TxId)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, onchainTransactions[0].State)
+}
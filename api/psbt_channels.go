@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// channel opens funded by an external wallet:
+// start -> (optionally) verify the funded PSBT -> finalize with the signed PSBT or raw transaction
+
+func (api *api) StartPsbtChannelOpen(ctx context.Context, openChannelRequest *OpenChannelRequest) (*PsbtChannelOpenResponse, error) {
+	psbtChannelFunder, err := api.getPsbtChannelFunder()
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"peer_id": openChannelRequest.Pubkey,
+		"amount":  openChannelRequest.Amount,
+	}).Info("Starting PSBT channel open")
+	return psbtChannelFunder.StartPsbtChannelOpen(ctx, openChannelRequest)
+}
+
+func (api *api) VerifyPsbtChannelFunding(ctx context.Context, pendingChannelId string, verifyPsbtChannelFundingRequest *VerifyPsbtChannelFundingRequest) error {
+	psbtChannelFunder, err := api.getPsbtChannelFunder()
+	if err != nil {
+		return err
+	}
+	if verifyPsbtChannelFundingRequest.Psbt == "" {
+		return errors.New("no PSBT provided")
+	}
+	return psbtChannelFunder.VerifyPsbtChannelFunding(ctx, pendingChannelId, verifyPsbtChannelFundingRequest.Psbt)
+}
+
+func (api *api) FinalizePsbtChannelOpen(ctx context.Context, pendingChannelId string, finalizePsbtChannelOpenRequest *FinalizePsbtChannelOpenRequest) (*OpenChannelResponse, error) {
+	psbtChannelFunder, err := api.getPsbtChannelFunder()
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger.WithField("pending_channel_id", pendingChannelId).Info("Finalizing PSBT channel open")
+	return psbtChannelFunder.FinalizePsbtChannelOpen(ctx, pendingChannelId, finalizePsbtChannelOpenRequest.SignedPsbt, finalizePsbtChannelOpenRequest.RawTx)
+}
+
+func (api *api) CancelPsbtChannelOpen(ctx context.Context, pendingChannelId string) error {
+	psbtChannelFunder, err := api.getPsbtChannelFunder()
+	if err != nil {
+		return err
+	}
+	logger.Logger.WithField("pending_channel_id", pendingChannelId).Info("Cancelling PSBT channel open")
+	return psbtChannelFunder.CancelPsbtChannelOpen(ctx, pendingChannelId)
+}
+
+func (api *api) getPsbtChannelFunder() (lnclient.PsbtChannelFunder, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	psbtChannelFunder, ok := lnClient.(lnclient.PsbtChannelFunder)
+	if !ok {
+		return nil, errors.New("PSBT channel funding is not supported by this backend")
+	}
+	return psbtChannelFunder, nil
+}
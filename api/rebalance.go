@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/constants"
+)
+
+func (api *api) Rebalance(ctx context.Context, rebalanceRequest *RebalanceRequest) (*RebalanceResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transaction, err := api.svc.GetTransactionsService().Rebalance(ctx, rebalanceRequest.OutgoingChannelId, rebalanceRequest.IncomingChannelId, rebalanceRequest.AmountMsat, rebalanceRequest.MaxFeeMsat, api.svc.GetLNClient())
+	if err != nil {
+		return nil, err
+	}
+	return toApiTransaction(transaction), nil
+}
+
+// ListRebalances also returns pending and failed rebalances
+func (api *api) ListRebalances(ctx context.Context, limit uint64, offset uint64) (*ListTransactionsResponse, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	transactionType := constants.TRANSACTION_TYPE_REBALANCE
+	transactions, err := api.svc.GetTransactionsService().ListTransactions(ctx, 0, 0, limit, offset, true, &transactionType, api.svc.GetLNClient(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apiTransactions := []Transaction{}
+	for _, transaction := range transactions {
+		apiTransactions = append(apiTransactions, *toApiTransaction(&transaction))
+	}
+
+	return &apiTransactions, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// used if no limit is given, a limit of 0 would not return any requests
+const defaultAppRequestsLimit = 20
+
+func (api *api) ListAppRequests(appPubkey string, filters *ListAppRequestsFilters, limit uint64, offset uint64) (*ListAppRequestsResponse, error) {
+	if limit == 0 {
+		limit = defaultAppRequestsLimit
+	}
+
+	app := db.App{}
+	err := api.db.Where("nostr_pubkey = ?", appPubkey).First(&app).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("app does not exist")
+		}
+		return nil, err
+	}
+
+	query := api.db.Model(&db.RequestEvent{}).Where("app_id = ?", app.ID)
+	if filters.Method != "" {
+		query = query.Where("method = ?", filters.Method)
+	}
+	if filters.State != "" {
+		query = query.Where("state = ?", filters.State)
+	}
+	if filters.From != nil {
+		query = query.Where("created_at >= ?", *filters.From)
+	}
+	if filters.Until != nil {
+		query = query.Where("created_at < ?", *filters.Until)
+	}
+
+	var totalCount int64
+	err = query.Count(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	requestEvents := []db.RequestEvent{}
+	err = query.Order("id desc").Limit(int(limit)).Offset(int(offset)).Find(&requestEvents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	requestEventIds := []uint{}
+	for _, requestEvent := range requestEvents {
+		requestEventIds = append(requestEventIds, requestEvent.ID)
+	}
+
+	// a request can have multiple responses and transactions, e.g. multi_pay_invoice
+	responseEvents := []db.ResponseEvent{}
+	err = api.db.Where("request_id IN ?", requestEventIds).Order("id").Find(&responseEvents).Error
+	if err != nil {
+		return nil, err
+	}
+	responsesMap := make(map[uint][]AppRequestResponse)
+	for _, responseEvent := range responseEvents {
+		responsesMap[responseEvent.RequestId] = append(responsesMap[responseEvent.RequestId], AppRequestResponse{
+			NostrId:    responseEvent.NostrId,
+			State:      responseEvent.State,
+			RepliedAt:  responseEvent.RepliedAt,
+			RetryCount: responseEvent.RetryCount,
+		})
+	}
+
+	transactions := []db.Transaction{}
+	err = api.db.Where("request_event_id IN ?", requestEventIds).Order("id").Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	transactionsMap := make(map[uint][]AppRequestTransaction)
+	for _, transaction := range transactions {
+		transactionsMap[*transaction.RequestEventId] = append(transactionsMap[*transaction.RequestEventId], AppRequestTransaction{
+			ID:          transaction.ID,
+			Type:        transaction.Type,
+			State:       transaction.State,
+			PaymentHash: transaction.PaymentHash,
+			AmountMsat:  transaction.AmountMsat,
+			FeeMsat:     transaction.FeeMsat,
+		})
+	}
+
+	appRequests := []AppRequest{}
+	for _, requestEvent := range requestEvents {
+		appRequest := AppRequest{
+			ID:           requestEvent.ID,
+			NostrId:      requestEvent.NostrId,
+			Method:       requestEvent.Method,
+			State:        requestEvent.State,
+			CreatedAt:    requestEvent.CreatedAt,
+			UpdatedAt:    requestEvent.UpdatedAt,
+			Responses:    responsesMap[requestEvent.ID],
+			Transactions: transactionsMap[requestEvent.ID],
+		}
+		if appRequest.Responses == nil {
+			appRequest.Responses = []AppRequestResponse{}
+		}
+		if appRequest.Transactions == nil {
+			appRequest.Transactions = []AppRequestTransaction{}
+		}
+
+		// the decrypted request is only stored once it could be parsed
+		if requestEvent.ContentData != "" {
+			content := struct {
+				Params json.RawMessage `json:"params"`
+			}{}
+			jsonErr := json.Unmarshal([]byte(requestEvent.ContentData), &content)
+			if jsonErr != nil {
+				logger.Logger.WithError(jsonErr).WithFields(logrus.Fields{
+					"request_event_id": requestEvent.ID,
+				}).Error("Failed to deserialize request content")
+			}
+			appRequest.Params = content.Params
+		}
+
+		appRequests = append(appRequests, appRequest)
+	}
+
+	return &ListAppRequestsResponse{
+		Requests:   appRequests,
+		TotalCount: totalCount,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestListAppRequests_DefaultLimit(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	api := &api{db: svc.DB, cfg: svc.Cfg}
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	for i := 0; i < defaultAppRequestsLimit+5; i++ {
+		err = svc.DB.Create(&db.RequestEvent{NostrId: fmt.Sprintf("event%d", i), AppId: &app.ID, State: db.REQUEST_EVENT_STATE_HANDLER_EXECUTED}).Error
+		assert.NoError(t, err)
+	}
+
+	// a limit of 0 is treated as no limit given
+	appRequests, err := api.ListAppRequests(app.NostrPubkey, &ListAppRequestsFilters{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, appRequests.Requests, defaultAppRequestsLimit)
+	assert.Equal(t, int64(defaultAppRequestsLimit+5), appRequests.TotalCount)
+}
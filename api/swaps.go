@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) GetSwapInfo(ctx context.Context) (*SwapInfo, error) {
+	return api.svc.GetSwapsService().GetInfo(ctx)
+}
+
+func (api *api) SwapIn(ctx context.Context, swapInRequest *SwapInRequest) (*Swap, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	swap, err := api.svc.GetSwapsService().SwapIn(ctx, api.svc.GetLNClient(), swapInRequest.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return toApiSwap(swap), nil
+}
+
+func (api *api) SwapOut(ctx context.Context, swapOutRequest *SwapOutRequest) (*Swap, error) {
+	if api.svc.GetLNClient() == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	swap, err := api.svc.GetSwapsService().SwapOut(ctx, api.svc.GetLNClient(), swapOutRequest.Amount, swapOutRequest.Destination)
+	if err != nil {
+		return nil, err
+	}
+	return toApiSwap(swap), nil
+}
+
+func (api *api) ListSwaps(limit uint64, offset uint64) ([]Swap, error) {
+	swaps, err := api.svc.GetSwapsService().ListSwaps(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	apiSwaps := []Swap{}
+	for _, swap := range swaps {
+		apiSwaps = append(apiSwaps, *toApiSwap(&swap))
+	}
+	return apiSwaps, nil
+}
+
+func (api *api) GetSwap(swapId string) (*Swap, error) {
+	swap, err := api.svc.GetSwapsService().GetSwap(swapId)
+	if err != nil {
+		return nil, err
+	}
+	return toApiSwap(swap), nil
+}
+
+func toApiSwap(swap *db.Swap) *Swap {
+	return &Swap{
+		SwapId:             swap.SwapId,
+		Type:               swap.Type,
+		State:              swap.State,
+		ProviderStatus:     swap.ProviderStatus,
+		AmountSat:          swap.AmountSat,
+		OnchainAmountSat:   swap.OnchainAmountSat,
+		PaymentHash:        swap.PaymentHash,
+		Invoice:            swap.Invoice,
+		LockupAddress:      swap.LockupAddress,
+		TimeoutBlockHeight: swap.TimeoutBlockHeight,
+		LockupTxId:         swap.LockupTxId,
+		ClaimTxId:          swap.ClaimTxId,
+		DestinationAddress: swap.DestinationAddress,
+		FailureReason:      swap.FailureReason,
+		CreatedAt:          swap.CreatedAt,
+		UpdatedAt:          swap.UpdatedAt,
+	}
+}
@@ -71,6 +71,11 @@ func (cfg *config) init(env *AppConfig) {
 		cfg.SetUpdate("PhoenixdAuthorization", cfg.Env.PhoenixdAuthorization, "")
 	}
 
+	// swaps are not available on networks without a default swap service, unless one is configured
+	if cfg.Env.SwapServiceUrl == "" {
+		cfg.Env.SwapServiceUrl = defaultSwapServiceUrls[cfg.Env.LDKNetwork]
+	}
+
 	// set the JWT secret to the one from the env
 	// if no JWT secret is configured we create a random one and store it in the DB
 	cfg.JWTSecret = cfg.Env.JWTSecret
@@ -13,6 +13,12 @@ const (
 	OnchainAddressKey = "OnchainAddress"
 )
 
+// swap services used if SWAP_SERVICE_URL is not set, by LDK_NETWORK
+var defaultSwapServiceUrls = map[string]string{
+	"bitcoin": "https://api.boltz.exchange",
+	"testnet": "https://api.testnet.boltz.exchange",
+}
+
 type AppConfig struct {
 	Relay                 string `envconfig:"RELAY" default:"wss://relay.getalby.com/v1"`
 	LNBackendType         string `envconfig:"LN_BACKEND_TYPE"`
@@ -43,6 +49,15 @@ type AppConfig struct {
 	DdProfilerEnabled     bool   `envconfig:"DD_PROFILER_ENABLED" default:"false"`
 	EnableAdvancedSetup   bool   `envconfig:"ENABLE_ADVANCED_SETUP" default:"true"`
 	AutoUnlockPassword    string `envconfig:"AUTO_UNLOCK_PASSWORD"`
+	LSPS2Lsp              string `envconfig:"LSPS2_LSP"` // node URI (pubkey@host:port) of the LSPS2 LSP
+	LSPS2Token            string `envconfig:"LSPS2_TOKEN"`
+	SwapServiceUrl        string `envconfig:"SWAP_SERVICE_URL"`
+	NIP47MaxRequestAge    int    `envconfig:"NIP47_MAX_REQUEST_AGE" default:"0"` // in seconds, 0 to accept requests of any age
+	NIP47Workers          int    `envconfig:"NIP47_WORKERS" default:"20"`
+	NIP47AppConcurrency   int    `envconfig:"NIP47_APP_CONCURRENCY" default:"5"`
+	NIP47AppMaxQueue      int    `envconfig:"NIP47_APP_MAX_QUEUE" default:"100"` // 0 for no limit
+	NIP47AppRateLimit     int    `envconfig:"NIP47_APP_RATE_LIMIT" default:"10"` // requests per second, 0 for no limit
+	NIP47RetentionDays    int    `envconfig:"NIP47_RETENTION_DAYS" default:"90"` // 0 to keep events forever
 }
 
 func (c *AppConfig) IsDefaultClientId() bool {
@@ -5,6 +5,8 @@ package constants
 const (
 	TRANSACTION_TYPE_INCOMING = "incoming"
 	TRANSACTION_TYPE_OUTGOING = "outgoing"
+	// payment from one of the node's channels to another, not part of any app's history
+	TRANSACTION_TYPE_REBALANCE = "rebalance"
 
 	TRANSACTION_STATE_PENDING = "PENDING"
 	TRANSACTION_STATE_SETTLED = "SETTLED"
@@ -20,10 +22,10 @@ const (
 )
 
 const (
-	PAY_INVOICE_SCOPE       = "pay_invoice" // also covers pay_keysend and multi_* payment methods
+	PAY_INVOICE_SCOPE       = "pay_invoice" // also covers pay_keysend, send_cashu_token, get_budget and multi_* payment methods
 	GET_BALANCE_SCOPE       = "get_balance"
 	GET_INFO_SCOPE          = "get_info"
-	MAKE_INVOICE_SCOPE      = "make_invoice"
+	MAKE_INVOICE_SCOPE      = "make_invoice" // also covers receive_cashu_token
 	LOOKUP_INVOICE_SCOPE    = "lookup_invoice"
 	LIST_TRANSACTIONS_SCOPE = "list_transactions"
 	SIGN_MESSAGE_SCOPE      = "sign_message"
@@ -48,4 +50,5 @@ const (
 	ERROR_BAD_REQUEST          = "BAD_REQUEST"
 	ERROR_NOT_FOUND            = "NOT_FOUND"
 	ERROR_OTHER                = "OTHER"
+	ERROR_RATE_LIMITED         = "RATE_LIMITED"
 )
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table to keep track of onchain sends made by the hub
+var _202409031512_onchain_transactions = &gormigrate.Migration{
+	ID: "202409031512_onchain_transactions",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE onchain_transactions(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	tx_id text,
+	type text,
+	state text,
+	address text,
+	amount_sat integer,
+	fee_sat integer,
+	send_all boolean,
+	created_at datetime,
+	updated_at datetime
+);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
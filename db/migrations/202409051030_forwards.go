@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table to store HTLCs routed through the node
+var _202409051030_forwards = &gormigrate.Migration{
+	ID: "202409051030_forwards",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE forwards(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	external_id text,
+	incoming_channel_id text,
+	outgoing_channel_id text,
+	incoming_peer text,
+	outgoing_peer text,
+	amount_in_msat integer,
+	amount_out_msat integer,
+	fee_msat integer,
+	failed boolean,
+	failure_reason text,
+	forwarded_at datetime,
+	created_at datetime
+);
+CREATE UNIQUE INDEX idx_forwards_external_id ON forwards(external_id);
+CREATE INDEX idx_forwards_forwarded_at ON forwards(forwarded_at);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table to store user labels for onchain transactions and addresses
+var _202409061200_onchain_labels = &gormigrate.Migration{
+	ID: "202409061200_onchain_labels",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE onchain_labels(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	type text,
+	value text,
+	label text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX idx_onchain_labels_type_value ON onchain_labels(type, value);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds tables for the LSP registry and LSPS1 orders.
+// The registry is seeded with the LSPs which were previously hard-coded as trusted zero-conf peers,
+// their LSPS1 urls are not known so they cannot be used to order channels.
+var _202409071400_lsps = &gormigrate.Migration{
+	ID: "202409071400_lsps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE lsps(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name text,
+	url text,
+	pubkey text,
+	protocols text,
+	network text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX idx_lsps_url ON lsps(url) WHERE url != '';
+CREATE UNIQUE INDEX idx_lsps_pubkey_network ON lsps(pubkey, network);
+
+INSERT INTO lsps (name, url, pubkey, protocols, network, created_at, updated_at) VALUES
+	('Olympus', '', '031b301307574bbe9b9ac7b79cbe1700e31e544513eae0b5d7497483083f99e581', '', 'bitcoin', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	('Alby Plebs', '', '029ca15ad2ea3077f5f0524c4c9bc266854c14b9fc81b9cc3d6b48e2460af13f65', '', 'bitcoin', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	('Megalith', '', '038a9e56512ec98da2b5789761f7af8f280baf98a09282360cd6ff1381b5e889bf', '', 'bitcoin', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	('Alby Plebs (Mutinynet)', '', '02f7029c14f3d805843e065d42e9bdc57f5f414249f335906bbe282ff99b2be17a', '', 'signet', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	('Olympus (Mutinynet)', '', '032ae843e4d7d177f151d021ac8044b0636ec72b1ce3ffcde5c04748db2517ab03', '', 'signet', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	('Megalith (Mutinynet)', '', '03e30fda71887a916ef5548a4d02b06fe04aaa1a8de9e24134ce7f139cf79d7579', '', 'signet', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+CREATE TABLE lsp_orders(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	lsp_id integer,
+	lsp_url text,
+	lsp_type text,
+	order_id text,
+	state text,
+	payment_state text,
+	invoice text,
+	fee_sat integer,
+	lsp_balance_sat integer,
+	public boolean,
+	refund_onchain_address text,
+	funding_outpoint text,
+	refund_state text,
+	created_at datetime,
+	updated_at datetime,
+	CONSTRAINT fk_lsp_orders_lsp FOREIGN KEY (lsp_id) REFERENCES lsps(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_lsp_orders_state ON lsp_orders(state);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table to log the decisions of the inbound liquidity autopilot
+var _202409081000_liquidity_decisions = &gormigrate.Migration{
+	ID: "202409081000_liquidity_decisions",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE liquidity_decisions(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	action text,
+	reason text,
+	receivable_sat integer,
+	threshold_sat integer,
+	amount_sat integer,
+	fee_sat integer,
+	provider text,
+	invoice text,
+	dry_run boolean,
+	created_at datetime
+);
+CREATE INDEX idx_liquidity_decisions_created_at ON liquidity_decisions(created_at);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
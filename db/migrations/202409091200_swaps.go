@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table to persist the state of submarine swaps
+var _202409091200_swaps = &gormigrate.Migration{
+	ID: "202409091200_swaps",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE swaps(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	swap_id text,
+	type text,
+	state text,
+	provider_status text,
+	amount_sat integer,
+	onchain_amount_sat integer,
+	payment_hash text,
+	preimage text,
+	invoice text,
+	lockup_address text,
+	provider_pubkey text,
+	claim_leaf text,
+	refund_leaf text,
+	timeout_block_height integer,
+	lockup_tx_id text,
+	claim_tx_id text,
+	claim_fee_rate integer,
+	destination_address text,
+	failure_reason text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX idx_swaps_swap_id ON swaps(swap_id);
+CREATE INDEX idx_swaps_state ON swaps(state);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
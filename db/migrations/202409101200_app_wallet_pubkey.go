@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds the wallet service pubkey of connections which use their own key,
+// and the index of the key which is incremented when the key is rotated
+var _202409101200_app_wallet_pubkey = &gormigrate.Migration{
+	ID: "202409101200_app_wallet_pubkey",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+ALTER TABLE apps ADD COLUMN wallet_pubkey text;
+ALTER TABLE apps ADD COLUMN wallet_key_index integer NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX idx_apps_wallet_pubkey ON apps(wallet_pubkey);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds a table for connections requested by apps which are pending approval
+var _202409111200_connection_requests = &gormigrate.Migration{
+	ID: "202409111200_connection_requests",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+CREATE TABLE connection_requests(
+	id integer PRIMARY KEY AUTOINCREMENT,
+	name text,
+	nostr_pubkey text,
+	relay_url text,
+	scopes text,
+	max_amount_sat integer,
+	budget_renewal text,
+	expires_at datetime,
+	isolated boolean,
+	return_to text,
+	created_at datetime,
+	updated_at datetime
+);
+CREATE UNIQUE INDEX idx_connection_requests_nostr_pubkey ON connection_requests(nostr_pubkey);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
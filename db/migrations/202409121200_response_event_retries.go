@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration stores the signed response events which could not be published,
+// so they can be republished, and how often that was attempted
+var _202409121200_response_event_retries = &gormigrate.Migration{
+	ID: "202409121200_response_event_retries",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+ALTER TABLE response_events ADD COLUMN event_data text;
+ALTER TABLE response_events ADD COLUMN retry_count integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_response_events_state ON response_events(state);
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
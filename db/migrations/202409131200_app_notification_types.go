@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds the NIP-47 notification types each app opted in to,
+// and when the app was last warned about its upcoming expiry and its budget usage
+var _202409131200_app_notification_types = &gormigrate.Migration{
+	ID: "202409131200_app_notification_types",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+ALTER TABLE apps ADD COLUMN notification_types text NOT NULL DEFAULT '';
+ALTER TABLE apps ADD COLUMN expiry_notified_at datetime;
+ALTER TABLE app_permissions ADD COLUMN budget_warning_notified_at datetime;
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
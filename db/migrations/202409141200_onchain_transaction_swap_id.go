@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds the swap ID of onchain sends made via a swap,
+// whose transaction ID is only known once the swap has progressed
+var _202409141200_onchain_transaction_swap_id = &gormigrate.Migration{
+	ID: "202409141200_onchain_transaction_swap_id",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+ALTER TABLE onchain_transactions ADD COLUMN swap_id text NOT NULL DEFAULT '';
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
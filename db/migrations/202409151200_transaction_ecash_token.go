@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	_ "embed"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// This migration adds the ecash token of sent and received cashu tokens.
+// Tokens are bearer assets, so they are kept out of the preimage which is shared with apps
+var _202409151200_transaction_ecash_token = &gormigrate.Migration{
+	ID: "202409151200_transaction_ecash_token",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := tx.Exec(`
+ALTER TABLE transactions ADD COLUMN ecash_token text;
+`).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -22,6 +22,18 @@ func Migrate(gormDB *gorm.DB) error {
 		_202408061737_add_boostagrams_and_use_json,
 		_202408191242_transaction_failure_reason,
 		_202408291715_app_metadata,
+		_202409031512_onchain_transactions,
+		_202409051030_forwards,
+		_202409061200_onchain_labels,
+		_202409071400_lsps,
+		_202409081000_liquidity_decisions,
+		_202409091200_swaps,
+		_202409101200_app_wallet_pubkey,
+		_202409111200_connection_requests,
+		_202409121200_response_event_retries,
+		_202409131200_app_notification_types,
+		_202409141200_onchain_transaction_swap_id,
+		_202409151200_transaction_ecash_token,
 	})
 
 	return m.Migrate()
@@ -24,6 +24,14 @@ type App struct {
 	UpdatedAt   time.Time
 	Isolated    bool
 	Metadata    datatypes.JSON
+	// unique wallet service pubkey of this connection, nil if the hub's shared key is used
+	WalletPubkey *string
+	// incremented when the wallet service key of the app is rotated
+	WalletKeyIndex uint
+	// space-separated NIP-47 notification types the app opted in to, empty for the defaults
+	NotificationTypes string
+	// when the app was last notified that its connection is about to expire
+	ExpiryNotifiedAt *time.Time
 }
 
 type AppPermission struct {
@@ -34,8 +42,10 @@ type AppPermission struct {
 	MaxAmountSat  int
 	BudgetRenewal string
 	ExpiresAt     *time.Time
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// when the app was last warned that it used most of its budget
+	BudgetWarningNotifiedAt *time.Time
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
 }
 
 type RequestEvent struct {
@@ -58,6 +68,9 @@ type ResponseEvent struct {
 	RepliedAt time.Time
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// signed event, kept so it can be republished and returned for requests received again over HTTP
+	EventData  string
+	RetryCount int
 }
 
 type Transaction struct {
@@ -76,6 +89,7 @@ type Transaction struct {
 	Description     string
 	DescriptionHash string
 	Preimage        *string
+	EcashToken      *string // sent or received cashu token, never shared with apps
 	CreatedAt       time.Time
 	ExpiresAt       *time.Time
 	UpdatedAt       time.Time
@@ -86,17 +100,176 @@ type Transaction struct {
 	FailureReason   string
 }
 
+type OnchainTransaction struct {
+	ID        uint
+	TxId      string
+	Type      string
+	State     string
+	Address   string
+	AmountSat uint64
+	FeeSat    *uint64
+	SendAll   bool
+	// set for sends made via a swap, the transaction ID is resolved later
+	SwapId    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OnchainLabel is a user label for an onchain transaction or address (address book)
+type OnchainLabel struct {
+	ID uint
+	// transaction or address
+	Type string
+	// transaction ID or address
+	Value     string
+	Label     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Forward struct {
+	ID                uint
+	ExternalId        string
+	IncomingChannelId string
+	OutgoingChannelId string
+	IncomingPeer      string
+	OutgoingPeer      string
+	AmountInMsat      uint64
+	AmountOutMsat     uint64
+	FeeMsat           uint64
+	Failed            bool
+	FailureReason     string
+	ForwardedAt       time.Time
+	CreatedAt         time.Time
+}
+
+// LSP is a registered Lightning Service Provider
+type LSP struct {
+	ID     uint
+	Name   string
+	Url    string
+	Pubkey string
+	// comma-separated, e.g. LSPS1,LSPS2
+	Protocols string
+	Network   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// LSPOrder is an LSPS1 channel order which is polled until the channel is open
+// or the order failed and the payment was refunded
+type LSPOrder struct {
+	ID      uint
+	LSPId   *uint
+	LSP     *LSP
+	LSPUrl  string
+	LSPType string
+	OrderId string
+	// LSPS1 order state: CREATED, COMPLETED or FAILED
+	State string
+	// LSPS1 payment state: EXPECT_PAYMENT, HOLD, PAID or REFUNDED
+	PaymentState         string
+	Invoice              string
+	FeeSat               uint64
+	LSPBalanceSat        uint64
+	Public               bool
+	RefundOnchainAddress string
+	FundingOutpoint      string
+	// PENDING while the LSP has to refund a failed order, then REFUNDED
+	RefundState string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// LiquidityDecision is logged by the inbound liquidity autopilot whenever the
+// receivable balance is below the configured threshold
+type LiquidityDecision struct {
+	ID uint
+	// purchased, pending, dry_run, skipped or failed
+	Action        string
+	Reason        string
+	ReceivableSat uint64
+	ThresholdSat  uint64
+	AmountSat     uint64
+	FeeSat        uint64
+	// alby or the url of a registered LSP
+	Provider  string
+	Invoice   string
+	DryRun    bool
+	CreatedAt time.Time
+}
+
+// Swap is a submarine swap between onchain and Lightning which is
+// polled until it completed, failed or was refunded
+type Swap struct {
+	ID uint
+	// id of the swap at the provider
+	SwapId string
+	// in (onchain to Lightning) or out (Lightning to onchain)
+	Type  string
+	State string
+	// last status reported by the provider
+	ProviderStatus string
+	// Lightning amount
+	AmountSat        uint64
+	OnchainAmountSat uint64
+	PaymentHash      string
+	// only set for swaps out, needed to claim the onchain funds
+	Preimage      string
+	Invoice       string
+	LockupAddress string
+	// the key of the provider in the HTLC and the hex encoded scripts of its taproot tree
+	ProviderPubkey     string
+	ClaimLeaf          string
+	RefundLeaf         string
+	TimeoutBlockHeight uint32
+	LockupTxId         string
+	// claim transaction of a swap out or refund transaction of a swap in
+	ClaimTxId string
+	// fee rate of the claim transaction in sat/vB, which is replaced with a higher fee rate until it confirms
+	ClaimFeeRate uint64
+	// where the claimed or refunded funds are sent to
+	DestinationAddress string
+	FailureReason      string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
 type DBService interface {
 	CreateApp(name string, pubkey string, maxAmountSat uint64, budgetRenewal string, expiresAt *time.Time, scopes []string, isolated bool, metadata map[string]interface{}) (*App, string, error)
 }
 
 const (
-	REQUEST_EVENT_STATE_HANDLER_EXECUTING = "executing"
-	REQUEST_EVENT_STATE_HANDLER_EXECUTED  = "executed"
-	REQUEST_EVENT_STATE_HANDLER_ERROR     = "error"
+	REQUEST_EVENT_STATE_HANDLER_EXECUTING    = "executing"
+	REQUEST_EVENT_STATE_HANDLER_EXECUTED     = "executed"
+	REQUEST_EVENT_STATE_HANDLER_ERROR        = "error"
+	REQUEST_EVENT_STATE_HANDLER_EXPIRED      = "expired"
+	REQUEST_EVENT_STATE_HANDLER_RATE_LIMITED = "rate_limited"
 )
 const (
 	RESPONSE_EVENT_STATE_PUBLISH_CONFIRMED   = "confirmed"
 	RESPONSE_EVENT_STATE_PUBLISH_FAILED      = "failed"
 	RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED = "unconfirmed"
 )
+const (
+	ONCHAIN_LABEL_TYPE_TRANSACTION = "transaction"
+	ONCHAIN_LABEL_TYPE_ADDRESS     = "address"
+)
+
+// ConnectionRequest is a connection requested by an app (nostr+walletauth)
+// which is waiting to be approved by the user
+type ConnectionRequest struct {
+	ID          uint
+	Name        string
+	NostrPubkey string
+	RelayUrl    string
+	// comma-separated, e.g. pay_invoice,get_balance
+	Scopes        string
+	MaxAmountSat  uint64
+	BudgetRenewal string
+	ExpiresAt     *time.Time
+	Isolated      bool
+	ReturnTo      string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
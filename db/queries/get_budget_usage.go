@@ -15,12 +15,20 @@ func GetBudgetUsageSat(tx *gorm.DB, appPermission *db.AppPermission) uint64 {
 	tx.
 		Table("transactions").
 		Select("SUM(amount_msat + fee_msat + fee_reserve_msat) as sum").
-		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission.BudgetRenewal)).Scan(&result)
+		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission.BudgetRenewal, time.Now())).Scan(&result)
 	return result.Sum / 1000
 }
 
-func getStartOfBudget(budget_type string) time.Time {
-	now := time.Now()
+// GetBudgetRenewsAt returns when the budget is next reset, or nil if it never renews
+func GetBudgetRenewsAt(budgetRenewal string) *time.Time {
+	return getEndOfBudget(budgetRenewal, time.Now())
+}
+
+func GetBudgetStartsAt(budgetRenewal string) time.Time {
+	return getStartOfBudget(budgetRenewal, time.Now())
+}
+
+func getStartOfBudget(budget_type string, now time.Time) time.Time {
 	switch budget_type {
 	case constants.BUDGET_RENEWAL_DAILY:
 		// TODO: Use the location of the user, instead of the server
@@ -42,3 +50,21 @@ func getStartOfBudget(budget_type string) time.Time {
 		return time.Time{}
 	}
 }
+
+func getEndOfBudget(budget_type string, now time.Time) *time.Time {
+	startOfBudget := getStartOfBudget(budget_type, now)
+	var endOfBudget time.Time
+	switch budget_type {
+	case constants.BUDGET_RENEWAL_DAILY:
+		endOfBudget = startOfBudget.AddDate(0, 0, 1)
+	case constants.BUDGET_RENEWAL_WEEKLY:
+		endOfBudget = startOfBudget.AddDate(0, 0, 7)
+	case constants.BUDGET_RENEWAL_MONTHLY:
+		endOfBudget = startOfBudget.AddDate(0, 1, 0)
+	case constants.BUDGET_RENEWAL_YEARLY:
+		endOfBudget = startOfBudget.AddDate(1, 0, 0)
+	default: //"never"
+		return nil
+	}
+	return &endOfBudget
+}
@@ -0,0 +1,59 @@
+package queries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/constants"
+)
+
+func date(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+func TestGetStartOfBudget(t *testing.T) {
+	testCases := []struct {
+		name          string
+		budgetRenewal string
+		now           time.Time
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{"daily at midnight", constants.BUDGET_RENEWAL_DAILY, date(2024, time.March, 10, 0, 0, 0), date(2024, time.March, 10, 0, 0, 0), date(2024, time.March, 11, 0, 0, 0)},
+		{"daily before midnight", constants.BUDGET_RENEWAL_DAILY, date(2024, time.March, 10, 23, 59, 59), date(2024, time.March, 10, 0, 0, 0), date(2024, time.March, 11, 0, 0, 0)},
+		{"daily at end of month", constants.BUDGET_RENEWAL_DAILY, date(2024, time.April, 30, 12, 0, 0), date(2024, time.April, 30, 0, 0, 0), date(2024, time.May, 1, 0, 0, 0)},
+		{"weekly on monday", constants.BUDGET_RENEWAL_WEEKLY, date(2024, time.September, 2, 0, 0, 0), date(2024, time.September, 2, 0, 0, 0), date(2024, time.September, 9, 0, 0, 0)},
+		{"weekly on wednesday", constants.BUDGET_RENEWAL_WEEKLY, date(2024, time.September, 4, 15, 30, 0), date(2024, time.September, 2, 0, 0, 0), date(2024, time.September, 9, 0, 0, 0)},
+		{"weekly on sunday", constants.BUDGET_RENEWAL_WEEKLY, date(2024, time.September, 8, 23, 59, 59), date(2024, time.September, 2, 0, 0, 0), date(2024, time.September, 9, 0, 0, 0)},
+		{"weekly across months", constants.BUDGET_RENEWAL_WEEKLY, date(2024, time.October, 1, 8, 0, 0), date(2024, time.September, 30, 0, 0, 0), date(2024, time.October, 7, 0, 0, 0)},
+		{"monthly on first day", constants.BUDGET_RENEWAL_MONTHLY, date(2024, time.March, 1, 0, 0, 0), date(2024, time.March, 1, 0, 0, 0), date(2024, time.April, 1, 0, 0, 0)},
+		{"monthly on leap day", constants.BUDGET_RENEWAL_MONTHLY, date(2024, time.February, 29, 23, 59, 59), date(2024, time.February, 1, 0, 0, 0), date(2024, time.March, 1, 0, 0, 0)},
+		{"monthly in december", constants.BUDGET_RENEWAL_MONTHLY, date(2024, time.December, 31, 12, 0, 0), date(2024, time.December, 1, 0, 0, 0), date(2025, time.January, 1, 0, 0, 0)},
+		{"yearly on first day", constants.BUDGET_RENEWAL_YEARLY, date(2025, time.January, 1, 0, 0, 0), date(2025, time.January, 1, 0, 0, 0), date(2026, time.January, 1, 0, 0, 0)},
+		{"yearly on last day", constants.BUDGET_RENEWAL_YEARLY, date(2024, time.December, 31, 23, 59, 59), date(2024, time.January, 1, 0, 0, 0), date(2025, time.January, 1, 0, 0, 0)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedStart, getStartOfBudget(testCase.budgetRenewal, testCase.now))
+
+			endOfBudget := getEndOfBudget(testCase.budgetRenewal, testCase.now)
+			assert.NotNil(t, endOfBudget)
+			assert.Equal(t, testCase.expectedEnd, *endOfBudget)
+
+			// the next budget period starts when the current one ends
+			assert.Equal(t, testCase.expectedEnd, getStartOfBudget(testCase.budgetRenewal, *endOfBudget))
+			assert.Equal(t, testCase.expectedStart, getStartOfBudget(testCase.budgetRenewal, endOfBudget.Add(-time.Second)))
+		})
+	}
+}
+
+func TestGetStartOfBudget_Never(t *testing.T) {
+	now := date(2024, time.March, 10, 12, 0, 0)
+	for _, budgetRenewal := range []string{constants.BUDGET_RENEWAL_NEVER, ""} {
+		assert.True(t, getStartOfBudget(budgetRenewal, now).IsZero())
+		assert.Nil(t, getEndOfBudget(budgetRenewal, now))
+	}
+}
@@ -9,9 +9,10 @@ func GetIsolatedBalance(tx *gorm.DB, appId uint) uint64 {
 	var received struct {
 		Sum uint64
 	}
+	// the fee of an incoming payment was deducted by the LSP of a JIT channel
 	tx.
 		Table("transactions").
-		Select("SUM(amount_msat) as sum").
+		Select("SUM(amount_msat - fee_msat) as sum").
 		Where("app_id = ? AND type = ? AND state = ?", appId, constants.TRANSACTION_TYPE_INCOMING, constants.TRANSACTION_STATE_SETTLED).Scan(&received)
 
 	var spent struct {
@@ -0,0 +1,223 @@
+package forwards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const maxIntervals = 1000
+
+type forwardsService struct {
+	db *gorm.DB
+}
+
+type ForwardsService interface {
+	events.EventSubscriber
+	// from and until are unix timestamps, until 0 means now. If interval (in seconds)
+	// is set, the stats are additionally split into consecutive windows of that length.
+	GetForwardingStats(ctx context.Context, from, until, interval uint64) (*ForwardingStats, error)
+}
+
+type Stats struct {
+	SettledCount   uint64  `json:"settledCount"`
+	FailedCount    uint64  `json:"failedCount"`
+	FailureRate    float64 `json:"failureRate"`
+	FeesEarnedMsat uint64  `json:"feesEarnedMsat"`
+	// outgoing amount of settled forwards
+	VolumeMsat uint64 `json:"volumeMsat"`
+}
+
+// fees, volume and failures are attributed to the outgoing channel / peer
+type ChannelStats struct {
+	ChannelId string `json:"channelId"`
+	Peer      string `json:"peer"`
+	Stats
+	IncomingVolumeMsat uint64 `json:"incomingVolumeMsat"`
+}
+
+type PeerStats struct {
+	Peer string `json:"peer"`
+	Stats
+	IncomingVolumeMsat uint64 `json:"incomingVolumeMsat"`
+}
+
+type IntervalStats struct {
+	From  uint64 `json:"from"`
+	Until uint64 `json:"until"`
+	Stats
+}
+
+type ForwardingStats struct {
+	From      uint64          `json:"from"`
+	Until     uint64          `json:"until"`
+	Total     Stats           `json:"total"`
+	Channels  []ChannelStats  `json:"channels"`
+	Peers     []PeerStats     `json:"peers"`
+	Intervals []IntervalStats `json:"intervals,omitempty"`
+}
+
+func NewForwardsService(db *gorm.DB) *forwardsService {
+	return &forwardsService{
+		db: db,
+	}
+}
+
+func (svc *forwardsService) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	if event.Event != "nwc_lnclient_forward" {
+		return
+	}
+
+	forward, ok := event.Properties.(*lnclient.Forward)
+	if !ok {
+		logger.Logger.WithField("event", event).Error("Failed to cast event")
+		return
+	}
+
+	// backends may publish the same forward more than once
+	err := svc.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&db.Forward{
+		ExternalId:        forward.Id,
+		IncomingChannelId: forward.IncomingChannelId,
+		OutgoingChannelId: forward.OutgoingChannelId,
+		IncomingPeer:      forward.IncomingPeer,
+		OutgoingPeer:      forward.OutgoingPeer,
+		AmountInMsat:      forward.AmountInMsat,
+		AmountOutMsat:     forward.AmountOutMsat,
+		FeeMsat:           forward.FeeMsat,
+		Failed:            forward.Failed,
+		FailureReason:     forward.FailureReason,
+		ForwardedAt:       time.Unix(forward.Timestamp, 0),
+	}).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"forward": forward,
+		}).Error("Failed to save forward")
+	}
+}
+
+func (svc *forwardsService) GetForwardingStats(ctx context.Context, from, until, interval uint64) (*ForwardingStats, error) {
+	if until == 0 {
+		until = uint64(time.Now().Unix())
+	}
+	if from >= until {
+		return nil, errors.New("from must be before until")
+	}
+	if interval > 0 && (until-from)/interval > maxIntervals {
+		return nil, fmt.Errorf("too many intervals, the maximum is %d", maxIntervals)
+	}
+
+	forwards := []db.Forward{}
+	err := svc.db.
+		Where("forwarded_at >= ? AND forwarded_at < ?", time.Unix(int64(from), 0), time.Unix(int64(until), 0)).
+		Order("forwarded_at ASC").
+		Find(&forwards).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list forwards")
+		return nil, err
+	}
+
+	stats := &ForwardingStats{
+		From:     from,
+		Until:    until,
+		Channels: []ChannelStats{},
+		Peers:    []PeerStats{},
+	}
+
+	channelStats := map[string]*ChannelStats{}
+	peerStats := map[string]*PeerStats{}
+
+	if interval > 0 {
+		for intervalFrom := from; intervalFrom < until; intervalFrom += interval {
+			stats.Intervals = append(stats.Intervals, IntervalStats{
+				From:  intervalFrom,
+				Until: min(intervalFrom+interval, until),
+			})
+		}
+	}
+
+	for _, forward := range forwards {
+		stats.Total.add(&forward)
+
+		if interval > 0 {
+			intervalIndex := (uint64(forward.ForwardedAt.Unix()) - from) / interval
+			stats.Intervals[intervalIndex].add(&forward)
+		}
+
+		if forward.OutgoingChannelId != "" {
+			if _, ok := channelStats[forward.OutgoingChannelId]; !ok {
+				channelStats[forward.OutgoingChannelId] = &ChannelStats{ChannelId: forward.OutgoingChannelId}
+			}
+			channelStats[forward.OutgoingChannelId].add(&forward)
+			if forward.OutgoingPeer != "" {
+				channelStats[forward.OutgoingChannelId].Peer = forward.OutgoingPeer
+			}
+		}
+		if forward.OutgoingPeer != "" {
+			if _, ok := peerStats[forward.OutgoingPeer]; !ok {
+				peerStats[forward.OutgoingPeer] = &PeerStats{Peer: forward.OutgoingPeer}
+			}
+			peerStats[forward.OutgoingPeer].add(&forward)
+		}
+
+		if forward.Failed {
+			continue
+		}
+		if forward.IncomingChannelId != "" {
+			if _, ok := channelStats[forward.IncomingChannelId]; !ok {
+				channelStats[forward.IncomingChannelId] = &ChannelStats{ChannelId: forward.IncomingChannelId, Peer: forward.IncomingPeer}
+			}
+			channelStats[forward.IncomingChannelId].IncomingVolumeMsat += forward.AmountInMsat
+		}
+		if forward.IncomingPeer != "" {
+			if _, ok := peerStats[forward.IncomingPeer]; !ok {
+				peerStats[forward.IncomingPeer] = &PeerStats{Peer: forward.IncomingPeer}
+			}
+			peerStats[forward.IncomingPeer].IncomingVolumeMsat += forward.AmountInMsat
+		}
+	}
+
+	for _, channel := range channelStats {
+		stats.Channels = append(stats.Channels, *channel)
+	}
+	for _, peer := range peerStats {
+		stats.Peers = append(stats.Peers, *peer)
+	}
+
+	// most profitable first
+	sort.Slice(stats.Channels, func(i, j int) bool {
+		if stats.Channels[i].FeesEarnedMsat == stats.Channels[j].FeesEarnedMsat {
+			return stats.Channels[i].ChannelId < stats.Channels[j].ChannelId
+		}
+		return stats.Channels[i].FeesEarnedMsat > stats.Channels[j].FeesEarnedMsat
+	})
+	sort.Slice(stats.Peers, func(i, j int) bool {
+		if stats.Peers[i].FeesEarnedMsat == stats.Peers[j].FeesEarnedMsat {
+			return stats.Peers[i].Peer < stats.Peers[j].Peer
+		}
+		return stats.Peers[i].FeesEarnedMsat > stats.Peers[j].FeesEarnedMsat
+	})
+
+	return stats, nil
+}
+
+func (stats *Stats) add(forward *db.Forward) {
+	if forward.Failed {
+		stats.FailedCount++
+	} else {
+		stats.SettledCount++
+		stats.FeesEarnedMsat += forward.FeeMsat
+		stats.VolumeMsat += forward.AmountOutMsat
+	}
+	stats.FailureRate = float64(stats.FailedCount) / float64(stats.SettledCount+stats.FailedCount)
+}
@@ -0,0 +1,151 @@
+package forwards
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+const (
+	peer1 = "02b4552a7a85274e4da01a7c71ca57407181752e8568b31d51f13c111a2941dce3"
+	peer2 = "03cbd788f5b22bd56e2714bff756372d2293504c064e03250ed16a4dd80ad70e2c"
+)
+
+func publishForward(svc *forwardsService, forward *lnclient.Forward) {
+	svc.ConsumeEvent(context.TODO(), &events.Event{
+		Event:      "nwc_lnclient_forward",
+		Properties: forward,
+	}, map[string]interface{}{})
+}
+
+func TestConsumeEvent_IgnoresDuplicates(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	forwardsService := NewForwardsService(svc.DB)
+	forward := &lnclient.Forward{
+		Id:                "forward1",
+		IncomingChannelId: "1",
+		OutgoingChannelId: "2",
+		AmountInMsat:      100_100,
+		AmountOutMsat:     100_000,
+		FeeMsat:           100,
+		Timestamp:         1000,
+	}
+	publishForward(forwardsService, forward)
+	publishForward(forwardsService, forward)
+
+	var forwards []db.Forward
+	svc.DB.Find(&forwards)
+	assert.Equal(t, 1, len(forwards))
+	assert.Equal(t, "forward1", forwards[0].ExternalId)
+	assert.Equal(t, uint64(100), forwards[0].FeeMsat)
+	assert.Equal(t, int64(1000), forwards[0].ForwardedAt.Unix())
+}
+
+func TestGetForwardingStats(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	forwardsService := NewForwardsService(svc.DB)
+	publishForward(forwardsService, &lnclient.Forward{
+		Id:                "forward1",
+		IncomingChannelId: "1",
+		OutgoingChannelId: "2",
+		IncomingPeer:      peer1,
+		OutgoingPeer:      peer2,
+		AmountInMsat:      100_100,
+		AmountOutMsat:     100_000,
+		FeeMsat:           100,
+		Timestamp:         1000,
+	})
+	publishForward(forwardsService, &lnclient.Forward{
+		Id:                "forward2",
+		IncomingChannelId: "2",
+		OutgoingChannelId: "1",
+		IncomingPeer:      peer2,
+		OutgoingPeer:      peer1,
+		AmountInMsat:      200_300,
+		AmountOutMsat:     200_000,
+		FeeMsat:           300,
+		Timestamp:         1500,
+	})
+	publishForward(forwardsService, &lnclient.Forward{
+		Id:                "forward3",
+		IncomingChannelId: "1",
+		OutgoingChannelId: "2",
+		IncomingPeer:      peer1,
+		OutgoingPeer:      peer2,
+		AmountInMsat:      50_050,
+		AmountOutMsat:     50_000,
+		Failed:            true,
+		FailureReason:     "insufficient balance",
+		Timestamp:         1600,
+	})
+	// outside of the window
+	publishForward(forwardsService, &lnclient.Forward{
+		Id:                "forward4",
+		IncomingChannelId: "1",
+		OutgoingChannelId: "2",
+		AmountInMsat:      1_000_000,
+		AmountOutMsat:     999_000,
+		FeeMsat:           1000,
+		Timestamp:         5000,
+	})
+
+	stats, err := forwardsService.GetForwardingStats(ctx, 1000, 2000, 500)
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint64(2), stats.Total.SettledCount)
+	assert.Equal(t, uint64(1), stats.Total.FailedCount)
+	assert.InDelta(t, 1.0/3.0, stats.Total.FailureRate, 0.0001)
+	assert.Equal(t, uint64(400), stats.Total.FeesEarnedMsat)
+	assert.Equal(t, uint64(300_000), stats.Total.VolumeMsat)
+
+	assert.Equal(t, 2, len(stats.Channels))
+	assert.Equal(t, "1", stats.Channels[0].ChannelId)
+	assert.Equal(t, peer1, stats.Channels[0].Peer)
+	assert.Equal(t, uint64(300), stats.Channels[0].FeesEarnedMsat)
+	assert.Equal(t, uint64(200_000), stats.Channels[0].VolumeMsat)
+	assert.Equal(t, uint64(100_100), stats.Channels[0].IncomingVolumeMsat)
+	assert.Zero(t, stats.Channels[0].FailureRate)
+	assert.Equal(t, "2", stats.Channels[1].ChannelId)
+	assert.Equal(t, uint64(100), stats.Channels[1].FeesEarnedMsat)
+	assert.Equal(t, uint64(1), stats.Channels[1].FailedCount)
+	assert.InDelta(t, 0.5, stats.Channels[1].FailureRate, 0.0001)
+	assert.Equal(t, uint64(200_300), stats.Channels[1].IncomingVolumeMsat)
+
+	assert.Equal(t, 2, len(stats.Peers))
+	assert.Equal(t, peer1, stats.Peers[0].Peer)
+	assert.Equal(t, uint64(300), stats.Peers[0].FeesEarnedMsat)
+	assert.Equal(t, peer2, stats.Peers[1].Peer)
+	assert.Equal(t, uint64(100), stats.Peers[1].FeesEarnedMsat)
+
+	assert.Equal(t, 2, len(stats.Intervals))
+	assert.Equal(t, uint64(1000), stats.Intervals[0].From)
+	assert.Equal(t, uint64(1500), stats.Intervals[0].Until)
+	assert.Equal(t, uint64(1), stats.Intervals[0].SettledCount)
+	assert.Equal(t, uint64(100), stats.Intervals[0].FeesEarnedMsat)
+	assert.Equal(t, uint64(1), stats.Intervals[1].SettledCount)
+	assert.Equal(t, uint64(1), stats.Intervals[1].FailedCount)
+	assert.Equal(t, uint64(300), stats.Intervals[1].FeesEarnedMsat)
+}
+
+func TestGetForwardingStats_TooManyIntervals(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	forwardsService := NewForwardsService(svc.DB)
+	_, err = forwardsService.GetForwardingStats(context.TODO(), 0, 1_000_000, 1)
+	assert.Error(t, err)
+}
@@ -117,7 +117,8 @@ const NewAppInternal = ({ capabilities }: NewAppInternalProps) => {
       requestMethodsSet.has("pay_invoice") ||
       requestMethodsSet.has("pay_keysend") ||
       requestMethodsSet.has("multi_pay_invoice") ||
-      requestMethodsSet.has("multi_pay_keysend")
+      requestMethodsSet.has("multi_pay_keysend") ||
+      requestMethodsSet.has("get_budget")
     ) {
       scopes.push("pay_invoice");
     }
@@ -201,6 +202,10 @@ const NewAppInternal = ({ capabilities }: NewAppInternalProps) => {
         metadata: {
           app_store_app_id: appStoreApp?.id,
         },
+        // apps which do not request notification types receive the defaults
+        notificationTypes: notificationTypesParam
+          ? (notificationTypesParam.split(" ") as Nip47NotificationType[])
+          : undefined,
       };
 
       const createAppResponse = await createApp(createAppRequest);
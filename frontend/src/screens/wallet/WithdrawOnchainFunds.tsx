@@ -23,7 +23,7 @@ import { ONCHAIN_DUST_SATS } from "src/constants";
 import { useBalances } from "src/hooks/useBalances";
 
 import { copyToClipboard } from "src/lib/clipboard";
-import { RedeemOnchainFundsResponse } from "src/types";
+import { OnchainFeeEstimate, RedeemOnchainFundsResponse } from "src/types";
 import { request } from "src/utils/request";
 
 export default function WithdrawOnchainFunds() {
@@ -34,12 +34,38 @@ export default function WithdrawOnchainFunds() {
   const [amount, setAmount] = React.useState("");
   const [sendAll, setSendAll] = React.useState(false);
   const [transactionId, setTransactionId] = React.useState("");
+  const [swapId, setSwapId] = React.useState("");
   const [confirmDialogOpen, setConfirmDialogOpen] = React.useState(false);
+  const [feeEstimate, setFeeEstimate] = React.useState<OnchainFeeEstimate>();
 
   const copy = (text: string) => {
     copyToClipboard(text, toast);
   };
 
+  const estimateFees = React.useCallback(async () => {
+    setFeeEstimate(undefined);
+    try {
+      const response = await request<OnchainFeeEstimate>(
+        "/api/wallet/estimate-onchain-fees",
+        {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+          },
+          body: JSON.stringify({
+            toAddress: onchainAddress,
+            amount: +amount,
+            sendAll,
+          }),
+        }
+      );
+      setFeeEstimate(response);
+    } catch (error) {
+      // not all backends support fee estimation
+      console.error(error);
+    }
+  }, [amount, onchainAddress, sendAll]);
+
   const redeemFunds = React.useCallback(async () => {
     setLoading(true);
     try {
@@ -74,10 +100,14 @@ export default function WithdrawOnchainFunds() {
         }
       );
       console.info("Redeemed onchain funds", response);
-      if (!response?.txId) {
-        throw new Error("No address in response");
+      if (response?.swapId) {
+        // the transaction is only broadcast once the swap has progressed
+        setSwapId(response.swapId);
+      } else if (response?.txId) {
+        setTransactionId(response.txId);
+      } else {
+        throw new Error("No transaction ID in response");
       }
-      setTransactionId(response.txId);
     } catch (error) {
       console.error(error);
       toast({
@@ -89,6 +119,22 @@ export default function WithdrawOnchainFunds() {
     setLoading(false);
   }, [amount, onchainAddress, sendAll, toast]);
 
+  if (swapId) {
+    return (
+      <div className="grid gap-5">
+        <AppHeader
+          title="Withdrawal Started"
+          description={
+            "Your withdrawal is being sent via a swap, the transaction will show in your transaction history once it has been broadcast"
+          }
+        />
+        <p className="text-primary">Swap Id</p>
+        <p className="break-all font-semibold">{swapId}</p>
+        <p>Your savings balance in Alby Hub may take some time to update.</p>
+      </div>
+    );
+  }
+
   if (transactionId) {
     return (
       <div className="grid gap-5">
@@ -148,6 +194,7 @@ export default function WithdrawOnchainFunds() {
           onSubmit={(e) => {
             e.preventDefault();
             setConfirmDialogOpen(true);
+            estimateFees();
           }}
           className="grid gap-5 mt-4"
         >
@@ -272,6 +319,22 @@ export default function WithdrawOnchainFunds() {
                         )}
                       </span>
                     </p>
+                    {feeEstimate && (
+                      <p className="mt-4">
+                        Estimated fee:{" "}
+                        <span className="font-bold">
+                          {new Intl.NumberFormat().format(feeEstimate.fee)} sats
+                        </span>
+                        {sendAll && (
+                          <>
+                            {" "}
+                            (
+                            {new Intl.NumberFormat().format(feeEstimate.amount)}{" "}
+                            sats will be received)
+                          </>
+                        )}
+                      </p>
+                    )}
                   </AlertDialogDescription>
                 </AlertDialogHeader>
                 <AlertDialogFooter>
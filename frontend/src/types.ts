@@ -28,7 +28,8 @@ export type Nip47RequestMethod =
   | "list_transactions"
   | "sign_message"
   | "multi_pay_invoice"
-  | "multi_pay_keysend";
+  | "multi_pay_keysend"
+  | "get_budget";
 
 export type BudgetRenewalType =
   | "daily"
@@ -39,7 +40,7 @@ export type BudgetRenewalType =
   | "";
 
 export type Scope =
-  | "pay_invoice" // also used for pay_keysend, multi_pay_invoice, multi_pay_keysend
+  | "pay_invoice" // also used for pay_keysend, multi_pay_invoice, multi_pay_keysend, get_budget
   | "get_balance"
   | "get_info"
   | "make_invoice"
@@ -48,7 +49,13 @@ export type Scope =
   | "sign_message"
   | "notifications"; // covers all notification types
 
-export type Nip47NotificationType = "payment_received" | "payment_sent";
+export type Nip47NotificationType =
+  | "payment_received"
+  | "payment_sent"
+  | "payment_failed"
+  | "balance_changed"
+  | "budget_warning"
+  | "app_expiring";
 
 export type ScopeIconMap = {
   [key in Scope]: LucideIcon;
@@ -125,6 +132,8 @@ export interface App {
   budgetUsage: number;
   budgetRenewal: BudgetRenewalType;
   metadata?: AppMetadata;
+  walletPubkey?: string;
+  notificationTypes: Nip47NotificationType[];
 }
 
 export interface AppPermissions {
@@ -173,6 +182,8 @@ export interface CreateAppRequest {
   returnTo?: string;
   isolated?: boolean;
   metadata?: AppMetadata;
+  uniqueWalletPubkey?: boolean;
+  notificationTypes?: Nip47NotificationType[];
 }
 
 export interface CreateAppResponse {
@@ -181,6 +192,7 @@ export interface CreateAppResponse {
   pairingUri: string;
   pairingPublicKey: string;
   pairingSecretKey: string;
+  walletPubkey: string;
   returnTo: string;
 }
 
@@ -191,6 +203,7 @@ export type UpdateAppRequest = {
   expiresAt: string | undefined;
   scopes: Scope[];
   metadata?: AppMetadata;
+  notificationTypes?: Nip47NotificationType[];
 };
 
 export type Channel = {
@@ -205,6 +218,11 @@ export type Channel = {
   confirmations?: number;
   confirmationsRequired?: number;
   forwardingFeeBaseMsat: number;
+  forwardingFeeProportionalMillionths: number;
+  cltvExpiryDelta: number;
+  htlcMinimumMsat?: number;
+  htlcMaximumMsat?: number;
+  forceCloseAvoidanceMaxFeeSat?: number;
   unspendablePunishmentReserve: number;
   counterpartyUnspendablePunishmentReserve: number;
   error?: string;
@@ -212,10 +230,38 @@ export type Channel = {
   isOutbound: boolean;
 };
 
-export type UpdateChannelRequest = {
-  forwardingFeeBaseMsat: number;
+export type ChannelPolicyUpdate = {
+  forwardingFeeBaseMsat?: number;
+  forwardingFeeProportionalMillionths?: number;
+  cltvExpiryDelta?: number;
+  htlcMinimumMsat?: number;
+  htlcMaximumMsat?: number;
+  maxDustHtlcExposureMsat?: number;
+  maxDustHtlcExposureFeeRateMultiplier?: number;
+  forceCloseAvoidanceMaxFeeSat?: number;
+};
+
+export type UpdateChannelRequest = ChannelPolicyUpdate;
+
+export type UpdateChannelsRequest = ChannelPolicyUpdate;
+
+export type UpdateChannelsResponse = {
+  channels: {
+    channelId: string;
+    nodeId: string;
+    error?: string;
+  }[];
+};
+
+export type RebalanceRequest = {
+  outgoingChannelId: string;
+  incomingChannelId: string;
+  amountMsat: number;
+  maxFeeMsat: number;
 };
 
+export type RebalanceResponse = Transaction;
+
 export type Peer = {
   nodeId: string;
   address: string;
@@ -258,12 +304,47 @@ export type OpenChannelRequest = {
   pubkey: string;
   amount: number;
   public: boolean;
+  pushAmount?: number;
+  feeRate?: number;
+  zeroConf?: boolean;
+  anchors?: boolean;
+  closeAddress?: string;
+  minHtlcMsat?: number;
+};
+
+export type BatchOpenChannelRequest = {
+  channels: OpenChannelRequest[];
+  feeRate: number;
+};
+
+export type BatchOpenChannelResponse = {
+  fundingTxId: string;
+  channels: {
+    pubkey: string;
+    outputIndex: number;
+  }[];
 };
 
 export type OpenChannelResponse = {
   fundingTxId: string;
 };
 
+export type PsbtChannelOpenResponse = {
+  pendingChannelId: string;
+  fundingAddress: string;
+  fundingAmount: number;
+  psbt: string;
+};
+
+export type VerifyPsbtChannelFundingRequest = {
+  psbt: string;
+};
+
+export type FinalizePsbtChannelOpenRequest = {
+  signedPsbt?: string;
+  rawTx?: string;
+};
+
 // eslint-disable-next-line @typescript-eslint/ban-types
 export type CloseChannelResponse = {};
 
@@ -298,7 +379,7 @@ export type SetupNodeInfo = Partial<{
   phoenixdAuthorization?: string;
 }>;
 
-export type LSPType = "LSPS1";
+export type LSPType = "LSPS1" | "LSPS2";
 
 export type RecommendedChannelPeer = {
   network: Network;
@@ -345,6 +426,182 @@ export type LSPOrderRequest = {
   lspType: LSPType;
   lspUrl: string;
   public: boolean;
+  lspId?: number;
+};
+
+export type LSP = {
+  id: number;
+  name: string;
+  url: string;
+  pubkey: string;
+  protocols: LSPType[];
+  network: Network;
+  createdAt: string;
+};
+
+export type AddLSPRequest = {
+  name: string;
+  url: string;
+  pubkey: string;
+  protocols: LSPType[];
+  network: Network;
+};
+
+export type LSPOrder = {
+  id: number;
+  lspId?: number;
+  lspName: string;
+  lspUrl: string;
+  lspType: LSPType;
+  orderId: string;
+  state: "CREATED" | "COMPLETED" | "FAILED";
+  paymentState: "EXPECT_PAYMENT" | "HOLD" | "PAID" | "REFUNDED";
+  invoice: string;
+  feeSat: number;
+  lspBalanceSat: number;
+  public: boolean;
+  refundOnchainAddress: string;
+  fundingOutpoint: string;
+  refundState: "" | "PENDING" | "REFUNDED";
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type LiquiditySettings = {
+  enabled: boolean;
+  dryRun: boolean;
+  minReceivableSat: number;
+  channelSizeSat: number;
+  budgetSat: number;
+  lspId: number;
+  public: boolean;
+};
+
+export type LiquidityDecision = {
+  id: number;
+  action: "purchased" | "pending" | "dry_run" | "skipped" | "failed";
+  reason: string;
+  receivableSat: number;
+  thresholdSat: number;
+  amountSat: number;
+  feeSat: number;
+  provider: string;
+  invoice: string;
+  dryRun: boolean;
+  createdAt: string;
+};
+
+export type CreateConnectionRequestRequest = {
+  uri: string;
+};
+
+export type ConnectionRequest = {
+  id: number;
+  name: string;
+  pubkey: string;
+  scopes: Scope[];
+  maxAmount: number;
+  budgetRenewal: BudgetRenewalType;
+  expiresAt?: string;
+  isolated: boolean;
+  returnTo: string;
+  createdAt: string;
+};
+
+export type AppRequestResponse = {
+  nostrId: string;
+  state: string;
+  repliedAt: string;
+  retryCount: number;
+};
+
+export type AppRequestTransaction = {
+  id: number;
+  type: string;
+  state: string;
+  paymentHash: string;
+  amount: number;
+  fee: number;
+};
+
+export type AppRequest = {
+  id: number;
+  nostrId: string;
+  method: string;
+  state: string;
+  params?: unknown;
+  createdAt: string;
+  updatedAt: string;
+  responses: AppRequestResponse[];
+  transactions: AppRequestTransaction[];
+};
+
+export type ListAppRequestsResponse = {
+  requests: AppRequest[];
+  totalCount: number;
+};
+
+export type RelayStatus = {
+  url: string;
+  connected: boolean;
+  authRequired: boolean;
+  authenticated: boolean;
+  authError: string;
+  updatedAt: string;
+};
+
+export type Nip47AppQueueStats = {
+  appId?: number;
+  appName: string;
+  appPubkey: string;
+  queued: number;
+  running: number;
+};
+
+export type Nip47QueueStats = {
+  maxWorkers: number;
+  maxPaymentWorkers: number;
+  running: number;
+  runningPayments: number;
+  queued: number;
+  apps: Nip47AppQueueStats[];
+};
+
+export type SwapInfo = {
+  minAmountSat: number;
+  maxAmountSat: number;
+  swapInFeePercentage: number;
+  swapOutFeePercentage: number;
+  swapOutLockupFeeSat: number;
+  swapInClaimFeeSat: number;
+};
+
+export type SwapInRequest = {
+  amount: number;
+};
+
+export type SwapOutRequest = {
+  amount: number;
+  destination?: string;
+};
+
+export type Swap = {
+  swapId: string;
+  type: "in" | "out";
+  state: "pending" | "completed" | "failed" | "refunded";
+  providerStatus: string;
+  amountSat: number;
+  onchainAmountSat: number;
+  paymentHash: string;
+  invoice: string;
+  lockupAddress: string;
+  timeoutBlockHeight: number;
+  lockupTxId: string;
+  claimTxId: string;
+  destinationAddress: string;
+  failureReason: string;
+  createdAt: string;
+  updatedAt: string;
 };
 
 export type LSPOrderResponse = {
@@ -366,6 +623,65 @@ export type AutoChannelResponse = {
 
 export type RedeemOnchainFundsResponse = {
   txId: string;
+  swapId?: string;
+};
+
+export type OnchainFeeEstimate = {
+  amount: number;
+  fee: number;
+  feeRate: number;
+};
+
+export type OnchainTransaction = {
+  txId: string;
+  type: "incoming" | "outgoing";
+  state: "PENDING" | "SETTLED";
+  address: string;
+  amountSat: number;
+  feeSat?: number;
+  sendAll: boolean;
+  confirmations: number;
+  channelId?: string;
+  channelAction?: "open" | "close";
+  label?: string;
+  createdAt: string;
+};
+
+export type OnchainLabel = {
+  type: "transaction" | "address";
+  value: string;
+  label: string;
+};
+
+export type HistoryItem = {
+  type: "lightning" | "onchain";
+  timestamp: string;
+  lightning?: Transaction;
+  onchain?: OnchainTransaction;
+};
+
+export type OutPoint = {
+  txId: string;
+  outputIndex: number;
+};
+
+export type Utxo = OutPoint & {
+  address: string;
+  amountSat: number;
+  confirmations: number;
+};
+
+export type SendOnchainRequest = {
+  outputs: { address: string; amountSat: number }[];
+  inputs?: OutPoint[];
+  feeRate?: number;
+  targetConfirmations?: number;
+};
+
+export type BumpOnchainFeeRequest = {
+  txId: string;
+  feeRate?: number;
+  targetConfirmations?: number;
 };
 
 export type LightningBalanceResponse = {
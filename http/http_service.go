@@ -13,6 +13,7 @@ import (
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/nbd-wtf/go-nostr"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
@@ -99,6 +100,10 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	e.POST("/api/backup", httpSvc.createBackupHandler, unlockRateLimiter)
 	e.GET("/logout", httpSvc.logoutHandler, unlockRateLimiter)
 
+	// NIP-47 requests are authenticated by the signature of the request event
+	nip47RateLimiter := middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(10))
+	e.POST("/api/nip47", httpSvc.nip47RequestHandler, middleware.BodyLimit("64K"), nip47RateLimiter)
+
 	frontend.RegisterHandlers(e)
 
 	// restricted routes
@@ -116,13 +121,39 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	restrictedGroup.GET("/api/apps/:pubkey", httpSvc.appsShowHandler)
 	restrictedGroup.PATCH("/api/apps/:pubkey", httpSvc.appsUpdateHandler)
 	restrictedGroup.DELETE("/api/apps/:pubkey", httpSvc.appsDeleteHandler)
+	restrictedGroup.GET("/api/apps/:pubkey/requests", httpSvc.appRequestsListHandler)
+	restrictedGroup.POST("/api/apps/:pubkey/rotate-wallet-key", httpSvc.appsRotateWalletKeyHandler)
 	restrictedGroup.POST("/api/apps", httpSvc.appsCreateHandler)
 	restrictedGroup.POST("/api/mnemonic", httpSvc.mnemonicHandler)
 	restrictedGroup.PATCH("/api/backup-reminder", httpSvc.backupReminderHandler)
 	restrictedGroup.GET("/api/channels", httpSvc.channelsListHandler)
 	restrictedGroup.POST("/api/channels", httpSvc.openChannelHandler)
+	restrictedGroup.POST("/api/channels/batch", httpSvc.openChannelsHandler)
+	restrictedGroup.GET("/api/channels/funding/:fundingTxId", httpSvc.listFundingTransactionChannelsHandler)
+	restrictedGroup.POST("/api/channels/psbt", httpSvc.startPsbtChannelOpenHandler)
+	restrictedGroup.POST("/api/channels/psbt/:pendingChannelId/verify", httpSvc.verifyPsbtChannelFundingHandler)
+	restrictedGroup.POST("/api/channels/psbt/:pendingChannelId/finalize", httpSvc.finalizePsbtChannelOpenHandler)
+	restrictedGroup.DELETE("/api/channels/psbt/:pendingChannelId", httpSvc.cancelPsbtChannelOpenHandler)
 	restrictedGroup.GET("/api/channels/suggestions", httpSvc.channelPeerSuggestionsHandler)
 	restrictedGroup.POST("/api/lsp-orders", httpSvc.newInstantChannelInvoiceHandler)
+	restrictedGroup.GET("/api/lsp-orders", httpSvc.listLSPOrdersHandler)
+	restrictedGroup.GET("/api/lsps", httpSvc.listLSPsHandler)
+	restrictedGroup.POST("/api/lsps", httpSvc.addLSPHandler)
+	restrictedGroup.DELETE("/api/lsps/:id", httpSvc.deleteLSPHandler)
+	restrictedGroup.GET("/api/liquidity/settings", httpSvc.liquiditySettingsHandler)
+	restrictedGroup.PATCH("/api/liquidity/settings", httpSvc.updateLiquiditySettingsHandler)
+	restrictedGroup.GET("/api/liquidity/decisions", httpSvc.listLiquidityDecisionsHandler)
+	restrictedGroup.GET("/api/swaps/info", httpSvc.swapInfoHandler)
+	restrictedGroup.POST("/api/swaps/in", httpSvc.swapInHandler)
+	restrictedGroup.POST("/api/swaps/out", httpSvc.swapOutHandler)
+	restrictedGroup.GET("/api/swaps", httpSvc.listSwapsHandler)
+	restrictedGroup.GET("/api/swaps/:swapId", httpSvc.getSwapHandler)
+	restrictedGroup.GET("/api/nip47/queue", httpSvc.nip47QueueStatsHandler)
+	restrictedGroup.GET("/api/relay/status", httpSvc.relayStatusHandler)
+	restrictedGroup.GET("/api/connection-requests", httpSvc.listConnectionRequestsHandler)
+	restrictedGroup.POST("/api/connection-requests", httpSvc.createConnectionRequestHandler)
+	restrictedGroup.POST("/api/connection-requests/:id/approve", httpSvc.approveConnectionRequestHandler)
+	restrictedGroup.DELETE("/api/connection-requests/:id", httpSvc.rejectConnectionRequestHandler)
 	restrictedGroup.GET("/api/node/connection-info", httpSvc.nodeConnectionInfoHandler)
 	restrictedGroup.GET("/api/node/status", httpSvc.nodeStatusHandler)
 	restrictedGroup.GET("/api/node/network-graph", httpSvc.nodeNetworkGraphHandler)
@@ -131,13 +162,28 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	restrictedGroup.DELETE("/api/peers/:peerId", httpSvc.disconnectPeerHandler)
 	restrictedGroup.DELETE("/api/peers/:peerId/channels/:channelId", httpSvc.closeChannelHandler)
 	restrictedGroup.PATCH("/api/peers/:peerId/channels/:channelId", httpSvc.updateChannelHandler)
+	restrictedGroup.PATCH("/api/channels", httpSvc.updateChannelsHandler)
+	restrictedGroup.GET("/api/forwards/stats", httpSvc.forwardingStatsHandler)
+	restrictedGroup.POST("/api/channels/rebalance", httpSvc.rebalanceHandler)
+	restrictedGroup.GET("/api/rebalances", httpSvc.listRebalancesHandler)
 	restrictedGroup.GET("/api/wallet/address", httpSvc.onchainAddressHandler)
 	restrictedGroup.POST("/api/wallet/new-address", httpSvc.newOnchainAddressHandler)
 	restrictedGroup.POST("/api/wallet/redeem-onchain-funds", httpSvc.redeemOnchainFundsHandler)
+	restrictedGroup.POST("/api/wallet/estimate-onchain-fees", httpSvc.estimateOnchainFeesHandler)
+	restrictedGroup.GET("/api/wallet/onchain-transactions", httpSvc.listOnchainTransactionsHandler)
+	restrictedGroup.GET("/api/wallet/utxos", httpSvc.listUtxosHandler)
+	restrictedGroup.GET("/api/wallet/labels", httpSvc.listOnchainLabelsHandler)
+	restrictedGroup.PUT("/api/wallet/labels", httpSvc.setOnchainLabelHandler)
+	restrictedGroup.GET("/api/history", httpSvc.listHistoryHandler)
+	restrictedGroup.POST("/api/wallet/send-onchain", httpSvc.sendOnchainHandler)
+	restrictedGroup.POST("/api/wallet/bump-fee", httpSvc.bumpOnchainFeeHandler)
 	restrictedGroup.POST("/api/wallet/sign-message", httpSvc.signMessageHandler)
 	restrictedGroup.POST("/api/wallet/sync", httpSvc.walletSyncHandler)
 	restrictedGroup.GET("/api/wallet/capabilities", httpSvc.capabilitiesHandler)
 	restrictedGroup.POST("/api/payments/:invoice", httpSvc.sendPaymentHandler)
+	restrictedGroup.POST("/api/ecash/send", httpSvc.sendEcashTokenHandler)
+	restrictedGroup.POST("/api/ecash/receive", httpSvc.receiveEcashTokenHandler)
+	restrictedGroup.POST("/api/ecash/swap", httpSvc.swapEcashHandler)
 	restrictedGroup.POST("/api/invoices", httpSvc.makeInvoiceHandler)
 	restrictedGroup.GET("/api/transactions", httpSvc.listTransactionsHandler)
 	restrictedGroup.GET("/api/transactions/:paymentHash", httpSvc.lookupTransactionHandler)
@@ -508,6 +554,90 @@ func (httpSvc *HttpService) listTransactionsHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, transactions)
 }
 
+func (httpSvc *HttpService) listRebalancesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	rebalances, err := httpSvc.api.ListRebalances(ctx, limit, offset)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list rebalances: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, rebalances)
+}
+
+func (httpSvc *HttpService) rebalanceHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var rebalanceRequest api.RebalanceRequest
+	if err := c.Bind(&rebalanceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	rebalanceResponse, err := httpSvc.api.Rebalance(ctx, &rebalanceRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rebalance: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, rebalanceResponse)
+}
+
+func (httpSvc *HttpService) forwardingStatsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var from, until, interval uint64
+
+	if fromParam := c.QueryParam("from"); fromParam != "" {
+		if parsedFrom, err := strconv.ParseUint(fromParam, 10, 64); err == nil {
+			from = parsedFrom
+		}
+	}
+
+	if untilParam := c.QueryParam("until"); untilParam != "" {
+		if parsedUntil, err := strconv.ParseUint(untilParam, 10, 64); err == nil {
+			until = parsedUntil
+		}
+	}
+
+	if intervalParam := c.QueryParam("interval"); intervalParam != "" {
+		if parsedInterval, err := strconv.ParseUint(intervalParam, 10, 64); err == nil {
+			interval = parsedInterval
+		}
+	}
+
+	forwardingStats, err := httpSvc.api.GetForwardingStats(ctx, from, until, interval)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get forwarding stats: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, forwardingStats)
+}
+
 func (httpSvc *HttpService) walletSyncHandler(c echo.Context) error {
 	httpSvc.api.SyncWallet()
 
@@ -598,235 +728,969 @@ func (httpSvc *HttpService) openChannelHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, openChannelResponse)
 }
 
-func (httpSvc *HttpService) disconnectPeerHandler(c echo.Context) error {
+func (httpSvc *HttpService) openChannelsHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	err := httpSvc.api.DisconnectPeer(ctx, c.Param("peerId"))
+	var batchOpenChannelRequest api.BatchOpenChannelRequest
+	if err := c.Bind(&batchOpenChannelRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	batchOpenChannelResponse, err := httpSvc.api.OpenChannels(ctx, &batchOpenChannelRequest)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to disconnect peer: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to open channels: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, batchOpenChannelResponse)
 }
 
-func (httpSvc *HttpService) closeChannelHandler(c echo.Context) error {
+func (httpSvc *HttpService) listFundingTransactionChannelsHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	closeChannelResponse, err := httpSvc.api.CloseChannel(ctx, c.Param("peerId"), c.Param("channelId"), c.QueryParam("force") == "true")
+	channels, err := httpSvc.api.ListFundingTransactionChannels(ctx, c.Param("fundingTxId"))
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to close channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to list channels: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, closeChannelResponse)
+	return c.JSON(http.StatusOK, channels)
 }
 
-func (httpSvc *HttpService) updateChannelHandler(c echo.Context) error {
+func (httpSvc *HttpService) startPsbtChannelOpenHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var updateChannelRequest api.UpdateChannelRequest
-	if err := c.Bind(&updateChannelRequest); err != nil {
+	var openChannelRequest api.OpenChannelRequest
+	if err := c.Bind(&openChannelRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	updateChannelRequest.NodeId = c.Param("peerId")
-	updateChannelRequest.ChannelId = c.Param("channelId")
+	psbtChannelOpenResponse, err := httpSvc.api.StartPsbtChannelOpen(ctx, &openChannelRequest)
 
-	err := httpSvc.api.UpdateChannel(ctx, &updateChannelRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to start channel open: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, psbtChannelOpenResponse)
+}
+
+func (httpSvc *HttpService) verifyPsbtChannelFundingHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var verifyPsbtChannelFundingRequest api.VerifyPsbtChannelFundingRequest
+	if err := c.Bind(&verifyPsbtChannelFundingRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.VerifyPsbtChannelFunding(ctx, c.Param("pendingChannelId"), &verifyPsbtChannelFundingRequest)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to update channel: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to verify PSBT: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) newInstantChannelInvoiceHandler(c echo.Context) error {
+func (httpSvc *HttpService) finalizePsbtChannelOpenHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var newWrappedInvoiceRequest api.LSPOrderRequest
-	if err := c.Bind(&newWrappedInvoiceRequest); err != nil {
+	var finalizePsbtChannelOpenRequest api.FinalizePsbtChannelOpenRequest
+	if err := c.Bind(&finalizePsbtChannelOpenRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	newLSPOrderResponse, err := httpSvc.api.RequestLSPOrder(ctx, &newWrappedInvoiceRequest)
+	openChannelResponse, err := httpSvc.api.FinalizePsbtChannelOpen(ctx, c.Param("pendingChannelId"), &finalizePsbtChannelOpenRequest)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new channel order from LSP: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to open channel: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, newLSPOrderResponse)
+	return c.JSON(http.StatusOK, openChannelResponse)
 }
 
-func (httpSvc *HttpService) onchainAddressHandler(c echo.Context) error {
+func (httpSvc *HttpService) cancelPsbtChannelOpenHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	address, err := httpSvc.api.GetUnusedOnchainAddress(ctx)
+	err := httpSvc.api.CancelPsbtChannelOpen(ctx, c.Param("pendingChannelId"))
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to cancel channel open: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, address)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) newOnchainAddressHandler(c echo.Context) error {
+func (httpSvc *HttpService) disconnectPeerHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	address, err := httpSvc.api.GetNewOnchainAddress(ctx)
+	err := httpSvc.api.DisconnectPeer(ctx, c.Param("peerId"))
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to disconnect peer: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, address)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) redeemOnchainFundsHandler(c echo.Context) error {
+func (httpSvc *HttpService) closeChannelHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var redeemOnchainFundsRequest api.RedeemOnchainFundsRequest
-	if err := c.Bind(&redeemOnchainFundsRequest); err != nil {
+	closeChannelResponse, err := httpSvc.api.CloseChannel(ctx, c.Param("peerId"), c.Param("channelId"), c.QueryParam("force") == "true")
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to close channel: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, closeChannelResponse)
+}
+
+func (httpSvc *HttpService) updateChannelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var updateChannelRequest api.UpdateChannelRequest
+	if err := c.Bind(&updateChannelRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.SendAll)
+	updateChannelRequest.NodeId = c.Param("peerId")
+	updateChannelRequest.ChannelId = c.Param("channelId")
+
+	err := httpSvc.api.UpdateChannel(ctx, &updateChannelRequest)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to redeem onchain funds: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to update channel: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, redeemOnchainFundsResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) signMessageHandler(c echo.Context) error {
+func (httpSvc *HttpService) updateChannelsHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var signMessageRequest api.SignMessageRequest
-	if err := c.Bind(&signMessageRequest); err != nil {
+	var updateChannelsRequest api.UpdateChannelsRequest
+	if err := c.Bind(&updateChannelsRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	signMessageResponse, err := httpSvc.api.SignMessage(ctx, signMessageRequest.Message)
+	updateChannelsResponse, err := httpSvc.api.UpdateChannels(ctx, &updateChannelsRequest)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to sign messae: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to update channels: %s", err.Error()),
 		})
 	}
-	return c.JSON(http.StatusOK, signMessageResponse)
+
+	return c.JSON(http.StatusOK, updateChannelsResponse)
 }
 
-func (httpSvc *HttpService) appsListHandler(c echo.Context) error {
+func (httpSvc *HttpService) listLSPOrdersHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
 
-	apps, err := httpSvc.api.ListApps()
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	orders, err := httpSvc.api.ListLSPOrders(limit, offset)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to list LSP orders: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, apps)
+	return c.JSON(http.StatusOK, orders)
 }
 
-func (httpSvc *HttpService) appsShowHandler(c echo.Context) error {
-
-	// TODO: move this to DB service
-	dbApp := db.App{}
-	findResult := httpSvc.db.Where("nostr_pubkey = ?", c.Param("pubkey")).First(&dbApp)
+func (httpSvc *HttpService) listLSPsHandler(c echo.Context) error {
+	lsps, err := httpSvc.api.ListLSPs()
 
-	if findResult.RowsAffected == 0 {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App does not exist",
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list LSPs: %s", err.Error()),
 		})
 	}
 
-	response := httpSvc.api.GetApp(&dbApp)
-
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, lsps)
 }
 
-func (httpSvc *HttpService) appsUpdateHandler(c echo.Context) error {
-	var requestData api.UpdateAppRequest
-	if err := c.Bind(&requestData); err != nil {
+func (httpSvc *HttpService) addLSPHandler(c echo.Context) error {
+	var addLSPRequest api.AddLSPRequest
+	if err := c.Bind(&addLSPRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	// TODO: move this to DB service
-	dbApp := db.App{}
-	findResult := httpSvc.db.Where("nostr_pubkey = ?", c.Param("pubkey")).First(&dbApp)
+	lsp, err := httpSvc.api.AddLSP(&addLSPRequest)
 
-	if findResult.RowsAffected == 0 {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Message: "App does not exist",
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to add LSP: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.UpdateApp(&dbApp, &requestData)
+	return c.JSON(http.StatusOK, lsp)
+}
+
+func (httpSvc *HttpService) deleteLSPHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid LSP id: %s", err.Error()),
+		})
+	}
+
+	err = httpSvc.api.DeleteLSP(uint(id))
 
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to update app")
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to update app: %v", err),
+			Message: fmt.Sprintf("Failed to delete LSP: %s", err.Error()),
 		})
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) appsDeleteHandler(c echo.Context) error {
-	pubkey := c.Param("pubkey")
-	if pubkey == "" {
+func (httpSvc *HttpService) liquiditySettingsHandler(c echo.Context) error {
+	settings, err := httpSvc.api.GetLiquiditySettings()
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get liquidity settings: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (httpSvc *HttpService) updateLiquiditySettingsHandler(c echo.Context) error {
+	var settings api.LiquiditySettings
+	if err := c.Bind(&settings); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid pubkey parameter",
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
-	// TODO: move this to DB service
-	dbApp := db.App{}
-	result := httpSvc.db.Where("nostr_pubkey = ?", pubkey).First(&dbApp)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Message: "App not found",
-			})
-		}
+
+	err := httpSvc.api.UpdateLiquiditySettings(&settings)
+
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "Failed to fetch app",
+			Message: fmt.Sprintf("Failed to update liquidity settings: %s", err.Error()),
 		})
 	}
 
-	if err := httpSvc.api.DeleteApp(&dbApp); err != nil {
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listLiquidityDecisionsHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	decisions, err := httpSvc.api.ListLiquidityDecisions(limit, offset)
+
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "Failed to delete app",
+			Message: fmt.Sprintf("Failed to list liquidity decisions: %s", err.Error()),
 		})
 	}
-	return c.NoContent(http.StatusNoContent)
+
+	return c.JSON(http.StatusOK, decisions)
+}
+
+func (httpSvc *HttpService) swapInfoHandler(c echo.Context) error {
+	swapInfo, err := httpSvc.api.GetSwapInfo(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap info: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapInfo)
+}
+
+func (httpSvc *HttpService) swapInHandler(c echo.Context) error {
+	var swapInRequest api.SwapInRequest
+	if err := c.Bind(&swapInRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	swap, err := httpSvc.api.SwapIn(c.Request().Context(), &swapInRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to swap in: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swap)
+}
+
+func (httpSvc *HttpService) swapOutHandler(c echo.Context) error {
+	var swapOutRequest api.SwapOutRequest
+	if err := c.Bind(&swapOutRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	swap, err := httpSvc.api.SwapOut(c.Request().Context(), &swapOutRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to swap out: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swap)
+}
+
+func (httpSvc *HttpService) listSwapsHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	swaps, err := httpSvc.api.ListSwaps(limit, offset)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list swaps: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swaps)
+}
+
+func (httpSvc *HttpService) getSwapHandler(c echo.Context) error {
+	swap, err := httpSvc.api.GetSwap(c.Param("swapId"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swap)
+}
+
+func (httpSvc *HttpService) listConnectionRequestsHandler(c echo.Context) error {
+	connectionRequests, err := httpSvc.api.ListConnectionRequests()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list connection requests: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, connectionRequests)
+}
+
+func (httpSvc *HttpService) createConnectionRequestHandler(c echo.Context) error {
+	var createConnectionRequestRequest api.CreateConnectionRequestRequest
+	if err := c.Bind(&createConnectionRequestRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	connectionRequest, err := httpSvc.api.CreateConnectionRequest(&createConnectionRequestRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create connection request: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, connectionRequest)
+}
+
+func (httpSvc *HttpService) approveConnectionRequestHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid connection request id: %s", err.Error()),
+		})
+	}
+
+	app, err := httpSvc.api.ApproveConnectionRequest(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to approve connection request: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, app)
+}
+
+func (httpSvc *HttpService) rejectConnectionRequestHandler(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid connection request id: %s", err.Error()),
+		})
+	}
+
+	err = httpSvc.api.RejectConnectionRequest(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to reject connection request: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) relayStatusHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, httpSvc.api.GetRelayStatus())
+}
+
+func (httpSvc *HttpService) nip47RequestHandler(c echo.Context) error {
+	var event nostr.Event
+	if err := c.Bind(&event); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	responseBody, err := httpSvc.api.HandleNip47Request(c.Request().Context(), &event)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to handle NIP-47 request: %s", err.Error()),
+		})
+	}
+
+	if responseBody.RateLimited {
+		return c.JSON(http.StatusTooManyRequests, responseBody)
+	}
+	return c.JSON(http.StatusOK, responseBody)
+}
+
+func (httpSvc *HttpService) nip47QueueStatsHandler(c echo.Context) error {
+	queueStats, err := httpSvc.api.GetNip47QueueStats()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get NIP-47 queue stats: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, queueStats)
+}
+
+func (httpSvc *HttpService) newInstantChannelInvoiceHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var newWrappedInvoiceRequest api.LSPOrderRequest
+	if err := c.Bind(&newWrappedInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	newLSPOrderResponse, err := httpSvc.api.RequestLSPOrder(ctx, &newWrappedInvoiceRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new channel order from LSP: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, newLSPOrderResponse)
+}
+
+func (httpSvc *HttpService) onchainAddressHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	address, err := httpSvc.api.GetUnusedOnchainAddress(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+func (httpSvc *HttpService) newOnchainAddressHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	address, err := httpSvc.api.GetNewOnchainAddress(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to request new onchain address: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, address)
+}
+
+func (httpSvc *HttpService) redeemOnchainFundsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var redeemOnchainFundsRequest api.RedeemOnchainFundsRequest
+	if err := c.Bind(&redeemOnchainFundsRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.SendAll)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to redeem onchain funds: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, redeemOnchainFundsResponse)
+}
+
+func (httpSvc *HttpService) estimateOnchainFeesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var redeemOnchainFundsRequest api.RedeemOnchainFundsRequest
+	if err := c.Bind(&redeemOnchainFundsRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	feeEstimate, err := httpSvc.api.EstimateOnchainFees(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.SendAll)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to estimate onchain fees: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feeEstimate)
+}
+
+func (httpSvc *HttpService) listOnchainTransactionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	onchainTransactions, err := httpSvc.api.ListOnchainTransactions(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list onchain transactions: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, onchainTransactions)
+}
+
+func (httpSvc *HttpService) listOnchainLabelsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	onchainLabels, err := httpSvc.api.ListOnchainLabels(ctx, c.QueryParam("type"))
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list labels: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, onchainLabels)
+}
+
+func (httpSvc *HttpService) setOnchainLabelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var setOnchainLabelRequest api.SetOnchainLabelRequest
+	if err := c.Bind(&setOnchainLabelRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.SetOnchainLabel(ctx, &setOnchainLabelRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set label: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listHistoryHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	history, err := httpSvc.api.ListHistory(ctx, limit, offset)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list history: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+func (httpSvc *HttpService) listUtxosHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	utxos, err := httpSvc.api.ListUtxos(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list UTXOs: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, utxos)
+}
+
+func (httpSvc *HttpService) sendOnchainHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var sendOnchainRequest api.SendOnchainRequest
+	if err := c.Bind(&sendOnchainRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	sendOnchainResponse, err := httpSvc.api.SendOnchain(ctx, &sendOnchainRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to send onchain: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, sendOnchainResponse)
+}
+
+func (httpSvc *HttpService) bumpOnchainFeeHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var bumpOnchainFeeRequest api.BumpOnchainFeeRequest
+	if err := c.Bind(&bumpOnchainFeeRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	bumpOnchainFeeResponse, err := httpSvc.api.BumpOnchainFee(ctx, &bumpOnchainFeeRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to bump fee: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, bumpOnchainFeeResponse)
+}
+
+func (httpSvc *HttpService) signMessageHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var signMessageRequest api.SignMessageRequest
+	if err := c.Bind(&signMessageRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	signMessageResponse, err := httpSvc.api.SignMessage(ctx, signMessageRequest.Message)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to sign messae: %s", err.Error()),
+		})
+	}
+	return c.JSON(http.StatusOK, signMessageResponse)
+}
+
+func (httpSvc *HttpService) sendEcashTokenHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var sendEcashTokenRequest api.SendEcashTokenRequest
+	if err := c.Bind(&sendEcashTokenRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	sendEcashTokenResponse, err := httpSvc.api.SendEcashToken(ctx, &sendEcashTokenRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to send ecash token: %s", err.Error()),
+		})
+	}
+	return c.JSON(http.StatusOK, sendEcashTokenResponse)
+}
+
+func (httpSvc *HttpService) receiveEcashTokenHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var receiveEcashTokenRequest api.ReceiveEcashTokenRequest
+	if err := c.Bind(&receiveEcashTokenRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	receiveEcashTokenResponse, err := httpSvc.api.ReceiveEcashToken(ctx, &receiveEcashTokenRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to receive ecash token: %s", err.Error()),
+		})
+	}
+	return c.JSON(http.StatusOK, receiveEcashTokenResponse)
+}
+
+func (httpSvc *HttpService) swapEcashHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var swapEcashRequest api.SwapEcashRequest
+	if err := c.Bind(&swapEcashRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.SwapEcashToDefaultMint(ctx, &swapEcashRequest)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to swap ecash: %s", err.Error()),
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) appsListHandler(c echo.Context) error {
+
+	apps, err := httpSvc.api.ListApps()
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, apps)
+}
+
+func (httpSvc *HttpService) appsShowHandler(c echo.Context) error {
+
+	// TODO: move this to DB service
+	dbApp := db.App{}
+	findResult := httpSvc.db.Where("nostr_pubkey = ?", c.Param("pubkey")).First(&dbApp)
+
+	if findResult.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App does not exist",
+		})
+	}
+
+	response := httpSvc.api.GetApp(&dbApp)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (httpSvc *HttpService) appsUpdateHandler(c echo.Context) error {
+	var requestData api.UpdateAppRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	// TODO: move this to DB service
+	dbApp := db.App{}
+	findResult := httpSvc.db.Where("nostr_pubkey = ?", c.Param("pubkey")).First(&dbApp)
+
+	if findResult.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App does not exist",
+		})
+	}
+
+	err := httpSvc.api.UpdateApp(&dbApp, &requestData)
+
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to update app")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update app: %v", err),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) appsRotateWalletKeyHandler(c echo.Context) error {
+	// TODO: move this to DB service
+	dbApp := db.App{}
+	findResult := httpSvc.db.Where("nostr_pubkey = ?", c.Param("pubkey")).First(&dbApp)
+
+	if findResult.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "App does not exist",
+		})
+	}
+
+	app, err := httpSvc.api.RotateAppWalletKey(&dbApp)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to rotate app wallet key")
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rotate app wallet key: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, app)
+}
+
+func (httpSvc *HttpService) appsDeleteHandler(c echo.Context) error {
+	pubkey := c.Param("pubkey")
+	if pubkey == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid pubkey parameter",
+		})
+	}
+	// TODO: move this to DB service
+	dbApp := db.App{}
+	result := httpSvc.db.Where("nostr_pubkey = ?", pubkey).First(&dbApp)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{
+				Message: "App not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "Failed to fetch app",
+		})
+	}
+
+	if err := httpSvc.api.DeleteApp(&dbApp); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "Failed to delete app",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) appRequestsListHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	filters := &api.ListAppRequestsFilters{
+		Method: c.QueryParam("method"),
+		State:  c.QueryParam("state"),
+	}
+	if fromParam := c.QueryParam("from"); fromParam != "" {
+		if parsedFrom, err := strconv.ParseInt(fromParam, 10, 64); err == nil {
+			from := time.Unix(parsedFrom, 0)
+			filters.From = &from
+		}
+	}
+	if untilParam := c.QueryParam("until"); untilParam != "" {
+		if parsedUntil, err := strconv.ParseInt(untilParam, 10, 64); err == nil {
+			until := time.Unix(parsedUntil, 0)
+			filters.Until = &until
+		}
+	}
+
+	appRequests, err := httpSvc.api.ListAppRequests(c.Param("pubkey"), filters, limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list app requests: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, appRequests)
 }
 
 func (httpSvc *HttpService) appsCreateHandler(c echo.Context) error {
@@ -0,0 +1,490 @@
+package liquidity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/transactions"
+)
+
+const settingsKey = "LiquidityAutopilot"
+
+const checkInterval = 30 * time.Minute
+
+// no new channel is purchased while the previously purchased channel is still opening
+// and failed purchases are not retried immediately
+const purchaseCooldown = 2 * time.Hour
+
+// the spending budget applies to a rolling window
+const budgetWindow = 30 * 24 * time.Hour
+
+const (
+	DECISION_ACTION_PURCHASED = "purchased"
+	DECISION_ACTION_PENDING   = "pending" // the payment is still in flight, e.g. after a timeout
+	DECISION_ACTION_DRY_RUN   = "dry_run"
+	DECISION_ACTION_SKIPPED   = "skipped"
+	DECISION_ACTION_FAILED    = "failed"
+)
+
+const PROVIDER_ALBY = "alby"
+
+type Settings struct {
+	Enabled bool `json:"enabled"`
+	// log the decisions without purchasing anything
+	DryRun bool `json:"dryRun"`
+	// inbound liquidity is purchased when the receivable balance falls below this amount
+	MinReceivableSat uint64 `json:"minReceivableSat"`
+	// size of the purchased channel, the Alby auto channel size is chosen by the LSP
+	ChannelSizeSat uint64 `json:"channelSizeSat"`
+	// maximum amount spent on channel fees in a rolling 30 day window
+	BudgetSat uint64 `json:"budgetSat"`
+	// registered LSPS1 LSP to purchase from, 0 requests an Alby auto channel
+	LSPId  uint `json:"lspId"`
+	Public bool `json:"public"`
+}
+
+type liquidityService struct {
+	db                  *gorm.DB
+	cfg                 config.Config
+	eventPublisher      events.EventPublisher
+	lspService          lsp.LSPService
+	albyOAuthSvc        alby.AlbyOAuthService
+	transactionsService transactions.TransactionsService
+	lnClient            lnclient.LNClient
+	lnClientMutex       sync.RWMutex
+	checkMutex          sync.Mutex
+}
+
+type LiquidityService interface {
+	events.EventSubscriber
+	GetSettings() (*Settings, error)
+	UpdateSettings(settings *Settings) error
+	// checks the receivable balance and purchases inbound liquidity if it is below the threshold.
+	// Returns nil if there is enough inbound liquidity or the autopilot is disabled.
+	Check(ctx context.Context) (*db.LiquidityDecision, error)
+	ListDecisions(limit, offset uint64) ([]db.LiquidityDecision, error)
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+}
+
+func NewLiquidityService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher, lspService lsp.LSPService, albyOAuthSvc alby.AlbyOAuthService, transactionsService transactions.TransactionsService) *liquidityService {
+	return &liquidityService{
+		db:                  db,
+		cfg:                 cfg,
+		eventPublisher:      eventPublisher,
+		lspService:          lspService,
+		albyOAuthSvc:        albyOAuthSvc,
+		transactionsService: transactionsService,
+	}
+}
+
+func (svc *liquidityService) GetSettings() (*Settings, error) {
+	settings := &Settings{}
+	settingsJson, err := svc.cfg.Get(settingsKey, "")
+	if err != nil {
+		return nil, err
+	}
+	if settingsJson == "" {
+		return settings, nil
+	}
+	err = json.Unmarshal([]byte(settingsJson), settings)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (svc *liquidityService) UpdateSettings(settings *Settings) error {
+	if settings.Enabled {
+		if settings.MinReceivableSat == 0 {
+			return errors.New("no minimum receivable amount provided")
+		}
+		if settings.BudgetSat == 0 {
+			return errors.New("no budget provided")
+		}
+		if settings.LSPId != 0 {
+			if settings.ChannelSizeSat <= settings.MinReceivableSat {
+				return errors.New("channel size must be greater than the minimum receivable amount")
+			}
+			registeredLSP, err := svc.lspService.GetLSP(settings.LSPId)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(strings.Split(registeredLSP.Protocols, ","), lsp.LSP_TYPE_LSPS1) {
+				return fmt.Errorf("LSP %s does not support %s", registeredLSP.Name, lsp.LSP_TYPE_LSPS1)
+			}
+		}
+	}
+
+	settingsJson, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	svc.cfg.SetUpdate(settingsKey, string(settingsJson), "")
+	return nil
+}
+
+func (svc *liquidityService) ListDecisions(limit, offset uint64) ([]db.LiquidityDecision, error) {
+	decisions := []db.LiquidityDecision{}
+	query := svc.db.Order("created_at desc")
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if offset > 0 {
+		query = query.Offset(int(offset))
+	}
+	err := query.Find(&decisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+func (svc *liquidityService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	svc.setLNClient(lnClient)
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				svc.setLNClient(nil)
+				return
+			case <-ticker.C:
+				svc.check(ctx)
+			}
+		}
+	}()
+}
+
+func (svc *liquidityService) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	switch event.Event {
+	case "nwc_channel_closed", "nwc_incoming_liquidity_required", "nwc_lnclient_payment_received":
+		svc.check(ctx)
+	}
+}
+
+func (svc *liquidityService) check(ctx context.Context) {
+	_, err := svc.Check(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to check inbound liquidity")
+	}
+}
+
+func (svc *liquidityService) Check(ctx context.Context) (*db.LiquidityDecision, error) {
+	settings, err := svc.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, nil
+	}
+
+	lnClient := svc.getLNClient()
+	if lnClient == nil {
+		return nil, nil
+	}
+
+	// events can trigger concurrent checks which must not purchase twice
+	svc.checkMutex.Lock()
+	defer svc.checkMutex.Unlock()
+
+	balances, err := lnClient.GetBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	receivable := uint64(max(balances.Lightning.TotalReceivable, 0)) / 1000
+	if receivable >= settings.MinReceivableSat {
+		return nil, nil
+	}
+
+	decision := &db.LiquidityDecision{
+		ReceivableSat: receivable,
+		ThresholdSat:  settings.MinReceivableSat,
+		AmountSat:     settings.ChannelSizeSat,
+		Provider:      PROVIDER_ALBY,
+		DryRun:        settings.DryRun,
+	}
+
+	var registeredLSP *db.LSP
+	if settings.LSPId != 0 {
+		registeredLSP, err = svc.lspService.GetLSP(settings.LSPId)
+		if err != nil {
+			return nil, err
+		}
+		decision.Provider = registeredLSP.Url
+	}
+
+	reason, err := svc.getSkipReason(ctx, lnClient, settings)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		decision.Action = DECISION_ACTION_SKIPPED
+		decision.Reason = reason
+		return svc.saveDecision(decision)
+	}
+
+	if settings.DryRun {
+		decision.Action = DECISION_ACTION_DRY_RUN
+		decision.Reason = "receivable balance is below the threshold"
+		return svc.saveDecision(decision)
+	}
+
+	svc.purchase(ctx, lnClient, settings, registeredLSP, decision)
+	return svc.saveDecision(decision)
+}
+
+func (svc *liquidityService) getSkipReason(ctx context.Context, lnClient lnclient.LNClient, settings *Settings) (string, error) {
+	pending, err := svc.updatePendingDecisions()
+	if err != nil {
+		return "", err
+	}
+	if pending {
+		return "the payment of the last purchase is still pending", nil
+	}
+
+	var lastAttempt db.LiquidityDecision
+	result := svc.db.Where("action IN ?", []string{DECISION_ACTION_PURCHASED, DECISION_ACTION_FAILED}).Order("created_at desc").Limit(1).Find(&lastAttempt)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected > 0 && time.Since(lastAttempt.CreatedAt) < purchaseCooldown {
+		if lastAttempt.Action == DECISION_ACTION_FAILED {
+			return "the last purchase failed recently", nil
+		}
+		return "a channel was purchased recently", nil
+	}
+
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, channel := range channels {
+		if channel.Confirmations != nil && channel.ConfirmationsRequired != nil && *channel.Confirmations < *channel.ConfirmationsRequired {
+			return "a channel is still opening", nil
+		}
+	}
+
+	spent, err := svc.getSpentSat()
+	if err != nil {
+		return "", err
+	}
+	if spent >= settings.BudgetSat {
+		return "budget exhausted", nil
+	}
+
+	return "", nil
+}
+
+// updatePendingDecisions updates the decisions whose payment was still in flight
+// and returns whether any payment is still pending
+func (svc *liquidityService) updatePendingDecisions() (bool, error) {
+	var pendingDecisions []db.LiquidityDecision
+	err := svc.db.Where("action = ?", DECISION_ACTION_PENDING).Find(&pendingDecisions).Error
+	if err != nil {
+		return false, err
+	}
+
+	pending := false
+	for i := range pendingDecisions {
+		decision := &pendingDecisions[i]
+		transaction, err := svc.findPayment(decision.Invoice)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case transaction != nil && transaction.State == constants.TRANSACTION_STATE_SETTLED:
+			decision.Action = DECISION_ACTION_PURCHASED
+			decision.Reason = "receivable balance is below the threshold"
+		case transaction == nil || transaction.State == constants.TRANSACTION_STATE_FAILED:
+			decision.Action = DECISION_ACTION_FAILED
+			decision.Reason = "failed to pay invoice"
+			if transaction != nil && transaction.FailureReason != "" {
+				decision.Reason = fmt.Sprintf("failed to pay invoice: %s", transaction.FailureReason)
+			}
+		default:
+			pending = true
+			continue
+		}
+
+		err = svc.db.Model(decision).Updates(map[string]interface{}{
+			"action": decision.Action,
+			"reason": decision.Reason,
+		}).Error
+		if err != nil {
+			return false, err
+		}
+		if decision.Action == DECISION_ACTION_PURCHASED {
+			svc.publishPurchase(decision)
+		}
+	}
+	return pending, nil
+}
+
+// findPayment returns the latest payment of an invoice, or nil if it was not paid
+func (svc *liquidityService) findPayment(invoice string) (*db.Transaction, error) {
+	paymentRequest, err := decodepay.Decodepay(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction db.Transaction
+	result := svc.db.Limit(1).Order("created_at desc").Find(&transaction, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash: paymentRequest.PaymentHash,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &transaction, nil
+}
+
+// getSpentSat returns the fees of purchases in the budget window, including those whose payment is still pending
+func (svc *liquidityService) getSpentSat() (uint64, error) {
+	var spent uint64
+	err := svc.db.Model(&db.LiquidityDecision{}).
+		Where("action IN ? AND created_at > ?", []string{DECISION_ACTION_PURCHASED, DECISION_ACTION_PENDING}, time.Now().Add(-budgetWindow)).
+		Select("COALESCE(SUM(fee_sat), 0)").
+		Scan(&spent).Error
+	return spent, err
+}
+
+// purchase requests a channel from the provider and pays for it if the cost is within the budget
+func (svc *liquidityService) purchase(ctx context.Context, lnClient lnclient.LNClient, settings *Settings, registeredLSP *db.LSP, decision *db.LiquidityDecision) {
+	if registeredLSP != nil {
+		order, err := svc.lspService.RequestOrder(ctx, lnClient, registeredLSP.Url, settings.ChannelSizeSat, settings.Public)
+		if err != nil {
+			decision.Action = DECISION_ACTION_FAILED
+			decision.Reason = err.Error()
+			return
+		}
+		decision.Invoice = order.Invoice
+	} else {
+		autoChannelResponse, err := svc.albyOAuthSvc.RequestAutoChannel(ctx, lnClient, settings.Public)
+		if err != nil {
+			decision.Action = DECISION_ACTION_FAILED
+			decision.Reason = err.Error()
+			return
+		}
+		decision.Invoice = autoChannelResponse.Invoice
+		decision.AmountSat = autoChannelResponse.ChannelSize
+	}
+
+	// the LSP is paid the full invoice amount
+	paymentRequest, err := decodepay.Decodepay(decision.Invoice)
+	if err != nil {
+		decision.Action = DECISION_ACTION_FAILED
+		decision.Reason = fmt.Sprintf("failed to decode invoice: %v", err)
+		return
+	}
+	decision.FeeSat = uint64(paymentRequest.MSatoshi / 1000)
+
+	spent, err := svc.getSpentSat()
+	if err != nil {
+		decision.Action = DECISION_ACTION_FAILED
+		decision.Reason = err.Error()
+		return
+	}
+	if spent+decision.FeeSat > settings.BudgetSat {
+		decision.Action = DECISION_ACTION_SKIPPED
+		decision.Reason = fmt.Sprintf("fee of %d sats exceeds the remaining budget of %d sats", decision.FeeSat, settings.BudgetSat-spent)
+		return
+	}
+
+	_, err = svc.transactionsService.SendPaymentSync(ctx, decision.Invoice, lnClient, nil, nil)
+	if err != nil {
+		// a payment which timed out can still succeed, so it is not recorded as failed
+		transaction, findErr := svc.findPayment(decision.Invoice)
+		if findErr == nil && transaction != nil && transaction.State == constants.TRANSACTION_STATE_PENDING {
+			decision.Action = DECISION_ACTION_PENDING
+			decision.Reason = fmt.Sprintf("payment is still in flight: %v", err)
+			return
+		}
+		decision.Action = DECISION_ACTION_FAILED
+		decision.Reason = fmt.Sprintf("failed to pay invoice: %v", err)
+		return
+	}
+
+	decision.Action = DECISION_ACTION_PURCHASED
+	decision.Reason = "receivable balance is below the threshold"
+	svc.publishPurchase(decision)
+}
+
+func (svc *liquidityService) publishPurchase(decision *db.LiquidityDecision) {
+	logger.Logger.WithFields(logrus.Fields{
+		"provider":   decision.Provider,
+		"amount":     decision.AmountSat,
+		"fee":        decision.FeeSat,
+		"receivable": decision.ReceivableSat,
+	}).Info("Purchased inbound liquidity")
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_liquidity_purchased",
+		Properties: map[string]interface{}{
+			"provider": decision.Provider,
+			"amount":   decision.AmountSat,
+			"fee":      decision.FeeSat,
+		},
+	})
+}
+
+func (svc *liquidityService) saveDecision(decision *db.LiquidityDecision) (*db.LiquidityDecision, error) {
+	if decision.Action == DECISION_ACTION_SKIPPED || decision.Action == DECISION_ACTION_DRY_RUN {
+		// checks run frequently, so the same decision is only logged once in a row
+		var lastDecision db.LiquidityDecision
+		result := svc.db.Order("created_at desc").Limit(1).Find(&lastDecision)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 && lastDecision.Action == decision.Action && lastDecision.Reason == decision.Reason {
+			return &lastDecision, nil
+		}
+	}
+
+	err := svc.db.Create(decision).Error
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"action":     decision.Action,
+		"reason":     decision.Reason,
+		"receivable": decision.ReceivableSat,
+		"threshold":  decision.ThresholdSat,
+	}).Info("Inbound liquidity autopilot decision")
+	return decision, nil
+}
+
+func (svc *liquidityService) setLNClient(lnClient lnclient.LNClient) {
+	svc.lnClientMutex.Lock()
+	defer svc.lnClientMutex.Unlock()
+	svc.lnClient = lnClient
+}
+
+func (svc *liquidityService) getLNClient() lnclient.LNClient {
+	svc.lnClientMutex.RLock()
+	defer svc.lnClientMutex.RUnlock()
+	return svc.lnClient
+}
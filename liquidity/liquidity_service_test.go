@@ -0,0 +1,209 @@
+package liquidity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+func newMockLSPS1Server() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get_info", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&lsp.LSPS1Info{
+			MaxChannelExpiryBlocks: 13000,
+			URIs:                   []string{tests.MockLSPPubkey + "@127.0.0.1:9735"},
+		})
+	})
+	mux.HandleFunc("/create_order", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&lsp.LSPS1Order{
+			OrderId:    "mock-order",
+			OrderState: lsp.LSPS1_ORDER_STATE_CREATED,
+			Payment: &lsp.LSPS1OrderPayment{
+				Bolt11: lsp.LSPS1OrderPaymentBolt11{
+					State:       lsp.LSPS1_PAYMENT_STATE_EXPECT_PAYMENT,
+					Invoice:     tests.MockInvoice,
+					FeeTotalSat: "123",
+				},
+			},
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func setupLiquidityService(t *testing.T, settings *Settings) (*liquidityService, *tests.TestService, *httptest.Server) {
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	server := newMockLSPS1Server()
+	lspService := lsp.NewLSPService(svc.DB, svc.Cfg, svc.EventPublisher)
+	registeredLSP, err := lspService.AddLSP("Mock", server.URL, tests.MockLSPPubkey, []string{lsp.LSP_TYPE_LSPS1}, "bitcoin")
+	require.NoError(t, err)
+
+	svc.LNClient.(*tests.MockLn).Balances = &lnclient.BalancesResponse{
+		Lightning: lnclient.LightningBalanceResponse{
+			TotalReceivable: 10_000_000,
+		},
+	}
+
+	liquiditySvc := NewLiquidityService(svc.DB, svc.Cfg, svc.EventPublisher, lspService, nil, transactions.NewTransactionsService(svc.DB, svc.EventPublisher))
+	settings.LSPId = registeredLSP.ID
+	err = liquiditySvc.UpdateSettings(settings)
+	require.NoError(t, err)
+	liquiditySvc.setLNClient(svc.LNClient)
+
+	return liquiditySvc, svc, server
+}
+
+func TestCheck_EnoughReceivable(t *testing.T) {
+	defer tests.RemoveTestService()
+	liquiditySvc, _, server := setupLiquidityService(t, &Settings{
+		Enabled:          true,
+		MinReceivableSat: 5_000,
+		ChannelSizeSat:   1_000_000,
+		BudgetSat:        10_000,
+	})
+	defer server.Close()
+
+	decision, err := liquiditySvc.Check(context.TODO())
+	assert.NoError(t, err)
+	assert.Nil(t, decision)
+}
+
+func TestCheck_DryRun(t *testing.T) {
+	defer tests.RemoveTestService()
+	liquiditySvc, svc, server := setupLiquidityService(t, &Settings{
+		Enabled:          true,
+		DryRun:           true,
+		MinReceivableSat: 100_000,
+		ChannelSizeSat:   1_000_000,
+		BudgetSat:        10_000,
+	})
+	defer server.Close()
+
+	decision, err := liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_DRY_RUN, decision.Action)
+	assert.Equal(t, uint64(10_000), decision.ReceivableSat)
+	assert.Equal(t, uint64(100_000), decision.ThresholdSat)
+	assert.Equal(t, uint64(1_000_000), decision.AmountSat)
+	assert.Equal(t, server.URL, decision.Provider)
+
+	// the same decision is only logged once
+	_, err = liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	decisions, err := liquiditySvc.ListDecisions(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(decisions))
+
+	var orderCount int64
+	svc.DB.Model(&db.LSPOrder{}).Count(&orderCount)
+	assert.Equal(t, int64(0), orderCount)
+}
+
+func TestCheck_Purchase(t *testing.T) {
+	defer tests.RemoveTestService()
+	liquiditySvc, svc, server := setupLiquidityService(t, &Settings{
+		Enabled:          true,
+		MinReceivableSat: 100_000,
+		ChannelSizeSat:   1_000_000,
+		BudgetSat:        10_000,
+	})
+	defer server.Close()
+
+	decision, err := liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_PURCHASED, decision.Action)
+	assert.Equal(t, tests.MockInvoice, decision.Invoice)
+	assert.Equal(t, uint64(123), decision.FeeSat)
+
+	var order db.LSPOrder
+	err = svc.DB.First(&order).Error
+	require.NoError(t, err)
+	assert.Equal(t, "mock-order", order.OrderId)
+	assert.Equal(t, uint64(1_000_000), order.LSPBalanceSat)
+
+	var payment db.Transaction
+	err = svc.DB.First(&payment, &db.Transaction{PaymentHash: tests.MockPaymentHash}).Error
+	require.NoError(t, err)
+
+	// the purchased channel is not open yet
+	decision, err = liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_SKIPPED, decision.Action)
+	assert.Equal(t, "a channel was purchased recently", decision.Reason)
+}
+
+func TestCheck_OverBudget(t *testing.T) {
+	defer tests.RemoveTestService()
+	liquiditySvc, svc, server := setupLiquidityService(t, &Settings{
+		Enabled:          true,
+		MinReceivableSat: 100_000,
+		ChannelSizeSat:   1_000_000,
+		BudgetSat:        100,
+	})
+	defer server.Close()
+
+	decision, err := liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_SKIPPED, decision.Action)
+	assert.Equal(t, "fee of 123 sats exceeds the remaining budget of 100 sats", decision.Reason)
+
+	var paymentCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&paymentCount)
+	assert.Equal(t, int64(0), paymentCount)
+}
+
+func TestCheck_PaymentTimeout(t *testing.T) {
+	defer tests.RemoveTestService()
+	liquiditySvc, svc, server := setupLiquidityService(t, &Settings{
+		Enabled:          true,
+		MinReceivableSat: 100_000,
+		ChannelSizeSat:   1_000_000,
+		BudgetSat:        200,
+	})
+	defer server.Close()
+
+	mockLn := svc.LNClient.(*tests.MockLn)
+	mockLn.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{nil}
+	mockLn.PayInvoiceErrors = []error{lnclient.NewTimeoutError()}
+
+	// the payment may still succeed, so it is not recorded as failed
+	decision, err := liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_PENDING, decision.Action)
+	spent, err := liquiditySvc.getSpentSat()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123), spent)
+
+	// nothing is purchased while the payment is in flight, even after the cooldown
+	svc.DB.Model(decision).Update("created_at", time.Now().Add(-purchaseCooldown))
+	decision, err = liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_SKIPPED, decision.Action)
+	assert.Equal(t, "the payment of the last purchase is still pending", decision.Reason)
+
+	// the decision is updated once the payment settled
+	err = svc.DB.Model(&db.Transaction{}).Where("payment_hash = ?", tests.MockPaymentHash).Update("state", constants.TRANSACTION_STATE_SETTLED).Error
+	require.NoError(t, err)
+	decision, err = liquiditySvc.Check(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, DECISION_ACTION_SKIPPED, decision.Action)
+	assert.Equal(t, "fee of 123 sats exceeds the remaining budget of 77 sats", decision.Reason)
+
+	var purchaseCount int64
+	svc.DB.Model(&db.LiquidityDecision{}).Where("action = ?", DECISION_ACTION_PURCHASED).Count(&purchaseCount)
+	assert.Equal(t, int64(1), purchaseCount)
+}
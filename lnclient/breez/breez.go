@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -374,14 +375,15 @@ func (bs *BreezService) GetOnchainBalance(ctx context.Context) (*lnclient.Onchai
 }
 
 func (bs *BreezService) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (txId string, err error) {
-	if !sendAll {
-		return "", errors.New("only send all is supported")
-	}
-
 	if toAddress == "" {
 		return "", errors.New("No address provided")
 	}
 
+	if !sendAll {
+		// the transaction ID of a swap is only known later
+		return "", errors.New("sending a specific amount onchain requires a swap")
+	}
+
 	recommendedFees, err := bs.svc.RecommendedFees()
 	if err != nil {
 		logger.Logger.Errorf("Failed to get recommended fees info: %v", err)
@@ -410,6 +412,157 @@ func (bs *BreezService) RedeemOnchainFunds(ctx context.Context, toAddress string
 	return hex.EncodeToString(redeemOnchainFundsResponse.Txid), nil
 }
 
+// PayOnchainViaSwap sends a specific amount onchain from the lightning balance using a reverse swap
+func (bs *BreezService) PayOnchainViaSwap(ctx context.Context, toAddress string, amount uint64) (swapId string, err error) {
+	if toAddress == "" {
+		return "", errors.New("No address provided")
+	}
+
+	prepareOnchainPaymentResponse, err := bs.prepareOnchainPayment(amount)
+	if err != nil {
+		return "", err
+	}
+
+	payOnchainResponse, err := bs.svc.PayOnchain(breez_sdk.PayOnchainRequest{
+		RecipientAddress: toAddress,
+		PrepareRes:       *prepareOnchainPaymentResponse,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to pay onchain")
+		return "", err
+	}
+	logger.Logger.WithField("reverseSwapInfo", payOnchainResponse.ReverseSwapInfo).Info("Started reverse swap")
+
+	return payOnchainResponse.ReverseSwapInfo.Id, nil
+}
+
+func (bs *BreezService) GetOnchainSwap(ctx context.Context, swapId string) (*lnclient.OnchainSwap, error) {
+	inProgressOnchainPayments, err := bs.svc.InProgressOnchainPayments()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list in progress onchain payments")
+		return nil, err
+	}
+	for _, reverseSwapInfo := range inProgressOnchainPayments {
+		if reverseSwapInfo.Id == swapId {
+			return toOnchainSwap(&reverseSwapInfo), nil
+		}
+	}
+
+	// finished swaps are only referenced by the lightning payment which funded them
+	filters := []breez_sdk.PaymentTypeFilter{breez_sdk.PaymentTypeFilterSent}
+	includeFailures := true
+	payments, err := bs.svc.ListPayments(breez_sdk.ListPaymentsRequest{
+		Filters:         &filters,
+		IncludeFailures: &includeFailures,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list payments")
+		return nil, err
+	}
+	for _, payment := range payments {
+		lnDetails, ok := payment.Details.(breez_sdk.PaymentDetailsLn)
+		if ok && lnDetails.Data.ReverseSwapInfo != nil && lnDetails.Data.ReverseSwapInfo.Id == swapId {
+			return toOnchainSwap(lnDetails.Data.ReverseSwapInfo), nil
+		}
+	}
+
+	return nil, fmt.Errorf("reverse swap not found: %s", swapId)
+}
+
+func toOnchainSwap(reverseSwapInfo *breez_sdk.ReverseSwapInfo) *lnclient.OnchainSwap {
+	onchainSwap := &lnclient.OnchainSwap{
+		Confirmed: reverseSwapInfo.Status == breez_sdk.ReverseSwapStatusCompletedConfirmed,
+		Failed:    reverseSwapInfo.Status == breez_sdk.ReverseSwapStatusCancelled,
+	}
+	// the claim transaction pays the recipient, it is only broadcast
+	// once the swap lockup transaction has been confirmed
+	if reverseSwapInfo.ClaimTxid != nil {
+		onchainSwap.TxId = *reverseSwapInfo.ClaimTxid
+	}
+	return onchainSwap
+}
+
+func (bs *BreezService) prepareOnchainPayment(amount uint64) (*breez_sdk.PrepareOnchainPaymentResponse, error) {
+	limits, err := bs.svc.OnchainPaymentLimits()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get onchain payment limits")
+		return nil, err
+	}
+	if amount < limits.MinSat || amount > limits.MaxSat {
+		return nil, fmt.Errorf("amount must be between %d and %d sats", limits.MinSat, limits.MaxSat)
+	}
+
+	recommendedFees, err := bs.svc.RecommendedFees()
+	if err != nil {
+		logger.Logger.Errorf("Failed to get recommended fees info: %v", err)
+		return nil, err
+	}
+
+	prepareOnchainPaymentResponse, err := bs.svc.PrepareOnchainPayment(breez_sdk.PrepareOnchainPaymentRequest{
+		AmountSat:      amount,
+		AmountType:     breez_sdk.SwapAmountTypeReceive,
+		ClaimTxFeerate: uint32(recommendedFees.FastestFee),
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to prepare onchain payment")
+		return nil, err
+	}
+	if prepareOnchainPaymentResponse.SenderAmountSat > limits.MaxPayableSat {
+		return nil, fmt.Errorf("insufficient balance: %d sats required including fees", prepareOnchainPaymentResponse.SenderAmountSat)
+	}
+	logger.Logger.Infof("PrepareOnchainPayment response: %#v", prepareOnchainPaymentResponse)
+
+	return &prepareOnchainPaymentResponse, nil
+}
+
+func (bs *BreezService) EstimateOnchainFees(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*lnclient.OnchainFeeEstimate, error) {
+	if !sendAll {
+		prepareOnchainPaymentResponse, err := bs.prepareOnchainPayment(amount)
+		if err != nil {
+			return nil, err
+		}
+		return &lnclient.OnchainFeeEstimate{
+			Amount: prepareOnchainPaymentResponse.RecipientAmountSat,
+			Fee:    prepareOnchainPaymentResponse.TotalFees,
+		}, nil
+	}
+
+	if toAddress == "" {
+		return nil, errors.New("No address provided")
+	}
+
+	recommendedFees, err := bs.svc.RecommendedFees()
+	if err != nil {
+		logger.Logger.Errorf("Failed to get recommended fees info: %v", err)
+		return nil, err
+	}
+
+	nodeInfo, err := bs.svc.NodeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	prepareRedeemOnchainFundsResponse, err := bs.svc.PrepareRedeemOnchainFunds(breez_sdk.PrepareRedeemOnchainFundsRequest{
+		SatPerVbyte: uint32(recommendedFees.FastestFee),
+		ToAddress:   toAddress,
+	})
+	if err != nil {
+		logger.Logger.Errorf("Failed to prepare onchain address: %v", err)
+		return nil, err
+	}
+
+	onchainBalance := nodeInfo.OnchainBalanceMsat / 1000
+	if prepareRedeemOnchainFundsResponse.TxFeeSat > onchainBalance {
+		return nil, errors.New("onchain balance is too low to cover the fees")
+	}
+
+	return &lnclient.OnchainFeeEstimate{
+		Amount:  onchainBalance - prepareRedeemOnchainFundsResponse.TxFeeSat,
+		Fee:     prepareRedeemOnchainFundsResponse.TxFeeSat,
+		FeeRate: recommendedFees.FastestFee,
+	}, nil
+}
+
 func (bs *BreezService) ResetRouter(key string) error {
 	return nil
 }
@@ -422,6 +575,10 @@ func (bs *BreezService) SendSpontaneousPaymentProbes(ctx context.Context, amount
 	return nil
 }
 
+func (bs *BreezService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	return []lnclient.OnchainTransaction{}, nil
+}
+
 func (bs *BreezService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	return nil, nil
 }
@@ -486,7 +643,7 @@ func (bs *BreezService) DisconnectPeer(ctx context.Context, peerId string) error
 }
 
 func (bs *BreezService) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice" /*"pay_keysend",*/, "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message"}
+	return []string{"pay_invoice" /*"pay_keysend",*/, "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "get_budget"}
 }
 
 func (bs *BreezService) GetSupportedNIP47NotificationTypes() []string {
@@ -3,12 +3,16 @@ package cashu
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"time"
 
+	"github.com/elnosh/gonuts/cashu/nuts/nut05"
 	"github.com/elnosh/gonuts/wallet"
 	"github.com/elnosh/gonuts/wallet/storage"
 	"github.com/getAlby/hub/lnclient"
@@ -19,9 +23,12 @@ import (
 
 type CashuService struct {
 	wallet *wallet.Wallet
+	// persists the mints trusted by the wallet so they are included when it is restored
+	saveTrustedMints func(mintUrls []string)
 }
 
-func NewCashuService(workDir string, mintUrl string) (result lnclient.LNClient, err error) {
+// trustedMintUrls are the mints other than mintUrl the wallet held funds on, which are restored as well
+func NewCashuService(workDir string, mintUrl string, trustedMintUrls []string, mnemonic string, saveTrustedMints func(mintUrls []string)) (result lnclient.LNClient, err error) {
 	if workDir == "" {
 		return nil, errors.New("one or more required cashu configuration are missing")
 	}
@@ -40,6 +47,24 @@ func NewCashuService(workDir string, mintUrl string) (result lnclient.LNClient,
 		return nil, err
 	}
 
+	if isFirstSetup && mnemonic != "" {
+		restoreMintUrls := []string{mintUrl}
+		for _, trustedMintUrl := range trustedMintUrls {
+			if !slices.Contains(restoreMintUrls, trustedMintUrl) {
+				restoreMintUrls = append(restoreMintUrls, trustedMintUrl)
+			}
+		}
+		err = restoreWallet(newpath, mnemonic, restoreMintUrls)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to restore cashu wallet")
+			removeErr := os.RemoveAll(newpath)
+			if removeErr != nil {
+				logger.Logger.WithError(removeErr).Error("Failed to remove broken wallet directory")
+			}
+			return nil, err
+		}
+	}
+
 	logger.Logger.WithField("mintUrl", mintUrl).Info("Setting up cashu wallet")
 	config := wallet.Config{WalletPath: newpath, CurrentMintURL: mintUrl}
 
@@ -49,8 +74,13 @@ func NewCashuService(workDir string, mintUrl string) (result lnclient.LNClient,
 		return nil, err
 	}
 
+	if mnemonic != "" && wallet.Mnemonic() != mnemonic {
+		logger.Logger.Warn("Cashu wallet was not created from the hub mnemonic and cannot be restored from it")
+	}
+
 	cs := CashuService{
-		wallet: wallet,
+		wallet:           wallet,
+		saveTrustedMints: saveTrustedMints,
 	}
 
 	// try to make an invoice to ensure the mint is running
@@ -69,9 +99,71 @@ func NewCashuService(workDir string, mintUrl string) (result lnclient.LNClient,
 		return nil, err
 	}
 
+	cs.persistTrustedMints()
+
 	return &cs, nil
 }
 
+func (cs *CashuService) persistTrustedMints() {
+	if cs.saveTrustedMints != nil {
+		cs.saveTrustedMints(cs.wallet.TrustedMints())
+	}
+}
+
+// restoreWallet creates a new wallet from the mnemonic and restores its proofs
+// by re-deriving the blinded messages (NUT-13) and querying the mint for their signatures (NUT-09).
+// gonuts does not allow closing the wallet storage, so the wallet is restored in
+// a temporary directory and the resulting wallet db is copied to the wallet directory.
+func restoreWallet(walletPath string, mnemonic string, mintUrls []string) error {
+	tmpDir, err := os.MkdirTemp("", "cashu-restore-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := os.RemoveAll(tmpDir)
+		if err != nil {
+			logger.Logger.WithError(err).Warn("Failed to remove temporary cashu restore directory")
+		}
+	}()
+
+	logger.Logger.WithField("mintUrls", mintUrls).Info("Restoring cashu wallet from mnemonic")
+	proofs, err := wallet.Restore(tmpDir, mnemonic, mintUrls)
+	if err != nil {
+		return err
+	}
+
+	err = copyFile(filepath.Join(tmpDir, "wallet.db"), filepath.Join(walletPath, "wallet.db"))
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"mintUrls": mintUrls,
+		"amount":   proofs.Amount(),
+	}).Info("Restored cashu wallet")
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
 func (cs *CashuService) Shutdown() error {
 	return nil
 }
@@ -248,6 +340,10 @@ func (cs *CashuService) DisconnectPeer(ctx context.Context, peerId string) error
 	return nil
 }
 
+func (cs *CashuService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	return []lnclient.OnchainTransaction{}, nil
+}
+
 func (cs *CashuService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	return nil, nil
 }
@@ -285,6 +381,21 @@ func (cs *CashuService) GetBalances(ctx context.Context) (*lnclient.BalancesResp
 		logger.Logger.WithError(err).Error("Failed to get balance")
 		return nil, err
 	}
+
+	// only the current mint can be used to pay invoices
+	currentMintBalance := int64(cs.wallet.GetBalanceByMints()[cs.wallet.CurrentMint()] * 1000)
+
+	mints := []lnclient.MintBalance{}
+	for mintUrl, mintBalance := range cs.wallet.GetBalanceByMints() {
+		mints = append(mints, lnclient.MintBalance{
+			MintUrl: mintUrl,
+			Balance: int64(mintBalance * 1000),
+		})
+	}
+	sort.SliceStable(mints, func(i, j int) bool {
+		return mints[i].MintUrl < mints[j].MintUrl
+	})
+
 	return &lnclient.BalancesResponse{
 		Onchain: lnclient.OnchainBalanceResponse{
 			Spendable: 0,
@@ -293,14 +404,115 @@ func (cs *CashuService) GetBalances(ctx context.Context) (*lnclient.BalancesResp
 		Lightning: lnclient.LightningBalanceResponse{
 			TotalSpendable:       balance,
 			TotalReceivable:      0,
-			NextMaxSpendable:     balance,
+			NextMaxSpendable:     currentMintBalance,
 			NextMaxReceivable:    0,
-			NextMaxSpendableMPP:  balance,
+			NextMaxSpendableMPP:  currentMintBalance,
 			NextMaxReceivableMPP: 0,
 		},
+		Mints: mints,
 	}, nil
 }
 
+func (cs *CashuService) SendToken(ctx context.Context, amount uint64, mintUrl string) (*lnclient.EcashToken, error) {
+	// cashu tokens are denominated in sats
+	if amount%1000 != 0 {
+		return nil, errors.New("token amount must be a whole number of sats")
+	}
+	if mintUrl == "" {
+		mintUrl = cs.wallet.CurrentMint()
+	}
+
+	// include the fees the receiver will need to pay to swap the proofs
+	token, err := cs.wallet.Send(amount/1000, mintUrl, true)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"amount":  amount,
+			"mintUrl": mintUrl,
+		}).WithError(err).Error("Failed to create cashu token")
+		return nil, err
+	}
+
+	return &lnclient.EcashToken{
+		Token:   token.ToString(),
+		MintUrl: mintUrl,
+		Amount:  token.TotalAmount() * 1000,
+	}, nil
+}
+
+func (cs *CashuService) ReceiveToken(ctx context.Context, tokenString string, swapToDefaultMint bool) (uint64, error) {
+	token, err := decodeToken(tokenString)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to decode cashu token")
+		return 0, err
+	}
+	if token.Unit != "" && token.Unit != "sat" {
+		return 0, fmt.Errorf("unsupported token unit: %s", token.Unit)
+	}
+
+	// tokens from mints we already trust never need to be swapped
+	swapToTrusted := swapToDefaultMint && !slices.Contains(cs.wallet.TrustedMints(), token.Token[0].Mint)
+
+	// if not swapped, the token's mint is added to the list of trusted mints
+	amount, err := cs.wallet.Receive(*token, swapToTrusted)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"mintUrl":       token.Token[0].Mint,
+			"swapToTrusted": swapToTrusted,
+		}).WithError(err).Error("Failed to receive cashu token")
+		return 0, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"mintUrl":       token.Token[0].Mint,
+		"swapToTrusted": swapToTrusted,
+		"amount":        amount,
+	}).Info("Received cashu token")
+
+	if !swapToTrusted {
+		cs.persistTrustedMints()
+	}
+
+	return amount * 1000, nil
+}
+
+func (cs *CashuService) SwapToDefaultMint(ctx context.Context, fromMintUrl string, amount uint64) error {
+	if fromMintUrl == cs.wallet.CurrentMint() {
+		return errors.New("cannot swap from the default mint to itself")
+	}
+	if amount%1000 != 0 {
+		return errors.New("swap amount must be a whole number of sats")
+	}
+
+	// the default mint creates an invoice which is then paid by the other mint
+	mintResponse, err := cs.wallet.RequestMint(amount / 1000)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request mint quote")
+		return err
+	}
+
+	meltResponse, err := cs.wallet.Melt(mintResponse.Request, fromMintUrl)
+	if err != nil {
+		logger.Logger.WithField("fromMintUrl", fromMintUrl).WithError(err).Error("Failed to melt from mint")
+		return err
+	}
+	if meltResponse.State != nut05.Paid {
+		return errors.New("mint could not pay lightning invoice")
+	}
+
+	proofs, err := cs.wallet.MintTokens(mintResponse.Quote)
+	if err != nil {
+		logger.Logger.WithField("quote", mintResponse.Quote).WithError(err).Error("Failed to mint tokens")
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"fromMintUrl": fromMintUrl,
+		"amount":      proofs.Amount(),
+	}).Info("Swapped funds to default mint")
+
+	return nil
+}
+
 func (cs *CashuService) cashuInvoiceToTransaction(cashuInvoice *storage.Invoice) (*lnclient.Transaction, error) {
 	paymentRequest, err := decodepay.Decodepay(cashuInvoice.PaymentRequest)
 	if err != nil {
@@ -365,7 +577,7 @@ func (cs *CashuService) checkInvoice(cashuInvoice *storage.Invoice) {
 }
 
 func (cs *CashuService) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice"}
+	return []string{"pay_invoice", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "send_cashu_token", "receive_cashu_token", "get_budget"}
 }
 
 func (cs *CashuService) GetSupportedNIP47NotificationTypes() []string {
@@ -0,0 +1,219 @@
+package cashu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/elnosh/gonuts/cashu"
+	"github.com/elnosh/gonuts/cashu/nuts/nut01"
+	"github.com/elnosh/gonuts/cashu/nuts/nut02"
+	"github.com/elnosh/gonuts/cashu/nuts/nut07"
+	"github.com/elnosh/gonuts/cashu/nuts/nut09"
+	"github.com/elnosh/gonuts/cashu/nuts/nut13"
+	"github.com/elnosh/gonuts/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tyler-smith/go-bip39"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests"
+)
+
+const mockMnemonic = "limit reward expect search tissue call visa fit thank cream brave jump"
+
+// fakeMint only knows about the blinded messages it has previously signed,
+// which is enough to restore a wallet via NUT-09
+type fakeMint struct {
+	server *httptest.Server
+	keyset *crypto.MintKeyset
+	// hex-encoded blinded message to amount
+	issued map[string]uint64
+}
+
+func newFakeMint(t *testing.T) *fakeMint {
+	// every mint has its own keyset
+	seed := make([]byte, 32)
+	_, err := rand.Read(seed)
+	assert.NoError(t, err)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	assert.NoError(t, err)
+	keyset, err := crypto.GenerateKeyset(master, 0, 0)
+	assert.NoError(t, err)
+
+	mint := &fakeMint{
+		keyset: keyset,
+		issued: map[string]uint64{},
+	}
+
+	keysResponse := nut01.GetKeysResponse{Keysets: []nut01.Keyset{{
+		Id:   keyset.Id,
+		Unit: keyset.Unit,
+		Keys: keyset.DerivePublic(),
+	}}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"fake mint","nuts":{"7":{"supported":true},"9":{"supported":true}}}`))
+	})
+	mux.HandleFunc("/v1/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(keysResponse)
+	})
+	mux.HandleFunc("/v1/keys/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(keysResponse)
+	})
+	mux.HandleFunc("/v1/keysets", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(nut02.GetKeysetsResponse{Keysets: []nut02.Keyset{{
+			Id:     keyset.Id,
+			Unit:   keyset.Unit,
+			Active: true,
+		}}})
+	})
+	mux.HandleFunc("/v1/restore", func(w http.ResponseWriter, r *http.Request) {
+		var restoreRequest nut09.PostRestoreRequest
+		json.NewDecoder(r.Body).Decode(&restoreRequest)
+
+		restoreResponse := nut09.PostRestoreResponse{
+			Outputs:    cashu.BlindedMessages{},
+			Signatures: cashu.BlindedSignatures{},
+		}
+		for _, output := range restoreRequest.Outputs {
+			amount, ok := mint.issued[output.B_]
+			if !ok {
+				continue
+			}
+			B_bytes, _ := hex.DecodeString(output.B_)
+			B_, _ := secp256k1.ParsePubKey(B_bytes)
+			C_ := crypto.SignBlindedMessage(B_, keyset.Keys[amount].PrivateKey)
+
+			output.Amount = amount
+			restoreResponse.Outputs = append(restoreResponse.Outputs, output)
+			restoreResponse.Signatures = append(restoreResponse.Signatures, cashu.BlindedSignature{
+				Amount: amount,
+				C_:     hex.EncodeToString(C_.SerializeCompressed()),
+				Id:     keyset.Id,
+			})
+		}
+		json.NewEncoder(w).Encode(restoreResponse)
+	})
+	mux.HandleFunc("/v1/checkstate", func(w http.ResponseWriter, r *http.Request) {
+		var checkStateRequest nut07.PostCheckStateRequest
+		json.NewDecoder(r.Body).Decode(&checkStateRequest)
+
+		states := []map[string]string{}
+		for _, Y := range checkStateRequest.Ys {
+			states = append(states, map[string]string{"Y": Y, "state": "UNSPENT"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"states": states})
+	})
+	mux.HandleFunc("/v1/mint/quote/bolt11", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"quote":"quote1","request":"` + tests.MockInvoice + `","state":"UNPAID","paid":false}`))
+	})
+	mux.HandleFunc("/v1/mint/quote/bolt11/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"quote":"quote1","request":"` + tests.MockInvoice + `","state":"UNPAID","paid":false}`))
+	})
+
+	mint.server = httptest.NewServer(mux)
+	return mint
+}
+
+// issue simulates proofs previously minted by a wallet created from the mnemonic
+func (mint *fakeMint) issue(t *testing.T, mnemonic string, amounts []uint64) {
+	master, err := hdkeychain.NewMaster(bip39.NewSeed(mnemonic, ""), &chaincfg.MainNetParams)
+	assert.NoError(t, err)
+	keysetPath, err := nut13.DeriveKeysetPath(master, mint.keyset.Id)
+	assert.NoError(t, err)
+
+	for counter, amount := range amounts {
+		secret, err := nut13.DeriveSecret(keysetPath, uint32(counter))
+		assert.NoError(t, err)
+		r, err := nut13.DeriveBlindingFactor(keysetPath, uint32(counter))
+		assert.NoError(t, err)
+		B_, _, err := crypto.BlindMessage(secret, r)
+		assert.NoError(t, err)
+		mint.issued[hex.EncodeToString(B_.SerializeCompressed())] = amount
+	}
+}
+
+func TestMain(m *testing.M) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	os.Exit(m.Run())
+}
+
+func TestNewCashuService_RestoresFromMnemonic(t *testing.T) {
+	mint := newFakeMint(t)
+	defer mint.server.Close()
+	mint.issue(t, mockMnemonic, []uint64{1, 2, 4})
+
+	workDir := filepath.Join(t.TempDir(), "cashu")
+	lnClient, err := NewCashuService(workDir, mint.server.URL, nil, mockMnemonic, nil)
+	assert.NoError(t, err)
+
+	cs := lnClient.(*CashuService)
+	assert.Equal(t, mockMnemonic, cs.wallet.Mnemonic())
+	assert.Equal(t, uint64(7), cs.wallet.GetBalance())
+}
+
+func TestNewCashuService_RestoresNothingFromOtherMnemonic(t *testing.T) {
+	mint := newFakeMint(t)
+	defer mint.server.Close()
+	mint.issue(t, mockMnemonic, []uint64{1, 2, 4})
+
+	otherMnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	workDir := filepath.Join(t.TempDir(), "cashu")
+	lnClient, err := NewCashuService(workDir, mint.server.URL, nil, otherMnemonic, nil)
+	assert.NoError(t, err)
+
+	cs := lnClient.(*CashuService)
+	assert.Equal(t, otherMnemonic, cs.wallet.Mnemonic())
+	assert.Equal(t, uint64(0), cs.wallet.GetBalance())
+}
+
+func TestNewCashuService_RestoresTrustedMints(t *testing.T) {
+	mint := newFakeMint(t)
+	defer mint.server.Close()
+	mint.issue(t, mockMnemonic, []uint64{1, 2, 4})
+	trustedMint := newFakeMint(t)
+	defer trustedMint.server.Close()
+	trustedMint.issue(t, mockMnemonic, []uint64{8, 16})
+
+	var savedTrustedMints []string
+	workDir := filepath.Join(t.TempDir(), "cashu")
+	lnClient, err := NewCashuService(workDir, mint.server.URL, []string{trustedMint.server.URL}, mockMnemonic, func(mintUrls []string) {
+		savedTrustedMints = mintUrls
+	})
+	assert.NoError(t, err)
+
+	cs := lnClient.(*CashuService)
+	assert.Equal(t, uint64(31), cs.wallet.GetBalance())
+	assert.Equal(t, uint64(24), cs.wallet.GetBalanceByMints()[trustedMint.server.URL])
+	assert.ElementsMatch(t, []string{mint.server.URL, trustedMint.server.URL}, savedTrustedMints)
+}
+
+func TestSendToken_PartialSats(t *testing.T) {
+	mint := newFakeMint(t)
+	defer mint.server.Close()
+	mint.issue(t, mockMnemonic, []uint64{1, 2, 4})
+
+	workDir := filepath.Join(t.TempDir(), "cashu")
+	lnClient, err := NewCashuService(workDir, mint.server.URL, nil, mockMnemonic, nil)
+	assert.NoError(t, err)
+
+	cs := lnClient.(*CashuService)
+	_, err = cs.SendToken(context.TODO(), 1999, "")
+	assert.EqualError(t, err, "token amount must be a whole number of sats")
+	assert.Equal(t, uint64(7), cs.wallet.GetBalance())
+
+	err = cs.SwapToDefaultMint(context.TODO(), "http://localhost:3339", 1999)
+	assert.EqualError(t, err, "swap amount must be a whole number of sats")
+}
@@ -0,0 +1,105 @@
+package cashu
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elnosh/gonuts/cashu"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// V4 token format. See https://github.com/cashubtc/nuts/blob/main/00.md#v4-tokens
+type tokenV4 struct {
+	MintUrl     string          `cbor:"m"`
+	Unit        string          `cbor:"u"`
+	Memo        string          `cbor:"d,omitempty"`
+	TokenProofs []tokenV4Proofs `cbor:"t"`
+}
+
+type tokenV4Proofs struct {
+	KeysetId []byte         `cbor:"i"`
+	Proofs   []tokenV4Proof `cbor:"p"`
+}
+
+type tokenV4Proof struct {
+	Amount  uint64 `cbor:"a"`
+	Secret  string `cbor:"s"`
+	C       []byte `cbor:"c"`
+	Witness string `cbor:"w,omitempty"`
+}
+
+// decodeToken decodes both V3 (cashuA) and V4 (cashuB) tokens.
+// Only tokens of a single mint are supported, as the proofs are received from the first mint of the token.
+func decodeToken(tokenString string) (*cashu.Token, error) {
+	tokenString = strings.TrimSpace(tokenString)
+	tokenString = strings.TrimPrefix(tokenString, "cashu:")
+
+	if len(tokenString) < 6 {
+		return nil, errors.New("invalid token")
+	}
+
+	var token *cashu.Token
+	var err error
+	switch tokenString[:6] {
+	case "cashuA":
+		token, err = cashu.DecodeToken(tokenString)
+	case "cashuB":
+		token, err = decodeTokenV4(tokenString[6:])
+	default:
+		return nil, fmt.Errorf("unsupported token version: %s", tokenString[:6])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(token.Token) == 0 {
+		return nil, errors.New("token has no proofs")
+	}
+	for _, tokenProof := range token.Token {
+		if len(tokenProof.Proofs) == 0 {
+			return nil, errors.New("token has no proofs")
+		}
+		if tokenProof.Mint != token.Token[0].Mint {
+			return nil, errors.New("tokens of multiple mints are not supported")
+		}
+	}
+	return token, nil
+}
+
+func decodeTokenV4(base64Token string) (*cashu.Token, error) {
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(base64Token, "="))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding token: %v", err)
+	}
+
+	var v4 tokenV4
+	err = cbor.Unmarshal(tokenBytes, &v4)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling token: %v", err)
+	}
+
+	if v4.MintUrl == "" || len(v4.TokenProofs) == 0 {
+		return nil, errors.New("token has no mint or proofs")
+	}
+
+	proofs := cashu.Proofs{}
+	for _, tokenProofs := range v4.TokenProofs {
+		keysetId := hex.EncodeToString(tokenProofs.KeysetId)
+		for _, proof := range tokenProofs.Proofs {
+			proofs = append(proofs, cashu.Proof{
+				Amount:  proof.Amount,
+				Id:      keysetId,
+				Secret:  proof.Secret,
+				C:       hex.EncodeToString(proof.C),
+				Witness: proof.Witness,
+			})
+		}
+	}
+
+	token := cashu.NewToken(proofs, v4.MintUrl, v4.Unit)
+	token.Memo = v4.Memo
+	return &token, nil
+}
@@ -0,0 +1,69 @@
+package cashu
+
+import (
+	"testing"
+
+	"github.com/elnosh/gonuts/cashu"
+	"github.com/stretchr/testify/assert"
+)
+
+// test vector from https://github.com/cashubtc/nuts/blob/main/00.md#v4-tokens
+const tokenV4String = "cashuBpGF0gaJhaUgArSaMTR9YJmFwgaNhYQFhc3hAOWE2ZGJiODQ3YmQyMzJiYTc2ZGIwZGYxOTcyMTZiMjlkM2I4Y2MxNDU1M2NkMjc4MjdmYzFjYzk0MmZlZGI0ZWFjWCEDhhhUP_trhpXfStS6vN6So0qWvc2X3O4NfM-Y1HISZ5JhZGlUaGFuayB5b3VhbXVodHRwOi8vbG9jYWxob3N0OjMzMzhhdWNzYXQ="
+
+func TestDecodeToken_V4(t *testing.T) {
+	token, err := decodeToken(tokenV4String)
+	assert.NoError(t, err)
+	assert.Equal(t, "sat", token.Unit)
+	assert.Equal(t, "Thank you", token.Memo)
+	assert.Equal(t, 1, len(token.Token))
+	assert.Equal(t, "http://localhost:3338", token.Token[0].Mint)
+	assert.Equal(t, cashu.Proofs{{
+		Amount: 1,
+		Id:     "00ad268c4d1f5826",
+		Secret: "9a6dbb847bd232ba76db0df197216b29d3b8cc14553cd27827fc1cc942fedb4e",
+		C:      "038618543ffb6b8695df4ad4babcde92a34a96bdcd97dcee0d7ccf98d472126792",
+	}}, token.Token[0].Proofs)
+}
+
+func TestDecodeToken_V3(t *testing.T) {
+	v4Token, err := decodeToken(tokenV4String)
+	assert.NoError(t, err)
+
+	token, err := decodeToken("cashu:" + v4Token.ToString())
+	assert.NoError(t, err)
+	assert.Equal(t, v4Token, token)
+	assert.Equal(t, uint64(1), token.TotalAmount())
+}
+
+func TestDecodeToken_Invalid(t *testing.T) {
+	_, err := decodeToken("cashuC123")
+	assert.Error(t, err)
+
+	_, err = decodeToken("cashuB!!!")
+	assert.Error(t, err)
+
+	_, err = decodeToken("abc")
+	assert.Error(t, err)
+}
+
+func TestDecodeToken_NoProofs(t *testing.T) {
+	token := cashu.Token{Token: []cashu.TokenProof{}, Unit: "sat"}
+	_, err := decodeToken(token.ToString())
+	assert.EqualError(t, err, "token has no proofs")
+
+	token = cashu.NewToken(cashu.Proofs{}, "http://localhost:3338", "sat")
+	_, err = decodeToken(token.ToString())
+	assert.EqualError(t, err, "token has no proofs")
+}
+
+func TestDecodeToken_MultipleMints(t *testing.T) {
+	v4Token, err := decodeToken(tokenV4String)
+	assert.NoError(t, err)
+
+	v4Token.Token = append(v4Token.Token, cashu.TokenProof{
+		Mint:   "http://localhost:3339",
+		Proofs: v4Token.Token[0].Proofs,
+	})
+	_, err = decodeToken(v4Token.ToString())
+	assert.EqualError(t, err, "tokens of multiple mints are not supported")
+}
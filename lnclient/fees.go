@@ -0,0 +1,41 @@
+package lnclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type RecommendedFeesResponse struct {
+	FastestFee  uint64 `json:"fastestFee"`
+	HalfHourFee uint64 `json:"halfHourFee"`
+	HourFee     uint64 `json:"hourFee"`
+	EconomyFee  uint64 `json:"economyFee"`
+	MinimumFee  uint64 `json:"minimumFee"`
+}
+
+// GetRecommendedFeeRate returns the fee rate in sat/vB recommended by the
+// mempool API for confirmation within ~30 minutes
+func GetRecommendedFeeRate(mempoolApi string) (uint64, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(mempoolApi + "/v1/fees/recommended")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("failed to fetch recommended fees: status %d", resp.StatusCode)
+	}
+
+	var feesRes RecommendedFeesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&feesRes); err != nil {
+		return 0, err
+	}
+	if feesRes.HalfHourFee == 0 {
+		return 0, errors.New("no recommended fee rate available")
+	}
+	return feesRes.HalfHourFee, nil
+}
@@ -24,9 +24,10 @@ import (
 )
 
 type GreenlightService struct {
-	workdir string
-	client  *glalby.BlockingGreenlightAlbyClient
-	pubkey  string
+	workdir    string
+	client     *glalby.BlockingGreenlightAlbyClient
+	pubkey     string
+	mempoolApi string
 }
 
 const DEVICE_CREDENTIALS_KEY = "GreenlightCreds"
@@ -89,9 +90,10 @@ func NewGreenlightService(cfg config.Config, mnemonic, inviteCode, workDir, encr
 	nodeInfo, err := client.GetInfo()
 
 	gs := GreenlightService{
-		workdir: newpath,
-		client:  client,
-		pubkey:  nodeInfo.Pubkey,
+		workdir:    newpath,
+		client:     client,
+		pubkey:     nodeInfo.Pubkey,
+		mempoolApi: cfg.GetEnv().MempoolApi,
 	}
 
 	if err != nil {
@@ -509,13 +511,16 @@ func (gs *GreenlightService) GetOnchainBalance(ctx context.Context) (*lnclient.O
 }
 
 func (gs *GreenlightService) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (string, error) {
+	var amountOrAll glalby.AmountOrAll = glalby.AmountOrAllAll{}
 	if !sendAll {
-		return "", errors.New("only send all is supported")
+		if amount == 0 {
+			return "", errors.New("no amount provided")
+		}
+		amountOrAll = glalby.AmountOrAllAmount{Msat: amount * 1000}
 	}
-	amountAll := glalby.AmountOrAll(glalby.AmountOrAllAll{})
 	txId, err := gs.client.Withdraw(glalby.WithdrawRequest{
 		Destination: toAddress,
-		Amount:      &amountAll,
+		Amount:      &amountOrAll,
 	})
 	if err != nil {
 		logger.Logger.WithError(err).Error("Withdraw failed")
@@ -526,6 +531,72 @@ func (gs *GreenlightService) RedeemOnchainFunds(ctx context.Context, toAddress s
 	return txId.Txid, nil
 }
 
+// approximate P2WPKH transaction sizes in vbytes
+const (
+	txOverheadVbytes = 11
+	txInputVbytes    = 68
+	txOutputVbytes   = 31
+)
+
+// EstimateOnchainFees selects confirmed outputs largest-first, which roughly
+// matches CLN's coin selection, and applies the recommended half hour fee rate.
+// The fee rate CLN uses for the withdrawal may differ slightly.
+func (gs *GreenlightService) EstimateOnchainFees(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*lnclient.OnchainFeeEstimate, error) {
+	response, err := gs.client.ListFunds(glalby.ListFundsRequest{})
+	if err != nil {
+		logger.Logger.Errorf("Failed to list funds: %v", err)
+		return nil, err
+	}
+
+	outputAmounts := []uint64{}
+	for _, output := range response.Outputs {
+		if output.AmountMsat != nil && output.Status == 1 && !output.Reserved {
+			outputAmounts = append(outputAmounts, *output.AmountMsat/1000)
+		}
+	}
+	sort.Slice(outputAmounts, func(i, j int) bool {
+		return outputAmounts[i] > outputAmounts[j]
+	})
+
+	feeRate, err := lnclient.GetRecommendedFeeRate(gs.mempoolApi)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch recommended fees")
+		return nil, err
+	}
+
+	if sendAll {
+		var total uint64
+		for _, outputAmount := range outputAmounts {
+			total += outputAmount
+		}
+		fee := feeRate * uint64(txOverheadVbytes+len(outputAmounts)*txInputVbytes+txOutputVbytes)
+		if len(outputAmounts) == 0 || fee >= total {
+			return nil, errors.New("onchain balance is too low to cover the fees")
+		}
+		return &lnclient.OnchainFeeEstimate{
+			Amount:  total - fee,
+			Fee:     fee,
+			FeeRate: feeRate,
+		}, nil
+	}
+
+	var selected uint64
+	for i, outputAmount := range outputAmounts {
+		selected += outputAmount
+		// one output to the destination and one for change
+		fee := feeRate * uint64(txOverheadVbytes+(i+1)*txInputVbytes+2*txOutputVbytes)
+		if selected >= amount+fee {
+			return &lnclient.OnchainFeeEstimate{
+				Amount:  amount,
+				Fee:     fee,
+				FeeRate: feeRate,
+			}, nil
+		}
+	}
+
+	return nil, errors.New("insufficient onchain balance")
+}
+
 func (gs *GreenlightService) SendPaymentProbes(ctx context.Context, invoice string) error {
 	return nil
 }
@@ -534,6 +605,10 @@ func (gs *GreenlightService) SendSpontaneousPaymentProbes(ctx context.Context, a
 	return nil
 }
 
+func (gs *GreenlightService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	return []lnclient.OnchainTransaction{}, nil
+}
+
 func (gs *GreenlightService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	return nil, nil
 }
@@ -687,7 +762,7 @@ func (gs *GreenlightService) DisconnectPeer(ctx context.Context, peerId string)
 }
 
 func (gs *GreenlightService) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice" /*"pay_keysend",*/, "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message"}
+	return []string{"pay_invoice" /*"pay_keysend",*/, "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "get_budget"}
 }
 
 func (gs *GreenlightService) GetSupportedNIP47NotificationTypes() []string {
@@ -24,6 +24,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
@@ -44,11 +45,24 @@ type LDKService struct {
 	cfg                   config.Config
 	lastWalletSyncRequest time.Time
 	pubkey                string
+	jitChannelsEnabled    bool
 }
 
+// The LDK backend does not implement these optional interfaces:
+//   - lnclient.Rebalancer: ldk-node does not allow choosing the outgoing and incoming
+//     channel of a bolt11 payment, so rebalances are rejected as not supported.
+//   - lnclient.OnchainCoinController: ldk-node does not expose the UTXOs of its wallet, and
+//     onchain sends cannot choose inputs or a fee rate, or be bumped with RBF or CPFP.
+//   - lnclient.PsbtChannelFunder: ldk-node always funds channels from its own wallet and does
+//     not accept an externally provided funding transaction, so PSBT channel opens are rejected.
+//   - lnclient.BatchChannelOpener: ldk-node funds every channel in its own transaction,
+//     so batch channel opens are rejected.
+var _ lnclient.LNClient = (*LDKService)(nil)
+var _ lnclient.JitChannelInvoiceMaker = (*LDKService)(nil)
+
 const resetRouterKey = "ResetRouter"
 
-func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events.EventPublisher, mnemonic, workDir string, network string) (result lnclient.LNClient, err error) {
+func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events.EventPublisher, mnemonic, workDir string, network string, trustedPeers []string) (result lnclient.LNClient, err error) {
 	if mnemonic == "" || workDir == "" {
 		return nil, errors.New("one or more required LDK configuration are missing")
 	}
@@ -68,32 +82,29 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 		"0.0.0.0:9735",
 		"[::]:9735",
 	}
-	ldkConfig.TrustedPeers0conf = []string{
-		lsp.OlympusLSP().Pubkey,
-		lsp.AlbyPlebsLSP().Pubkey,
-		lsp.MegalithLSP().Pubkey,
-
-		// Mutinynet
-		lsp.AlbyMutinynetPlebsLSP().Pubkey,
-		lsp.OlympusMutinynetLSP().Pubkey,
-		lsp.MegalithMutinynetLSP().Pubkey,
-	}
-	ldkConfig.AnchorChannelsConfig.TrustedPeersNoReserve = []string{
-		lsp.OlympusLSP().Pubkey,
-		lsp.AlbyPlebsLSP().Pubkey,
-		lsp.MegalithLSP().Pubkey,
+	ldkConfig.TrustedPeers0conf = append([]string{}, trustedPeers...)
+	// invoices are received through JIT channels of the LSPS2 LSP when there is not enough inbound liquidity
+	var lsps2Pubkey, lsps2Address string
+	if cfg.GetEnv().LSPS2Lsp != "" {
+		pubkey, address, port, err := lsp.ParseNodeUri([]string{cfg.GetEnv().LSPS2Lsp})
+		if err != nil {
+			return nil, fmt.Errorf("invalid LSPS2 LSP: %w", err)
+		}
+		lsps2Pubkey = pubkey
+		lsps2Address = fmt.Sprintf("%s:%d", address, port)
+		// JIT channels are zero-conf
+		ldkConfig.TrustedPeers0conf = append(ldkConfig.TrustedPeers0conf, lsps2Pubkey)
+	}
+	ldkConfig.AnchorChannelsConfig.TrustedPeersNoReserve = append([]string{
 		"02b4552a7a85274e4da01a7c71ca57407181752e8568b31d51f13c111a2941dce3", // LNServer_Wave
 		"0296b2db342fcf87ea94d981757fdf4d3e545bd5cef4919f58b5d38dfdd73bf5c9", // blocktank
 		"038ba8f67ba8ff5c48764cdd3251c33598d55b203546d08a8f0ec9dcd9f27e3637", // flashsats
 		"0370a5392cd7c81ff5128fa656ee6db0c4d11c778fcd6cb98cb6ba3b48394f5705", // lqwd
 
 		// Mutinynet
-		lsp.AlbyMutinynetPlebsLSP().Pubkey,
-		lsp.OlympusMutinynetLSP().Pubkey,
-		lsp.MegalithMutinynetLSP().Pubkey,
 		"0296820bbba5bd33719962bafd69996ee89e03ce7164d8f368cbb85463f5f47876", // flashsats
 		"035e8a9034a8c68f219aacadae748c7a3cd719109309db39b09886e5ff17696b1b", // lqwd
-	}
+	}, trustedPeers...)
 
 	ldkConfig.ListeningAddresses = &listeningAddresses
 	ldkConfig.LogDirPath = &logDirPath
@@ -113,10 +124,20 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 	}
 	builder.SetStorageDirPath(filepath.Join(newpath, "./storage"))
 
-	// TODO: remove when https://github.com/lightningdevkit/rust-lightning/issues/2914 is merged
-	// LDK default HTLC inflight value is 10% of the channel size. If an LSPS service is configured this will be set to 0.
-	// The liquidity source below is not used because we do not use the native LDK-node LSPS2 API.
-	builder.SetLiquiditySourceLsps2("52.88.33.119:9735", lsp.OlympusLSP().Pubkey, nil)
+	if lsps2Pubkey != "" {
+		// ldk-node buys JIT channels from the LSP over LSPS0 (JSON-RPC over BOLT8 messages)
+		var lsps2Token *string
+		if cfg.GetEnv().LSPS2Token != "" {
+			token := cfg.GetEnv().LSPS2Token
+			lsps2Token = &token
+		}
+		builder.SetLiquiditySourceLsps2(lsps2Address, lsps2Pubkey, lsps2Token)
+	} else {
+		// TODO: remove when https://github.com/lightningdevkit/rust-lightning/issues/2914 is merged
+		// LDK default HTLC inflight value is 10% of the channel size. If an LSPS service is configured this will be set to 0.
+		// The liquidity source below is not used for JIT channels, as no LSPS2 LSP is configured.
+		builder.SetLiquiditySourceLsps2("52.88.33.119:9735", lsp.OlympusLSP().Pubkey, nil)
+	}
 
 	//builder.SetLogDirPath (filepath.Join(newpath, "./logs")); // missing?
 	node, err := builder.Build()
@@ -140,6 +161,7 @@ func NewLDKService(ctx context.Context, cfg config.Config, eventPublisher events
 		eventPublisher:      eventPublisher,
 		cfg:                 cfg,
 		pubkey:              nodeId,
+		jitChannelsEnabled:  lsps2Pubkey != "",
 	}
 
 	// TODO: remove when LDK supports this
@@ -690,6 +712,72 @@ func (ls *LDKService) MakeInvoice(ctx context.Context, amount int64, description
 	return transaction, nil
 }
 
+func (ls *LDKService) SupportsJitChannels() bool {
+	return ls.jitChannelsEnabled
+}
+
+// MakeJitChannelInvoice creates an invoice which is paid through a JIT channel of the LSPS2 LSP.
+// ldk-node negotiates the opening fee with the LSP and accepts the payment minus the fee.
+func (ls *LDKService) MakeJitChannelInvoice(ctx context.Context, amount int64, description string, expiry int64, maxOpeningFeeMsat uint64) (transaction *lnclient.Transaction, err error) {
+	if expiry == 0 {
+		expiry = lnclient.DEFAULT_INVOICE_EXPIRY
+	}
+
+	invoice, err := ls.node.Bolt11Payment().ReceiveViaJitChannel(uint64(amount), description, uint32(expiry), &maxOpeningFeeMsat)
+	if err != nil {
+		logger.Logger.WithError(err).Error("ReceiveViaJitChannel failed")
+		return nil, err
+	}
+
+	return ls.jitChannelInvoiceToTransaction(invoice, amount)
+}
+
+func (ls *LDKService) MakeVariableAmountJitChannelInvoice(ctx context.Context, description string, expiry int64, maxOpeningFeePpm uint64) (transaction *lnclient.Transaction, err error) {
+	if expiry == 0 {
+		expiry = lnclient.DEFAULT_INVOICE_EXPIRY
+	}
+
+	invoice, err := ls.node.Bolt11Payment().ReceiveVariableAmountViaJitChannel(description, uint32(expiry), &maxOpeningFeePpm)
+	if err != nil {
+		logger.Logger.WithError(err).Error("ReceiveVariableAmountViaJitChannel failed")
+		return nil, err
+	}
+
+	return ls.jitChannelInvoiceToTransaction(invoice, 0)
+}
+
+func (ls *LDKService) jitChannelInvoiceToTransaction(invoice string, amount int64) (*lnclient.Transaction, error) {
+	paymentRequest, err := decodepay.Decodepay(invoice)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": invoice,
+		}).Errorf("Failed to decode bolt11 invoice: %v", err)
+		return nil, err
+	}
+	expiresAt := time.UnixMilli(int64(paymentRequest.CreatedAt) * 1000).Add(time.Duration(paymentRequest.Expiry) * time.Second).Unix()
+
+	preimage := ""
+	payment := ls.node.Payment(paymentRequest.PaymentHash)
+	if payment != nil {
+		jitPaymentKind, ok := payment.Kind.(ldk_node.PaymentKindBolt11Jit)
+		if ok && jitPaymentKind.Preimage != nil {
+			preimage = *jitPaymentKind.Preimage
+		}
+	}
+
+	return &lnclient.Transaction{
+		Type:            "incoming",
+		Invoice:         invoice,
+		PaymentHash:     paymentRequest.PaymentHash,
+		Preimage:        preimage,
+		Amount:          amount,
+		CreatedAt:       int64(paymentRequest.CreatedAt),
+		ExpiresAt:       &expiresAt,
+		Description:     paymentRequest.Description,
+		DescriptionHash: paymentRequest.DescriptionHash,
+	}, nil
+}
+
 func (ls *LDKService) LookupInvoice(ctx context.Context, paymentHash string) (transaction *lnclient.Transaction, err error) {
 
 	payment := ls.node.Payment(paymentHash)
@@ -818,6 +906,10 @@ func (ls *LDKService) ListChannels(ctx context.Context) ([]lnclient.Channel, err
 
 		isActive := ldkChannel.IsUsable /* superset of ldkChannel.IsReady */ && channelError == nil
 
+		// the HTLC limits are not part of the forwarding config, so they are not returned.
+		// InboundHtlcMinimumMsat/MaximumMsat only limit the HTLCs the counterparty may send us.
+		forceCloseAvoidanceMaxFeeSat := ldkChannel.Config.ForceCloseAvoidanceMaxFeeSatoshis()
+
 		channels = append(channels, lnclient.Channel{
 			InternalChannel:                          internalChannel,
 			LocalBalance:                             int64(ldkChannel.ChannelValueSats*1000 - ldkChannel.InboundCapacityMsat - ldkChannel.CounterpartyUnspendablePunishmentReserve*1000),
@@ -831,6 +923,9 @@ func (ls *LDKService) ListChannels(ctx context.Context) ([]lnclient.Channel, err
 			Confirmations:                            ldkChannel.Confirmations,
 			ConfirmationsRequired:                    ldkChannel.ConfirmationsRequired,
 			ForwardingFeeBaseMsat:                    ldkChannel.Config.ForwardingFeeBaseMsat(),
+			ForwardingFeeProportionalMillionths:      ldkChannel.Config.ForwardingFeeProportionalMillionths(),
+			CltvExpiryDelta:                          ldkChannel.Config.CltvExpiryDelta(),
+			ForceCloseAvoidanceMaxFeeSat:             &forceCloseAvoidanceMaxFeeSat,
 			UnspendablePunishmentReserve:             unspendablePunishmentReserve,
 			CounterpartyUnspendablePunishmentReserve: ldkChannel.CounterpartyUnspendablePunishmentReserve,
 			Error:                                    channelError,
@@ -889,11 +984,26 @@ func (ls *LDKService) OpenChannel(ctx context.Context, openChannelRequest *lncli
 		return nil, errors.New("node is not peered yet")
 	}
 
+	// TODO: support the remaining parameters once ldk-node exposes them
+	if openChannelRequest.FeeRate != 0 || openChannelRequest.ZeroConf || openChannelRequest.CloseAddress != "" || openChannelRequest.MinHtlcMsat != 0 {
+		return nil, errors.New("fee rate, zero-conf, close address and min HTLC are not supported by this backend")
+	}
+	// anchor channels are always used
+	if openChannelRequest.Anchors != nil && !*openChannelRequest.Anchors {
+		return nil, errors.New("non-anchor channels are not supported by this backend")
+	}
+
+	var pushToCounterpartyMsat *uint64
+	if openChannelRequest.PushAmount > 0 {
+		pushMsat := uint64(openChannelRequest.PushAmount) * 1000
+		pushToCounterpartyMsat = &pushMsat
+	}
+
 	ldkEventSubscription := ls.ldkEventBroadcaster.Subscribe()
 	defer ls.ldkEventBroadcaster.CancelSubscription(ldkEventSubscription)
 
 	logger.Logger.WithField("peer_id", foundPeer.NodeId).Info("Opening channel")
-	userChannelId, err := ls.node.ConnectOpenChannel(foundPeer.NodeId, foundPeer.Address, uint64(openChannelRequest.Amount), nil, nil, openChannelRequest.Public)
+	userChannelId, err := ls.node.ConnectOpenChannel(foundPeer.NodeId, foundPeer.Address, uint64(openChannelRequest.Amount), pushToCounterpartyMsat, nil, openChannelRequest.Public)
 	if err != nil {
 		logger.Logger.WithError(err).Error("OpenChannel failed")
 		return nil, err
@@ -934,6 +1044,11 @@ func (ls *LDKService) OpenChannel(ctx context.Context, openChannelRequest *lncli
 }
 
 func (ls *LDKService) UpdateChannel(ctx context.Context, updateChannelRequest *lnclient.UpdateChannelRequest) error {
+	err := validateChannelPolicyUpdate(&updateChannelRequest.ChannelPolicyUpdate)
+	if err != nil {
+		return err
+	}
+
 	channels := ls.node.ListChannels()
 
 	var foundChannel *ldk_node.ChannelDetails
@@ -950,9 +1065,26 @@ func (ls *LDKService) UpdateChannel(ctx context.Context, updateChannelRequest *l
 	}
 
 	existingConfig := foundChannel.Config
-	existingConfig.SetForwardingFeeBaseMsat(updateChannelRequest.ForwardingFeeBaseMsat)
+	if updateChannelRequest.ForwardingFeeBaseMsat != nil {
+		existingConfig.SetForwardingFeeBaseMsat(*updateChannelRequest.ForwardingFeeBaseMsat)
+	}
+	if updateChannelRequest.ForwardingFeeProportionalMillionths != nil {
+		existingConfig.SetForwardingFeeProportionalMillionths(*updateChannelRequest.ForwardingFeeProportionalMillionths)
+	}
+	if updateChannelRequest.CltvExpiryDelta != nil {
+		existingConfig.SetCltvExpiryDelta(*updateChannelRequest.CltvExpiryDelta)
+	}
+	if updateChannelRequest.MaxDustHtlcExposureMsat != nil {
+		existingConfig.SetMaxDustHtlcExposureFromFixedLimit(*updateChannelRequest.MaxDustHtlcExposureMsat)
+	}
+	if updateChannelRequest.MaxDustHtlcExposureFeeRateMultiplier != nil {
+		existingConfig.SetMaxDustHtlcExposureFromFeeRateMultiplier(*updateChannelRequest.MaxDustHtlcExposureFeeRateMultiplier)
+	}
+	if updateChannelRequest.ForceCloseAvoidanceMaxFeeSat != nil {
+		existingConfig.SetForceCloseAvoidanceMaxFeeSatoshis(*updateChannelRequest.ForceCloseAvoidanceMaxFeeSat)
+	}
 
-	err := ls.node.UpdateChannelConfig(updateChannelRequest.ChannelId, updateChannelRequest.NodeId, existingConfig)
+	err = ls.node.UpdateChannelConfig(updateChannelRequest.ChannelId, updateChannelRequest.NodeId, existingConfig)
 	if err != nil {
 		logger.Logger.WithError(err).Error("UpdateChannelConfig failed")
 		return err
@@ -960,6 +1092,18 @@ func (ls *LDKService) UpdateChannel(ctx context.Context, updateChannelRequest *l
 	return nil
 }
 
+// validateChannelPolicyUpdate rejects the fields which are not part of the ldk-node channel config
+func validateChannelPolicyUpdate(update *lnclient.ChannelPolicyUpdate) error {
+	err := update.Validate()
+	if err != nil {
+		return err
+	}
+	if update.HtlcMinimumMsat != nil || update.HtlcMaximumMsat != nil {
+		return errors.New("updating the HTLC limits of a channel is not supported by LDK")
+	}
+	return nil
+}
+
 func (ls *LDKService) CloseChannel(ctx context.Context, closeChannelRequest *lnclient.CloseChannelRequest) (*lnclient.CloseChannelResponse, error) {
 	logger.Logger.WithFields(logrus.Fields{
 		"request": closeChannelRequest,
@@ -1063,6 +1207,30 @@ func (ls *LDKService) RedeemOnchainFunds(ctx context.Context, toAddress string,
 	return txId, nil
 }
 
+// ListOnchainTransactions only returns the funding transactions of channels opened by us,
+// as ldk-node does not expose the transactions of its onchain wallet
+func (ls *LDKService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	transactions := []lnclient.OnchainTransaction{}
+	for _, ldkChannel := range ls.node.ListChannels() {
+		if !ldkChannel.IsOutbound || ldkChannel.FundingTxo == nil {
+			continue
+		}
+		var confirmations uint32
+		if ldkChannel.Confirmations != nil {
+			confirmations = *ldkChannel.Confirmations
+		}
+		transactions = append(transactions, lnclient.OnchainTransaction{
+			TxId:          ldkChannel.FundingTxo.Txid,
+			Type:          constants.TRANSACTION_TYPE_OUTGOING,
+			AmountSat:     ldkChannel.ChannelValueSats,
+			Confirmations: confirmations,
+			ChannelId:     ldkChannel.UserChannelId,
+			ChannelAction: lnclient.ONCHAIN_CHANNEL_ACTION_OPEN,
+		})
+	}
+	return transactions, nil
+}
+
 func (ls *LDKService) ResetRouter(key string) error {
 	ls.cfg.SetUpdate(resetRouterKey, key, "")
 
@@ -1127,6 +1295,20 @@ func (ls *LDKService) ldkPaymentToTransaction(payment *ldk_node.PaymentDetails)
 		paymentHash = bolt11PaymentKind.Hash
 	}
 
+	// the invoice of a JIT channel payment is not stored by ldk-node
+	jitPaymentKind, isJitPaymentKind := payment.Kind.(ldk_node.PaymentKindBolt11Jit)
+	if isJitPaymentKind {
+		createdAt = int64(payment.CreatedAt)
+		if payment.Status == ldk_node.PaymentStatusSucceeded {
+			if jitPaymentKind.Preimage != nil {
+				preimage = *jitPaymentKind.Preimage
+			}
+			lastUpdate := int64(payment.LastUpdate)
+			settledAt = &lastUpdate
+		}
+		paymentHash = jitPaymentKind.Hash
+	}
+
 	spontaneousPaymentKind, isSpontaneousPaymentKind := payment.Kind.(ldk_node.PaymentKindSpontaneous)
 	if isSpontaneousPaymentKind {
 		// keysend payment
@@ -1293,6 +1475,8 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 		"event": event,
 	}).Info("Received LDK event")
 
+	// NOTE: forwards are not recorded for LDK. The ldk-node-go version in use does not
+	// expose the PaymentForwarded event, so no nwc_lnclient_forward events are published.
 	switch eventType := (*event).(type) {
 	case ldk_node.EventChannelReady:
 		channels := ls.node.ListChannels()
@@ -1322,10 +1506,13 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 			return
 		}
 		// set a super-high forwarding fee of 100K sats by default to disable unwanted routing
+		forwardingFeeBaseMsat := uint32(100_000_000)
 		err := ls.UpdateChannel(context.Background(), &lnclient.UpdateChannelRequest{
-			ChannelId:             eventType.UserChannelId,
-			NodeId:                *eventType.CounterpartyNodeId,
-			ForwardingFeeBaseMsat: 100_000_000,
+			ChannelId: eventType.UserChannelId,
+			NodeId:    *eventType.CounterpartyNodeId,
+			ChannelPolicyUpdate: lnclient.ChannelPolicyUpdate{
+				ForwardingFeeBaseMsat: &forwardingFeeBaseMsat,
+			},
 		})
 
 		if err != nil {
@@ -1365,6 +1552,15 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 			return
 		}
 
+		// the LSP deducted its opening fee from a payment received through a JIT channel
+		if _, isJitPayment := payment.Kind.(ldk_node.PaymentKindBolt11Jit); isJitPayment && transaction.Amount > int64(eventType.AmountMsat) {
+			transaction.FeesPaid = transaction.Amount - int64(eventType.AmountMsat)
+			logger.Logger.WithFields(logrus.Fields{
+				"payment_hash":     eventType.PaymentHash,
+				"opening_fee_msat": transaction.FeesPaid,
+			}).Info("Received payment through a JIT channel")
+		}
+
 		ls.eventPublisher.Publish(&events.Event{
 			Event:      "nwc_lnclient_payment_received",
 			Properties: transaction,
@@ -1544,11 +1740,11 @@ func (ls *LDKService) UpdateLastWalletSyncRequest() {
 }
 
 func (ls *LDKService) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message"}
+	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "get_budget"}
 }
 
 func (ls *LDKService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{"payment_received", "payment_sent"}
+	return []string{"payment_received", "payment_sent", "payment_failed", "balance_changed", "budget_warning", "app_expiring"}
 }
 
 func (ls *LDKService) getPaymentFailReason(eventPaymentFailed *ldk_node.EventPaymentFailed) string {
@@ -0,0 +1,86 @@
+package ldk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+func TestValidateChannelPolicyUpdate(t *testing.T) {
+	forwardingFeeBaseMsat := uint32(1000)
+	cltvExpiryDelta := uint16(72)
+	htlcMinimumMsat := uint64(2000)
+	htlcMaximumMsat := uint64(1000)
+	maxDustHtlcExposureMsat := uint64(5_000_000)
+	maxDustHtlcExposureFeeRateMultiplier := uint64(5000)
+	forceCloseAvoidanceMaxFeeSat := uint64(1000)
+
+	testCases := []struct {
+		name          string
+		update        lnclient.ChannelPolicyUpdate
+		expectedError string
+	}{
+		{
+			name: "forwarding fee and cltv expiry delta",
+			update: lnclient.ChannelPolicyUpdate{
+				ForwardingFeeBaseMsat: &forwardingFeeBaseMsat,
+				CltvExpiryDelta:       &cltvExpiryDelta,
+			},
+		},
+		{
+			name: "dust exposure fee rate multiplier and force close avoidance fee",
+			update: lnclient.ChannelPolicyUpdate{
+				MaxDustHtlcExposureFeeRateMultiplier: &maxDustHtlcExposureFeeRateMultiplier,
+				ForceCloseAvoidanceMaxFeeSat:         &forceCloseAvoidanceMaxFeeSat,
+			},
+		},
+		{
+			name:          "no fields",
+			update:        lnclient.ChannelPolicyUpdate{},
+			expectedError: "no channel policy fields to update",
+		},
+		{
+			name: "HTLC minimum",
+			update: lnclient.ChannelPolicyUpdate{
+				HtlcMinimumMsat: &htlcMinimumMsat,
+			},
+			expectedError: "updating the HTLC limits of a channel is not supported by LDK",
+		},
+		{
+			name: "HTLC maximum",
+			update: lnclient.ChannelPolicyUpdate{
+				HtlcMaximumMsat: &htlcMaximumMsat,
+			},
+			expectedError: "updating the HTLC limits of a channel is not supported by LDK",
+		},
+		{
+			name: "HTLC minimum greater than maximum",
+			update: lnclient.ChannelPolicyUpdate{
+				HtlcMinimumMsat: &htlcMinimumMsat,
+				HtlcMaximumMsat: &htlcMaximumMsat,
+			},
+			expectedError: "HTLC minimum cannot be greater than the HTLC maximum",
+		},
+		{
+			name: "fixed and fee rate dust exposure",
+			update: lnclient.ChannelPolicyUpdate{
+				MaxDustHtlcExposureMsat:              &maxDustHtlcExposureMsat,
+				MaxDustHtlcExposureFeeRateMultiplier: &maxDustHtlcExposureFeeRateMultiplier,
+			},
+			expectedError: "max dust HTLC exposure can either be a fixed limit or a fee rate multiplier, not both",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateChannelPolicyUpdate(&tc.update)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
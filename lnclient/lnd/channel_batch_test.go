@@ -0,0 +1,117 @@
+package lnd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+const mockPeerPubkey1 = "0314a8a4fb4f2a8e1bc1ae9b8e6fa3a3f4a0c1d6b0ad6f0c8bd3b6f5b8a4a8e1b1"
+const mockPeerPubkey2 = "0314a8a4fb4f2a8e1bc1ae9b8e6fa3a3f4a0c1d6b0ad6f0c8bd3b6f5b8a4a8e1b2"
+
+func TestOpenChannels(t *testing.T) {
+	lightningClient := &mockLightningClient{
+		peers: []*lnrpc.Peer{{PubKey: mockPeerPubkey1}, {PubKey: mockPeerPubkey2}},
+	}
+	svc := newTestLNDService(lightningClient, &mockWalletKitClient{})
+	anchors := false
+
+	response, err := svc.OpenChannels(context.TODO(), &lnclient.BatchOpenChannelRequest{
+		Channels: []lnclient.OpenChannelRequest{
+			{
+				Pubkey:     mockPeerPubkey1,
+				Amount:     100_000,
+				Public:     true,
+				PushAmount: 1_000,
+			},
+			{
+				Pubkey:       mockPeerPubkey2,
+				Amount:       200_000,
+				ZeroConf:     true,
+				CloseAddress: "bcrt1qclose",
+				MinHtlcMsat:  2_000,
+			},
+		},
+		FeeRate: 5,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, lightningClient.batchOpenRequests, 1)
+	batchOpenRequest := lightningClient.batchOpenRequests[0]
+	assert.Equal(t, int64(5), batchOpenRequest.SatPerVbyte)
+	require.Len(t, batchOpenRequest.Channels, 2)
+
+	assert.Equal(t, mockPeerPubkey1, hex.EncodeToString(batchOpenRequest.Channels[0].NodePubkey))
+	assert.Equal(t, int64(100_000), batchOpenRequest.Channels[0].LocalFundingAmount)
+	assert.Equal(t, int64(1_000), batchOpenRequest.Channels[0].PushSat)
+	assert.False(t, batchOpenRequest.Channels[0].Private)
+	assert.Equal(t, lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE, batchOpenRequest.Channels[0].CommitmentType)
+
+	assert.Equal(t, mockPeerPubkey2, hex.EncodeToString(batchOpenRequest.Channels[1].NodePubkey))
+	assert.True(t, batchOpenRequest.Channels[1].Private)
+	assert.True(t, batchOpenRequest.Channels[1].ZeroConf)
+	assert.Equal(t, lnrpc.CommitmentType_ANCHORS, batchOpenRequest.Channels[1].CommitmentType)
+	assert.Equal(t, "bcrt1qclose", batchOpenRequest.Channels[1].CloseAddress)
+	assert.Equal(t, int64(2_000), batchOpenRequest.Channels[1].MinHtlcMsat)
+
+	assert.Len(t, response.FundingTxId, 64)
+	assert.Equal(t, []lnclient.BatchOpenChannelResult{
+		{Pubkey: mockPeerPubkey1, OutputIndex: 0},
+		{Pubkey: mockPeerPubkey2, OutputIndex: 1},
+	}, response.Channels)
+
+	// anchors can be disabled explicitly
+	_, err = svc.OpenChannels(context.TODO(), &lnclient.BatchOpenChannelRequest{
+		Channels: []lnclient.OpenChannelRequest{{Pubkey: mockPeerPubkey1, Amount: 100_000, Anchors: &anchors}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, lnrpc.CommitmentType_STATIC_REMOTE_KEY, lightningClient.batchOpenRequests[1].Channels[0].CommitmentType)
+}
+
+func TestOpenChannels_Errors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		channels       []lnclient.OpenChannelRequest
+		batchOpenError error
+		expectedError  string
+	}{
+		{
+			name:          "no channels",
+			expectedError: "no channels to open",
+		},
+		{
+			name: "peer not connected",
+			channels: []lnclient.OpenChannelRequest{
+				{Pubkey: mockPeerPubkey1, Amount: 100_000},
+				{Pubkey: mockPeerPubkey2, Amount: 100_000},
+			},
+			expectedError: "node is not peered yet: " + mockPeerPubkey2,
+		},
+		{
+			name:           "batch open fails",
+			channels:       []lnclient.OpenChannelRequest{{Pubkey: mockPeerPubkey1, Amount: 100_000}},
+			batchOpenError: errors.New("insufficient funds"),
+			expectedError:  "failed to open channels: insufficient funds",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lightningClient := &mockLightningClient{
+				peers:          []*lnrpc.Peer{{PubKey: mockPeerPubkey1}},
+				batchOpenError: tc.batchOpenError,
+			}
+			svc := newTestLNDService(lightningClient, &mockWalletKitClient{})
+
+			_, err := svc.OpenChannels(context.TODO(), &lnclient.BatchOpenChannelRequest{Channels: tc.channels})
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package lnd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const forwardingHistoryPollInterval = 1 * time.Minute
+const forwardingHistoryPageSize = 1000
+
+// pollForwardingHistory publishes all settled forwards from LND's forwarding history.
+// The whole history is published on startup, duplicates are ignored by the consumer.
+func (svc *LNDService) pollForwardingHistory(ctx context.Context, eventPublisher events.EventPublisher) {
+	var indexOffset uint32
+	for {
+		channelPeers := svc.getChannelPeers(ctx)
+		for {
+			response, err := svc.client.ForwardingHistory(ctx, &lnrpc.ForwardingHistoryRequest{
+				// LND only returns the last day of forwards if no start time is set
+				StartTime:    1,
+				EndTime:      uint64(time.Now().Unix()),
+				IndexOffset:  indexOffset,
+				NumMaxEvents: forwardingHistoryPageSize,
+			})
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to fetch forwarding history")
+				break
+			}
+
+			for _, forwardingEvent := range response.ForwardingEvents {
+				eventPublisher.Publish(&events.Event{
+					Event:      "nwc_lnclient_forward",
+					Properties: lndForwardingEventToForward(forwardingEvent, channelPeers),
+				})
+			}
+			indexOffset = response.LastOffsetIndex
+
+			if len(response.ForwardingEvents) < forwardingHistoryPageSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(forwardingHistoryPollInterval):
+		}
+	}
+}
+
+// subscribeFailedForwards publishes forwards which failed, as they are not part of the forwarding history
+func (svc *LNDService) subscribeFailedForwards(ctx context.Context, eventPublisher events.EventPublisher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			htlcEventStream, err := svc.client.SubscribeHtlcEvents(ctx, &routerrpc.SubscribeHtlcEventsRequest{})
+			if err != nil {
+				logger.Logger.WithError(err).Error("Error subscribing to HTLC events")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(10 * time.Second):
+					continue
+				}
+			}
+
+			// the forward fail event does not contain the HTLC info, so keep it from the forward event
+			pendingForwards := map[string]*routerrpc.HtlcInfo{}
+		htlcEventsLoop:
+			for {
+				htlcEvent, err := htlcEventStream.Recv()
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to receive HTLC event")
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(2 * time.Second):
+						break htlcEventsLoop
+					}
+				}
+
+				if htlcEvent.EventType != routerrpc.HtlcEvent_FORWARD {
+					continue
+				}
+
+				key := fmt.Sprintf("%d_%d", htlcEvent.IncomingChannelId, htlcEvent.IncomingHtlcId)
+
+				var info *routerrpc.HtlcInfo
+				var failureReason string
+				switch event := htlcEvent.Event.(type) {
+				case *routerrpc.HtlcEvent_ForwardEvent:
+					pendingForwards[key] = event.ForwardEvent.Info
+					continue
+				case *routerrpc.HtlcEvent_SettleEvent:
+					delete(pendingForwards, key)
+					continue
+				case *routerrpc.HtlcEvent_ForwardFailEvent:
+					info = pendingForwards[key]
+					delete(pendingForwards, key)
+					failureReason = "failed downstream"
+				case *routerrpc.HtlcEvent_LinkFailEvent:
+					info = event.LinkFailEvent.Info
+					failureReason = event.LinkFailEvent.FailureDetail.String()
+					if event.LinkFailEvent.FailureString != "" {
+						failureReason = event.LinkFailEvent.FailureString
+					}
+				default:
+					continue
+				}
+
+				logger.Logger.WithFields(logrus.Fields{
+					"htlc_event": htlcEvent,
+				}).Info("Received failed forward")
+
+				channelPeers := svc.getChannelPeers(ctx)
+				forward := &lnclient.Forward{
+					Id:                fmt.Sprintf("%d_%d_%d_failed", htlcEvent.TimestampNs, htlcEvent.IncomingChannelId, htlcEvent.IncomingHtlcId),
+					IncomingChannelId: strconv.FormatUint(htlcEvent.IncomingChannelId, 10),
+					IncomingPeer:      channelPeers[htlcEvent.IncomingChannelId],
+					Failed:            true,
+					FailureReason:     failureReason,
+					Timestamp:         int64(htlcEvent.TimestampNs / uint64(time.Second)),
+				}
+				if htlcEvent.OutgoingChannelId != 0 {
+					forward.OutgoingChannelId = strconv.FormatUint(htlcEvent.OutgoingChannelId, 10)
+					forward.OutgoingPeer = channelPeers[htlcEvent.OutgoingChannelId]
+				}
+				if info != nil {
+					forward.AmountInMsat = info.IncomingAmtMsat
+					forward.AmountOutMsat = info.OutgoingAmtMsat
+				}
+
+				eventPublisher.Publish(&events.Event{
+					Event:      "nwc_lnclient_forward",
+					Properties: forward,
+				})
+			}
+		}
+	}
+}
+
+// getChannelPeers maps the IDs of open channels to the pubkey of the channel peer
+func (svc *LNDService) getChannelPeers(ctx context.Context) map[uint64]string {
+	channelPeers := map[uint64]string{}
+	response, err := svc.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channels")
+		return channelPeers
+	}
+	for _, channel := range response.Channels {
+		channelPeers[channel.ChanId] = channel.RemotePubkey
+	}
+	return channelPeers
+}
+
+func lndForwardingEventToForward(forwardingEvent *lnrpc.ForwardingEvent, channelPeers map[uint64]string) *lnclient.Forward {
+	return &lnclient.Forward{
+		Id:                fmt.Sprintf("%d_%d_%d", forwardingEvent.TimestampNs, forwardingEvent.ChanIdIn, forwardingEvent.ChanIdOut),
+		IncomingChannelId: strconv.FormatUint(forwardingEvent.ChanIdIn, 10),
+		OutgoingChannelId: strconv.FormatUint(forwardingEvent.ChanIdOut, 10),
+		IncomingPeer:      channelPeers[forwardingEvent.ChanIdIn],
+		OutgoingPeer:      channelPeers[forwardingEvent.ChanIdOut],
+		AmountInMsat:      forwardingEvent.AmtInMsat,
+		AmountOutMsat:     forwardingEvent.AmtOutMsat,
+		FeeMsat:           forwardingEvent.FeeMsat,
+		Timestamp:         int64(forwardingEvent.TimestampNs / uint64(time.Second)),
+	}
+}
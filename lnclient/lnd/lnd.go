@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -33,7 +34,11 @@ import (
 type LNDService struct {
 	client   *wrapper.LNDWrapper
 	nodeInfo *lnclient.NodeInfo
+	ctx      context.Context
 	cancel   context.CancelFunc
+	// channel opens funded by an external wallet, by pending channel ID
+	psbtChannelOpens      map[string]*psbtChannelOpen
+	psbtChannelOpensMutex sync.Mutex
 }
 
 func (svc *LNDService) GetBalance(ctx context.Context) (balance int64, err error) {
@@ -178,7 +183,7 @@ func (svc *LNDService) ListChannels(ctx context.Context) ([]lnclient.Channel, er
 		channelOpeningBlockHeight := lndChannel.ChanId >> 40
 		confirmations := nodeInfo.BlockHeight - uint32(channelOpeningBlockHeight)
 
-		var forwardingFee uint32
+		var nodePolicy *lnrpc.RoutingPolicy
 		if !lndChannel.Private {
 			channelEdge, err := svc.client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{
 				ChanId: lndChannel.ChanId,
@@ -188,12 +193,26 @@ func (svc *LNDService) ListChannels(ctx context.Context) ([]lnclient.Channel, er
 			}
 
 			if channelEdge.Node1Pub == nodeInfo.IdentityPubkey {
-				forwardingFee = uint32(channelEdge.Node1Policy.FeeBaseMsat)
+				nodePolicy = channelEdge.Node1Policy
 			} else {
-				forwardingFee = uint32(channelEdge.Node2Policy.FeeBaseMsat)
+				nodePolicy = channelEdge.Node2Policy
 			}
 		}
 
+		var forwardingFee uint32
+		var forwardingFeeProportionalMillionths uint32
+		var cltvExpiryDelta uint16
+		var htlcMinimumMsat *uint64
+		var htlcMaximumMsat *uint64
+		if nodePolicy != nil {
+			forwardingFee = uint32(nodePolicy.FeeBaseMsat)
+			forwardingFeeProportionalMillionths = uint32(nodePolicy.FeeRateMilliMsat)
+			cltvExpiryDelta = uint16(nodePolicy.TimeLockDelta)
+			minHtlc := uint64(nodePolicy.MinHtlc)
+			htlcMinimumMsat = &minHtlc
+			htlcMaximumMsat = &nodePolicy.MaxHtlcMsat
+		}
+
 		channels[i] = lnclient.Channel{
 			InternalChannel:                          lndChannel,
 			LocalBalance:                             lndChannel.LocalBalance * 1000,
@@ -210,6 +229,10 @@ func (svc *LNDService) ListChannels(ctx context.Context) ([]lnclient.Channel, er
 			CounterpartyUnspendablePunishmentReserve: lndChannel.RemoteConstraints.ChanReserveSat,
 			IsOutbound:                               lndChannel.Initiator,
 			ForwardingFeeBaseMsat:                    forwardingFee,
+			ForwardingFeeProportionalMillionths:      forwardingFeeProportionalMillionths,
+			CltvExpiryDelta:                          cltvExpiryDelta,
+			HtlcMinimumMsat:                          htlcMinimumMsat,
+			HtlcMaximumMsat:                          htlcMaximumMsat,
 		}
 	}
 
@@ -244,6 +267,8 @@ func (svc *LNDService) ListChannels(ctx context.Context) ([]lnclient.Channel, er
 	return channels, nil
 }
 
+// NOTE: LND does not implement lnclient.JitChannelInvoiceMaker: lnd v0.18 rejects HTLCs below the
+// amount of the invoice, so an LSPS2 LSP cannot deduct its opening fee from the payment.
 func (svc *LNDService) MakeInvoice(ctx context.Context, amount int64, description string, descriptionHash string, expiry int64) (transaction *lnclient.Transaction, err error) {
 	var descriptionHashBytes []byte
 
@@ -344,6 +369,68 @@ func (svc *LNDService) SendPaymentSync(ctx context.Context, payReq string) (*lnc
 	}, nil
 }
 
+func (svc *LNDService) SendRebalancePayment(ctx context.Context, payReq string, outgoingChannelId string, incomingChannelId string, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	outgoingChanId, err := strconv.ParseUint(outgoingChannelId, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	incomingChanId, err := strconv.ParseUint(incomingChannelId, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if outgoingChanId == incomingChanId {
+		return nil, errors.New("outgoing and incoming channel must be different")
+	}
+
+	channelPeers := svc.getChannelPeers(ctx)
+	if _, ok := channelPeers[outgoingChanId]; !ok {
+		return nil, fmt.Errorf("outgoing channel not found: %s", outgoingChannelId)
+	}
+	lastHopPubkey, ok := channelPeers[incomingChanId]
+	if !ok {
+		return nil, fmt.Errorf("incoming channel not found: %s", incomingChannelId)
+	}
+	lastHopPubkeyBytes, err := hex.DecodeString(lastHopPubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last hop pubkey can only pin the peer, so if there are multiple channels
+	// with the same peer LND may choose any of them to receive the payment
+	resp, err := svc.client.SendPaymentSync(ctx, &lnrpc.SendRequest{
+		PaymentRequest:   payReq,
+		OutgoingChanId:   outgoingChanId,
+		LastHopPubkey:    lastHopPubkeyBytes,
+		AllowSelfPayment: true,
+		FeeLimit: &lnrpc.FeeLimit{
+			Limit: &lnrpc.FeeLimit_FixedMsat{
+				FixedMsat: int64(maxFeeMsat),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.PaymentError != "" {
+		return nil, errors.New(resp.PaymentError)
+	}
+
+	if resp.PaymentPreimage == nil {
+		return nil, errors.New("no preimage in response")
+	}
+
+	var fee uint64 = 0
+	if resp.PaymentRoute != nil {
+		fee = uint64(resp.PaymentRoute.TotalFeesMsat)
+	}
+
+	return &lnclient.PayInvoiceResponse{
+		Preimage: hex.EncodeToString(resp.PaymentPreimage),
+		Fee:      fee,
+	}, nil
+}
+
 func (svc *LNDService) SendKeysend(ctx context.Context, amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	destBytes, err := hex.DecodeString(destination)
 	if err != nil {
@@ -450,7 +537,13 @@ func NewLNDService(ctx context.Context, eventPublisher events.EventPublisher, ln
 
 	lndCtx, cancel := context.WithCancel(ctx)
 
-	lndService := &LNDService{client: lndClient, nodeInfo: nodeInfo, cancel: cancel}
+	lndService := &LNDService{
+		client:           lndClient,
+		nodeInfo:         nodeInfo,
+		ctx:              lndCtx,
+		cancel:           cancel,
+		psbtChannelOpens: map[string]*psbtChannelOpen{},
+	}
 
 	// Subscribe to payments
 	go func() {
@@ -569,6 +662,9 @@ func NewLNDService(ctx context.Context, eventPublisher events.EventPublisher, ln
 		}
 	}()
 
+	go lndService.pollForwardingHistory(lndCtx, eventPublisher)
+	go lndService.subscribeFailedForwards(lndCtx, eventPublisher)
+
 	logger.Logger.Infof("Connected to LND - alias %s", nodeInfo.Alias)
 
 	return lndService, nil
@@ -655,13 +751,7 @@ func (svc *LNDService) OpenChannel(ctx context.Context, openChannelRequest *lncl
 		return nil, errors.New("failed to decode pubkey")
 	}
 
-	channel, err := svc.client.OpenChannelSync(ctx, &lnrpc.OpenChannelRequest{
-		NodePubkey:         nodePub,
-		Private:            !openChannelRequest.Public,
-		LocalFundingAmount: openChannelRequest.Amount,
-		// set a super-high forwarding fee of 100K sats by default to disable unwanted routing
-		BaseFee: 100_000_000,
-	})
+	channel, err := svc.client.OpenChannelSync(ctx, newLndOpenChannelRequest(nodePub, openChannelRequest))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open channel with %s: %s", foundPeer.NodeId, err)
 	}
@@ -678,6 +768,98 @@ func (svc *LNDService) OpenChannel(ctx context.Context, openChannelRequest *lncl
 	}, err
 }
 
+func newLndOpenChannelRequest(nodePub []byte, openChannelRequest *lnclient.OpenChannelRequest) *lnrpc.OpenChannelRequest {
+	return &lnrpc.OpenChannelRequest{
+		NodePubkey:         nodePub,
+		Private:            !openChannelRequest.Public,
+		LocalFundingAmount: openChannelRequest.Amount,
+		PushSat:            openChannelRequest.PushAmount,
+		SatPerVbyte:        openChannelRequest.FeeRate,
+		ZeroConf:           openChannelRequest.ZeroConf,
+		CommitmentType:     getLndCommitmentType(openChannelRequest),
+		CloseAddress:       openChannelRequest.CloseAddress,
+		MinHtlcMsat:        int64(openChannelRequest.MinHtlcMsat),
+		// set a super-high forwarding fee of 100K sats by default to disable unwanted routing
+		BaseFee: 100_000_000,
+	}
+}
+
+func getLndCommitmentType(openChannelRequest *lnclient.OpenChannelRequest) lnrpc.CommitmentType {
+	if openChannelRequest.Anchors == nil {
+		// LND requires an explicit commitment type for zero-conf channels
+		if openChannelRequest.ZeroConf {
+			return lnrpc.CommitmentType_ANCHORS
+		}
+		return lnrpc.CommitmentType_UNKNOWN_COMMITMENT_TYPE
+	}
+	if *openChannelRequest.Anchors {
+		return lnrpc.CommitmentType_ANCHORS
+	}
+	return lnrpc.CommitmentType_STATIC_REMOTE_KEY
+}
+
+func (svc *LNDService) OpenChannels(ctx context.Context, batchOpenChannelRequest *lnclient.BatchOpenChannelRequest) (*lnclient.BatchOpenChannelResponse, error) {
+	if len(batchOpenChannelRequest.Channels) == 0 {
+		return nil, errors.New("no channels to open")
+	}
+
+	peers, err := svc.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batchChannels := []*lnrpc.BatchOpenChannel{}
+	for _, openChannelRequest := range batchOpenChannelRequest.Channels {
+		if !slices.ContainsFunc(peers, func(peer lnclient.PeerDetails) bool { return peer.NodeId == openChannelRequest.Pubkey }) {
+			return nil, fmt.Errorf("node is not peered yet: %s", openChannelRequest.Pubkey)
+		}
+		nodePub, err := hex.DecodeString(openChannelRequest.Pubkey)
+		if err != nil {
+			return nil, errors.New("failed to decode pubkey")
+		}
+		batchChannels = append(batchChannels, &lnrpc.BatchOpenChannel{
+			NodePubkey:         nodePub,
+			Private:            !openChannelRequest.Public,
+			LocalFundingAmount: openChannelRequest.Amount,
+			PushSat:            openChannelRequest.PushAmount,
+			ZeroConf:           openChannelRequest.ZeroConf,
+			CommitmentType:     getLndCommitmentType(&openChannelRequest),
+			CloseAddress:       openChannelRequest.CloseAddress,
+			MinHtlcMsat:        int64(openChannelRequest.MinHtlcMsat),
+			// set a super-high forwarding fee of 100K sats by default to disable unwanted routing
+			BaseFee: 100_000_000,
+		})
+	}
+
+	logger.Logger.WithField("channels", len(batchChannels)).Info("Opening channels in batch")
+
+	resp, err := svc.client.BatchOpenChannel(ctx, &lnrpc.BatchOpenChannelRequest{
+		Channels:    batchChannels,
+		SatPerVbyte: int64(batchOpenChannelRequest.FeeRate),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channels: %s", err)
+	}
+
+	// channels are returned in the same order as requested
+	response := &lnclient.BatchOpenChannelResponse{
+		Channels: []lnclient.BatchOpenChannelResult{},
+	}
+	for i, pendingChannel := range resp.PendingChannels {
+		fundingTxId, err := chainhash.NewHash(pendingChannel.Txid)
+		if err != nil {
+			return nil, err
+		}
+		response.FundingTxId = fundingTxId.String()
+		response.Channels = append(response.Channels, lnclient.BatchOpenChannelResult{
+			Pubkey:      batchOpenChannelRequest.Channels[i].Pubkey,
+			OutputIndex: pendingChannel.OutputIndex,
+		})
+	}
+
+	return response, nil
+}
+
 func (svc *LNDService) CloseChannel(ctx context.Context, closeChannelRequest *lnclient.CloseChannelRequest) (*lnclient.CloseChannelResponse, error) {
 	logger.Logger.WithFields(logrus.Fields{
 		"request": closeChannelRequest,
@@ -805,6 +987,9 @@ func (svc *LNDService) GetLogOutput(ctx context.Context, maxLen int) ([]byte, er
 
 func (svc *LNDService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	resp, err := svc.client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		return nil, err
+	}
 	ret := make([]lnclient.PeerDetails, 0, len(resp.Peers))
 	for _, peer := range resp.Peers {
 		ret = append(ret, lnclient.PeerDetails{
@@ -814,7 +999,7 @@ func (svc *LNDService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, e
 			IsConnected: true,
 		})
 	}
-	return ret, err
+	return ret, nil
 }
 
 func (svc *LNDService) SignMessage(ctx context.Context, message string) (string, error) {
@@ -1045,6 +1230,13 @@ func (svc *LNDService) UpdateChannel(ctx context.Context, updateChannelRequest *
 		return err
 	}
 
+	if updateChannelRequest.MaxDustHtlcExposureMsat != nil || updateChannelRequest.MaxDustHtlcExposureFeeRateMultiplier != nil {
+		return errors.New("max dust HTLC exposure cannot be set per channel in LND")
+	}
+	if updateChannelRequest.ForceCloseAvoidanceMaxFeeSat != nil {
+		return errors.New("force close avoidance max fee cannot be set per channel in LND")
+	}
+
 	var nodePolicy *lnrpc.RoutingPolicy
 	if channelEdge.Node1Pub == svc.client.IdentityPubkey {
 		nodePolicy = channelEdge.Node1Policy
@@ -1052,15 +1244,34 @@ func (svc *LNDService) UpdateChannel(ctx context.Context, updateChannelRequest *
 		nodePolicy = channelEdge.Node2Policy
 	}
 
-	_, err = svc.client.UpdateChannel(ctx, &lnrpc.PolicyUpdateRequest{
+	// LND replaces the whole policy, so unchanged fields must be passed from the existing one
+	policyUpdateRequest := &lnrpc.PolicyUpdateRequest{
 		Scope: &lnrpc.PolicyUpdateRequest_ChanPoint{
 			ChanPoint: channelPoint,
 		},
-		BaseFeeMsat:   int64(updateChannelRequest.ForwardingFeeBaseMsat),
+		BaseFeeMsat:   nodePolicy.FeeBaseMsat,
 		FeeRatePpm:    uint32(nodePolicy.FeeRateMilliMsat),
 		TimeLockDelta: nodePolicy.TimeLockDelta,
 		MaxHtlcMsat:   nodePolicy.MaxHtlcMsat,
-	})
+	}
+	if updateChannelRequest.ForwardingFeeBaseMsat != nil {
+		policyUpdateRequest.BaseFeeMsat = int64(*updateChannelRequest.ForwardingFeeBaseMsat)
+	}
+	if updateChannelRequest.ForwardingFeeProportionalMillionths != nil {
+		policyUpdateRequest.FeeRatePpm = *updateChannelRequest.ForwardingFeeProportionalMillionths
+	}
+	if updateChannelRequest.CltvExpiryDelta != nil {
+		policyUpdateRequest.TimeLockDelta = uint32(*updateChannelRequest.CltvExpiryDelta)
+	}
+	if updateChannelRequest.HtlcMinimumMsat != nil {
+		policyUpdateRequest.MinHtlcMsat = *updateChannelRequest.HtlcMinimumMsat
+		policyUpdateRequest.MinHtlcMsatSpecified = true
+	}
+	if updateChannelRequest.HtlcMaximumMsat != nil {
+		policyUpdateRequest.MaxHtlcMsat = *updateChannelRequest.HtlcMaximumMsat
+	}
+
+	_, err = svc.client.UpdateChannel(ctx, policyUpdateRequest)
 
 	if err != nil {
 		return err
@@ -1080,12 +1291,12 @@ func (svc *LNDService) DisconnectPeer(ctx context.Context, peerId string) error
 
 func (svc *LNDService) GetSupportedNIP47Methods() []string {
 	return []string{
-		"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message",
+		"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "get_budget",
 	}
 }
 
 func (svc *LNDService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{"payment_received", "payment_sent"}
+	return []string{"payment_received", "payment_sent", "payment_failed", "balance_changed", "budget_warning", "app_expiring"}
 }
 
 func (svc *LNDService) GetPubkey() string {
@@ -0,0 +1,212 @@
+package lnd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/getAlby/hub/lnclient/lnd/wrapper"
+	"github.com/getAlby/hub/logger"
+)
+
+const mockIdentityPubkey = "02a2f4c9d1b1d1b0a0e9f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1"
+
+// mockLightningClient only implements the calls used by the tests,
+// calling any other method panics
+type mockLightningClient struct {
+	lnrpc.LightningClient
+	transactions      []*lnrpc.Transaction
+	utxos             []*lnrpc.Utxo
+	openChannelStream *mockOpenChannelStream
+	fundingStateSteps []*lnrpc.FundingTransitionMsg
+	psbtVerified      bool
+	peers             []*lnrpc.Peer
+	batchOpenRequests []*lnrpc.BatchOpenChannelRequest
+	batchOpenError    error
+	mutex             sync.Mutex
+}
+
+func (mock *mockLightningClient) GetTransactions(ctx context.Context, req *lnrpc.GetTransactionsRequest, options ...grpc.CallOption) (*lnrpc.TransactionDetails, error) {
+	return &lnrpc.TransactionDetails{Transactions: mock.transactions}, nil
+}
+
+func (mock *mockLightningClient) ListChannels(ctx context.Context, req *lnrpc.ListChannelsRequest, options ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
+	return &lnrpc.ListChannelsResponse{}, nil
+}
+
+func (mock *mockLightningClient) PendingChannels(ctx context.Context, req *lnrpc.PendingChannelsRequest, options ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error) {
+	return &lnrpc.PendingChannelsResponse{}, nil
+}
+
+func (mock *mockLightningClient) ClosedChannels(ctx context.Context, req *lnrpc.ClosedChannelsRequest, options ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error) {
+	return &lnrpc.ClosedChannelsResponse{}, nil
+}
+
+func (mock *mockLightningClient) ListUnspent(ctx context.Context, req *lnrpc.ListUnspentRequest, options ...grpc.CallOption) (*lnrpc.ListUnspentResponse, error) {
+	return &lnrpc.ListUnspentResponse{Utxos: mock.utxos}, nil
+}
+
+func (mock *mockLightningClient) ListPeers(ctx context.Context, req *lnrpc.ListPeersRequest, options ...grpc.CallOption) (*lnrpc.ListPeersResponse, error) {
+	return &lnrpc.ListPeersResponse{Peers: mock.peers}, nil
+}
+
+// BatchOpenChannel funds all channels with a single transaction,
+// using the index of the channel in the request as its output index
+func (mock *mockLightningClient) BatchOpenChannel(ctx context.Context, req *lnrpc.BatchOpenChannelRequest, options ...grpc.CallOption) (*lnrpc.BatchOpenChannelResponse, error) {
+	mock.batchOpenRequests = append(mock.batchOpenRequests, req)
+	if mock.batchOpenError != nil {
+		return nil, mock.batchOpenError
+	}
+	pendingChannels := []*lnrpc.PendingUpdate{}
+	for i := range req.Channels {
+		pendingChannels = append(pendingChannels, &lnrpc.PendingUpdate{
+			Txid:        bytes.Repeat([]byte{0x01}, 32),
+			OutputIndex: uint32(i),
+		})
+	}
+	return &lnrpc.BatchOpenChannelResponse{PendingChannels: pendingChannels}, nil
+}
+
+func (mock *mockLightningClient) OpenChannel(ctx context.Context, req *lnrpc.OpenChannelRequest, options ...grpc.CallOption) (lnrpc.Lightning_OpenChannelClient, error) {
+	mock.openChannelStream = &mockOpenChannelStream{
+		ctx:     ctx,
+		updates: make(chan *lnrpc.OpenStatusUpdate, 2),
+	}
+	mock.openChannelStream.updates <- &lnrpc.OpenStatusUpdate{
+		Update: &lnrpc.OpenStatusUpdate_PsbtFund{
+			PsbtFund: &lnrpc.ReadyForPsbtFunding{
+				FundingAddress: "bcrt1qfunding",
+				FundingAmount:  100_000,
+			},
+		},
+	}
+	return mock.openChannelStream, nil
+}
+
+// FundingStateStep follows the PSBT funding states of LND: the funding can only be
+// verified once and must be verified before it is finalized
+func (mock *mockLightningClient) FundingStateStep(ctx context.Context, req *lnrpc.FundingTransitionMsg, options ...grpc.CallOption) (*lnrpc.FundingStateStepResp, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.fundingStateSteps = append(mock.fundingStateSteps, req)
+
+	switch req.Trigger.(type) {
+	case *lnrpc.FundingTransitionMsg_PsbtVerify:
+		if mock.psbtVerified {
+			return nil, errors.New("invalid state, PSBT was already verified")
+		}
+		mock.psbtVerified = true
+	case *lnrpc.FundingTransitionMsg_PsbtFinalize:
+		if !mock.psbtVerified {
+			return nil, errors.New("invalid state, PSBT must be verified first")
+		}
+		mock.openChannelStream.updates <- &lnrpc.OpenStatusUpdate{
+			Update: &lnrpc.OpenStatusUpdate_ChanPending{
+				ChanPending: &lnrpc.PendingUpdate{Txid: make([]byte, 32)},
+			},
+		}
+	}
+	return &lnrpc.FundingStateStepResp{}, nil
+}
+
+func (mock *mockLightningClient) getFundingStateSteps() []*lnrpc.FundingTransitionMsg {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	return mock.fundingStateSteps
+}
+
+type mockOpenChannelStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	updates chan *lnrpc.OpenStatusUpdate
+}
+
+func (mock *mockOpenChannelStream) Recv() (*lnrpc.OpenStatusUpdate, error) {
+	select {
+	case update := <-mock.updates:
+		return update, nil
+	case <-mock.ctx.Done():
+		return nil, mock.ctx.Err()
+	}
+}
+
+type mockWalletKitClient struct {
+	walletrpc.WalletKitClient
+	fundPsbtResponse     *walletrpc.FundPsbtResponse
+	publishError         string
+	fundPsbtRequests     []*walletrpc.FundPsbtRequest
+	finalizePsbtRequests []*walletrpc.FinalizePsbtRequest
+	publishedTxs         [][]byte
+	releasedOutputs      []*lnrpc.OutPoint
+	bumpFeeRequests      []*walletrpc.BumpFeeRequest
+	mutex                sync.Mutex
+}
+
+func (mock *mockWalletKitClient) FundPsbt(ctx context.Context, req *walletrpc.FundPsbtRequest, options ...grpc.CallOption) (*walletrpc.FundPsbtResponse, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.fundPsbtRequests = append(mock.fundPsbtRequests, req)
+	return mock.fundPsbtResponse, nil
+}
+
+// FinalizePsbt returns the unsigned transaction of the PSBT as the final transaction
+func (mock *mockWalletKitClient) FinalizePsbt(ctx context.Context, req *walletrpc.FinalizePsbtRequest, options ...grpc.CallOption) (*walletrpc.FinalizePsbtResponse, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.finalizePsbtRequests = append(mock.finalizePsbtRequests, req)
+
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(req.FundedPsbt), false)
+	if err != nil {
+		return nil, err
+	}
+	var rawFinalTx bytes.Buffer
+	err = packet.UnsignedTx.Serialize(&rawFinalTx)
+	if err != nil {
+		return nil, err
+	}
+	return &walletrpc.FinalizePsbtResponse{RawFinalTx: rawFinalTx.Bytes()}, nil
+}
+
+func (mock *mockWalletKitClient) PublishTransaction(ctx context.Context, req *walletrpc.Transaction, options ...grpc.CallOption) (*walletrpc.PublishResponse, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.publishedTxs = append(mock.publishedTxs, req.TxHex)
+	return &walletrpc.PublishResponse{PublishError: mock.publishError}, nil
+}
+
+func (mock *mockWalletKitClient) ReleaseOutput(ctx context.Context, req *walletrpc.ReleaseOutputRequest, options ...grpc.CallOption) (*walletrpc.ReleaseOutputResponse, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.releasedOutputs = append(mock.releasedOutputs, req.Outpoint)
+	return &walletrpc.ReleaseOutputResponse{}, nil
+}
+
+func (mock *mockWalletKitClient) BumpFee(ctx context.Context, req *walletrpc.BumpFeeRequest, options ...grpc.CallOption) (*walletrpc.BumpFeeResponse, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.bumpFeeRequests = append(mock.bumpFeeRequests, req)
+	return &walletrpc.BumpFeeResponse{}, nil
+}
+
+func newTestLNDService(lightningClient lnrpc.LightningClient, walletKitClient walletrpc.WalletKitClient) *LNDService {
+	return &LNDService{
+		client:           wrapper.NewLNDWrapper(lightningClient, nil, nil, walletKitClient, mockIdentityPubkey),
+		ctx:              context.Background(),
+		psbtChannelOpens: map[string]*psbtChannelOpen{},
+	}
+}
+
+func TestMain(m *testing.M) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	os.Exit(m.Run())
+}
@@ -0,0 +1,426 @@
+package lnd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+func (svc *LNDService) ListUtxos(ctx context.Context) ([]lnclient.Utxo, error) {
+	response, err := svc.client.ListUnspent(ctx, &lnrpc.ListUnspentRequest{
+		MinConfs: 0,
+		MaxConfs: math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utxos := []lnclient.Utxo{}
+	for _, utxo := range response.Utxos {
+		utxos = append(utxos, lnclient.Utxo{
+			OutPoint: lnclient.OutPoint{
+				TxId:        utxo.Outpoint.TxidStr,
+				OutputIndex: utxo.Outpoint.OutputIndex,
+			},
+			Address:       utxo.Address,
+			AmountSat:     uint64(utxo.AmountSat),
+			Confirmations: uint32(utxo.Confirmations),
+		})
+	}
+	return utxos, nil
+}
+
+// SendOnchain funds, signs and publishes a PSBT so that inputs, multiple outputs
+// and the fee rate can be chosen, which is not possible with SendCoins / SendMany
+func (svc *LNDService) SendOnchain(ctx context.Context, sendOnchainRequest *lnclient.SendOnchainRequest) (string, error) {
+	if len(sendOnchainRequest.Outputs) == 0 {
+		return "", errors.New("no outputs")
+	}
+	if sendOnchainRequest.FeeRate == 0 {
+		return "", errors.New("no fee rate")
+	}
+
+	outputs := map[string]uint64{}
+	for _, output := range sendOnchainRequest.Outputs {
+		if _, ok := outputs[output.Address]; ok {
+			return "", fmt.Errorf("duplicate output address: %s", output.Address)
+		}
+		outputs[output.Address] = output.AmountSat
+	}
+
+	inputs := []*lnrpc.OutPoint{}
+	for _, input := range sendOnchainRequest.Inputs {
+		inputs = append(inputs, &lnrpc.OutPoint{
+			TxidStr:     input.TxId,
+			OutputIndex: input.OutputIndex,
+		})
+	}
+
+	fundResponse, err := svc.client.FundPsbt(ctx, &walletrpc.FundPsbtRequest{
+		Template: &walletrpc.FundPsbtRequest_Raw{
+			Raw: &walletrpc.TxTemplate{
+				Inputs:  inputs,
+				Outputs: outputs,
+			},
+		},
+		Fees: &walletrpc.FundPsbtRequest_SatPerVbyte{
+			SatPerVbyte: sendOnchainRequest.FeeRate,
+		},
+		// explicitly chosen inputs may be unconfirmed
+		SpendUnconfirmed: len(inputs) > 0,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fund PSBT")
+		return "", err
+	}
+
+	txId, err := svc.finalizeAndPublishPsbt(ctx, fundResponse.FundedPsbt)
+	if err != nil {
+		// unlock the inputs so they can be used again
+		for _, lockedUtxo := range fundResponse.LockedUtxos {
+			_, releaseErr := svc.client.ReleaseOutput(ctx, &walletrpc.ReleaseOutputRequest{
+				Id:       lockedUtxo.Id,
+				Outpoint: lockedUtxo.Outpoint,
+			})
+			if releaseErr != nil {
+				logger.Logger.WithError(releaseErr).WithFields(logrus.Fields{
+					"outpoint": lockedUtxo.Outpoint,
+				}).Error("Failed to release output")
+			}
+		}
+		return "", err
+	}
+
+	return txId, nil
+}
+
+func (svc *LNDService) finalizeAndPublishPsbt(ctx context.Context, fundedPsbt []byte) (string, error) {
+	finalizeResponse, err := svc.client.FinalizePsbt(ctx, &walletrpc.FinalizePsbtRequest{
+		FundedPsbt: fundedPsbt,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to finalize PSBT")
+		return "", err
+	}
+
+	var tx wire.MsgTx
+	err = tx.Deserialize(bytes.NewReader(finalizeResponse.RawFinalTx))
+	if err != nil {
+		return "", err
+	}
+
+	publishResponse, err := svc.client.PublishTransaction(ctx, &walletrpc.Transaction{
+		TxHex: finalizeResponse.RawFinalTx,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to publish transaction")
+		return "", err
+	}
+	if publishResponse.PublishError != "" {
+		return "", errors.New(publishResponse.PublishError)
+	}
+
+	return tx.TxHash().String(), nil
+}
+
+// replacements must pay at least the minimum relay fee rate on top of the original fee (BIP125)
+const minRelayFeeRate = 1
+
+// outputs below this amount would not be relayed
+const dustLimitSat = 546
+
+var errCannotReplaceTransaction = errors.New("transaction cannot be replaced")
+
+// BumpOnchainFee replaces an unconfirmed wallet send with one paying the new fee rate (RBF),
+// paying the additional fee from its change output. Transactions which cannot be replaced,
+// e.g. deposits or sends without change, are bumped by spending an unconfirmed output
+// of the transaction which belongs to our wallet instead (CPFP).
+func (svc *LNDService) BumpOnchainFee(ctx context.Context, txId string, feeRate uint64) (string, error) {
+	replacementTxId, err := svc.replaceOnchainTransaction(ctx, txId, feeRate)
+	if err == nil {
+		return replacementTxId, nil
+	}
+	if !errors.Is(err, errCannotReplaceTransaction) {
+		return "", err
+	}
+	logger.Logger.WithError(err).WithField("txId", txId).Info("Bumping fee with CPFP")
+
+	err = svc.bumpOnchainFeeWithChild(ctx, txId, feeRate)
+	if err != nil {
+		return "", err
+	}
+	return txId, nil
+}
+
+// replaceOnchainTransaction signs and publishes a copy of the transaction with
+// a smaller change output, returning the ID of the replacement transaction
+func (svc *LNDService) replaceOnchainTransaction(ctx context.Context, txId string, feeRate uint64) (string, error) {
+	response, err := svc.client.GetTransactions(ctx, &lnrpc.GetTransactionsRequest{})
+	if err != nil {
+		return "", err
+	}
+
+	walletTransactions := map[string]*lnrpc.Transaction{}
+	for _, walletTransaction := range response.Transactions {
+		walletTransactions[walletTransaction.TxHash] = walletTransaction
+	}
+	walletTransaction, ok := walletTransactions[txId]
+	if !ok {
+		return "", errors.New("transaction not found")
+	}
+	if walletTransaction.NumConfirmations > 0 {
+		return "", errors.New("transaction is already confirmed")
+	}
+	if len(walletTransaction.PreviousOutpoints) == 0 {
+		return "", fmt.Errorf("%w: inputs are not known", errCannotReplaceTransaction)
+	}
+	for _, previousOutpoint := range walletTransaction.PreviousOutpoints {
+		if !previousOutpoint.IsOurOutput {
+			return "", fmt.Errorf("%w: not all inputs belong to the wallet", errCannotReplaceTransaction)
+		}
+	}
+
+	tx, err := decodeTransaction(walletTransaction.RawTxHex)
+	if err != nil {
+		return "", err
+	}
+
+	changeOutputIndex := -1
+	for _, outputDetail := range walletTransaction.OutputDetails {
+		if outputDetail.IsOurAddress && (changeOutputIndex == -1 || tx.TxOut[outputDetail.OutputIndex].Value > tx.TxOut[changeOutputIndex].Value) {
+			changeOutputIndex = int(outputDetail.OutputIndex)
+		}
+	}
+	if changeOutputIndex == -1 {
+		return "", fmt.Errorf("%w: no change output", errCannotReplaceTransaction)
+	}
+
+	vsize := uint64(mempool.GetTxVirtualSize(btcutil.NewTx(tx)))
+	currentFee := uint64(walletTransaction.TotalFees)
+	newFee := feeRate * vsize
+	if newFee < currentFee+minRelayFeeRate*vsize {
+		return "", fmt.Errorf("fee rate must be at least %d sat/vB", (currentFee+vsize-1)/vsize+minRelayFeeRate)
+	}
+	changeOutput := tx.TxOut[changeOutputIndex]
+	if changeOutput.Value-int64(newFee-currentFee) < dustLimitSat {
+		return "", fmt.Errorf("%w: change output is too small", errCannotReplaceTransaction)
+	}
+
+	replacementTx := tx.Copy()
+	replacementTx.TxOut[changeOutputIndex].Value -= int64(newFee - currentFee)
+	for _, txIn := range replacementTx.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(replacementTx)
+	if err != nil {
+		return "", err
+	}
+	// the wallet needs the spent outputs to sign the inputs
+	for i, txIn := range replacementTx.TxIn {
+		parentTransaction, ok := walletTransactions[txIn.PreviousOutPoint.Hash.String()]
+		if !ok {
+			return "", fmt.Errorf("%w: input transaction not found", errCannotReplaceTransaction)
+		}
+		parentTx, err := decodeTransaction(parentTransaction.RawTxHex)
+		if err != nil {
+			return "", err
+		}
+		packet.Inputs[i].WitnessUtxo = parentTx.TxOut[txIn.PreviousOutPoint.Index]
+	}
+
+	var packetBytes bytes.Buffer
+	err = packet.Serialize(&packetBytes)
+	if err != nil {
+		return "", err
+	}
+
+	replacementTxId, err := svc.finalizeAndPublishPsbt(ctx, packetBytes.Bytes())
+	if err != nil {
+		return "", err
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"txId":            txId,
+		"replacementTxId": replacementTxId,
+		"feeRate":         feeRate,
+	}).Info("Replaced onchain transaction")
+	return replacementTxId, nil
+}
+
+// bumpOnchainFeeWithChild spends an unconfirmed output of the transaction which belongs to our wallet (CPFP)
+func (svc *LNDService) bumpOnchainFeeWithChild(ctx context.Context, txId string, feeRate uint64) error {
+	response, err := svc.client.ListUnspent(ctx, &lnrpc.ListUnspentRequest{
+		MinConfs: 0,
+		MaxConfs: 0,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, utxo := range response.Utxos {
+		if utxo.Outpoint.TxidStr != txId {
+			continue
+		}
+		_, err := svc.client.BumpFee(ctx, &walletrpc.BumpFeeRequest{
+			Outpoint:    utxo.Outpoint,
+			SatPerVbyte: feeRate,
+			Immediate:   true,
+		})
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"txId":     txId,
+				"outpoint": utxo.Outpoint,
+			}).Error("Failed to bump fee")
+		}
+		return err
+	}
+
+	return errors.New("no unconfirmed wallet output found for this transaction")
+}
+
+func decodeTransaction(rawTxHex string) (*wire.MsgTx, error) {
+	rawTx, err := hex.DecodeString(rawTxHex)
+	if err != nil {
+		return nil, err
+	}
+	var tx wire.MsgTx
+	err = tx.Deserialize(bytes.NewReader(rawTx))
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (svc *LNDService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	response, err := svc.client.GetTransactions(ctx, &lnrpc.GetTransactionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	channelTransactions, err := svc.getChannelTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := []lnclient.OnchainTransaction{}
+	for _, lndTransaction := range response.Transactions {
+		transactionType := constants.TRANSACTION_TYPE_INCOMING
+		amount := lndTransaction.Amount
+		if amount < 0 {
+			transactionType = constants.TRANSACTION_TYPE_OUTGOING
+			amount = -amount
+		}
+		var confirmations uint32
+		if lndTransaction.NumConfirmations > 0 {
+			confirmations = uint32(lndTransaction.NumConfirmations)
+		}
+
+		transaction := lnclient.OnchainTransaction{
+			TxId:          lndTransaction.TxHash,
+			Type:          transactionType,
+			AmountSat:     uint64(amount),
+			FeeSat:        uint64(lndTransaction.TotalFees),
+			Confirmations: confirmations,
+			Timestamp:     lndTransaction.TimeStamp,
+			Address:       getTransactionAddress(lndTransaction, transactionType),
+			Label:         lndTransaction.Label,
+		}
+		if channelTransaction, ok := channelTransactions[lndTransaction.TxHash]; ok {
+			transaction.ChannelId = channelTransaction.ChannelId
+			transaction.ChannelAction = channelTransaction.ChannelAction
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
+
+// getTransactionAddress returns our receiving address for deposits and the first
+// address which does not belong to the wallet for sends
+func getTransactionAddress(lndTransaction *lnrpc.Transaction, transactionType string) string {
+	for _, outputDetail := range lndTransaction.OutputDetails {
+		if outputDetail.IsOurAddress == (transactionType == constants.TRANSACTION_TYPE_INCOMING) {
+			return outputDetail.Address
+		}
+	}
+	return ""
+}
+
+// getChannelTransactions maps funding and closing transaction IDs to the channel
+func (svc *LNDService) getChannelTransactions(ctx context.Context) (map[string]lnclient.OnchainTransaction, error) {
+	channelTransactions := map[string]lnclient.OnchainTransaction{}
+	addFunding := func(channelPoint string, channelId string) {
+		fundingTxId := strings.Split(channelPoint, ":")[0]
+		channelTransactions[fundingTxId] = lnclient.OnchainTransaction{
+			ChannelId:     channelId,
+			ChannelAction: lnclient.ONCHAIN_CHANNEL_ACTION_OPEN,
+		}
+	}
+	addClosing := func(closingTxId string, channelId string) {
+		if closingTxId == "" {
+			return
+		}
+		channelTransactions[closingTxId] = lnclient.OnchainTransaction{
+			ChannelId:     channelId,
+			ChannelAction: lnclient.ONCHAIN_CHANNEL_ACTION_CLOSE,
+		}
+	}
+
+	activeResponse, err := svc.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range activeResponse.Channels {
+		addFunding(channel.ChannelPoint, strconv.FormatUint(channel.ChanId, 10))
+	}
+
+	// pending channels do not have a channel ID yet
+	pendingResponse, err := svc.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range pendingResponse.PendingOpenChannels {
+		addFunding(channel.Channel.ChannelPoint, "")
+	}
+	for _, channel := range pendingResponse.WaitingCloseChannels {
+		addFunding(channel.Channel.ChannelPoint, "")
+		addClosing(channel.ClosingTxid, "")
+	}
+	for _, channel := range pendingResponse.PendingForceClosingChannels {
+		addFunding(channel.Channel.ChannelPoint, "")
+		addClosing(channel.ClosingTxid, "")
+	}
+
+	closedResponse, err := svc.client.ClosedChannels(ctx, &lnrpc.ClosedChannelsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range closedResponse.Channels {
+		channelId := ""
+		if channel.ChanId != 0 {
+			channelId = strconv.FormatUint(channel.ChanId, 10)
+		}
+		addFunding(channel.ChannelPoint, channelId)
+		addClosing(channel.ClosingTxHash, channelId)
+	}
+
+	return channelTransactions, nil
+}
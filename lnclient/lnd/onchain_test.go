@@ -0,0 +1,343 @@
+package lnd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+)
+
+var mockPkScript = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x01}, 20)...)
+
+func newMockTx(inputs []wire.OutPoint, outputValues ...int64) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	for _, input := range inputs {
+		txIn := wire.NewTxIn(&input, nil, wire.TxWitness{bytes.Repeat([]byte{0x02}, 72), bytes.Repeat([]byte{0x03}, 33)})
+		txIn.Sequence = wire.MaxTxInSequenceNum - 2
+		tx.AddTxIn(txIn)
+	}
+	for _, outputValue := range outputValues {
+		tx.AddTxOut(wire.NewTxOut(outputValue, mockPkScript))
+	}
+	return tx
+}
+
+func serializeTx(t *testing.T, tx *wire.MsgTx) string {
+	var rawTx bytes.Buffer
+	require.NoError(t, tx.Serialize(&rawTx))
+	return hex.EncodeToString(rawTx.Bytes())
+}
+
+func newMockPsbt(t *testing.T, tx *wire.MsgTx) []byte {
+	unsignedTx := tx.Copy()
+	for _, txIn := range unsignedTx.TxIn {
+		txIn.Witness = nil
+	}
+	packet, err := psbt.NewFromUnsignedTx(unsignedTx)
+	require.NoError(t, err)
+	var packetBytes bytes.Buffer
+	require.NoError(t, packet.Serialize(&packetBytes))
+	return packetBytes.Bytes()
+}
+
+func TestListUtxos(t *testing.T) {
+	lightningClient := &mockLightningClient{
+		utxos: []*lnrpc.Utxo{
+			{
+				Outpoint:      &lnrpc.OutPoint{TxidStr: "tx1", OutputIndex: 1},
+				Address:       "bc1qaddress1",
+				AmountSat:     100_000,
+				Confirmations: 3,
+			},
+			{
+				Outpoint:  &lnrpc.OutPoint{TxidStr: "tx2", OutputIndex: 0},
+				Address:   "bc1qaddress2",
+				AmountSat: 5_000,
+			},
+		},
+	}
+	svc := newTestLNDService(lightningClient, &mockWalletKitClient{})
+
+	utxos, err := svc.ListUtxos(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []lnclient.Utxo{
+		{
+			OutPoint:      lnclient.OutPoint{TxId: "tx1", OutputIndex: 1},
+			Address:       "bc1qaddress1",
+			AmountSat:     100_000,
+			Confirmations: 3,
+		},
+		{
+			OutPoint:  lnclient.OutPoint{TxId: "tx2", OutputIndex: 0},
+			Address:   "bc1qaddress2",
+			AmountSat: 5_000,
+		},
+	}, utxos)
+}
+
+func TestSendOnchain(t *testing.T) {
+	fundedTx := newMockTx([]wire.OutPoint{{Index: 1}}, 50_000, 20_000)
+	walletKitClient := &mockWalletKitClient{
+		fundPsbtResponse: &walletrpc.FundPsbtResponse{FundedPsbt: newMockPsbt(t, fundedTx)},
+	}
+	svc := newTestLNDService(&mockLightningClient{}, walletKitClient)
+
+	txId, err := svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{
+		Outputs: []lnclient.OnchainOutput{
+			{Address: "bc1qaddress1", AmountSat: 50_000},
+			{Address: "bc1qaddress2", AmountSat: 20_000},
+		},
+		Inputs:  []lnclient.OutPoint{{TxId: "tx1", OutputIndex: 1}},
+		FeeRate: 12,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, fundedTx.TxHash().String(), txId)
+
+	require.Equal(t, 1, len(walletKitClient.fundPsbtRequests))
+	fundPsbtRequest := walletKitClient.fundPsbtRequests[0]
+	assert.Equal(t, map[string]uint64{"bc1qaddress1": 50_000, "bc1qaddress2": 20_000}, fundPsbtRequest.GetRaw().Outputs)
+	assert.Equal(t, 1, len(fundPsbtRequest.GetRaw().Inputs))
+	assert.Equal(t, "tx1", fundPsbtRequest.GetRaw().Inputs[0].TxidStr)
+	assert.Equal(t, uint64(12), fundPsbtRequest.GetSatPerVbyte())
+	assert.True(t, fundPsbtRequest.SpendUnconfirmed)
+	assert.Equal(t, 1, len(walletKitClient.publishedTxs))
+	assert.Empty(t, walletKitClient.releasedOutputs)
+}
+
+func TestSendOnchain_AutomaticCoinSelection(t *testing.T) {
+	walletKitClient := &mockWalletKitClient{
+		fundPsbtResponse: &walletrpc.FundPsbtResponse{FundedPsbt: newMockPsbt(t, newMockTx([]wire.OutPoint{{Index: 1}}, 50_000))},
+	}
+	svc := newTestLNDService(&mockLightningClient{}, walletKitClient)
+
+	_, err := svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{
+		Outputs: []lnclient.OnchainOutput{{Address: "bc1qaddress1", AmountSat: 50_000}},
+		FeeRate: 12,
+	})
+	assert.NoError(t, err)
+
+	require.Equal(t, 1, len(walletKitClient.fundPsbtRequests))
+	// the wallet only selects confirmed inputs by itself
+	assert.Empty(t, walletKitClient.fundPsbtRequests[0].GetRaw().Inputs)
+	assert.False(t, walletKitClient.fundPsbtRequests[0].SpendUnconfirmed)
+}
+
+func TestSendOnchain_PublishFailedReleasesInputs(t *testing.T) {
+	lockedOutpoint := &lnrpc.OutPoint{TxidStr: "tx1", OutputIndex: 1}
+	walletKitClient := &mockWalletKitClient{
+		fundPsbtResponse: &walletrpc.FundPsbtResponse{
+			FundedPsbt:  newMockPsbt(t, newMockTx([]wire.OutPoint{{Index: 1}}, 50_000)),
+			LockedUtxos: []*walletrpc.UtxoLease{{Outpoint: lockedOutpoint}},
+		},
+		publishError: "insufficient fee",
+	}
+	svc := newTestLNDService(&mockLightningClient{}, walletKitClient)
+
+	_, err := svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{
+		Outputs: []lnclient.OnchainOutput{{Address: "bc1qaddress1", AmountSat: 50_000}},
+		FeeRate: 1,
+	})
+	assert.EqualError(t, err, "insufficient fee")
+	assert.Equal(t, []*lnrpc.OutPoint{lockedOutpoint}, walletKitClient.releasedOutputs)
+}
+
+func TestSendOnchain_InvalidRequest(t *testing.T) {
+	svc := newTestLNDService(&mockLightningClient{}, &mockWalletKitClient{})
+
+	_, err := svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{FeeRate: 1})
+	assert.EqualError(t, err, "no outputs")
+
+	_, err = svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{
+		Outputs: []lnclient.OnchainOutput{{Address: "bc1qaddress1", AmountSat: 50_000}},
+	})
+	assert.EqualError(t, err, "no fee rate")
+
+	_, err = svc.SendOnchain(context.TODO(), &lnclient.SendOnchainRequest{
+		Outputs: []lnclient.OnchainOutput{
+			{Address: "bc1qaddress1", AmountSat: 50_000},
+			{Address: "bc1qaddress1", AmountSat: 20_000},
+		},
+		FeeRate: 1,
+	})
+	assert.EqualError(t, err, "duplicate output address: bc1qaddress1")
+}
+
+// newMockWalletSend returns a wallet send spending a 100k sat wallet output,
+// paying 50k sats to the recipient (output 0) with a 1000 sat fee
+func newMockWalletSend(t *testing.T, changeSat int64) (*wire.MsgTx, *wire.MsgTx, []*lnrpc.Transaction) {
+	parentTx := newMockTx([]wire.OutPoint{{Hash: chainhash.Hash{0x01}, Index: 0}}, 100_000)
+	outputValues := []int64{50_000}
+	if changeSat > 0 {
+		outputValues = append(outputValues, changeSat)
+	}
+	tx := newMockTx([]wire.OutPoint{{Hash: parentTx.TxHash(), Index: 0}}, outputValues...)
+
+	outputDetails := []*lnrpc.OutputDetail{{OutputIndex: 0, Amount: 50_000}}
+	if changeSat > 0 {
+		outputDetails = append(outputDetails, &lnrpc.OutputDetail{OutputIndex: 1, Amount: changeSat, IsOurAddress: true})
+	}
+
+	transactions := []*lnrpc.Transaction{
+		{
+			TxHash:           parentTx.TxHash().String(),
+			Amount:           100_000,
+			NumConfirmations: 6,
+			RawTxHex:         serializeTx(t, parentTx),
+		},
+		{
+			TxHash:            tx.TxHash().String(),
+			Amount:            -51_000,
+			TotalFees:         100_000 - 50_000 - changeSat,
+			RawTxHex:          serializeTx(t, tx),
+			OutputDetails:     outputDetails,
+			PreviousOutpoints: []*lnrpc.PreviousOutPoint{{Outpoint: parentTx.TxHash().String() + ":0", IsOurOutput: true}},
+		},
+	}
+	return parentTx, tx, transactions
+}
+
+func TestBumpOnchainFee_ReplacesWalletSend(t *testing.T) {
+	parentTx, tx, transactions := newMockWalletSend(t, 49_000)
+	walletKitClient := &mockWalletKitClient{}
+	svc := newTestLNDService(&mockLightningClient{transactions: transactions}, walletKitClient)
+
+	replacementTxId, err := svc.BumpOnchainFee(context.TODO(), tx.TxHash().String(), 20)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tx.TxHash().String(), replacementTxId)
+	assert.Empty(t, walletKitClient.bumpFeeRequests)
+
+	require.Equal(t, 1, len(walletKitClient.finalizePsbtRequests))
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader(walletKitClient.finalizePsbtRequests[0].FundedPsbt), false)
+	require.NoError(t, err)
+	replacementTx := packet.UnsignedTx
+	assert.Equal(t, replacementTx.TxHash().String(), replacementTxId)
+
+	// same inputs, the recipient receives the same amount and the change pays the additional fee
+	vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx))
+	assert.Equal(t, tx.TxIn[0].PreviousOutPoint, replacementTx.TxIn[0].PreviousOutPoint)
+	assert.Equal(t, int64(50_000), replacementTx.TxOut[0].Value)
+	assert.Equal(t, int64(49_000)-(20*vsize-1000), replacementTx.TxOut[1].Value)
+	assert.Equal(t, parentTx.TxOut[0], packet.Inputs[0].WitnessUtxo)
+	assert.Equal(t, 1, len(walletKitClient.publishedTxs))
+}
+
+func TestBumpOnchainFee_FeeRateTooLow(t *testing.T) {
+	_, tx, transactions := newMockWalletSend(t, 49_000)
+	walletKitClient := &mockWalletKitClient{}
+	svc := newTestLNDService(&mockLightningClient{transactions: transactions}, walletKitClient)
+
+	// the original transaction already pays ~7 sat/vB
+	_, err := svc.BumpOnchainFee(context.TODO(), tx.TxHash().String(), 7)
+	assert.ErrorContains(t, err, "fee rate must be at least")
+	assert.Empty(t, walletKitClient.publishedTxs)
+	assert.Empty(t, walletKitClient.bumpFeeRequests)
+}
+
+func TestBumpOnchainFee_NoChangeUsesCPFP(t *testing.T) {
+	_, tx, transactions := newMockWalletSend(t, 0)
+	walletKitClient := &mockWalletKitClient{}
+	lightningClient := &mockLightningClient{transactions: transactions}
+	svc := newTestLNDService(lightningClient, walletKitClient)
+
+	// without change there is no wallet output to spend either
+	_, err := svc.BumpOnchainFee(context.TODO(), tx.TxHash().String(), 20)
+	assert.EqualError(t, err, "no unconfirmed wallet output found for this transaction")
+	assert.Empty(t, walletKitClient.publishedTxs)
+}
+
+func TestBumpOnchainFee_DepositUsesCPFP(t *testing.T) {
+	depositTx := newMockTx([]wire.OutPoint{{Hash: chainhash.Hash{0x02}, Index: 0}}, 30_000, 70_000)
+	depositOutpoint := &lnrpc.OutPoint{TxidStr: depositTx.TxHash().String(), OutputIndex: 0}
+	lightningClient := &mockLightningClient{
+		transactions: []*lnrpc.Transaction{{
+			TxHash:            depositTx.TxHash().String(),
+			Amount:            30_000,
+			RawTxHex:          serializeTx(t, depositTx),
+			OutputDetails:     []*lnrpc.OutputDetail{{OutputIndex: 0, Amount: 30_000, IsOurAddress: true}},
+			PreviousOutpoints: []*lnrpc.PreviousOutPoint{{Outpoint: chainhash.Hash{0x02}.String() + ":0"}},
+		}},
+		utxos: []*lnrpc.Utxo{{Outpoint: depositOutpoint, AmountSat: 30_000}},
+	}
+	walletKitClient := &mockWalletKitClient{}
+	svc := newTestLNDService(lightningClient, walletKitClient)
+
+	txId, err := svc.BumpOnchainFee(context.TODO(), depositTx.TxHash().String(), 20)
+	assert.NoError(t, err)
+	assert.Equal(t, depositTx.TxHash().String(), txId)
+	assert.Empty(t, walletKitClient.publishedTxs)
+
+	require.Equal(t, 1, len(walletKitClient.bumpFeeRequests))
+	assert.Equal(t, depositOutpoint, walletKitClient.bumpFeeRequests[0].Outpoint)
+	assert.Equal(t, uint64(20), walletKitClient.bumpFeeRequests[0].SatPerVbyte)
+}
+
+func TestBumpOnchainFee_Confirmed(t *testing.T) {
+	_, tx, transactions := newMockWalletSend(t, 49_000)
+	transactions[1].NumConfirmations = 1
+	svc := newTestLNDService(&mockLightningClient{transactions: transactions}, &mockWalletKitClient{})
+
+	_, err := svc.BumpOnchainFee(context.TODO(), tx.TxHash().String(), 20)
+	assert.EqualError(t, err, "transaction is already confirmed")
+}
+
+func TestListOnchainTransactions(t *testing.T) {
+	lightningClient := &mockLightningClient{
+		transactions: []*lnrpc.Transaction{
+			{
+				TxHash:           "deposit",
+				Amount:           100_000,
+				NumConfirmations: 3,
+				TimeStamp:        1725969600,
+				OutputDetails: []*lnrpc.OutputDetail{
+					{OutputIndex: 0, Address: "bc1qsender", Amount: 20_000},
+					{OutputIndex: 1, Address: "bc1qreceive", Amount: 100_000, IsOurAddress: true},
+				},
+			},
+			{
+				TxHash:    "send",
+				Amount:    -50_150,
+				TotalFees: 150,
+				Label:     "external wallet",
+				OutputDetails: []*lnrpc.OutputDetail{
+					{OutputIndex: 0, Address: "bc1qchange", Amount: 30_000, IsOurAddress: true},
+					{OutputIndex: 1, Address: "bc1qdestination", Amount: 50_000},
+				},
+			},
+		},
+	}
+	svc := newTestLNDService(lightningClient, &mockWalletKitClient{})
+
+	transactions, err := svc.ListOnchainTransactions(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []lnclient.OnchainTransaction{
+		{
+			TxId:          "deposit",
+			Type:          constants.TRANSACTION_TYPE_INCOMING,
+			AmountSat:     100_000,
+			Confirmations: 3,
+			Timestamp:     1725969600,
+			Address:       "bc1qreceive",
+		},
+		{
+			TxId:      "send",
+			Type:      constants.TRANSACTION_TYPE_OUTGOING,
+			AmountSat: 50_150,
+			FeeSat:    150,
+			Address:   "bc1qdestination",
+			Label:     "external wallet",
+		},
+	}, transactions)
+}
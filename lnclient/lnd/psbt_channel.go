@@ -0,0 +1,350 @@
+package lnd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// how long to wait for LND to report the channel as pending after the funding transaction was handed over
+const psbtChannelPendingTimeout = 30 * time.Second
+
+// how long the external wallet has to fund a started PSBT channel open before it is cancelled
+var psbtChannelOpenTimeout = 10 * time.Minute
+
+type psbtChannelOpen struct {
+	pendingChannelId []byte
+	cancel           context.CancelFunc
+	pendingUpdates   chan *lnrpc.PendingUpdate
+	errors           chan error
+	expiryTimer      *time.Timer
+	// LND only accepts a single PsbtVerify per pending channel,
+	// so a verified channel open is finalized without verifying again
+	verified bool
+}
+
+func (svc *LNDService) StartPsbtChannelOpen(ctx context.Context, openChannelRequest *lnclient.OpenChannelRequest) (*lnclient.PsbtChannelOpen, error) {
+	nodePub, err := hex.DecodeString(openChannelRequest.Pubkey)
+	if err != nil {
+		return nil, errors.New("failed to decode pubkey")
+	}
+
+	pendingChannelId := make([]byte, 32)
+	_, err = rand.Read(pendingChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the open channel stream must outlive this request, as the external wallet
+	// funds the channel in a later request
+	streamCtx, cancel := context.WithCancel(svc.ctx)
+	lndOpenChannelRequest := newLndOpenChannelRequest(nodePub, openChannelRequest)
+	// the fee rate is chosen by the external wallet
+	lndOpenChannelRequest.SatPerVbyte = 0
+	lndOpenChannelRequest.FundingShim = &lnrpc.FundingShim{
+		Shim: &lnrpc.FundingShim_PsbtShim{
+			PsbtShim: &lnrpc.PsbtShim{
+				PendingChanId: pendingChannelId,
+			},
+		},
+	}
+	stream, err := svc.client.OpenChannel(streamCtx, lndOpenChannelRequest)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open channel with %s: %s", openChannelRequest.Pubkey, err)
+	}
+
+	update, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open channel with %s: %s", openChannelRequest.Pubkey, err)
+	}
+	psbtFund := update.GetPsbtFund()
+	if psbtFund == nil {
+		cancel()
+		return nil, errors.New("unexpected channel open update: expected PSBT funding request")
+	}
+
+	channelOpen := &psbtChannelOpen{
+		pendingChannelId: pendingChannelId,
+		cancel:           cancel,
+		pendingUpdates:   make(chan *lnrpc.PendingUpdate, 1),
+		errors:           make(chan error, 1),
+	}
+
+	go func() {
+		for {
+			update, err := stream.Recv()
+			if err != nil {
+				channelOpen.errors <- err
+				return
+			}
+			if chanPending := update.GetChanPending(); chanPending != nil {
+				channelOpen.pendingUpdates <- chanPending
+				return
+			}
+		}
+	}()
+
+	pendingChannelIdHex := hex.EncodeToString(pendingChannelId)
+	svc.psbtChannelOpensMutex.Lock()
+	channelOpen.expiryTimer = time.AfterFunc(psbtChannelOpenTimeout, func() {
+		svc.expirePsbtChannelOpen(pendingChannelIdHex)
+	})
+	svc.psbtChannelOpens[pendingChannelIdHex] = channelOpen
+	svc.psbtChannelOpensMutex.Unlock()
+
+	logger.Logger.WithFields(logrus.Fields{
+		"peer_id":            openChannelRequest.Pubkey,
+		"pending_channel_id": pendingChannelIdHex,
+		"funding_address":    psbtFund.FundingAddress,
+		"funding_amount":     psbtFund.FundingAmount,
+	}).Info("Started PSBT channel open")
+
+	return &lnclient.PsbtChannelOpen{
+		PendingChannelId: pendingChannelIdHex,
+		FundingAddress:   psbtFund.FundingAddress,
+		FundingAmount:    psbtFund.FundingAmount,
+		Psbt:             base64.StdEncoding.EncodeToString(psbtFund.Psbt),
+	}, nil
+}
+
+func (svc *LNDService) VerifyPsbtChannelFunding(ctx context.Context, pendingChannelId string, fundedPsbt string) error {
+	channelOpen, err := svc.getPsbtChannelOpen(pendingChannelId)
+	if err != nil {
+		return err
+	}
+
+	if svc.isPsbtChannelOpenVerified(channelOpen) {
+		return errors.New("the channel funding has already been verified")
+	}
+
+	fundedPsbtBytes, err := base64.StdEncoding.DecodeString(fundedPsbt)
+	if err != nil {
+		return fmt.Errorf("failed to decode PSBT: %w", err)
+	}
+
+	return svc.verifyPsbtChannelFunding(ctx, channelOpen, fundedPsbtBytes)
+}
+
+func (svc *LNDService) verifyPsbtChannelFunding(ctx context.Context, channelOpen *psbtChannelOpen, fundedPsbt []byte) error {
+	_, err := svc.client.FundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtVerify{
+			PsbtVerify: &lnrpc.FundingPsbtVerify{
+				FundedPsbt:    fundedPsbt,
+				PendingChanId: channelOpen.pendingChannelId,
+			},
+		},
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to verify PSBT")
+		return err
+	}
+
+	svc.psbtChannelOpensMutex.Lock()
+	channelOpen.verified = true
+	svc.psbtChannelOpensMutex.Unlock()
+	return nil
+}
+
+func (svc *LNDService) isPsbtChannelOpenVerified(channelOpen *psbtChannelOpen) bool {
+	svc.psbtChannelOpensMutex.Lock()
+	defer svc.psbtChannelOpensMutex.Unlock()
+	return channelOpen.verified
+}
+
+func (svc *LNDService) FinalizePsbtChannelOpen(ctx context.Context, pendingChannelId string, signedPsbt string, rawTx string) (*lnclient.OpenChannelResponse, error) {
+	if (signedPsbt == "") == (rawTx == "") {
+		return nil, errors.New("either a signed PSBT or a raw transaction must be provided")
+	}
+
+	channelOpen, err := svc.getPsbtChannelOpen(pendingChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the channel open must not expire while it is being finalized
+	if !channelOpen.expiryTimer.Stop() {
+		return nil, errors.New("the channel open has expired or is already being finalized")
+	}
+
+	openChannelResponse, err := svc.finalizePsbtChannelOpen(ctx, channelOpen, signedPsbt, rawTx)
+	if err != nil && !svc.isPsbtChannelOpenRemoved(pendingChannelId) {
+		// allow the funding to be retried until the channel open expires
+		channelOpen.expiryTimer.Reset(psbtChannelOpenTimeout)
+	}
+	return openChannelResponse, err
+}
+
+func (svc *LNDService) finalizePsbtChannelOpen(ctx context.Context, channelOpen *psbtChannelOpen, signedPsbt string, rawTx string) (*lnclient.OpenChannelResponse, error) {
+	pendingChannelId := hex.EncodeToString(channelOpen.pendingChannelId)
+	var err error
+
+	psbtFinalize := &lnrpc.FundingPsbtFinalize{
+		PendingChanId: channelOpen.pendingChannelId,
+	}
+	var fundedPsbt []byte
+
+	if signedPsbt != "" {
+		psbtFinalize.SignedPsbt, err = base64.StdEncoding.DecodeString(signedPsbt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode PSBT: %w", err)
+		}
+		fundedPsbt = psbtFinalize.SignedPsbt
+	} else {
+		psbtFinalize.FinalRawTx, err = hex.DecodeString(rawTx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		fundedPsbt, err = unsignedPsbtFromRawTx(psbtFinalize.FinalRawTx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// LND only accepts the funding transaction once it has been verified
+	if !svc.isPsbtChannelOpenVerified(channelOpen) {
+		err = svc.verifyPsbtChannelFunding(ctx, channelOpen, fundedPsbt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = svc.client.FundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtFinalize{
+			PsbtFinalize: psbtFinalize,
+		},
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to finalize PSBT")
+		return nil, err
+	}
+
+	defer svc.removePsbtChannelOpen(pendingChannelId)
+
+	select {
+	case pendingUpdate := <-channelOpen.pendingUpdates:
+		fundingTxId, err := chainhash.NewHash(pendingUpdate.Txid)
+		if err != nil {
+			return nil, err
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"pending_channel_id": pendingChannelId,
+			"funding_tx_id":      fundingTxId.String(),
+		}).Info("PSBT channel open is pending")
+		return &lnclient.OpenChannelResponse{
+			FundingTxId: fundingTxId.String(),
+		}, nil
+	case err := <-channelOpen.errors:
+		return nil, fmt.Errorf("failed to open channel: %s", err)
+	case <-time.After(psbtChannelPendingTimeout):
+		return nil, errors.New("timed out waiting for the channel to become pending")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (svc *LNDService) CancelPsbtChannelOpen(ctx context.Context, pendingChannelId string) error {
+	channelOpen, err := svc.getPsbtChannelOpen(pendingChannelId)
+	if err != nil {
+		return err
+	}
+
+	svc.cancelPsbtChannelOpen(ctx, channelOpen)
+	return nil
+}
+
+func (svc *LNDService) expirePsbtChannelOpen(pendingChannelId string) {
+	channelOpen, err := svc.getPsbtChannelOpen(pendingChannelId)
+	if err != nil {
+		return
+	}
+
+	logger.Logger.WithField("pending_channel_id", pendingChannelId).Warn("PSBT channel open was not funded in time, cancelling")
+	svc.cancelPsbtChannelOpen(svc.ctx, channelOpen)
+}
+
+func (svc *LNDService) cancelPsbtChannelOpen(ctx context.Context, channelOpen *psbtChannelOpen) {
+	pendingChannelId := hex.EncodeToString(channelOpen.pendingChannelId)
+	_, err := svc.client.FundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_ShimCancel{
+			ShimCancel: &lnrpc.FundingShimCancel{
+				PendingChanId: channelOpen.pendingChannelId,
+			},
+		},
+	})
+	if err != nil {
+		// the channel open is abandoned by closing the stream anyway
+		logger.Logger.WithError(err).WithField("pending_channel_id", pendingChannelId).Error("Failed to cancel funding shim")
+	}
+
+	svc.removePsbtChannelOpen(pendingChannelId)
+}
+
+func (svc *LNDService) getPsbtChannelOpen(pendingChannelId string) (*psbtChannelOpen, error) {
+	svc.psbtChannelOpensMutex.Lock()
+	defer svc.psbtChannelOpensMutex.Unlock()
+	channelOpen, ok := svc.psbtChannelOpens[pendingChannelId]
+	if !ok {
+		return nil, errors.New("pending channel not found")
+	}
+	return channelOpen, nil
+}
+
+func (svc *LNDService) removePsbtChannelOpen(pendingChannelId string) {
+	svc.psbtChannelOpensMutex.Lock()
+	defer svc.psbtChannelOpensMutex.Unlock()
+	channelOpen, ok := svc.psbtChannelOpens[pendingChannelId]
+	if !ok {
+		return
+	}
+	channelOpen.expiryTimer.Stop()
+	channelOpen.cancel()
+	delete(svc.psbtChannelOpens, pendingChannelId)
+}
+
+func (svc *LNDService) isPsbtChannelOpenRemoved(pendingChannelId string) bool {
+	svc.psbtChannelOpensMutex.Lock()
+	defer svc.psbtChannelOpensMutex.Unlock()
+	_, ok := svc.psbtChannelOpens[pendingChannelId]
+	return !ok
+}
+
+// unsignedPsbtFromRawTx creates a PSBT from a signed transaction so that the
+// funding outputs can be verified before the transaction is published
+func unsignedPsbtFromRawTx(rawTx []byte) ([]byte, error) {
+	var tx wire.MsgTx
+	err := tx.Deserialize(bytes.NewReader(rawTx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	for _, txIn := range tx.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(&tx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = packet.Serialize(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+package lnd
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+func startTestPsbtChannelOpen(t *testing.T) (*LNDService, *mockLightningClient, *lnclient.PsbtChannelOpen) {
+	lightningClient := &mockLightningClient{}
+	svc := newTestLNDService(lightningClient, &mockWalletKitClient{})
+
+	channelOpen, err := svc.StartPsbtChannelOpen(context.TODO(), &lnclient.OpenChannelRequest{
+		Pubkey: mockIdentityPubkey,
+		Amount: 100_000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bcrt1qfunding", channelOpen.FundingAddress)
+	assert.Equal(t, int64(100_000), channelOpen.FundingAmount)
+	return svc, lightningClient, channelOpen
+}
+
+func assertFundingStateSteps(t *testing.T, lightningClient *mockLightningClient, expectedTriggers ...interface{}) {
+	steps := lightningClient.getFundingStateSteps()
+	require.Equal(t, len(expectedTriggers), len(steps))
+	for i, expectedTrigger := range expectedTriggers {
+		assert.IsType(t, expectedTrigger, steps[i].Trigger)
+	}
+}
+
+func TestPsbtChannelOpen_VerifyAndFinalize(t *testing.T) {
+	svc, lightningClient, channelOpen := startTestPsbtChannelOpen(t)
+	signedPsbt := base64.StdEncoding.EncodeToString(newMockPsbt(t, newMockTx([]wire.OutPoint{{Index: 0}}, 100_000)))
+
+	err := svc.VerifyPsbtChannelFunding(context.TODO(), channelOpen.PendingChannelId, signedPsbt)
+	require.NoError(t, err)
+
+	err = svc.VerifyPsbtChannelFunding(context.TODO(), channelOpen.PendingChannelId, signedPsbt)
+	assert.EqualError(t, err, "the channel funding has already been verified")
+
+	response, err := svc.FinalizePsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId, signedPsbt, "")
+	require.NoError(t, err)
+	assert.Len(t, response.FundingTxId, 64)
+
+	// the funding is only verified once
+	assertFundingStateSteps(t, lightningClient, &lnrpc.FundingTransitionMsg_PsbtVerify{}, &lnrpc.FundingTransitionMsg_PsbtFinalize{})
+	assert.True(t, svc.isPsbtChannelOpenRemoved(channelOpen.PendingChannelId))
+}
+
+func TestPsbtChannelOpen_FinalizeWithoutVerify(t *testing.T) {
+	svc, lightningClient, channelOpen := startTestPsbtChannelOpen(t)
+	rawTx := serializeTx(t, newMockTx([]wire.OutPoint{{Index: 0}}, 100_000))
+
+	response, err := svc.FinalizePsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId, "", rawTx)
+	require.NoError(t, err)
+	assert.Len(t, response.FundingTxId, 64)
+
+	assertFundingStateSteps(t, lightningClient, &lnrpc.FundingTransitionMsg_PsbtVerify{}, &lnrpc.FundingTransitionMsg_PsbtFinalize{})
+	verify := lightningClient.getFundingStateSteps()[0].GetPsbtVerify()
+	finalize := lightningClient.getFundingStateSteps()[1].GetPsbtFinalize()
+	assert.NotEmpty(t, verify.FundedPsbt)
+	assert.NotEmpty(t, finalize.FinalRawTx)
+	assert.True(t, svc.isPsbtChannelOpenRemoved(channelOpen.PendingChannelId))
+}
+
+func TestPsbtChannelOpen_Cancel(t *testing.T) {
+	svc, lightningClient, channelOpen := startTestPsbtChannelOpen(t)
+
+	err := svc.CancelPsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId)
+	require.NoError(t, err)
+
+	assertFundingStateSteps(t, lightningClient, &lnrpc.FundingTransitionMsg_ShimCancel{})
+	assert.True(t, svc.isPsbtChannelOpenRemoved(channelOpen.PendingChannelId))
+
+	_, err = svc.FinalizePsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId, "", "00")
+	assert.EqualError(t, err, "pending channel not found")
+}
+
+func TestPsbtChannelOpen_Expiry(t *testing.T) {
+	defaultTimeout := psbtChannelOpenTimeout
+	psbtChannelOpenTimeout = 10 * time.Millisecond
+	defer func() { psbtChannelOpenTimeout = defaultTimeout }()
+
+	svc, lightningClient, channelOpen := startTestPsbtChannelOpen(t)
+
+	require.Eventually(t, func() bool {
+		return svc.isPsbtChannelOpenRemoved(channelOpen.PendingChannelId)
+	}, time.Second, 5*time.Millisecond)
+	assertFundingStateSteps(t, lightningClient, &lnrpc.FundingTransitionMsg_ShimCancel{})
+}
+
+func TestPsbtChannelOpen_InvalidFinalizeRequest(t *testing.T) {
+	svc, lightningClient, channelOpen := startTestPsbtChannelOpen(t)
+
+	_, err := svc.FinalizePsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId, "", "")
+	assert.EqualError(t, err, "either a signed PSBT or a raw transaction must be provided")
+
+	_, err = svc.FinalizePsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId, "", "not hex")
+	assert.Error(t, err)
+
+	// a failed finalize leaves the channel open to be funded again
+	assertFundingStateSteps(t, lightningClient)
+	assert.False(t, svc.isPsbtChannelOpenRemoved(channelOpen.PendingChannelId))
+	require.NoError(t, svc.CancelPsbtChannelOpen(context.TODO(), channelOpen.PendingChannelId))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"github.com/lightningnetwork/lnd/macaroons"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -33,6 +34,7 @@ type LNDWrapper struct {
 	client         lnrpc.LightningClient
 	routerClient   routerrpc.RouterClient
 	stateClient    lnrpc.StateClient
+	walletClient   walletrpc.WalletKitClient
 	IdentityPubkey string
 }
 
@@ -82,11 +84,18 @@ func NewLNDclient(lndOptions LNDoptions) (result *LNDWrapper, err error) {
 		return nil, err
 	}
 	lnClient := lnrpc.NewLightningClient(conn)
+	return NewLNDWrapper(lnClient, routerrpc.NewRouterClient(conn), lnrpc.NewStateClient(conn), walletrpc.NewWalletKitClient(conn), ""), nil
+}
+
+// NewLNDWrapper wraps already created clients, e.g. mocks in tests
+func NewLNDWrapper(client lnrpc.LightningClient, routerClient routerrpc.RouterClient, stateClient lnrpc.StateClient, walletClient walletrpc.WalletKitClient, identityPubkey string) *LNDWrapper {
 	return &LNDWrapper{
-		client:       lnClient,
-		routerClient: routerrpc.NewRouterClient(conn),
-		stateClient:  lnrpc.NewStateClient(conn),
-	}, nil
+		client:         client,
+		routerClient:   routerClient,
+		stateClient:    stateClient,
+		walletClient:   walletClient,
+		IdentityPubkey: identityPubkey,
+	}
 }
 
 func (wrapper *LNDWrapper) ListChannels(ctx context.Context, req *lnrpc.ListChannelsRequest, options ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
@@ -215,6 +224,54 @@ func (wrapper *LNDWrapper) UpdateChannel(ctx context.Context, req *lnrpc.PolicyU
 	return wrapper.client.UpdateChannelPolicy(ctx, req, options...)
 }
 
+func (wrapper *LNDWrapper) ForwardingHistory(ctx context.Context, req *lnrpc.ForwardingHistoryRequest, options ...grpc.CallOption) (*lnrpc.ForwardingHistoryResponse, error) {
+	return wrapper.client.ForwardingHistory(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) SubscribeHtlcEvents(ctx context.Context, req *routerrpc.SubscribeHtlcEventsRequest, options ...grpc.CallOption) (routerrpc.Router_SubscribeHtlcEventsClient, error) {
+	return wrapper.routerClient.SubscribeHtlcEvents(ctx, req, options...)
+}
+
 func (wrapper *LNDWrapper) DisconnectPeer(ctx context.Context, req *lnrpc.DisconnectPeerRequest, options ...grpc.CallOption) (*lnrpc.DisconnectPeerResponse, error) {
 	return wrapper.client.DisconnectPeer(ctx, req, options...)
 }
+
+func (wrapper *LNDWrapper) ListUnspent(ctx context.Context, req *lnrpc.ListUnspentRequest, options ...grpc.CallOption) (*lnrpc.ListUnspentResponse, error) {
+	return wrapper.client.ListUnspent(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) FundPsbt(ctx context.Context, req *walletrpc.FundPsbtRequest, options ...grpc.CallOption) (*walletrpc.FundPsbtResponse, error) {
+	return wrapper.walletClient.FundPsbt(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) FinalizePsbt(ctx context.Context, req *walletrpc.FinalizePsbtRequest, options ...grpc.CallOption) (*walletrpc.FinalizePsbtResponse, error) {
+	return wrapper.walletClient.FinalizePsbt(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) PublishTransaction(ctx context.Context, req *walletrpc.Transaction, options ...grpc.CallOption) (*walletrpc.PublishResponse, error) {
+	return wrapper.walletClient.PublishTransaction(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) ReleaseOutput(ctx context.Context, req *walletrpc.ReleaseOutputRequest, options ...grpc.CallOption) (*walletrpc.ReleaseOutputResponse, error) {
+	return wrapper.walletClient.ReleaseOutput(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) BumpFee(ctx context.Context, req *walletrpc.BumpFeeRequest, options ...grpc.CallOption) (*walletrpc.BumpFeeResponse, error) {
+	return wrapper.walletClient.BumpFee(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) ClosedChannels(ctx context.Context, req *lnrpc.ClosedChannelsRequest, options ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error) {
+	return wrapper.client.ClosedChannels(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) OpenChannel(ctx context.Context, req *lnrpc.OpenChannelRequest, options ...grpc.CallOption) (lnrpc.Lightning_OpenChannelClient, error) {
+	return wrapper.client.OpenChannel(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) FundingStateStep(ctx context.Context, req *lnrpc.FundingTransitionMsg, options ...grpc.CallOption) (*lnrpc.FundingStateStepResp, error) {
+	return wrapper.client.FundingStateStep(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) BatchOpenChannel(ctx context.Context, req *lnrpc.BatchOpenChannelRequest, options ...grpc.CallOption) (*lnrpc.BatchOpenChannelResponse, error) {
+	return wrapper.client.BatchOpenChannel(ctx, req, options...)
+}
@@ -2,6 +2,7 @@ package lnclient
 
 import (
 	"context"
+	"errors"
 )
 
 // TODO: remove JSON tags from these models (LNClient models should not be exposed directly)
@@ -68,6 +69,7 @@ type LNClient interface {
 	GetOnchainBalance(ctx context.Context) (*OnchainBalanceResponse, error)
 	GetBalances(ctx context.Context) (*BalancesResponse, error)
 	RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (txId string, err error)
+	ListOnchainTransactions(ctx context.Context) ([]OnchainTransaction, error)
 	SendPaymentProbes(ctx context.Context, invoice string) error
 	SendSpontaneousPaymentProbes(ctx context.Context, amountMsat uint64, nodeId string) error
 	ListPeers(ctx context.Context) ([]PeerDetails, error)
@@ -80,6 +82,158 @@ type LNClient interface {
 	GetSupportedNIP47NotificationTypes() []string
 }
 
+// EcashClient is implemented by LNClients which hold ecash and can
+// send and receive tokens directly, without going over Lightning
+type EcashClient interface {
+	// amount in millisats, returns an encoded token
+	SendToken(ctx context.Context, amount uint64, mintUrl string) (token *EcashToken, err error)
+	// returns the amount received in millisats
+	ReceiveToken(ctx context.Context, token string, swapToDefaultMint bool) (amount uint64, err error)
+	// moves funds from one of the trusted mints to the default mint via Lightning
+	SwapToDefaultMint(ctx context.Context, fromMintUrl string, amount uint64) error
+}
+
+type EcashToken struct {
+	Token   string
+	MintUrl string
+	// total amount in millisats, including fees required by the mint to redeem the token
+	Amount uint64
+}
+
+// OnchainFeeEstimator is implemented by LNClients which can estimate
+// the fees of an onchain send before it is made
+type OnchainFeeEstimator interface {
+	// amount in sats, ignored if sendAll is true
+	EstimateOnchainFees(ctx context.Context, toAddress string, amount uint64, sendAll bool) (*OnchainFeeEstimate, error)
+}
+
+// OnchainSwapSender is implemented by LNClients which send a specific amount onchain
+// from the lightning balance using a swap. The transaction ID is only known once
+// the swap has progressed, so the send is referenced by the swap ID until then.
+type OnchainSwapSender interface {
+	// amount in sats
+	PayOnchainViaSwap(ctx context.Context, toAddress string, amount uint64) (swapId string, err error)
+	GetOnchainSwap(ctx context.Context, swapId string) (*OnchainSwap, error)
+}
+
+type OnchainSwap struct {
+	// empty until the transaction paying the recipient has been broadcast
+	TxId      string
+	Confirmed bool
+	Failed    bool
+}
+
+const (
+	ONCHAIN_CHANNEL_ACTION_OPEN  = "open"
+	ONCHAIN_CHANNEL_ACTION_CLOSE = "close"
+)
+
+type OnchainTransaction struct {
+	TxId string
+	// incoming or outgoing, from the perspective of the onchain wallet
+	Type string
+	// net amount in sats received or sent by the onchain wallet
+	AmountSat uint64
+	// only known for transactions paid by the onchain wallet
+	FeeSat        uint64
+	Confirmations uint32
+	// unix timestamp in seconds, 0 if unknown
+	Timestamp int64
+	// set if the transaction opens or closes one of the node's channels
+	ChannelId     string
+	ChannelAction string
+	// receiving address of deposits or destination of sends, if known
+	Address string
+	// backend specific label, if any
+	Label string
+}
+
+type OnchainFeeEstimate struct {
+	// amount in sats that will be received by the destination address
+	Amount uint64 `json:"amount"`
+	// total fees in sats, including any swap fees
+	Fee uint64 `json:"fee"`
+	// sat/vB used for the estimate, 0 if not known
+	FeeRate uint64 `json:"feeRate"`
+}
+
+// OnchainCoinController is implemented by LNClients which allow choosing
+// the inputs, outputs and fee rate of onchain transactions
+type OnchainCoinController interface {
+	// lists confirmed and unconfirmed outputs owned by the onchain wallet
+	ListUtxos(ctx context.Context) ([]Utxo, error)
+	SendOnchain(ctx context.Context, sendOnchainRequest *SendOnchainRequest) (txId string, err error)
+	// feeRate in sat/vB. Returns the ID of the replacement transaction, or the
+	// original transaction ID if the fee was bumped by a child transaction
+	BumpOnchainFee(ctx context.Context, txId string, feeRate uint64) (newTxId string, err error)
+}
+
+type OutPoint struct {
+	TxId        string `json:"txId"`
+	OutputIndex uint32 `json:"outputIndex"`
+}
+
+type Utxo struct {
+	OutPoint
+	Address   string `json:"address"`
+	AmountSat uint64 `json:"amountSat"`
+	// 0 if unconfirmed
+	Confirmations uint32 `json:"confirmations"`
+}
+
+type OnchainOutput struct {
+	Address   string `json:"address"`
+	AmountSat uint64 `json:"amountSat"`
+}
+
+type SendOnchainRequest struct {
+	Outputs []OnchainOutput
+	// optional, the wallet selects the inputs if none are given
+	Inputs []OutPoint
+	// sat/vB
+	FeeRate uint64
+}
+
+// PsbtChannelFunder is implemented by LNClients which can open channels
+// funded by an external wallet (e.g. a hardware or multisig wallet)
+type PsbtChannelFunder interface {
+	// starts the channel open and returns the funding output which has to be paid by the external wallet
+	StartPsbtChannelOpen(ctx context.Context, openChannelRequest *OpenChannelRequest) (*PsbtChannelOpen, error)
+	// checks the funded (not yet signed) base64 PSBT pays the funding output
+	VerifyPsbtChannelFunding(ctx context.Context, pendingChannelId string, fundedPsbt string) error
+	// publishes the funding transaction, given either as a signed base64 PSBT or as a hex-encoded raw transaction
+	FinalizePsbtChannelOpen(ctx context.Context, pendingChannelId string, signedPsbt string, rawTx string) (*OpenChannelResponse, error)
+	CancelPsbtChannelOpen(ctx context.Context, pendingChannelId string) error
+}
+
+type PsbtChannelOpen struct {
+	PendingChannelId string `json:"pendingChannelId"`
+	FundingAddress   string `json:"fundingAddress"`
+	// in sats
+	FundingAmount int64 `json:"fundingAmount"`
+	// base64 PSBT containing only the funding output
+	Psbt string `json:"psbt"`
+}
+
+// JitChannelInvoiceMaker is implemented by LNClients which can receive through an
+// LSPS2 just-in-time channel. The LSP opens the channel when the invoice is paid and
+// deducts its opening fee from the payment, which is reported as the fee of the incoming transaction.
+type JitChannelInvoiceMaker interface {
+	// returns false if no LSPS2 LSP is configured
+	SupportsJitChannels() bool
+	MakeJitChannelInvoice(ctx context.Context, amount int64, description string, expiry int64, maxOpeningFeeMsat uint64) (transaction *Transaction, err error)
+	// the opening fee of a variable amount invoice is limited by its proportional part, in parts per million
+	MakeVariableAmountJitChannelInvoice(ctx context.Context, description string, expiry int64, maxOpeningFeePpm uint64) (transaction *Transaction, err error)
+}
+
+// Rebalancer is implemented by LNClients which can move liquidity
+// between their own channels by paying an invoice to themselves
+type Rebalancer interface {
+	// pays an invoice created by this node, leaving through the outgoing channel and
+	// coming back through the incoming channel. maxFeeMsat limits the routing fees.
+	SendRebalancePayment(ctx context.Context, payReq string, outgoingChannelId string, incomingChannelId string, maxFeeMsat uint64) (*PayInvoiceResponse, error)
+}
+
 type Channel struct {
 	LocalBalance                             int64
 	LocalSpendableBalance                    int64
@@ -93,6 +247,11 @@ type Channel struct {
 	Confirmations                            *uint32
 	ConfirmationsRequired                    *uint32
 	ForwardingFeeBaseMsat                    uint32
+	ForwardingFeeProportionalMillionths      uint32
+	CltvExpiryDelta                          uint16
+	HtlcMinimumMsat                          *uint64
+	HtlcMaximumMsat                          *uint64
+	ForceCloseAvoidanceMaxFeeSat             *uint64
 	UnspendablePunishmentReserve             uint64
 	CounterpartyUnspendablePunishmentReserve uint64
 	Error                                    *string
@@ -113,6 +272,42 @@ type OpenChannelRequest struct {
 	Pubkey string `json:"pubkey"`
 	Amount int64  `json:"amount"`
 	Public bool   `json:"public"`
+	// optional parameters below are not supported by every backend
+
+	// in sats, given to the peer as part of the channel open
+	PushAmount int64 `json:"pushAmount,omitempty"`
+	// in sat/vB, used for the funding transaction
+	FeeRate  uint64 `json:"feeRate,omitempty"`
+	ZeroConf bool   `json:"zeroConf,omitempty"`
+	// nil uses the backend default commitment type
+	Anchors *bool `json:"anchors,omitempty"`
+	// upfront shutdown script: funds are sent to this address when the channel is cooperatively closed
+	CloseAddress string `json:"closeAddress,omitempty"`
+	// smallest HTLC the peer may send us over this channel
+	MinHtlcMsat uint64 `json:"minHtlcMsat,omitempty"`
+}
+
+// BatchChannelOpener is implemented by LNClients which can open
+// multiple channels funded by a single transaction
+type BatchChannelOpener interface {
+	OpenChannels(ctx context.Context, batchOpenChannelRequest *BatchOpenChannelRequest) (*BatchOpenChannelResponse, error)
+}
+
+type BatchOpenChannelRequest struct {
+	// the fee rate of the individual channels is ignored
+	Channels []OpenChannelRequest `json:"channels"`
+	// in sat/vB, used for the shared funding transaction
+	FeeRate uint64 `json:"feeRate"`
+}
+
+type BatchOpenChannelResponse struct {
+	FundingTxId string                   `json:"fundingTxId"`
+	Channels    []BatchOpenChannelResult `json:"channels"`
+}
+
+type BatchOpenChannelResult struct {
+	Pubkey      string `json:"pubkey"`
+	OutputIndex uint32 `json:"outputIndex"`
 }
 
 type OpenChannelResponse struct {
@@ -125,10 +320,41 @@ type CloseChannelRequest struct {
 	Force     bool   `json:"force"`
 }
 
+// ChannelPolicyUpdate contains the channel policy fields to update.
+// Fields which are not set are left unchanged.
+type ChannelPolicyUpdate struct {
+	ForwardingFeeBaseMsat               *uint32 `json:"forwardingFeeBaseMsat,omitempty"`
+	ForwardingFeeProportionalMillionths *uint32 `json:"forwardingFeeProportionalMillionths,omitempty"`
+	CltvExpiryDelta                     *uint16 `json:"cltvExpiryDelta,omitempty"`
+	HtlcMinimumMsat                     *uint64 `json:"htlcMinimumMsat,omitempty"`
+	HtlcMaximumMsat                     *uint64 `json:"htlcMaximumMsat,omitempty"`
+	MaxDustHtlcExposureMsat             *uint64 `json:"maxDustHtlcExposureMsat,omitempty"`
+	// the limit scales with the fee rate instead of being fixed
+	MaxDustHtlcExposureFeeRateMultiplier *uint64 `json:"maxDustHtlcExposureFeeRateMultiplier,omitempty"`
+	// maximum fee worth paying to avoid a force close when the counterparty disagrees on the fee rate
+	ForceCloseAvoidanceMaxFeeSat *uint64 `json:"forceCloseAvoidanceMaxFeeSat,omitempty"`
+}
+
+func (update *ChannelPolicyUpdate) Validate() error {
+	if update.ForwardingFeeBaseMsat == nil && update.ForwardingFeeProportionalMillionths == nil &&
+		update.CltvExpiryDelta == nil && update.HtlcMinimumMsat == nil && update.HtlcMaximumMsat == nil &&
+		update.MaxDustHtlcExposureMsat == nil && update.MaxDustHtlcExposureFeeRateMultiplier == nil &&
+		update.ForceCloseAvoidanceMaxFeeSat == nil {
+		return errors.New("no channel policy fields to update")
+	}
+	if update.HtlcMinimumMsat != nil && update.HtlcMaximumMsat != nil && *update.HtlcMinimumMsat > *update.HtlcMaximumMsat {
+		return errors.New("HTLC minimum cannot be greater than the HTLC maximum")
+	}
+	if update.MaxDustHtlcExposureMsat != nil && update.MaxDustHtlcExposureFeeRateMultiplier != nil {
+		return errors.New("max dust HTLC exposure can either be a fixed limit or a fee rate multiplier, not both")
+	}
+	return nil
+}
+
 type UpdateChannelRequest struct {
-	ChannelId             string `json:"channelId"`
-	NodeId                string `json:"nodeId"`
-	ForwardingFeeBaseMsat uint32 `json:"forwardingFeeBaseMsat"`
+	ChannelId string `json:"channelId"`
+	NodeId    string `json:"nodeId"`
+	ChannelPolicyUpdate
 }
 
 type CloseChannelResponse struct {
@@ -165,9 +391,16 @@ type PayKeysendResponse struct {
 	Fee uint64 `json:"fee"`
 }
 
+type MintBalance struct {
+	MintUrl string `json:"mintUrl"`
+	Balance int64  `json:"balance"`
+}
+
 type BalancesResponse struct {
 	Onchain   OnchainBalanceResponse   `json:"onchain"`
 	Lightning LightningBalanceResponse `json:"lightning"`
+	// only set for ecash wallets which can hold funds in multiple mints
+	Mints []MintBalance `json:"mints,omitempty"`
 }
 
 type NetworkGraphResponse = interface{}
@@ -177,6 +410,24 @@ type PaymentFailedEventProperties struct {
 	Reason      string
 }
 
+// Forward is an HTLC routed through the node, published in nwc_lnclient_forward events
+type Forward struct {
+	// unique per forward, used to avoid storing the same forward twice
+	Id                string
+	IncomingChannelId string
+	OutgoingChannelId string
+	// pubkeys of the channel peers, empty if unknown (e.g. the channel is closed)
+	IncomingPeer  string
+	OutgoingPeer  string
+	AmountInMsat  uint64
+	AmountOutMsat uint64
+	FeeMsat       uint64
+	Failed        bool
+	FailureReason string
+	// unix timestamp in seconds
+	Timestamp int64
+}
+
 // default invoice expiry in seconds (1 day)
 const DEFAULT_INVOICE_EXPIRY = 86400
 
@@ -5,6 +5,8 @@ import (
 	b64 "encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
@@ -12,8 +14,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/gorilla/websocket"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 
 	"github.com/sirupsen/logrus"
@@ -65,30 +69,124 @@ type BalanceResponse struct {
 	FeeCreditSat int64 `json:"feeCreditSat"`
 }
 
+// PaymentEvent is sent by phoenixd over its websocket
+type PaymentEvent struct {
+	Type        string `json:"type"`
+	AmountSat   int64  `json:"amountSat"`
+	PaymentHash string `json:"paymentHash"`
+	ExternalId  string `json:"externalId"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
 type PhoenixService struct {
 	Address       string
 	Authorization string
 	pubkey        string
+	mempoolApi    string
+	cancel        context.CancelFunc
 }
 
-func NewPhoenixService(address string, authorization string) (result lnclient.LNClient, err error) {
+func NewPhoenixService(ctx context.Context, eventPublisher events.EventPublisher, address string, authorization string, mempoolApi string) (result lnclient.LNClient, err error) {
 	authorizationBase64 := b64.StdEncoding.EncodeToString([]byte(":" + authorization))
 	// some environments (e.g. in a cloud environment like render.com) can only get the address and the port but not the protocol
 	// in those cases we default to http for local requests
 	if !strings.HasPrefix(address, "http") {
 		address = "http://" + address
 	}
-	phoenixService := &PhoenixService{Address: address, Authorization: authorizationBase64}
+	phoenixService := &PhoenixService{Address: address, Authorization: authorizationBase64, mempoolApi: mempoolApi}
 
-	info, err := phoenixService.GetInfo(context.Background())
+	info, err := phoenixService.GetInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
 	phoenixService.pubkey = info.Pubkey
 
+	phoenixCtx, cancel := context.WithCancel(ctx)
+	phoenixService.cancel = cancel
+
+	// Subscribe to payments
+	go func() {
+		for {
+			select {
+			case <-phoenixCtx.Done():
+				return
+			default:
+				err := phoenixService.subscribePaymentEvents(phoenixCtx, eventPublisher)
+				if err != nil {
+					logger.Logger.WithError(err).Error("Phoenixd websocket subscription failed")
+				}
+				select {
+				case <-phoenixCtx.Done():
+					return
+				case <-time.After(10 * time.Second):
+					continue
+				}
+			}
+		}
+	}()
+
 	return phoenixService, nil
 }
 
+func (svc *PhoenixService) subscribePaymentEvents(ctx context.Context, eventPublisher events.EventPublisher) error {
+	websocketUrl := "ws" + strings.TrimPrefix(svc.Address, "http") + "/websocket"
+
+	header := http.Header{}
+	header.Add("Authorization", "Basic "+svc.Authorization)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, websocketUrl, header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// unblock ReadMessage when the context is cancelled. The connection context ends
+	// when this subscription returns, so a reconnect does not leave the goroutine behind
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		conn.Close()
+	}()
+
+	logger.Logger.WithField("url", websocketUrl).Info("Subscribed to phoenixd payment events")
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var paymentEvent PaymentEvent
+		if err := json.Unmarshal(message, &paymentEvent); err != nil {
+			logger.Logger.WithField("message", string(message)).WithError(err).Error("Failed to decode phoenixd websocket message")
+			continue
+		}
+
+		if paymentEvent.Type != "payment_received" {
+			continue
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"paymentHash": paymentEvent.PaymentHash,
+			"amountSat":   paymentEvent.AmountSat,
+		}).Info("Received phoenixd payment received event")
+
+		transaction, err := svc.LookupInvoice(ctx, paymentEvent.PaymentHash)
+		if err != nil {
+			logger.Logger.WithField("paymentHash", paymentEvent.PaymentHash).WithError(err).Error("Failed to lookup received phoenixd payment")
+			continue
+		}
+
+		eventPublisher.Publish(&events.Event{
+			Event:      "nwc_lnclient_payment_received",
+			Properties: transaction,
+		})
+	}
+}
+
 func (svc *PhoenixService) GetBalance(ctx context.Context) (balance int64, err error) {
 	req, err := http.NewRequest(http.MethodGet, svc.Address+"/getbalance", nil)
 	if err != nil {
@@ -302,8 +400,11 @@ func (svc *PhoenixService) MakeInvoice(ctx context.Context, amount int64, descri
 		form.Add("description", "invoice")
 	}
 
-	today := time.Now().UTC().Format("2006-02-01") // querying is too slow so we limit the invoices we query with the date - see list transactions
-	form.Add("externalId", today)                  // for some resone phoenixd requires an external id to query a list of invoices. thus we set this to nwc
+	// querying is too slow so we limit the invoices we query with the date - see list transactions.
+	// The day comes before the month to match the external ids of existing invoices
+	now := time.Now().UTC()
+	today := fmt.Sprintf("%d-%02d-%02d", now.Year(), now.Day(), now.Month())
+	form.Add("externalId", today) // for some resone phoenixd requires an external id to query a list of invoices. thus we set this to nwc
 	logger.Logger.WithFields(logrus.Fields{
 		"externalId": today,
 		"amountSat":  amountSat,
@@ -432,12 +533,50 @@ func (svc *PhoenixService) SendPaymentSync(ctx context.Context, payReq string) (
 	}, nil
 }
 
+// phoenixd has no documented endpoint to send keysend payments
 func (svc *PhoenixService) SendKeysend(ctx context.Context, amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
-	return nil, errors.New("not implemented")
+	return nil, errors.New("not supported")
 }
 
 func (svc *PhoenixService) RedeemOnchainFunds(ctx context.Context, toAddress string, amount uint64, sendAll bool) (txId string, err error) {
-	return "", errors.New("not implemented")
+	if sendAll {
+		// phoenixd pays the splice-out fee from the channel balance, so the exact amount must be provided
+		return "", errors.New("only sending a specific amount is supported")
+	}
+
+	feeRate, err := lnclient.GetRecommendedFeeRate(svc.mempoolApi)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch recommended fee rate")
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Add("amountSat", strconv.FormatUint(amount, 10))
+	form.Add("address", toAddress)
+	form.Add("feerateSatByte", strconv.FormatUint(feeRate, 10))
+	req, err := http.NewRequest(http.MethodPost, svc.Address+"/sendtoaddress", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Authorization", "Basic "+svc.Authorization)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	client := &http.Client{Timeout: 90 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to send to address: %s", string(body))
+	}
+
+	// phoenixd responds with the plain transaction id
+	return strings.TrimSpace(string(body)), nil
 }
 
 func (svc *PhoenixService) ResetRouter(key string) error {
@@ -445,6 +584,9 @@ func (svc *PhoenixService) ResetRouter(key string) error {
 }
 
 func (svc *PhoenixService) Shutdown() error {
+	if svc.cancel != nil {
+		svc.cancel()
+	}
 	return nil
 }
 
@@ -486,11 +628,14 @@ func (svc *PhoenixService) GetNewOnchainAddress(ctx context.Context) (string, er
 }
 
 func (svc *PhoenixService) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainBalanceResponse, error) {
-	return nil, errors.New("not implemented")
+	// phoenixd has no separate onchain wallet: onchain funds are swapped into the channel
+	// and withdrawals are spliced out of it
+	return nil, errors.New("onchain balance is not supported by phoenixd")
 }
 
+// phoenixd has no documented endpoint to sign messages with the node key
 func (svc *PhoenixService) SignMessage(ctx context.Context, message string) (string, error) {
-	return "", errors.New("not implemented")
+	return "", errors.New("not supported")
 }
 
 func (svc *PhoenixService) SendPaymentProbes(ctx context.Context, invoice string) error {
@@ -501,6 +646,10 @@ func (svc *PhoenixService) SendSpontaneousPaymentProbes(ctx context.Context, amo
 	return nil
 }
 
+func (svc *PhoenixService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	return []lnclient.OnchainTransaction{}, nil
+}
+
 func (svc *PhoenixService) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	return nil, nil
 }
@@ -532,11 +681,11 @@ func (svc *PhoenixService) UpdateChannel(ctx context.Context, updateChannelReque
 }
 
 func (svc *PhoenixService) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice"}
+	return []string{"pay_invoice", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "get_budget"}
 }
 
 func (svc *PhoenixService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{}
+	return []string{"payment_received", "balance_changed", "app_expiring"}
 }
 
 func (svc *PhoenixService) GetPubkey() string {
@@ -0,0 +1,210 @@
+package phoenixd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/tests"
+)
+
+const mockNodeId = "03a2f4c9d1b1d1b0a0e9f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1a1f1"
+const mockPreimage = "018465013e2337234a7e5530a21c4a8cf70d84231f4a8ff0b1e2cce3cb2bd03b"
+const mockTxId = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+type fakePhoenixd struct {
+	server        *httptest.Server
+	requests      map[string]http.Header
+	forms         map[string]map[string][]string
+	paymentEvents chan PaymentEvent
+	mtx           sync.Mutex
+}
+
+func newFakePhoenixd(t *testing.T) *fakePhoenixd {
+	fake := &fakePhoenixd{
+		requests:      map[string]http.Header{},
+		forms:         map[string]map[string][]string{},
+		paymentEvents: make(chan PaymentEvent, 1),
+	}
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	record := func(r *http.Request) {
+		r.ParseForm()
+		fake.mtx.Lock()
+		defer fake.mtx.Unlock()
+		fake.requests[r.URL.Path] = r.Header
+		fake.forms[r.URL.Path] = r.PostForm
+	}
+
+	mux.HandleFunc("/getinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(InfoResponse{NodeId: mockNodeId})
+	})
+	mux.HandleFunc("/sendtoaddress", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.Write([]byte(mockTxId))
+	})
+	mux.HandleFunc("/payments/incoming/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(InvoiceResponse{
+			PaymentHash: tests.MockPaymentHash,
+			Preimage:    "123preimage",
+			Invoice:     tests.MockInvoice,
+			IsPaid:      true,
+			ReceivedSat: 123,
+			CompletedAt: tests.MockTime.UnixMilli(),
+			CreatedAt:   tests.MockTime.UnixMilli(),
+		})
+	})
+	mux.HandleFunc("/v1/fees/recommended", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(lnclient.RecommendedFeesResponse{HalfHourFee: 7})
+	})
+	mux.HandleFunc("/websocket", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade websocket: %v", err)
+			return
+		}
+		defer conn.Close()
+		for paymentEvent := range fake.paymentEvents {
+			conn.WriteJSON(paymentEvent)
+		}
+	})
+
+	fake.server = httptest.NewServer(mux)
+	return fake
+}
+
+func (fake *fakePhoenixd) form(path string) map[string][]string {
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+	return fake.forms[path]
+}
+
+func (fake *fakePhoenixd) header(path string) http.Header {
+	fake.mtx.Lock()
+	defer fake.mtx.Unlock()
+	return fake.requests[path]
+}
+
+func (fake *fakePhoenixd) Close() {
+	close(fake.paymentEvents)
+	fake.server.Close()
+}
+
+type mockEventSubscriber struct {
+	events chan *events.Event
+}
+
+func (subscriber *mockEventSubscriber) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	subscriber.events <- event
+}
+
+func TestMain(m *testing.M) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	os.Exit(m.Run())
+}
+
+func createPhoenixService(t *testing.T, fake *fakePhoenixd, eventPublisher events.EventPublisher) *PhoenixService {
+	lnClient, err := NewPhoenixService(context.TODO(), eventPublisher, fake.server.URL, "password", fake.server.URL)
+	assert.NoError(t, err)
+	t.Cleanup(func() { lnClient.Shutdown() })
+	return lnClient.(*PhoenixService)
+}
+
+func TestSendKeysend_NotSupported(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+	svc := createPhoenixService(t, fake, events.NewEventPublisher())
+
+	_, err := svc.SendKeysend(context.TODO(), 123000, mockNodeId, []lnclient.TLVRecord{}, mockPreimage)
+	assert.EqualError(t, err, "not supported")
+	assert.NotContains(t, svc.GetSupportedNIP47Methods(), "pay_keysend")
+}
+
+func TestRedeemOnchainFunds(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+	svc := createPhoenixService(t, fake, events.NewEventPublisher())
+
+	txId, err := svc.RedeemOnchainFunds(context.TODO(), "bc1qtest", 50000, false)
+	assert.NoError(t, err)
+	assert.Equal(t, mockTxId, txId)
+
+	form := fake.form("/sendtoaddress")
+	assert.Equal(t, "50000", form["amountSat"][0])
+	assert.Equal(t, "bc1qtest", form["address"][0])
+	assert.Equal(t, "7", form["feerateSatByte"][0])
+}
+
+func TestRedeemOnchainFunds_SendAll(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+	svc := createPhoenixService(t, fake, events.NewEventPublisher())
+
+	_, err := svc.RedeemOnchainFunds(context.TODO(), "bc1qtest", 0, true)
+	assert.Error(t, err)
+	assert.Nil(t, fake.form("/sendtoaddress"))
+}
+
+func TestGetOnchainBalance(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+	svc := createPhoenixService(t, fake, events.NewEventPublisher())
+
+	balance, err := svc.GetOnchainBalance(context.TODO())
+	assert.Error(t, err)
+	assert.Nil(t, balance)
+}
+
+func TestSignMessage_NotSupported(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+	svc := createPhoenixService(t, fake, events.NewEventPublisher())
+
+	_, err := svc.SignMessage(context.TODO(), "hello")
+	assert.EqualError(t, err, "not supported")
+	assert.NotContains(t, svc.GetSupportedNIP47Methods(), "sign_message")
+}
+
+func TestPaymentReceivedEvent(t *testing.T) {
+	fake := newFakePhoenixd(t)
+	defer fake.Close()
+
+	eventPublisher := events.NewEventPublisher()
+	subscriber := &mockEventSubscriber{events: make(chan *events.Event, 1)}
+	eventPublisher.RegisterSubscriber(subscriber)
+	svc := createPhoenixService(t, fake, eventPublisher)
+
+	fake.paymentEvents <- PaymentEvent{
+		Type:        "payment_received",
+		AmountSat:   123,
+		PaymentHash: tests.MockPaymentHash,
+	}
+
+	select {
+	case event := <-subscriber.events:
+		assert.Equal(t, "nwc_lnclient_payment_received", event.Event)
+		transaction, ok := event.Properties.(*lnclient.Transaction)
+		assert.True(t, ok)
+		assert.Equal(t, tests.MockPaymentHash, transaction.PaymentHash)
+		assert.Equal(t, int64(123000), transaction.Amount)
+		assert.NotNil(t, transaction.SettledAt)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for payment received event")
+	}
+	assert.Equal(t, "Basic "+svc.Authorization, fake.header("/websocket").Get("Authorization"))
+}
@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/utils"
+)
+
+const orderPollingInterval = 1 * time.Minute
+
+// LSPS1 order states
+const (
+	LSPS1_ORDER_STATE_CREATED   = "CREATED"
+	LSPS1_ORDER_STATE_COMPLETED = "COMPLETED"
+	LSPS1_ORDER_STATE_FAILED    = "FAILED"
+)
+
+// LSPS1 payment states
+const (
+	LSPS1_PAYMENT_STATE_EXPECT_PAYMENT = "EXPECT_PAYMENT"
+	LSPS1_PAYMENT_STATE_HOLD           = "HOLD"
+	LSPS1_PAYMENT_STATE_PAID           = "PAID"
+	LSPS1_PAYMENT_STATE_REFUNDED       = "REFUNDED"
+)
+
+// refund states of failed orders
+const (
+	LSP_ORDER_REFUND_STATE_PENDING  = "PENDING"
+	LSP_ORDER_REFUND_STATE_REFUNDED = "REFUNDED"
+)
+
+type lspService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+}
+
+type LSPService interface {
+	// returns the LSPs of the network of the node. LSPs are trusted to open zero-conf channels.
+	ListLSPs() ([]db.LSP, error)
+	GetLSP(id uint) (*db.LSP, error)
+	AddLSP(name, url, pubkey string, protocols []string, network string) (*db.LSP, error)
+	DeleteLSP(id uint) error
+	// creates an LSPS1 order for an incoming channel, the invoice of the returned order still needs to be paid
+	RequestOrder(ctx context.Context, lnClient lnclient.LNClient, lspUrl string, amount uint64, public bool) (*db.LSPOrder, error)
+	CreateOrder(order *db.LSPOrder) error
+	ListOrders(limit, offset uint64) ([]db.LSPOrder, error)
+	// polls the LSP for the state of all orders which are not finished yet
+	PollOrders(ctx context.Context)
+	StartOrderPolling(ctx context.Context)
+}
+
+func NewLSPService(db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher) *lspService {
+	return &lspService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (svc *lspService) ListLSPs() ([]db.LSP, error) {
+	lsps := []db.LSP{}
+	err := svc.db.Where(&db.LSP{Network: svc.cfg.GetEnv().LDKNetwork}).Order("id").Find(&lsps).Error
+	if err != nil {
+		return nil, err
+	}
+	return lsps, nil
+}
+
+func (svc *lspService) GetLSP(id uint) (*db.LSP, error) {
+	var lsp db.LSP
+	result := svc.db.Limit(1).Find(&lsp, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("LSP not found")
+	}
+	return &lsp, nil
+}
+
+func (svc *lspService) AddLSP(name, lspUrl, pubkey string, protocols []string, network string) (*db.LSP, error) {
+	if name == "" {
+		return nil, errors.New("no name provided")
+	}
+	parsedUrl, err := url.Parse(lspUrl)
+	if err != nil || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") || parsedUrl.Host == "" {
+		return nil, fmt.Errorf("invalid LSP url: %s", lspUrl)
+	}
+	pubkeyBytes, err := hex.DecodeString(pubkey)
+	if err != nil || len(pubkeyBytes) != 33 {
+		return nil, fmt.Errorf("invalid LSP pubkey: %s", pubkey)
+	}
+	if len(protocols) == 0 {
+		return nil, errors.New("no protocols provided")
+	}
+	for _, protocol := range protocols {
+		if !slices.Contains([]string{LSP_TYPE_LSPS1, LSP_TYPE_LSPS2}, protocol) {
+			return nil, fmt.Errorf("unsupported LSP protocol: %s", protocol)
+		}
+	}
+	if network == "" {
+		return nil, errors.New("no network provided")
+	}
+	if network != svc.cfg.GetEnv().LDKNetwork {
+		return nil, fmt.Errorf("LSP network %s does not match the network of the node: %s", network, svc.cfg.GetEnv().LDKNetwork)
+	}
+
+	lsp := &db.LSP{
+		Name:      name,
+		Url:       strings.TrimSuffix(lspUrl, "/"),
+		Pubkey:    pubkey,
+		Protocols: strings.Join(protocols, ","),
+		Network:   network,
+	}
+	err = svc.db.Create(lsp).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("url", lspUrl).Error("Failed to add LSP")
+		return nil, err
+	}
+	return lsp, nil
+}
+
+func (svc *lspService) DeleteLSP(id uint) error {
+	result := svc.db.Delete(&db.LSP{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("LSP not found")
+	}
+	return nil
+}
+
+func (svc *lspService) CreateOrder(order *db.LSPOrder) error {
+	// link the order to the registry if the LSP is registered
+	var lsp db.LSP
+	result := svc.db.Limit(1).Find(&lsp, &db.LSP{Url: strings.TrimSuffix(order.LSPUrl, "/")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		order.LSPId = &lsp.ID
+	}
+	return svc.db.Create(order).Error
+}
+
+func (svc *lspService) ListOrders(limit, offset uint64) ([]db.LSPOrder, error) {
+	orders := []db.LSPOrder{}
+	query := svc.db.Preload("LSP").Order("created_at desc")
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if offset > 0 {
+		query = query.Offset(int(offset))
+	}
+	err := query.Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (svc *lspService) StartOrderPolling(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(orderPollingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.PollOrders(ctx)
+			}
+		}
+	}()
+}
+
+func (svc *lspService) PollOrders(ctx context.Context) {
+	orders := []db.LSPOrder{}
+	err := svc.db.Where("state IS NULL OR state = ? OR (state = ? AND payment_state NOT IN ?)",
+		LSPS1_ORDER_STATE_CREATED,
+		LSPS1_ORDER_STATE_FAILED,
+		[]string{LSPS1_PAYMENT_STATE_REFUNDED, LSPS1_PAYMENT_STATE_EXPECT_PAYMENT},
+	).Find(&orders).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch LSP orders")
+		return
+	}
+
+	for _, order := range orders {
+		err := svc.pollOrder(ctx, &order)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"order_id": order.OrderId,
+				"lsp_url":  order.LSPUrl,
+			}).Error("Failed to poll LSP order")
+		}
+	}
+}
+
+func (svc *lspService) pollOrder(ctx context.Context, order *db.LSPOrder) error {
+	var lsps1Order LSPS1Order
+	err := utils.JsonRequest(ctx, http.MethodGet, order.LSPUrl+"/get_order?order_id="+url.QueryEscape(order.OrderId), nil, &lsps1Order)
+	if err != nil {
+		return err
+	}
+
+	previousState := order.State
+	previousPaymentState := order.PaymentState
+	previousRefundState := order.RefundState
+
+	order.State = lsps1Order.OrderState
+	if lsps1Order.Payment != nil {
+		order.PaymentState = lsps1Order.Payment.Bolt11.State
+	}
+	if lsps1Order.Channel != nil {
+		order.FundingOutpoint = lsps1Order.Channel.FundingOutpoint
+	}
+
+	if order.State == LSPS1_ORDER_STATE_FAILED {
+		switch order.PaymentState {
+		case LSPS1_PAYMENT_STATE_HOLD, LSPS1_PAYMENT_STATE_PAID:
+			// the LSP has to refund the payment of a failed order
+			order.RefundState = LSP_ORDER_REFUND_STATE_PENDING
+		case LSPS1_PAYMENT_STATE_REFUNDED:
+			order.RefundState = LSP_ORDER_REFUND_STATE_REFUNDED
+		}
+	}
+
+	if order.State == previousState && order.PaymentState == previousPaymentState && order.RefundState == previousRefundState {
+		return nil
+	}
+
+	err = svc.db.Model(order).Updates(map[string]interface{}{
+		"state":            order.State,
+		"payment_state":    order.PaymentState,
+		"funding_outpoint": order.FundingOutpoint,
+		"refund_state":     order.RefundState,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"order_id":      order.OrderId,
+		"state":         order.State,
+		"payment_state": order.PaymentState,
+		"refund_state":  order.RefundState,
+	}).Info("LSP order updated")
+
+	if order.State == LSPS1_ORDER_STATE_COMPLETED && previousState != LSPS1_ORDER_STATE_COMPLETED {
+		svc.publishOrderEvent("nwc_lsp_order_completed", order)
+	}
+	if order.State == LSPS1_ORDER_STATE_FAILED && previousState != LSPS1_ORDER_STATE_FAILED {
+		// the order is polled until the refund is made
+		if order.RefundState == LSP_ORDER_REFUND_STATE_PENDING {
+			logger.Logger.WithFields(logrus.Fields{
+				"order_id":               order.OrderId,
+				"refund_onchain_address": order.RefundOnchainAddress,
+			}).Warn("LSP order failed after payment, waiting for refund")
+		}
+		svc.publishOrderEvent("nwc_lsp_order_failed", order)
+	}
+	if order.RefundState == LSP_ORDER_REFUND_STATE_REFUNDED && previousRefundState != LSP_ORDER_REFUND_STATE_REFUNDED {
+		svc.publishOrderEvent("nwc_lsp_order_refunded", order)
+	}
+
+	return nil
+}
+
+func (svc *lspService) publishOrderEvent(event string, order *db.LSPOrder) {
+	svc.eventPublisher.Publish(&events.Event{
+		Event: event,
+		Properties: map[string]interface{}{
+			"order_id":      order.OrderId,
+			"lsp_url":       order.LSPUrl,
+			"payment_state": order.PaymentState,
+			"refund_state":  order.RefundState,
+			"amount":        order.LSPBalanceSat,
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package lsp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestAddLSP_Validation(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	lspService := lsp.NewLSPService(svc.DB, svc.Cfg, svc.EventPublisher)
+
+	_, err = lspService.AddLSP("Mock", "not a url", tests.MockLSPPubkey, []string{lsp.LSP_TYPE_LSPS1}, "bitcoin")
+	assert.Error(t, err)
+	_, err = lspService.AddLSP("Mock", "https://lsp.example.com", "invalid", []string{lsp.LSP_TYPE_LSPS1}, "bitcoin")
+	assert.Error(t, err)
+	_, err = lspService.AddLSP("Mock", "https://lsp.example.com", tests.MockLSPPubkey, []string{"LSPS9"}, "bitcoin")
+	assert.Error(t, err)
+	_, err = lspService.AddLSP("Mock", "https://lsp.example.com", tests.MockLSPPubkey, []string{lsp.LSP_TYPE_LSPS1}, "signet")
+	assert.Error(t, err)
+
+	// the LSPs seeded by the migration
+	lsps, err := lspService.ListLSPs()
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(lsps))
+	for _, seededLSP := range lsps {
+		assert.Equal(t, "bitcoin", seededLSP.Network)
+	}
+
+	registeredLSP, err := lspService.AddLSP("Mock", "https://lsp.example.com/", tests.MockLSPPubkey, []string{lsp.LSP_TYPE_LSPS1, lsp.LSP_TYPE_LSPS2}, "bitcoin")
+	require.NoError(t, err)
+	assert.Equal(t, "https://lsp.example.com", registeredLSP.Url)
+	assert.Equal(t, "LSPS1,LSPS2", registeredLSP.Protocols)
+
+	lsps, err = lspService.ListLSPs()
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(lsps))
+	assert.Equal(t, registeredLSP.ID, lsps[3].ID)
+
+	err = lspService.DeleteLSP(registeredLSP.ID)
+	assert.NoError(t, err)
+	err = lspService.DeleteLSP(registeredLSP.ID)
+	assert.Error(t, err)
+}
+
+func TestPollOrders(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	orderState := lsp.LSPS1_ORDER_STATE_CREATED
+	paymentState := lsp.LSPS1_PAYMENT_STATE_PAID
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/get_order", r.URL.Path)
+		assert.Equal(t, "mock-order", r.URL.Query().Get("order_id"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order_id":"mock-order","order_state":"` + orderState + `","payment":{"bolt11":{"state":"` + paymentState + `"}},"channel":{"funding_outpoint":"abcd:0"}}`))
+	}))
+	defer server.Close()
+
+	lspService := lsp.NewLSPService(svc.DB, svc.Cfg, svc.EventPublisher)
+	registeredLSP, err := lspService.AddLSP("Mock", server.URL, tests.MockLSPPubkey, []string{lsp.LSP_TYPE_LSPS1}, "bitcoin")
+	require.NoError(t, err)
+
+	err = lspService.CreateOrder(&db.LSPOrder{
+		LSPUrl:       server.URL,
+		LSPType:      lsp.LSP_TYPE_LSPS1,
+		OrderId:      "mock-order",
+		State:        lsp.LSPS1_ORDER_STATE_CREATED,
+		PaymentState: lsp.LSPS1_PAYMENT_STATE_EXPECT_PAYMENT,
+	})
+	require.NoError(t, err)
+
+	lspService.PollOrders(context.TODO())
+
+	orders, err := lspService.ListOrders(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(orders))
+	assert.Equal(t, registeredLSP.ID, *orders[0].LSPId)
+	assert.Equal(t, "Mock", orders[0].LSP.Name)
+	assert.Equal(t, lsp.LSPS1_ORDER_STATE_CREATED, orders[0].State)
+	assert.Equal(t, lsp.LSPS1_PAYMENT_STATE_PAID, orders[0].PaymentState)
+
+	// paid order failed - keeps polling until refunded
+	orderState = lsp.LSPS1_ORDER_STATE_FAILED
+	lspService.PollOrders(context.TODO())
+	orders, err = lspService.ListOrders(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, lsp.LSPS1_ORDER_STATE_FAILED, orders[0].State)
+	assert.Equal(t, lsp.LSP_ORDER_REFUND_STATE_PENDING, orders[0].RefundState)
+
+	paymentState = lsp.LSPS1_PAYMENT_STATE_REFUNDED
+	lspService.PollOrders(context.TODO())
+	orders, err = lspService.ListOrders(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, lsp.LSPS1_PAYMENT_STATE_REFUNDED, orders[0].PaymentState)
+	assert.Equal(t, lsp.LSP_ORDER_REFUND_STATE_REFUNDED, orders[0].RefundState)
+}
+
+func TestPollOrders_FailedInOnePoll(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order_id":"mock-order","order_state":"FAILED","payment":{"bolt11":{"state":"REFUNDED"}}}`))
+	}))
+	defer server.Close()
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	lspService := lsp.NewLSPService(svc.DB, svc.Cfg, svc.EventPublisher)
+	err = lspService.CreateOrder(&db.LSPOrder{
+		LSPUrl:       server.URL,
+		LSPType:      lsp.LSP_TYPE_LSPS1,
+		OrderId:      "mock-order",
+		State:        lsp.LSPS1_ORDER_STATE_CREATED,
+		PaymentState: lsp.LSPS1_PAYMENT_STATE_HOLD,
+	})
+	require.NoError(t, err)
+
+	// the order failed and was refunded between two polls
+	lspService.PollOrders(context.TODO())
+	orders, err := lspService.ListOrders(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, lsp.LSP_ORDER_REFUND_STATE_REFUNDED, orders[0].RefundState)
+
+	time.Sleep(10 * time.Millisecond)
+	consumedEvents := []string{}
+	for _, event := range mockEventConsumer.GetConsumeEvents() {
+		consumedEvents = append(consumedEvents, event.Event)
+	}
+	assert.ElementsMatch(t, []string{"nwc_lsp_order_failed", "nwc_lsp_order_refunded"}, consumedEvents)
+}
@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/utils"
+)
+
+type LSPS1Info struct {
+	MaxChannelExpiryBlocks uint64   `json:"max_channel_expiry_blocks"`
+	URIs                   []string `json:"uris"`
+}
+
+type LSPS1CreateOrderRequest struct {
+	PublicKey                    string `json:"public_key"`
+	LSPBalanceSat                string `json:"lsp_balance_sat"`
+	ClientBalanceSat             string `json:"client_balance_sat"`
+	RequiredChannelConfirmations uint64 `json:"required_channel_confirmations"`
+	FundingConfirmsWithinBlocks  uint64 `json:"funding_confirms_within_blocks"`
+	ChannelExpiryBlocks          uint64 `json:"channel_expiry_blocks"`
+	Token                        string `json:"token"`
+	RefundOnchainAddress         string `json:"refund_onchain_address"`
+	AnnounceChannel              bool   `json:"announce_channel"`
+}
+
+// RequestOrder connects to the LSP and creates an LSPS1 order for a channel with amount inbound liquidity.
+// The order is saved and polled until the channel is open. The returned order invoice still needs to be paid.
+func (svc *lspService) RequestOrder(ctx context.Context, lnClient lnclient.LNClient, lspUrl string, amount uint64, public bool) (*db.LSPOrder, error) {
+	logger.Logger.Infoln("Requesting LSP info")
+	var lspInfo LSPS1Info
+	err := utils.JsonRequest(ctx, http.MethodGet, lspUrl+"/get_info", nil, &lspInfo)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request LSP info")
+		return nil, err
+	}
+
+	pubkey, address, port, err := ParseNodeUri(lspInfo.URIs)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Infoln("Requesting own node info")
+
+	nodeInfo, err := lnClient.GetInfo(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"url": lspUrl,
+		}).Error("Failed to request own node info")
+		return nil, err
+	}
+
+	logger.Logger.WithField("lspInfo", lspInfo).Info("Connecting to LSP node as a peer")
+
+	err = lnClient.ConnectPeer(ctx, &lnclient.ConnectPeerRequest{
+		Pubkey:  pubkey,
+		Address: address,
+		Port:    port,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to connect to peer")
+		return nil, err
+	}
+
+	refundAddress, err := lnClient.GetNewOnchainAddress(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request onchain address")
+		return nil, err
+	}
+
+	var requiredChannelConfirmations uint64 = 0
+
+	if public {
+		// as per BOLT-7 6 confirmations are required for the channel to be gossiped
+		// https://github.com/lightning/bolts/blob/master/07-routing-gossip.md#requirements
+		requiredChannelConfirmations = 6
+	}
+
+	var order LSPS1Order
+	err = utils.JsonRequest(ctx, http.MethodPost, lspUrl+"/create_order", &LSPS1CreateOrderRequest{
+		PublicKey:                    nodeInfo.Pubkey,
+		LSPBalanceSat:                strconv.FormatUint(amount, 10),
+		ClientBalanceSat:             "0",
+		RequiredChannelConfirmations: requiredChannelConfirmations,
+		FundingConfirmsWithinBlocks:  6,
+		ChannelExpiryBlocks:          lspInfo.MaxChannelExpiryBlocks,
+		Token:                        "",
+		RefundOnchainAddress:         refundAddress,
+		AnnounceChannel:              public,
+	}, &order)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to request invoice")
+		return nil, err
+	}
+	if order.Payment == nil {
+		return nil, errors.New("LSP order has no payment details")
+	}
+
+	fee, err := strconv.ParseUint(order.Payment.Bolt11.FeeTotalSat, 10, 64)
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"url": lspUrl,
+		}).Error("Failed to parse fee")
+		return nil, err
+	}
+
+	lspOrder := &db.LSPOrder{
+		LSPUrl:               lspUrl,
+		LSPType:              LSP_TYPE_LSPS1,
+		OrderId:              order.OrderId,
+		State:                order.OrderState,
+		PaymentState:         order.Payment.Bolt11.State,
+		Invoice:              order.Payment.Bolt11.Invoice,
+		FeeSat:               fee,
+		LSPBalanceSat:        amount,
+		Public:               public,
+		RefundOnchainAddress: refundAddress,
+	}
+	// the order is polled until the channel is open
+	err = svc.CreateOrder(lspOrder)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("order_id", order.OrderId).Error("Failed to save LSP order")
+	}
+
+	return lspOrder, nil
+}
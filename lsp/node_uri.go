@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/utils"
+)
+
+// ParseNodeUri returns the pubkey, IPv4 address and port of the first clearnet URI
+func ParseNodeUri(uris []string) (pubkey string, address string, port uint16, err error) {
+	httpUris := utils.Filter(uris, func(uri string) bool {
+		return !strings.Contains(uri, ".onion")
+	})
+	if len(httpUris) == 0 {
+		logger.Logger.WithField("uris", uris).Error("Couldn't find HTTP URI")
+		return "", "", 0, errors.New("could not find LSP URI")
+	}
+	uri := httpUris[0]
+
+	// make sure it's a valid IPv4 URI
+	regex := regexp.MustCompile(`^([0-9a-f]+)@([0-9]+\.[0-9]+\.[0-9]+\.[0-9]+):([0-9]+)$`)
+	parts := regex.FindStringSubmatch(uri)
+	logger.Logger.WithField("parts", parts).Info("Split URI")
+	if parts == nil || len(parts) != 4 {
+		logger.Logger.WithField("parts", parts).Error("Unsupported URI")
+		return "", "", 0, errors.New("could not decode LSP URI")
+	}
+
+	parsedPort, err := strconv.Atoi(parts[3])
+	if err != nil {
+		logger.Logger.WithField("port", parts[3]).WithError(err).Error("Failed to decode port number")
+		return "", "", 0, err
+	}
+
+	return parts[1], parts[2], uint16(parsedPort), nil
+}
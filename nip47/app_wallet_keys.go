@@ -0,0 +1,177 @@
+package nip47
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateAppWalletKey gives an app its own wallet service key, so the connection
+// cannot be correlated with other connections of the hub on relays
+func (svc *nip47Service) CreateAppWalletKey(app *db.App) error {
+	return svc.setAppWalletKey(app, app.WalletKeyIndex)
+}
+
+// RotateAppWalletKey replaces the wallet service key of an app, e.g. if the connection secret leaked.
+// Requests sent to the previous key are not handled anymore, and the app has to be connected again.
+func (svc *nip47Service) RotateAppWalletKey(app *db.App) error {
+	if app.WalletPubkey == nil {
+		return errors.New("app does not have its own wallet service key")
+	}
+	return svc.setAppWalletKey(app, app.WalletKeyIndex+1)
+}
+
+func (svc *nip47Service) setAppWalletKey(app *db.App, keyIndex uint) error {
+	walletSecretKey, err := svc.keys.GetAppWalletKey(app.ID, keyIndex)
+	if err != nil {
+		return err
+	}
+	walletPubkey, err := nostr.GetPublicKey(walletSecretKey)
+	if err != nil {
+		return err
+	}
+
+	err = svc.db.Model(app).Updates(map[string]interface{}{
+		"wallet_pubkey":    walletPubkey,
+		"wallet_key_index": keyIndex,
+	}).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("appId", app.ID).Error("Failed to save app wallet pubkey")
+		return err
+	}
+	app.WalletPubkey = &walletPubkey
+	app.WalletKeyIndex = keyIndex
+
+	svc.RefreshAppWalletKeys()
+	return nil
+}
+
+// RefreshAppWalletKeys replaces the subscription to the wallet service keys of apps, e.g. after an app
+// with its own key was deleted. Keys changed while not connected to the relay are subscribed to on the next connection.
+func (svc *nip47Service) RefreshAppWalletKeys() {
+	select {
+	case svc.appWalletKeysChanged <- struct{}{}:
+	default:
+		// a refresh is already pending
+	}
+}
+
+// StartAppWalletKeySubscriptions subscribes to requests sent to the wallet service keys of
+// individual apps. A single subscription filters on all keys, and is replaced when they change.
+func (svc *nip47Service) StartAppWalletKeySubscriptions(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient) {
+	go func() {
+		// info events of keys which were already published on this connection
+		publishedInfo := map[string]bool{}
+		var sub *nostr.Subscription
+		for {
+			// a pending refresh is covered by loading the keys now
+			select {
+			case <-svc.appWalletKeysChanged:
+			default:
+			}
+
+			if sub != nil {
+				sub.Unsub()
+				sub = nil
+			}
+			walletPubkeys := svc.loadAppWalletKeys(ctx, relay, publishedInfo, lnClient)
+			if len(walletPubkeys) > 0 {
+				sub = svc.subscribeAppWalletKeys(ctx, relay, walletPubkeys, lnClient)
+			}
+
+			select {
+			case <-ctx.Done():
+				// subscription ended
+				return
+			case <-svc.appWalletKeysChanged:
+			}
+		}
+	}()
+}
+
+// loadAppWalletKeys returns the wallet service pubkeys of all apps with their own key,
+// and publishes the info event of new keys
+func (svc *nip47Service) loadAppWalletKeys(ctx context.Context, relay *nostr.Relay, publishedInfo map[string]bool, lnClient lnclient.LNClient) []string {
+	apps := []db.App{}
+	err := svc.db.Where("wallet_pubkey IS NOT NULL").Find(&apps).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to load app wallet pubkeys")
+		return nil
+	}
+
+	walletPubkeys := []string{}
+	for _, app := range apps {
+		walletPubkey := *app.WalletPubkey
+		walletPubkeys = append(walletPubkeys, walletPubkey)
+		if publishedInfo[walletPubkey] {
+			continue
+		}
+		walletSecretKey, err := svc.keys.GetAppWalletKey(app.ID, app.WalletKeyIndex)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("appId", app.ID).Error("Failed to derive app wallet key")
+			continue
+		}
+		// the info event is tagged with the app pubkey, which also tells apps
+		// that requested a connection (nostr+walletauth) that it was approved
+		err = svc.publishNip47Info(ctx, relay, walletSecretKey, nostr.Tags{{"p", app.NostrPubkey}}, lnClient)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("walletPubkey", walletPubkey).Error("Could not publish NIP47 info")
+			continue
+		}
+		publishedInfo[walletPubkey] = true
+	}
+	return walletPubkeys
+}
+
+func (svc *nip47Service) subscribeAppWalletKeys(ctx context.Context, relay *nostr.Relay, walletPubkeys []string, lnClient lnclient.LNClient) *nostr.Subscription {
+	sub, err := relay.Subscribe(ctx, nostr.Filters{{
+		Tags:  nostr.TagMap{"p": walletPubkeys},
+		Kinds: []int{models.REQUEST_KIND},
+	}})
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"walletPubkeys": walletPubkeys,
+		}).Error("Failed to subscribe to app wallet keys")
+		return nil
+	}
+
+	go func() {
+		select {
+		case <-sub.EndOfStoredEvents:
+		case <-sub.Context.Done():
+			// replaced before the relay sent all stored events
+		}
+		// the events channel is closed when the subscription is replaced
+		for event := range sub.Events {
+			svc.ScheduleEvent(ctx, relay, event, lnClient)
+		}
+	}()
+	return sub
+}
+
+// getWalletSecretKey returns the secret key of the shared or an app's wallet service key
+func (svc *nip47Service) getWalletSecretKey(walletPubkey string) (string, error) {
+	if walletPubkey == svc.keys.GetNostrPublicKey() {
+		return svc.keys.GetNostrSecretKey(), nil
+	}
+
+	app := db.App{}
+	err := svc.db.Where("wallet_pubkey = ?", walletPubkey).First(&app).Error
+	if err != nil {
+		return "", err
+	}
+	return svc.keys.GetAppWalletKey(app.ID, app.WalletKeyIndex)
+}
+
+func (svc *nip47Service) getAppWalletPubkey(app *db.App) string {
+	if app.WalletPubkey != nil {
+		return *app.WalletPubkey
+	}
+	return svc.keys.GetNostrPublicKey()
+}
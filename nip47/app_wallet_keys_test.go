@@ -0,0 +1,201 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/stretchr/testify/assert"
+)
+
+func createGetInfoRequestEvent(t *testing.T, reqPrivateKey string, walletPubkey string) *nostr.Event {
+	ss, err := nip04.ComputeSharedSecret(walletPubkey, reqPrivateKey)
+	assert.NoError(t, err)
+
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": models.GET_INFO_METHOD,
+	})
+	assert.NoError(t, err)
+
+	msg, err := nip04.Encrypt(string(payloadBytes), ss)
+	assert.NoError(t, err)
+
+	reqEvent := &nostr.Event{
+		Kind:      models.REQUEST_KIND,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", walletPubkey}},
+		Content:   msg,
+	}
+	err = reqEvent.Sign(reqPrivateKey)
+	assert.NoError(t, err)
+	return reqEvent
+}
+
+func TestCreateAppWalletKey(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app2, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	err = nip47svc.CreateAppWalletKey(app)
+	assert.NoError(t, err)
+	err = nip47svc.CreateAppWalletKey(app2)
+	assert.NoError(t, err)
+
+	assert.NotNil(t, app.WalletPubkey)
+	assert.NotNil(t, app2.WalletPubkey)
+	assert.NotEqual(t, svc.Keys.GetNostrPublicKey(), *app.WalletPubkey)
+	assert.NotEqual(t, *app.WalletPubkey, *app2.WalletPubkey)
+
+	// the key is derived, so it is the same after a restart
+	walletSecretKey, err := svc.Keys.GetAppWalletKey(app.ID, app.WalletKeyIndex)
+	assert.NoError(t, err)
+	walletPubkey, err := nostr.GetPublicKey(walletSecretKey)
+	assert.NoError(t, err)
+	assert.Equal(t, *app.WalletPubkey, walletPubkey)
+
+	savedApp := db.App{}
+	err = svc.DB.First(&savedApp, app.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, app.WalletPubkey, savedApp.WalletPubkey)
+}
+
+func TestRotateAppWalletKey(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	// only connections with their own key can rotate it
+	err = nip47svc.RotateAppWalletKey(app)
+	assert.EqualError(t, err, "app does not have its own wallet service key")
+
+	err = nip47svc.CreateAppWalletKey(app)
+	assert.NoError(t, err)
+	previousWalletPubkey := *app.WalletPubkey
+	// drain the subscription refresh of the created key
+	<-nip47svc.appWalletKeysChanged
+
+	err = nip47svc.RotateAppWalletKey(app)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), app.WalletKeyIndex)
+	assert.NotEqual(t, previousWalletPubkey, *app.WalletPubkey)
+	assert.Len(t, nip47svc.appWalletKeysChanged, 1)
+
+	walletSecretKey, err := nip47svc.getWalletSecretKey(*app.WalletPubkey)
+	assert.NoError(t, err)
+	walletPubkey, err := nostr.GetPublicKey(walletSecretKey)
+	assert.NoError(t, err)
+	assert.Equal(t, *app.WalletPubkey, walletPubkey)
+
+	// requests sent to the previous key are not handled anymore
+	_, err = nip47svc.getWalletSecretKey(previousWalletPubkey)
+	assert.Error(t, err)
+}
+
+func TestHandleResponse_AppWalletKey(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, _, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.GET_BALANCE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	err = nip47svc.CreateAppWalletKey(app)
+	assert.NoError(t, err)
+
+	reqEvent := createGetInfoRequestEvent(t, reqPrivateKey, *app.WalletPubkey)
+	relay := tests.NewMockRelay()
+	nip47svc.HandleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	assert.NotNil(t, relay.PublishedEvent)
+	// the response is signed by the app's own wallet service key
+	assert.Equal(t, *app.WalletPubkey, relay.PublishedEvent.PubKey)
+
+	ss, err := nip04.ComputeSharedSecret(*app.WalletPubkey, reqPrivateKey)
+	assert.NoError(t, err)
+	decrypted, err := nip04.Decrypt(relay.PublishedEvent.Content, ss)
+	assert.NoError(t, err)
+	unmarshalledResponse := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &unmarshalledResponse)
+	assert.NoError(t, err)
+	assert.Nil(t, unmarshalledResponse.Error)
+	assert.Equal(t, models.GET_INFO_METHOD, unmarshalledResponse.ResultType)
+}
+
+func TestHandleResponse_AppWalletKey_SharedKey(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.GET_BALANCE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	err = nip47svc.CreateAppWalletKey(app)
+	assert.NoError(t, err)
+
+	// a connection with its own key cannot be used through the shared key
+	reqEvent := createGetInfoRequestEvent(t, reqPrivateKey, svc.Keys.GetNostrPublicKey())
+	relay := tests.NewMockRelay()
+	nip47svc.HandleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	assert.NotNil(t, relay.PublishedEvent)
+	assert.Equal(t, svc.Keys.GetNostrPublicKey(), relay.PublishedEvent.PubKey)
+	decrypted, err := nip04.Decrypt(relay.PublishedEvent.Content, ss)
+	assert.NoError(t, err)
+	unmarshalledResponse := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &unmarshalledResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.ERROR_UNAUTHORIZED, unmarshalledResponse.Error.Code)
+}
+
+func TestHandleResponse_UnknownWalletKey(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	_, _, err = tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	unknownWalletPubkey, err := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	assert.NoError(t, err)
+
+	reqEvent := createGetInfoRequestEvent(t, reqPrivateKey, unknownWalletPubkey)
+	relay := tests.NewMockRelay()
+	nip47svc.HandleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	// there is no key to respond with
+	assert.Nil(t, relay.PublishedEvent)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+)
+
+type sendCashuTokenParams struct {
+	Amount  uint64 `json:"amount"`
+	MintUrl string `json:"mint_url"`
+}
+
+type sendCashuTokenResponse struct {
+	Token    string `json:"token"`
+	Amount   uint64 `json:"amount"`
+	FeesPaid uint64 `json:"fees_paid"`
+}
+
+type receiveCashuTokenParams struct {
+	Token string `json:"token"`
+}
+
+type receiveCashuTokenResponse struct {
+	Amount uint64 `json:"amount"`
+}
+
+func (controller *nip47Controller) HandleSendCashuTokenEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
+	sendParams := &sendCashuTokenParams{}
+	resp := decodeRequest(nip47Request, sendParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"appId":            app.ID,
+		"amount":           sendParams.Amount,
+		"mintUrl":          sendParams.MintUrl,
+	}).Info("Sending cashu token")
+
+	transaction, err := controller.transactionsService.SendEcashToken(ctx, sendParams.Amount, sendParams.MintUrl, controller.lnClient, &app.ID, &requestEventId)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"appId":            app.ID,
+			"amount":           sendParams.Amount,
+		}).Infof("Failed to send cashu token: %v", err)
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: sendCashuTokenResponse{
+			Token:    *transaction.EcashToken,
+			Amount:   transaction.AmountMsat,
+			FeesPaid: transaction.FeeMsat,
+		},
+	}, nostr.Tags{})
+}
+
+func (controller *nip47Controller) HandleReceiveCashuTokenEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
+	receiveParams := &receiveCashuTokenParams{}
+	resp := decodeRequest(nip47Request, receiveParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"appId":            app.ID,
+	}).Info("Receiving cashu token")
+
+	// apps cannot add new trusted mints, so tokens from other mints are always swapped to the default mint
+	transaction, err := controller.transactionsService.ReceiveEcashToken(ctx, receiveParams.Token, true, controller.lnClient, &app.ID, &requestEventId)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"appId":            app.ID,
+		}).Infof("Failed to receive cashu token: %v", err)
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: receiveCashuTokenResponse{
+			Amount: transaction.AmountMsat,
+		},
+	}, nostr.Tags{})
+}
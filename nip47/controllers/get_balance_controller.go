@@ -18,8 +18,9 @@ const (
 
 type getBalanceResponse struct {
 	Balance uint64 `json:"balance"`
-	// MaxAmount     int    `json:"max_amount"`
-	// BudgetRenewal string `json:"budget_renewal"`
+	// budget of the app in msat, if it has one
+	MaxAmount     uint64 `json:"max_amount,omitempty"`
+	BudgetRenewal string `json:"budget_renewal,omitempty"`
 }
 
 // TODO: remove checkPermission - can it be a middleware?
@@ -54,15 +55,11 @@ func (controller *nip47Controller) HandleGetBalanceEvent(ctx context.Context, ni
 		Balance: balance,
 	}
 
-	// this is not part of the spec and does not seem to be used
-	/*appPermission := db.AppPermission{}
-	controller.db.Where("app_id = ? AND request_method = ?", app.ID, models.PAY_INVOICE_METHOD).First(&appPermission)
-
-	maxAmount := appPermission.MaxAmount
-	if maxAmount > 0 {
-		responsePayload.MaxAmount = maxAmount * MSAT_PER_SAT
-		responsePayload.BudgetRenewal = appPermission.BudgetRenewal
-	}*/
+	// not part of the spec, see get_budget for the remaining budget
+	if budget := controller.getBudget(app); budget != nil {
+		responsePayload.MaxAmount = budget.TotalBudget
+		responsePayload.BudgetRenewal = budget.RenewalPeriod
+	}
 
 	publishResponse(&models.Response{
 		ResultType: nip47Request.Method,
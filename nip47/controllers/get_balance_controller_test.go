@@ -134,3 +134,47 @@ func TestHandleGetBalanceEvent_IsolatedApp_Transactions(t *testing.T) {
 	assert.Equal(t, uint64(1000), publishedResponse.Result.(*getBalanceResponse).Balance)
 	assert.Nil(t, publishedResponse.Error)
 }
+
+func TestHandleGetBalanceEvent_WithBudget(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetBalanceJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  400,
+		BudgetRenewal: constants.BUDGET_RENEWAL_WEEKLY,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	NewNip47Controller(svc.LNClient, svc.DB, svc.EventPublisher, permissionsSvc, transactionsSvc).
+		HandleGetBalanceEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Nil(t, publishedResponse.Error)
+	balance := publishedResponse.Result.(*getBalanceResponse)
+	assert.Equal(t, uint64(21000), balance.Balance)
+	assert.Equal(t, uint64(400_000), balance.MaxAmount)
+	assert.Equal(t, constants.BUDGET_RENEWAL_WEEKLY, balance.BudgetRenewal)
+}
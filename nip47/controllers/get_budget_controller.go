@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+)
+
+type getBudgetResponse struct {
+	UsedBudget    uint64  `json:"used_budget"`
+	TotalBudget   uint64  `json:"total_budget"`
+	RenewsAt      *uint64 `json:"renews_at,omitempty"`
+	RenewalPeriod string  `json:"renewal_period"`
+}
+
+func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+	}).Debug("Getting budget")
+
+	responsePayload := controller.getBudget(app)
+	if responsePayload == nil {
+		// the app has no budget
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Result:     struct{}{},
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     responsePayload,
+	}, nostr.Tags{})
+}
+
+// getBudget returns the budget of the pay_invoice permission of the app in msat,
+// or nil if the app cannot make payments or has no budget
+func (controller *nip47Controller) getBudget(app *db.App) *getBudgetResponse {
+	appPermission := db.AppPermission{}
+	result := controller.db.Limit(1).Find(&appPermission, &db.AppPermission{
+		AppId: app.ID,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	})
+	if result.Error != nil || result.RowsAffected == 0 || appPermission.MaxAmountSat <= 0 {
+		return nil
+	}
+
+	budgetRenewal := appPermission.BudgetRenewal
+	if budgetRenewal == "" {
+		budgetRenewal = constants.BUDGET_RENEWAL_NEVER
+	}
+
+	budget := &getBudgetResponse{
+		UsedBudget:    queries.GetBudgetUsageSat(controller.db, &appPermission) * MSAT_PER_SAT,
+		TotalBudget:   uint64(appPermission.MaxAmountSat) * MSAT_PER_SAT,
+		RenewalPeriod: budgetRenewal,
+	}
+	if renewsAt := queries.GetBudgetRenewsAt(appPermission.BudgetRenewal); renewsAt != nil {
+		renewsAtUnix := uint64(renewsAt.Unix())
+		budget.RenewsAt = &renewsAtUnix
+	}
+	return budget
+}
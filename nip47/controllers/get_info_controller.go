@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"context"
+	"slices"
 
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/nip47/notifications"
+	"github.com/getAlby/hub/utils"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sirupsen/logrus"
 )
@@ -20,17 +23,24 @@ type getInfoResponse struct {
 	BlockHash     string   `json:"block_hash"`
 	Methods       []string `json:"methods"`
 	Notifications []string `json:"notifications"`
+	// budget of the app, if it can make payments and has one
+	Budget *getBudgetResponse `json:"budget,omitempty"`
 }
 
 func (controller *nip47Controller) HandleGetInfoEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
 	supportedNotifications := []string{}
 	if controller.permissionsService.PermitsNotifications(app) {
-		supportedNotifications = controller.lnClient.GetSupportedNIP47NotificationTypes()
+		// only the notification types the app opted in to are sent
+		appNotificationTypes := notifications.GetAppNotificationTypes(app)
+		supportedNotifications = utils.Filter(controller.lnClient.GetSupportedNIP47NotificationTypes(), func(notificationType string) bool {
+			return slices.Contains(appNotificationTypes, notificationType)
+		})
 	}
 
 	responsePayload := &getInfoResponse{
 		Methods:       controller.permissionsService.GetPermittedMethods(app, controller.lnClient),
 		Notifications: supportedNotifications,
+		Budget:        controller.getBudget(app),
 	}
 
 	// basic permissions check
@@ -173,3 +173,94 @@ func TestHandleGetInfoEvent_WithNotifications(t *testing.T) {
 	assert.Equal(t, []string{"get_info"}, nodeInfo.Methods)
 	assert.Equal(t, []string{"payment_received", "payment_sent"}, nodeInfo.Notifications)
 }
+
+func TestHandleGetInfoEvent_WithOptedInNotifications(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.NotificationTypes = "budget_warning payment_failed"
+	err = svc.DB.Save(app).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetInfoJson), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:     app.ID,
+		Scope:     constants.NOTIFICATIONS_SCOPE,
+		ExpiresAt: nil,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	NewNip47Controller(svc.LNClient, svc.DB, svc.EventPublisher, permissionsSvc, transactionsSvc).
+		HandleGetInfoEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	nodeInfo := publishedResponse.Result.(*getInfoResponse)
+	// in the order of the supported notification types
+	assert.Equal(t, []string{"payment_failed", "budget_warning"}, nodeInfo.Notifications)
+}
+
+func TestHandleGetInfoEvent_WithBudget(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetInfoJson), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  1000,
+		BudgetRenewal: constants.BUDGET_RENEWAL_DAILY,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	NewNip47Controller(svc.LNClient, svc.DB, svc.EventPublisher, permissionsSvc, transactionsSvc).
+		HandleGetInfoEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Nil(t, publishedResponse.Error)
+	nodeInfo := publishedResponse.Result.(*getInfoResponse)
+	assert.Equal(t, []string{"pay_invoice", "pay_keysend", "multi_pay_invoice", "multi_pay_keysend", "send_cashu_token", "get_budget"}, nodeInfo.Methods)
+	assert.NotNil(t, nodeInfo.Budget)
+	assert.Equal(t, uint64(0), nodeInfo.Budget.UsedBudget)
+	assert.Equal(t, uint64(1_000_000), nodeInfo.Budget.TotalBudget)
+	assert.Equal(t, constants.BUDGET_RENEWAL_DAILY, nodeInfo.Budget.RenewalPeriod)
+	assert.NotNil(t, nodeInfo.Budget.RenewsAt)
+}
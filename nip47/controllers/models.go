@@ -7,6 +7,9 @@ import (
 
 type publishFunc = func(*models.Response, nostr.Tags)
 
+// maximum number of payments of a multi_pay_* request which are sent at the same time
+const multiPayConcurrency = 5
+
 type payResponse struct {
 	Preimage string `json:"preimage"`
 	FeesPaid uint64 `json:"fees_paid"`
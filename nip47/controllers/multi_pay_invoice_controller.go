@@ -35,9 +35,12 @@ func (controller *nip47Controller) HandleMultiPayInvoiceEvent(ctx context.Contex
 
 	var wg sync.WaitGroup
 	wg.Add(len(multiPayParams.Invoices))
+	semaphore := make(chan struct{}, multiPayConcurrency)
 	for _, invoiceInfo := range multiPayParams.Invoices {
+		semaphore <- struct{}{}
 		go func(invoiceInfo multiPayInvoiceElement) {
 			defer wg.Done()
+			defer func() { <-semaphore }()
 			bolt11 := invoiceInfo.Invoice
 			// Convert invoice to lowercase string
 			bolt11 = strings.ToLower(bolt11)
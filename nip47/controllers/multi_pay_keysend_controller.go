@@ -28,9 +28,12 @@ func (controller *nip47Controller) HandleMultiPayKeysendEvent(ctx context.Contex
 
 	var wg sync.WaitGroup
 	wg.Add(len(multiPayParams.Keysends))
+	semaphore := make(chan struct{}, multiPayConcurrency)
 	for _, keysendInfo := range multiPayParams.Keysends {
+		semaphore <- struct{}{}
 		go func(keysendInfo multiPayKeysendElement) {
 			defer wg.Done()
+			defer func() { <-semaphore }()
 
 			keysendDTagValue := keysendInfo.Id
 			if keysendDTagValue == "" {
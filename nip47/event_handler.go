@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/getAlby/hub/constants"
@@ -22,7 +23,150 @@ import (
 	"gorm.io/gorm"
 )
 
+// requestRejection is the error response of a request which is not executed
+type requestRejection struct {
+	nip47Error *models.Error
+	// state recorded on the request event
+	state string
+}
+
+func newRateLimitedRejection(err error) *requestRejection {
+	return &requestRejection{
+		nip47Error: &models.Error{
+			Code:    constants.ERROR_RATE_LIMITED,
+			Message: err.Error(),
+		},
+		state: db.REQUEST_EVENT_STATE_HANDLER_RATE_LIMITED,
+	}
+}
+
+var (
+	errInvalidEventSignature = errors.New("invalid event signature")
+	errUnknownApp            = errors.New("the public key does not have a wallet connected")
+)
+
+var paymentMethods = map[string]bool{
+	models.PAY_INVOICE_METHOD:       true,
+	models.PAY_KEYSEND_METHOD:       true,
+	models.MULTI_PAY_INVOICE_METHOD: true,
+	models.MULTI_PAY_KEYSEND_METHOD: true,
+}
+
+// verifyRequest checks that the event is signed by a connected app before it is queued,
+// so the scheduler only holds requests of known apps, and returns whether it is a payment.
+// Requests which cannot be decrypted are not payments, handleEvent rejects them.
+func (svc *nip47Service) verifyRequest(event *nostr.Event) (bool, error) {
+	validEventSignature, err := event.CheckSignature()
+	if err != nil || !validEventSignature {
+		return false, errInvalidEventSignature
+	}
+
+	walletPubkey := svc.keys.GetNostrPublicKey()
+	if pTag := event.Tags.GetFirst([]string{"p", ""}); pTag != nil {
+		walletPubkey = pTag.Value()
+	}
+	app := db.App{}
+	err = svc.db.Limit(1).Find(&app, &db.App{
+		NostrPubkey: event.PubKey,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+	if app.ID == 0 || svc.getAppWalletPubkey(&app) != walletPubkey {
+		return false, errUnknownApp
+	}
+
+	walletSecretKey, err := svc.getWalletSecretKey(walletPubkey)
+	if err != nil {
+		return false, nil
+	}
+	ss, err := nip04.ComputeSharedSecret(app.NostrPubkey, walletSecretKey)
+	if err != nil {
+		return false, nil
+	}
+	payload, err := nip04.Decrypt(event.Content, ss)
+	if err != nil {
+		return false, nil
+	}
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(payload), nip47Request)
+	if err != nil {
+		return false, nil
+	}
+	return paymentMethods[nip47Request.Method], nil
+}
+
+// rejectEvent responds to a request which is not queued. Rejections are bounded
+// so a flood of requests cannot exhaust the hub, and returns false if the request was dropped.
+func (svc *nip47Service) rejectEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient, rejection *requestRejection) bool {
+	select {
+	case svc.rejectionSlots <- struct{}{}:
+		defer func() { <-svc.rejectionSlots }()
+		svc.handleEvent(ctx, relay, event, lnClient, rejection)
+		return true
+	default:
+		return false
+	}
+}
+
+// ScheduleEvent queues the request on the scheduler, or rejects it if it is not from a connected app
+// or the app exceeded its limits
+func (svc *nip47Service) ScheduleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient) {
+	payment, err := svc.verifyRequest(event)
+	if errors.Is(err, errInvalidEventSignature) {
+		logger.Logger.WithField("requestEventNostrId", event.ID).Error("invalid event signature")
+		return
+	}
+	// requests which are not scheduled must never be executed, so every rejection has an error response
+	var rejection *requestRejection
+	switch {
+	case err == nil:
+		err = svc.requestScheduler.Schedule(event.PubKey, payment, func() {
+			if ctx.Err() != nil {
+				// the subscription ended while the request was queued, it will be received again
+				return
+			}
+			svc.HandleEvent(ctx, relay, event, lnClient)
+		})
+		if err == nil {
+			return
+		}
+		rejection = newRateLimitedRejection(err)
+	case errors.Is(err, errUnknownApp):
+		rejection = &requestRejection{
+			nip47Error: &models.Error{
+				Code:    constants.ERROR_UNAUTHORIZED,
+				Message: "The public key does not have a wallet connected.",
+			},
+			state: db.REQUEST_EVENT_STATE_HANDLER_ERROR,
+		}
+	default:
+		rejection = &requestRejection{
+			nip47Error: &models.Error{
+				Code:    constants.ERROR_INTERNAL,
+				Message: fmt.Sprintf("Failed to verify request: %s", err.Error()),
+			},
+			state: db.REQUEST_EVENT_STATE_HANDLER_ERROR,
+		}
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"requestEventNostrId": event.ID,
+		"nostrPubkey":         event.PubKey,
+	}).WithError(err).Warn("Not scheduling NIP-47 request")
+
+	go func() {
+		if !svc.rejectEvent(ctx, relay, event, lnClient, rejection) {
+			logger.Logger.WithField("requestEventNostrId", event.ID).Warn("Dropping rejected NIP-47 request")
+		}
+	}()
+}
+
 func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient) {
+	svc.handleEvent(ctx, relay, event, lnClient, nil)
+}
+
+func (svc *nip47Service) handleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient, rejection *requestRejection) {
 	var nip47Response *models.Response
 	logger.Logger.WithFields(logrus.Fields{
 		"requestEventNostrId": event.ID,
@@ -46,7 +190,22 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 		return
 	}
 
-	ss, err := nip04.ComputeSharedSecret(event.PubKey, svc.keys.GetNostrSecretKey())
+	// the request can be addressed to the shared wallet service key or to the key of a single connection
+	walletPubkey := svc.keys.GetNostrPublicKey()
+	if pTag := event.Tags.GetFirst([]string{"p", ""}); pTag != nil {
+		walletPubkey = pTag.Value()
+	}
+	walletSecretKey, err := svc.getWalletSecretKey(walletPubkey)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"requestEventNostrId": event.ID,
+			"eventKind":           event.Kind,
+			"walletPubkey":        walletPubkey,
+		}).WithError(err).Error("Failed to find wallet service key")
+		return
+	}
+
+	ss, err := nip04.ComputeSharedSecret(event.PubKey, walletSecretKey)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"requestEventNostrId": event.ID,
@@ -75,7 +234,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 				Message: fmt.Sprintf("Failed to save nostr event: %s", err.Error()),
 			},
 		}
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss, walletSecretKey)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -90,6 +249,10 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	err = svc.db.First(&app, &db.App{
 		NostrPubkey: event.PubKey,
 	}).Error
+	if err == nil && svc.getAppWalletPubkey(&app) != walletPubkey {
+		// connections with their own wallet service key must not be usable through any other key
+		err = errors.New("request was not sent to the wallet service key of the app")
+	}
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"nostrPubkey": event.PubKey,
@@ -101,7 +264,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 				Message: "The public key does not have a wallet connected.",
 			},
 		}
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss, walletSecretKey)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -133,7 +296,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 				Message: fmt.Sprintf("Failed to save app to nostr event: %s", err.Error()),
 			},
 		}
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, ss, walletSecretKey)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -160,7 +323,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	}).Debug("App found for nostr event")
 
 	//to be extra safe, decrypt using the key found from the app
-	ss, err = nip04.ComputeSharedSecret(app.NostrPubkey, svc.keys.GetNostrSecretKey())
+	ss, err = nip04.ComputeSharedSecret(app.NostrPubkey, walletSecretKey)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"requestEventNostrId": event.ID,
@@ -225,7 +388,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	// TODO: replace with a channel
 	// TODO: update all previous occurences of svc.publishResponseEvent to also use the channel
 	publishResponse := func(nip47Response *models.Response, tags nostr.Tags) {
-		resp, err := svc.CreateResponse(event, nip47Response, tags, ss)
+		resp, err := svc.CreateResponse(event, nip47Response, tags, ss, walletSecretKey)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -269,6 +432,42 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 		"params":              nip47Request.Params,
 	}).Debug("Handling NIP-47 request")
 
+	// relays can deliver old requests, e.g. after a reconnect, which must not be executed anymore
+	if rejection == nil {
+		if nip47Error := svc.checkRequestExpiry(event, time.Now()); nip47Error != nil {
+			rejection = &requestRejection{
+				nip47Error: nip47Error,
+				state:      db.REQUEST_EVENT_STATE_HANDLER_EXPIRED,
+			}
+		}
+	}
+	if rejection != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"requestEventNostrId": event.ID,
+			"appId":               app.ID,
+			"method":              nip47Request.Method,
+			"createdAt":           event.CreatedAt,
+			"code":                rejection.nip47Error.Code,
+			"message":             rejection.nip47Error.Message,
+		}).Warn("Rejecting NIP-47 request")
+
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      rejection.nip47Error,
+		}, nostr.Tags{})
+
+		if requestEvent.State != db.REQUEST_EVENT_STATE_HANDLER_ERROR {
+			requestEvent.State = rejection.state
+			err = svc.db.Save(&requestEvent).Error
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"nostrPubkey": event.PubKey,
+				}).WithError(err).Error("Failed to save state to nostr event")
+			}
+		}
+		return
+	}
+
 	if nip47Request.Method != models.GET_INFO_METHOD {
 		scope, err := permissions.RequestMethodToScope(nip47Request.Method)
 		if err != nil {
@@ -330,6 +529,9 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	case models.GET_BALANCE_METHOD:
 		controller.
 			HandleGetBalanceEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
+	case models.GET_BUDGET_METHOD:
+		controller.
+			HandleGetBudgetEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
 	case models.MAKE_INVOICE_METHOD:
 		controller.
 			HandleMakeInvoiceEvent(ctx, nip47Request, requestEvent.ID, app.ID, publishResponse)
@@ -345,6 +547,12 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	case models.SIGN_MESSAGE_METHOD:
 		controller.
 			HandleSignMessageEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.SEND_CASHU_TOKEN_METHOD:
+		controller.
+			HandleSendCashuTokenEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
+	case models.RECEIVE_CASHU_TOKEN_METHOD:
+		controller.
+			HandleReceiveCashuTokenEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
 	default:
 		publishResponse(&models.Response{
 			ResultType: nip47Request.Method,
@@ -356,7 +564,59 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, relay nostrmodels.Rela
 	}
 }
 
-func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, ss []byte) (result *nostr.Event, err error) {
+// checkRequestExpiry returns an error if the request has an expiration tag (NIP-40) in the past.
+// Requests without an expiration tag are rejected if they were created longer ago than the
+// maximum request age, if one is configured.
+// Requests outside of the event retention are always rejected, as they could be replays of pruned requests.
+func (svc *nip47Service) checkRequestExpiry(event *nostr.Event, now time.Time) *models.Error {
+	if retentionDays := svc.cfg.GetEnv().NIP47RetentionDays; retentionDays > 0 {
+		createdAt := event.CreatedAt.Time()
+		if createdAt.Before(now.AddDate(0, 0, -retentionDays)) {
+			return &models.Error{
+				Code:    constants.ERROR_EXPIRED,
+				Message: fmt.Sprintf("This request was created more than %d days ago", retentionDays),
+			}
+		}
+		// the request event is pruned after the retention, so it must not be valid for longer than that
+		if createdAt.After(now.Add(maxRequestClockSkew)) {
+			return &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: "This request was created in the future",
+			}
+		}
+	}
+
+	expirationTag := event.Tags.GetFirst([]string{"expiration", ""})
+	if expirationTag != nil {
+		expiration, err := strconv.ParseInt(expirationTag.Value(), 10, 64)
+		if err != nil {
+			return &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: fmt.Sprintf("Invalid expiration tag: %s", expirationTag.Value()),
+			}
+		}
+		if expiration <= now.Unix() {
+			return &models.Error{
+				Code:    constants.ERROR_EXPIRED,
+				Message: "This request has expired",
+			}
+		}
+		// the expiration chosen by the app takes precedence over the maximum request age
+		return nil
+	}
+
+	maxRequestAge := svc.cfg.GetEnv().NIP47MaxRequestAge
+	if maxRequestAge > 0 && event.CreatedAt.Time().Before(now.Add(-time.Duration(maxRequestAge)*time.Second)) {
+		return &models.Error{
+			Code:    constants.ERROR_OTHER,
+			Message: fmt.Sprintf("This request was created more than %d seconds ago", maxRequestAge),
+		}
+	}
+
+	return nil
+}
+
+func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, ss []byte, walletSecretKey string) (result *nostr.Event, err error) {
 	payloadBytes, err := json.Marshal(content)
 	if err != nil {
 		return nil, err
@@ -370,13 +630,13 @@ func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content inter
 	allTags = append(allTags, tags...)
 
 	resp := &nostr.Event{
-		PubKey:    svc.keys.GetNostrPublicKey(),
 		CreatedAt: nostr.Now(),
 		Kind:      models.RESPONSE_KIND,
 		Tags:      allTags,
 		Content:   msg,
 	}
-	err = resp.Sign(svc.keys.GetNostrSecretKey())
+	// also sets the pubkey of the event
+	err = resp.Sign(walletSecretKey)
 	if err != nil {
 		return nil, err
 	}
@@ -388,8 +648,13 @@ func (svc *nip47Service) publishResponseEvent(ctx context.Context, relay nostrmo
 	if app != nil {
 		appId = &app.ID
 	}
-	responseEvent := db.ResponseEvent{NostrId: resp.ID, RequestId: requestEvent.ID, State: "received"}
-	err := svc.db.Create(&responseEvent).Error
+	// keep the signed event so the republisher can retry it, and it can be returned to HTTP clients
+	eventData, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	responseEvent := db.ResponseEvent{NostrId: resp.ID, RequestId: requestEvent.ID, State: "received", EventData: string(eventData)}
+	err = svc.db.Create(&responseEvent).Error
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"requestEventNostrId": requestEvent.NostrId,
@@ -402,6 +667,10 @@ func (svc *nip47Service) publishResponseEvent(ctx context.Context, relay nostrmo
 	err = relay.Publish(ctx, *resp)
 	if err != nil {
 		responseEvent.State = db.RESPONSE_EVENT_STATE_PUBLISH_FAILED
+		if errors.Is(err, context.DeadlineExceeded) {
+			// the relay did not reply in time, but might have received the event
+			responseEvent.State = db.RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED
+		}
 		logger.Logger.WithFields(logrus.Fields{
 			"requestEventId":       requestEvent.ID,
 			"requestNostrEventId":  requestEvent.NostrId,
@@ -3,7 +3,10 @@ package nip47
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
@@ -12,6 +15,7 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 // TODO: test HandleEvent
@@ -51,7 +55,7 @@ func TestCreateResponse(t *testing.T) {
 
 	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
 
-	res, err := nip47svc.CreateResponse(reqEvent, nip47Response, nostr.Tags{}, ss)
+	res, err := nip47svc.CreateResponse(reqEvent, nip47Response, nostr.Tags{}, ss, svc.Keys.GetNostrSecretKey())
 	assert.NoError(t, err)
 	assert.Equal(t, reqPubkey, res.Tags.GetFirst([]string{"p"}).Value())
 	assert.Equal(t, reqEvent.ID, res.Tags.GetFirst([]string{"e"}).Value())
@@ -70,6 +74,54 @@ func TestCreateResponse(t *testing.T) {
 	assert.Equal(t, nip47Response.Result, *unmarshalledResponse.Result.(*dummyResponse))
 }
 
+func TestScheduleEvent_VerifyError(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.GET_BALANCE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	// only the app lookup of verifyRequest fails
+	failedAppLookup := false
+	err = svc.DB.Callback().Query().Before("gorm:query").Register("fail_app_lookup", func(tx *gorm.DB) {
+		if tx.Statement.Table == "apps" && !failedAppLookup {
+			failedAppLookup = true
+			tx.AddError(errors.New("database is locked"))
+		}
+	})
+	assert.NoError(t, err)
+
+	reqEvent := createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+	relay := &httpRelay{}
+	nip47svc.ScheduleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	assert.Eventually(t, func() bool {
+		return len(relay.publishedEvents()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, failedAppLookup)
+	assert.Empty(t, nip47svc.requestScheduler.Stats().Apps)
+
+	// the request is rejected instead of being executed outside the scheduler
+	response := decryptHttpResponse(t, relay.publishedEvents()[0], ss)
+	assert.NotNil(t, response.Error)
+	assert.Equal(t, constants.ERROR_INTERNAL, response.Error.Code)
+	assert.Nil(t, response.Result)
+
+	requestEvent := db.RequestEvent{}
+	err = svc.DB.First(&requestEvent, &db.RequestEvent{NostrId: reqEvent.ID}).Error
+	assert.NoError(t, err)
+	assert.Equal(t, db.REQUEST_EVENT_STATE_HANDLER_ERROR, requestEvent.State)
+}
+
 func TestHandleResponse_WithPermission(t *testing.T) {
 	defer tests.RemoveTestService()
 	svc, err := tests.CreateTestService()
@@ -303,3 +355,188 @@ func TestHandleResponse_IncorrectPubkey(t *testing.T) {
 
 	assert.Nil(t, relay.PublishedEvent)
 }
+
+func createPayInvoiceRequestEvent(t *testing.T, reqPrivateKey string, ss []byte, createdAt nostr.Timestamp, tags nostr.Tags) *nostr.Event {
+	reqPubkey, err := nostr.GetPublicKey(reqPrivateKey)
+	assert.NoError(t, err)
+
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": models.PAY_INVOICE_METHOD,
+		"params": map[string]interface{}{
+			"invoice": tests.MockInvoice,
+		},
+	})
+	assert.NoError(t, err)
+
+	msg, err := nip04.Encrypt(string(payloadBytes), ss)
+	assert.NoError(t, err)
+
+	reqEvent := &nostr.Event{
+		Kind:      models.REQUEST_KIND,
+		PubKey:    reqPubkey,
+		CreatedAt: createdAt,
+		Tags:      tags,
+		Content:   msg,
+	}
+	err = reqEvent.Sign(reqPrivateKey)
+	assert.NoError(t, err)
+	return reqEvent
+}
+
+func TestHandleResponse_ExpiredRequest(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	expiration := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	reqEvent := createPayInvoiceRequestEvent(t, reqPrivateKey, ss, nostr.Now(), nostr.Tags{{"expiration", expiration}})
+
+	relay := tests.NewMockRelay()
+	nip47svc.HandleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	assert.NotNil(t, relay.PublishedEvent)
+	decrypted, err := nip04.Decrypt(relay.PublishedEvent.Content, ss)
+	assert.NoError(t, err)
+	unmarshalledResponse := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &unmarshalledResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PAY_INVOICE_METHOD, unmarshalledResponse.ResultType)
+	assert.Equal(t, constants.ERROR_EXPIRED, unmarshalledResponse.Error.Code)
+
+	requestEvent := db.RequestEvent{}
+	err = svc.DB.First(&requestEvent, &db.RequestEvent{NostrId: reqEvent.ID}).Error
+	assert.NoError(t, err)
+	assert.Equal(t, db.REQUEST_EVENT_STATE_HANDLER_EXPIRED, requestEvent.State)
+
+	// the payment was not executed
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Zero(t, transactionCount)
+}
+
+func TestHandleResponse_StaleRequest(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	svc.Cfg.GetEnv().NIP47MaxRequestAge = 60
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	reqEvent := createPayInvoiceRequestEvent(t, reqPrivateKey, ss, nostr.Timestamp(time.Now().Add(-2*time.Minute).Unix()), nostr.Tags{})
+
+	relay := tests.NewMockRelay()
+	nip47svc.HandleEvent(context.TODO(), relay, reqEvent, svc.LNClient)
+
+	assert.NotNil(t, relay.PublishedEvent)
+	decrypted, err := nip04.Decrypt(relay.PublishedEvent.Content, ss)
+	assert.NoError(t, err)
+	unmarshalledResponse := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &unmarshalledResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.ERROR_OTHER, unmarshalledResponse.Error.Code)
+
+	requestEvent := db.RequestEvent{}
+	err = svc.DB.First(&requestEvent, &db.RequestEvent{NostrId: reqEvent.ID}).Error
+	assert.NoError(t, err)
+	assert.Equal(t, db.REQUEST_EVENT_STATE_HANDLER_EXPIRED, requestEvent.State)
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Zero(t, transactionCount)
+}
+
+func TestCheckRequestExpiry(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	svc.Cfg.GetEnv().NIP47MaxRequestAge = 60
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	now := time.Now()
+	nowTimestamp := nostr.Timestamp(now.Unix())
+
+	// valid expiration in the future
+	assert.Nil(t, nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nowTimestamp,
+		Tags:      nostr.Tags{{"expiration", strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}},
+	}, now))
+
+	// expires now
+	nip47Error := nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nowTimestamp,
+		Tags:      nostr.Tags{{"expiration", strconv.FormatInt(now.Unix(), 10)}},
+	}, now)
+	assert.Equal(t, constants.ERROR_EXPIRED, nip47Error.Code)
+
+	// invalid expiration
+	nip47Error = nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nowTimestamp,
+		Tags:      nostr.Tags{{"expiration", "tomorrow"}},
+	}, now)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, nip47Error.Code)
+
+	// within the maximum request age
+	assert.Nil(t, nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Add(-59 * time.Second).Unix()),
+	}, now))
+
+	// older than the maximum request age
+	nip47Error = nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Add(-61 * time.Second).Unix()),
+	}, now)
+	assert.Equal(t, constants.ERROR_OTHER, nip47Error.Code)
+
+	// older than the maximum request age, but the expiration is in the future
+	assert.Nil(t, nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Add(-10 * time.Minute).Unix()),
+		Tags:      nostr.Tags{{"expiration", strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}},
+	}, now))
+
+	// no maximum request age, the default
+	svc.Cfg.GetEnv().NIP47MaxRequestAge = 0
+	assert.Nil(t, nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Add(-24 * time.Hour).Unix()),
+	}, now))
+
+	// older than the event retention, even with an expiration in the future, as it could be a replay of a pruned request
+	svc.Cfg.GetEnv().NIP47RetentionDays = 90
+	nip47Error = nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.AddDate(0, 0, -91).Unix()),
+		Tags:      nostr.Tags{{"expiration", strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}},
+	}, now)
+	assert.Equal(t, constants.ERROR_EXPIRED, nip47Error.Code)
+
+	// created in the future, it would be valid after its request event was pruned
+	nip47Error = nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Add(time.Hour).Unix()),
+	}, now)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, nip47Error.Code)
+
+	// the event retention is disabled
+	svc.Cfg.GetEnv().NIP47RetentionDays = 0
+	assert.Nil(t, nip47svc.checkRequestExpiry(&nostr.Event{
+		CreatedAt: nostr.Timestamp(now.AddDate(0, 0, -91).Unix()),
+	}, now))
+}
@@ -0,0 +1,72 @@
+package nip47
+
+import (
+	"context"
+	"time"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const eventPruningInterval = 24 * time.Hour
+
+// pruning in batches keeps the database from being locked for too long
+const eventPruningBatchSize = 1000
+
+// requests created up to this long in the future are accepted. Events are pruned this much later
+// than the retention, so a request is rejected as too old before it can be replayed.
+const maxRequestClockSkew = 10 * time.Minute
+
+// StartEventPruning periodically deletes request and response events older than the configured retention
+func (svc *nip47Service) StartEventPruning(ctx context.Context) {
+	retentionDays := svc.cfg.GetEnv().NIP47RetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(eventPruningInterval)
+		defer ticker.Stop()
+		for {
+			_, err := svc.PruneEvents(time.Now().AddDate(0, 0, -retentionDays).Add(-maxRequestClockSkew))
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to prune NIP-47 events")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PruneEvents deletes request events created before the given time, and their response events.
+// The latest request of each app is kept as it is shown as the last usage of the app.
+func (svc *nip47Service) PruneEvents(before time.Time) (int64, error) {
+	var pruned int64
+	for {
+		// response events are deleted by the foreign key cascade
+		result := svc.db.Exec(`DELETE FROM request_events WHERE id IN (
+	SELECT id FROM request_events WHERE created_at < ? AND id NOT IN (
+		SELECT MAX(id) FROM request_events WHERE app_id IS NOT NULL GROUP BY app_id
+	) LIMIT ?
+)`, before, eventPruningBatchSize)
+		if result.Error != nil {
+			return pruned, result.Error
+		}
+		pruned += result.RowsAffected
+		if result.RowsAffected < eventPruningBatchSize {
+			break
+		}
+	}
+
+	if pruned > 0 {
+		logger.Logger.WithFields(logrus.Fields{
+			"pruned": pruned,
+			"before": before,
+		}).Info("Pruned NIP-47 events")
+	}
+	return pruned, nil
+}
@@ -0,0 +1,56 @@
+package nip47
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestPruneEvents(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app2, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -100)
+	createRequestEvent := func(nostrId string, appId *uint, createdAt time.Time) *db.RequestEvent {
+		requestEvent := &db.RequestEvent{NostrId: nostrId, AppId: appId, State: db.REQUEST_EVENT_STATE_HANDLER_EXECUTED, CreatedAt: createdAt}
+		assert.NoError(t, svc.DB.Create(requestEvent).Error)
+		return requestEvent
+	}
+
+	oldRequestEvent := createRequestEvent("old", &app.ID, old)
+	createRequestEvent("old2", &app.ID, old.Add(time.Hour))
+	createRequestEvent("recent", &app.ID, now)
+	// the only request of the app, which is kept as its last usage
+	createRequestEvent("old_only", &app2.ID, old)
+	// requests from unknown pubkeys have no app
+	createRequestEvent("old_no_app", nil, old)
+
+	err = svc.DB.Create(&db.ResponseEvent{NostrId: "old_response", RequestId: oldRequestEvent.ID, State: db.RESPONSE_EVENT_STATE_PUBLISH_CONFIRMED}).Error
+	assert.NoError(t, err)
+
+	pruned, err := nip47svc.PruneEvents(now.AddDate(0, 0, -90))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), pruned)
+
+	remainingNostrIds := []string{}
+	err = svc.DB.Model(&db.RequestEvent{}).Order("id").Pluck("nostr_id", &remainingNostrIds).Error
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recent", "old_only"}, remainingNostrIds)
+
+	var responseEventCount int64
+	err = svc.DB.Model(&db.ResponseEvent{}).Count(&responseEventCount).Error
+	assert.NoError(t, err)
+	assert.Zero(t, responseEventCount)
+}
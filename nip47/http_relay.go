@@ -0,0 +1,142 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// requests received over HTTP must be signed recently, as they are not delivered by a relay
+// which would reject stale or replayed events
+const httpRequestMaxAge = 10 * time.Minute
+
+// httpRelay collects the response events of a request received over HTTP
+// so they can be returned to the client instead of being published to a relay
+type httpRelay struct {
+	mutex  sync.Mutex
+	events []*nostr.Event
+}
+
+func (relay *httpRelay) Publish(ctx context.Context, event nostr.Event) error {
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	relay.events = append(relay.events, &event)
+	return nil
+}
+
+func (relay *httpRelay) publishedEvents() []*nostr.Event {
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	return relay.events
+}
+
+// HttpEventResult is the result of a request received over HTTP
+type HttpEventResult struct {
+	Events []*nostr.Event
+	// the app exceeded its limits, the events contain the RATE_LIMITED error response
+	RateLimited bool
+}
+
+// HandleHttpEvent handles a request event received over HTTP like one received from the relay,
+// and returns its response events once the request was processed.
+// The responses of a request which was already processed are returned again, e.g. if the client retries it.
+func (svc *nip47Service) HandleHttpEvent(ctx context.Context, event *nostr.Event, lnClient lnclient.LNClient) (*HttpEventResult, error) {
+	if event.Kind != models.REQUEST_KIND {
+		return nil, errors.New("event is not a NIP-47 request")
+	}
+	if event.GetID() != event.ID {
+		return nil, errors.New("event id does not match the event")
+	}
+	if age := time.Since(event.CreatedAt.Time()); age > httpRequestMaxAge || age < -httpRequestMaxAge {
+		return nil, errors.New("event was not created recently")
+	}
+
+	payment, err := svc.verifyRequest(event)
+	if err != nil {
+		return nil, err
+	}
+
+	storedResult, err := svc.getStoredResult(event)
+	if err != nil || storedResult != nil {
+		return storedResult, err
+	}
+
+	// the request must complete even if the client disconnects, e.g. while a payment is in flight
+	handlerCtx := context.WithoutCancel(ctx)
+	relay := &httpRelay{}
+	done := make(chan struct{})
+	err = svc.requestScheduler.Schedule(event.PubKey, payment, func() {
+		defer close(done)
+		svc.HandleEvent(handlerCtx, relay, event, lnClient)
+	})
+	rateLimited := err != nil
+	if rateLimited {
+		// the client is waiting for the rejection, so it is not bounded by the rejection slots
+		svc.handleEvent(handlerCtx, relay, event, lnClient, newRateLimitedRejection(err))
+	} else {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	events := relay.publishedEvents()
+	if len(events) == 0 {
+		// the same event was processed concurrently, e.g. it was also received from the relay
+		storedResult, err = svc.getStoredResult(event)
+		if err != nil {
+			return nil, err
+		}
+		if storedResult == nil {
+			return nil, errors.New("no response was created for the event")
+		}
+		return storedResult, nil
+	}
+	return &HttpEventResult{Events: events, RateLimited: rateLimited}, nil
+}
+
+// getStoredResult returns the response events of an already processed request,
+// or nil if the request was not received before
+func (svc *nip47Service) getStoredResult(event *nostr.Event) (*HttpEventResult, error) {
+	requestEvent := db.RequestEvent{}
+	result := svc.db.Limit(1).Find(&requestEvent, &db.RequestEvent{NostrId: event.ID})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	if requestEvent.State == db.REQUEST_EVENT_STATE_HANDLER_EXECUTING {
+		return nil, errors.New("event is already being processed")
+	}
+
+	responseEvents := []db.ResponseEvent{}
+	err := svc.db.Where("request_id = ?", requestEvent.ID).Order("id").Find(&responseEvents).Error
+	if err != nil {
+		return nil, err
+	}
+	events := []*nostr.Event{}
+	for _, responseEvent := range responseEvents {
+		resp := &nostr.Event{}
+		if err := json.Unmarshal([]byte(responseEvent.EventData), resp); err != nil {
+			// responses stored before the signed event was kept
+			continue
+		}
+		events = append(events, resp)
+	}
+	if len(events) == 0 {
+		return nil, errors.New("no response was created for the event")
+	}
+	return &HttpEventResult{
+		Events:      events,
+		RateLimited: requestEvent.State == db.REQUEST_EVENT_STATE_HANDLER_RATE_LIMITED,
+	}, nil
+}
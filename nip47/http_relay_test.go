@@ -0,0 +1,199 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+func createHttpRequestEvent(t *testing.T, reqPrivateKey string, ss []byte, method string, createdAt nostr.Timestamp) *nostr.Event {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": method,
+	})
+	assert.NoError(t, err)
+
+	msg, err := nip04.Encrypt(string(payloadBytes), ss)
+	assert.NoError(t, err)
+
+	reqEvent := &nostr.Event{
+		Kind:      models.REQUEST_KIND,
+		CreatedAt: createdAt,
+		Tags:      nostr.Tags{},
+		Content:   msg,
+	}
+	err = reqEvent.Sign(reqPrivateKey)
+	assert.NoError(t, err)
+	return reqEvent
+}
+
+func decryptHttpResponse(t *testing.T, event *nostr.Event, ss []byte) *models.Response {
+	decrypted, err := nip04.Decrypt(event.Content, ss)
+	assert.NoError(t, err)
+
+	response := &models.Response{}
+	err = json.Unmarshal([]byte(decrypted), response)
+	assert.NoError(t, err)
+	return response
+}
+
+func TestHandleHttpEvent(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	app, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.GET_BALANCE_SCOPE,
+	}).Error
+	assert.NoError(t, err)
+
+	reqEvent := createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+
+	result, err := nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+	events := result.Events
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.RESPONSE_KIND, events[0].Kind)
+	assert.Equal(t, svc.Keys.GetNostrPublicKey(), events[0].PubKey)
+	assert.Equal(t, reqEvent.ID, events[0].Tags.GetFirst([]string{"e", ""}).Value())
+
+	response := decryptHttpResponse(t, events[0], ss)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, models.GET_BALANCE_METHOD, response.ResultType)
+	assert.Equal(t, float64(21000), response.Result.(map[string]interface{})["balance"])
+
+	// the request is only handled once, retries return the stored response
+	result, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+	assert.Len(t, result.Events, 1)
+	assert.Equal(t, events[0].ID, result.Events[0].ID)
+}
+
+func TestHandleHttpEvent_NoPermission(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	_, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	reqEvent := createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+
+	result, err := nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.NoError(t, err)
+	assert.Len(t, result.Events, 1)
+
+	response := decryptHttpResponse(t, result.Events[0], ss)
+	assert.NotNil(t, response.Error)
+	assert.Equal(t, constants.ERROR_RESTRICTED, response.Error.Code)
+}
+
+func TestHandleHttpEvent_RateLimited(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+	// only one request is allowed
+	nip47svc.requestScheduler = newRequestScheduler(1, 1, 0, 0.001)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	_, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	result, err := nip47svc.HandleHttpEvent(context.TODO(), createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now()), svc.LNClient)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+
+	reqEvent := createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+	result, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.NoError(t, err)
+	assert.True(t, result.RateLimited)
+	assert.Len(t, result.Events, 1)
+
+	response := decryptHttpResponse(t, result.Events[0], ss)
+	assert.NotNil(t, response.Error)
+	assert.Equal(t, constants.ERROR_RATE_LIMITED, response.Error.Code)
+
+	// retries of the rejected request are still rate limited
+	result, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.NoError(t, err)
+	assert.True(t, result.RateLimited)
+}
+
+func TestHandleHttpEvent_InvalidEvent(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	_, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	reqEvent := createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+	reqEvent.Kind = models.RESPONSE_KIND
+	_, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.EqualError(t, err, "event is not a NIP-47 request")
+
+	reqEvent = createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+	reqEvent.ID = "bad" + reqEvent.ID[3:]
+	_, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.EqualError(t, err, "event id does not match the event")
+
+	reqEvent = createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Timestamp(time.Now().Add(-time.Hour).Unix()))
+	_, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.EqualError(t, err, "event was not created recently")
+
+	// forged events are not scheduled
+	reqEvent = createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now())
+	forgedSig := []byte(reqEvent.Sig)
+	forgedSig[0] ^= 1
+	reqEvent.Sig = string(forgedSig)
+	_, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.ErrorIs(t, err, errInvalidEventSignature)
+
+	// neither are events of pubkeys without a connection
+	reqEvent = createHttpRequestEvent(t, nostr.GeneratePrivateKey(), ss, models.GET_BALANCE_METHOD, nostr.Now())
+	_, err = nip47svc.HandleHttpEvent(context.TODO(), reqEvent, svc.LNClient)
+	assert.ErrorIs(t, err, errUnknownApp)
+	assert.Empty(t, nip47svc.requestScheduler.Stats().Apps)
+}
+
+func TestVerifyRequest_Payment(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	_, ss, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey)
+	assert.NoError(t, err)
+
+	payment, err := nip47svc.verifyRequest(createHttpRequestEvent(t, reqPrivateKey, ss, models.PAY_INVOICE_METHOD, nostr.Now()))
+	assert.NoError(t, err)
+	assert.True(t, payment)
+
+	payment, err = nip47svc.verifyRequest(createHttpRequestEvent(t, reqPrivateKey, ss, models.GET_BALANCE_METHOD, nostr.Now()))
+	assert.NoError(t, err)
+	assert.False(t, payment)
+}
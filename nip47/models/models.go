@@ -11,16 +11,19 @@ const (
 	NOTIFICATION_KIND = 23196
 
 	// request methods
-	PAY_INVOICE_METHOD       = "pay_invoice"
-	GET_BALANCE_METHOD       = "get_balance"
-	GET_INFO_METHOD          = "get_info"
-	MAKE_INVOICE_METHOD      = "make_invoice"
-	LOOKUP_INVOICE_METHOD    = "lookup_invoice"
-	LIST_TRANSACTIONS_METHOD = "list_transactions"
-	PAY_KEYSEND_METHOD       = "pay_keysend"
-	MULTI_PAY_INVOICE_METHOD = "multi_pay_invoice"
-	MULTI_PAY_KEYSEND_METHOD = "multi_pay_keysend"
-	SIGN_MESSAGE_METHOD      = "sign_message"
+	PAY_INVOICE_METHOD         = "pay_invoice"
+	GET_BALANCE_METHOD         = "get_balance"
+	GET_INFO_METHOD            = "get_info"
+	MAKE_INVOICE_METHOD        = "make_invoice"
+	LOOKUP_INVOICE_METHOD      = "lookup_invoice"
+	LIST_TRANSACTIONS_METHOD   = "list_transactions"
+	PAY_KEYSEND_METHOD         = "pay_keysend"
+	MULTI_PAY_INVOICE_METHOD   = "multi_pay_invoice"
+	MULTI_PAY_KEYSEND_METHOD   = "multi_pay_keysend"
+	SIGN_MESSAGE_METHOD        = "sign_message"
+	SEND_CASHU_TOKEN_METHOD    = "send_cashu_token"
+	RECEIVE_CASHU_TOKEN_METHOD = "receive_cashu_token"
+	GET_BUDGET_METHOD          = "get_budget"
 )
 
 type Transaction struct {
@@ -2,8 +2,11 @@ package nip47
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/nip47/notifications"
@@ -23,14 +26,38 @@ type nip47Service struct {
 	keys                   keys.Keys
 	db                     *gorm.DB
 	eventPublisher         events.EventPublisher
+	requestScheduler       *requestScheduler
+	rejectionSlots         chan struct{}
+	// signals that the wallet service keys of apps changed and have to be resubscribed
+	appWalletKeysChanged chan struct{}
+	// notifier of the current relay connection, nil while not connected
+	notifier      *notifications.Nip47Notifier
+	notifierMutex sync.Mutex
 }
 
+const (
+	appExpiryCheckInterval = time.Hour
+	// the first check waits for the relay connection
+	appExpiryCheckRetryInterval = time.Minute
+)
+
 type Nip47Service interface {
 	events.EventSubscriber
 	StartNotifier(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient)
+	StartAppExpiryChecks(ctx context.Context)
+	StartAppWalletKeySubscriptions(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient)
+	CreateAppWalletKey(app *db.App) error
+	RotateAppWalletKey(app *db.App) error
+	RefreshAppWalletKeys()
 	HandleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient)
+	ScheduleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient)
+	HandleHttpEvent(ctx context.Context, event *nostr.Event, lnClient lnclient.LNClient) (*HttpEventResult, error)
+	GetRequestSchedulerStats() *RequestSchedulerStats
+	StartEventPruning(ctx context.Context)
+	StartResponseRepublisher(ctx context.Context, relay nostrmodels.Relay)
+	PruneEvents(before time.Time) (int64, error)
 	PublishNip47Info(ctx context.Context, relay nostrmodels.Relay, lnClient lnclient.LNClient) error
-	CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, ss []byte) (result *nostr.Event, err error)
+	CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, ss []byte, walletSecretKey string) (result *nostr.Event, err error)
 }
 
 func NewNip47Service(db *gorm.DB, cfg config.Config, keys keys.Keys, eventPublisher events.EventPublisher) *nip47Service {
@@ -42,20 +69,34 @@ func NewNip47Service(db *gorm.DB, cfg config.Config, keys keys.Keys, eventPublis
 		transactionsService:    transactions.NewTransactionsService(db, eventPublisher),
 		eventPublisher:         eventPublisher,
 		keys:                   keys,
+		requestScheduler: newRequestScheduler(
+			cfg.GetEnv().NIP47Workers,
+			cfg.GetEnv().NIP47AppConcurrency,
+			cfg.GetEnv().NIP47AppMaxQueue,
+			float64(cfg.GetEnv().NIP47AppRateLimit),
+		),
+		rejectionSlots:       make(chan struct{}, max(cfg.GetEnv().NIP47Workers, 1)),
+		appWalletKeysChanged: make(chan struct{}, 1),
 	}
 }
 
+func (svc *nip47Service) GetRequestSchedulerStats() *RequestSchedulerStats {
+	return svc.requestScheduler.Stats()
+}
+
 func (svc *nip47Service) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
 	svc.nip47NotificationQueue.AddToQueue(event)
 }
 
 func (svc *nip47Service) StartNotifier(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient) {
 	nip47Notifier := notifications.NewNip47Notifier(relay, svc.db, svc.cfg, svc.keys, svc.permissionsService, svc.transactionsService, lnClient)
+	svc.setNotifier(nil, nip47Notifier)
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				// subscription ended
+				svc.setNotifier(nip47Notifier, nil)
 				return
 			case event := <-svc.nip47NotificationQueue.Channel():
 				nip47Notifier.ConsumeEvent(ctx, event)
@@ -63,3 +104,39 @@ func (svc *nip47Service) StartNotifier(ctx context.Context, relay *nostr.Relay,
 		}
 	}()
 }
+
+// StartAppExpiryChecks periodically warns apps which are about to expire. The checks run once
+// for the hub, through the notifier of the current relay connection.
+func (svc *nip47Service) StartAppExpiryChecks(ctx context.Context) {
+	go func() {
+		for {
+			wait := appExpiryCheckInterval
+			if notifier := svc.getNotifier(); notifier != nil {
+				notifier.NotifyExpiringApps(ctx, time.Now())
+			} else {
+				wait = appExpiryCheckRetryInterval
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// setNotifier replaces the notifier if it was not replaced by a new relay connection already
+func (svc *nip47Service) setNotifier(previous *notifications.Nip47Notifier, notifier *notifications.Nip47Notifier) {
+	svc.notifierMutex.Lock()
+	defer svc.notifierMutex.Unlock()
+	if previous == nil || svc.notifier == previous {
+		svc.notifier = notifier
+	}
+}
+
+func (svc *nip47Service) getNotifier() *notifications.Nip47Notifier {
+	svc.notifierMutex.Lock()
+	defer svc.notifierMutex.Unlock()
+	return svc.notifier
+}
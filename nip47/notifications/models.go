@@ -1,6 +1,11 @@
 package notifications
 
-import "github.com/getAlby/hub/nip47/models"
+import (
+	"strings"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+)
 
 type Notification struct {
 	Notification     interface{} `json:"notification,omitempty"`
@@ -10,8 +15,25 @@ type Notification struct {
 const (
 	PAYMENT_RECEIVED_NOTIFICATION = "payment_received"
 	PAYMENT_SENT_NOTIFICATION     = "payment_sent"
+	PAYMENT_FAILED_NOTIFICATION   = "payment_failed"
+	BALANCE_CHANGED_NOTIFICATION  = "balance_changed"
+	BUDGET_WARNING_NOTIFICATION   = "budget_warning"
+	APP_EXPIRING_NOTIFICATION     = "app_expiring"
 )
 
+// notification types apps can opt in to, the node backend may not support all of them
+var AllNotificationTypes = []string{
+	PAYMENT_RECEIVED_NOTIFICATION,
+	PAYMENT_SENT_NOTIFICATION,
+	PAYMENT_FAILED_NOTIFICATION,
+	BALANCE_CHANGED_NOTIFICATION,
+	BUDGET_WARNING_NOTIFICATION,
+	APP_EXPIRING_NOTIFICATION,
+}
+
+// notification types sent to apps which did not opt in to specific types
+var DefaultNotificationTypes = []string{PAYMENT_RECEIVED_NOTIFICATION, PAYMENT_SENT_NOTIFICATION}
+
 type PaymentSentNotification struct {
 	models.Transaction
 }
@@ -19,3 +41,32 @@ type PaymentSentNotification struct {
 type PaymentReceivedNotification struct {
 	models.Transaction
 }
+
+type PaymentFailedNotification struct {
+	models.Transaction
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+type BalanceChangedNotification struct {
+	// balance of the app (isolated apps) or the node in msat
+	Balance uint64 `json:"balance"`
+}
+
+type BudgetWarningNotification struct {
+	UsedBudget    uint64  `json:"used_budget"`
+	TotalBudget   uint64  `json:"total_budget"`
+	RenewsAt      *uint64 `json:"renews_at,omitempty"`
+	RenewalPeriod string  `json:"renewal_period"`
+}
+
+type AppExpiringNotification struct {
+	ExpiresAt uint64 `json:"expires_at"`
+}
+
+// GetAppNotificationTypes returns the notification types the app opted in to
+func GetAppNotificationTypes(app *db.App) []string {
+	if app.NotificationTypes == "" {
+		return DefaultNotificationTypes
+	}
+	return strings.Fields(app.NotificationTypes)
+}
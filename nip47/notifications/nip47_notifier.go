@@ -3,10 +3,13 @@ package notifications
 import (
 	"context"
 	"encoding/json"
+	"slices"
+	"time"
 
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
@@ -21,6 +24,13 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// apps are warned once their budget usage crosses this percentage
+	budgetWarningThresholdPercent = 80
+	// apps are warned this long before their connection expires
+	appExpiringNotice = 24 * time.Hour
+)
+
 type Nip47Notifier struct {
 	relay               nostrmodels.Relay
 	cfg                 config.Config
@@ -60,6 +70,7 @@ func (notifier *Nip47Notifier) ConsumeEvent(ctx context.Context, event *events.E
 			Notification:     notification,
 			NotificationType: PAYMENT_RECEIVED_NOTIFICATION,
 		}, nostr.Tags{}, transaction.AppId)
+		notifier.notifyBalanceChanged(ctx, transaction.AppId)
 
 	case "nwc_payment_sent":
 		transaction, ok := event.Properties.(*db.Transaction)
@@ -76,29 +87,209 @@ func (notifier *Nip47Notifier) ConsumeEvent(ctx context.Context, event *events.E
 			Notification:     notification,
 			NotificationType: PAYMENT_SENT_NOTIFICATION,
 		}, nostr.Tags{}, transaction.AppId)
+		notifier.notifyBalanceChanged(ctx, transaction.AppId)
+		notifier.notifyBudgetWarning(ctx, transaction)
+
+	case "nwc_payment_failed":
+		transaction, ok := event.Properties.(*db.Transaction)
+		if !ok {
+			logger.Logger.WithField("event", event).Error("Failed to cast event")
+			return
+		}
+
+		notification := PaymentFailedNotification{
+			Transaction:   *models.ToNip47Transaction(transaction),
+			FailureReason: transaction.FailureReason,
+		}
+
+		notifier.notifySubscribers(ctx, &Notification{
+			Notification:     notification,
+			NotificationType: PAYMENT_FAILED_NOTIFICATION,
+		}, nostr.Tags{}, transaction.AppId)
+
+	case "nwc_channel_ready", "nwc_channel_closed":
+		// only the balance of the node changes, isolated apps are not affected
+		notifier.notifyBalanceChanged(ctx, nil)
 	}
 }
 
 func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notification *Notification, tags nostr.Tags, appId *uint) {
+	for _, app := range notifier.getSubscribers(notification.NotificationType, appId) {
+		notifier.notifySubscriber(ctx, &app, notification, tags)
+	}
+}
+
+// getSubscribers returns the apps to notify about an event of the given app,
+// or of the node if appId is nil
+func (notifier *Nip47Notifier) getSubscribers(notificationType string, appId *uint) []db.App {
 	apps := []db.App{}
 
 	// TODO: join apps and permissions
 	err := notifier.db.Find(&apps).Error
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to list apps")
-		return
+		return nil
 	}
 
+	subscribers := []db.App{}
 	for _, app := range apps {
 		if app.Isolated && (appId == nil || app.ID != *appId) {
 			continue
 		}
 
-		hasPermission, _, _ := notifier.permissionsSvc.HasPermission(&app, constants.NOTIFICATIONS_SCOPE)
-		if !hasPermission {
+		if !notifier.isSubscribed(&app, notificationType) {
 			continue
 		}
-		notifier.notifySubscriber(ctx, &app, notification, tags)
+		subscribers = append(subscribers, app)
+	}
+	return subscribers
+}
+
+func (notifier *Nip47Notifier) isSubscribed(app *db.App, notificationType string) bool {
+	if !slices.Contains(GetAppNotificationTypes(app), notificationType) {
+		return false
+	}
+
+	hasPermission, _, _ := notifier.permissionsSvc.HasPermission(app, constants.NOTIFICATIONS_SCOPE)
+	return hasPermission
+}
+
+func (notifier *Nip47Notifier) notifyBalanceChanged(ctx context.Context, appId *uint) {
+	subscribers := notifier.getSubscribers(BALANCE_CHANGED_NOTIFICATION, appId)
+
+	var nodeBalance int64
+	var nodeBalanceErr error
+	if slices.ContainsFunc(subscribers, func(app db.App) bool { return !app.Isolated }) {
+		nodeBalance, nodeBalanceErr = notifier.lnClient.GetBalance(ctx)
+		if nodeBalanceErr != nil {
+			logger.Logger.WithError(nodeBalanceErr).Error("Failed to fetch balance")
+		}
+	}
+
+	for _, app := range subscribers {
+		balance := uint64(nodeBalance)
+		if app.Isolated {
+			balance = queries.GetIsolatedBalance(notifier.db, app.ID)
+		} else if nodeBalanceErr != nil {
+			continue
+		}
+
+		notifier.notifySubscriber(ctx, &app, &Notification{
+			Notification: BalanceChangedNotification{
+				Balance: balance,
+			},
+			NotificationType: BALANCE_CHANGED_NOTIFICATION,
+		}, nostr.Tags{})
+	}
+}
+
+// notifyBudgetWarning warns the app which sent the payment if its budget usage reached
+// the warning threshold, once per budget period
+func (notifier *Nip47Notifier) notifyBudgetWarning(ctx context.Context, transaction *db.Transaction) {
+	if transaction.AppId == nil {
+		return
+	}
+
+	appPermission := db.AppPermission{}
+	result := notifier.db.Limit(1).Find(&appPermission, &db.AppPermission{
+		AppId: *transaction.AppId,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	})
+	if result.Error != nil || result.RowsAffected == 0 || appPermission.MaxAmountSat <= 0 {
+		return
+	}
+
+	usedBudget := queries.GetBudgetUsageSat(notifier.db, &appPermission)
+	threshold := uint64(appPermission.MaxAmountSat) * budgetWarningThresholdPercent / 100
+	if usedBudget < threshold {
+		return
+	}
+
+	app := db.App{}
+	result = notifier.db.Limit(1).Find(&app, *transaction.AppId)
+	if result.Error != nil || result.RowsAffected == 0 || !notifier.isSubscribed(&app, BUDGET_WARNING_NOTIFICATION) {
+		return
+	}
+
+	// the crossing is recorded atomically, so concurrent payments only warn the app once
+	result = notifier.db.Model(&db.AppPermission{}).
+		Where("id = ? AND (budget_warning_notified_at IS NULL OR budget_warning_notified_at < ?)", appPermission.ID, queries.GetBudgetStartsAt(appPermission.BudgetRenewal)).
+		Update("budget_warning_notified_at", time.Now())
+	if result.Error != nil {
+		logger.Logger.WithError(result.Error).WithField("appId", app.ID).Error("Failed to save budget warning")
+		return
+	}
+	if result.RowsAffected == 0 {
+		// the app was already warned in this budget period
+		return
+	}
+
+	budgetRenewal := appPermission.BudgetRenewal
+	if budgetRenewal == "" {
+		budgetRenewal = constants.BUDGET_RENEWAL_NEVER
+	}
+
+	notification := BudgetWarningNotification{
+		UsedBudget:    usedBudget * 1000,
+		TotalBudget:   uint64(appPermission.MaxAmountSat) * 1000,
+		RenewalPeriod: budgetRenewal,
+	}
+	if renewsAt := queries.GetBudgetRenewsAt(appPermission.BudgetRenewal); renewsAt != nil {
+		renewsAtUnix := uint64(renewsAt.Unix())
+		notification.RenewsAt = &renewsAtUnix
+	}
+
+	notifier.notifySubscriber(ctx, &app, &Notification{
+		Notification:     notification,
+		NotificationType: BUDGET_WARNING_NOTIFICATION,
+	}, nostr.Tags{})
+}
+
+// NotifyExpiringApps warns apps which expire within the notice period,
+// once per expiry date
+func (notifier *Nip47Notifier) NotifyExpiringApps(ctx context.Context, now time.Time) {
+	// all permissions of an app share the same expiry
+	appPermissions := []db.AppPermission{}
+	err := notifier.db.
+		Where("expires_at > ? AND expires_at <= ?", now, now.Add(appExpiringNotice)).
+		Find(&appPermissions).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list expiring app permissions")
+		return
+	}
+
+	expiresAt := map[uint]time.Time{}
+	for _, appPermission := range appPermissions {
+		expiresAt[appPermission.AppId] = *appPermission.ExpiresAt
+	}
+
+	for appId, appExpiresAt := range expiresAt {
+		app := db.App{}
+		result := notifier.db.Limit(1).Find(&app, appId)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		// the app was already warned about this expiry
+		if app.ExpiryNotifiedAt != nil && app.ExpiryNotifiedAt.After(appExpiresAt.Add(-appExpiringNotice)) {
+			continue
+		}
+
+		if !notifier.isSubscribed(&app, APP_EXPIRING_NOTIFICATION) {
+			continue
+		}
+
+		notifier.notifySubscriber(ctx, &app, &Notification{
+			Notification: AppExpiringNotification{
+				ExpiresAt: uint64(appExpiresAt.Unix()),
+			},
+			NotificationType: APP_EXPIRING_NOTIFICATION,
+		}, nostr.Tags{})
+
+		err := notifier.db.Model(&app).UpdateColumn("expiry_notified_at", &now).Error
+		if err != nil {
+			logger.Logger.WithField("appId", app.ID).WithError(err).Error("Failed to save app expiry notification")
+		}
 	}
 }
 
@@ -108,7 +299,20 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 		"appId":        app.ID,
 	}).Debug("Notifying subscriber")
 
-	ss, err := nip04.ComputeSharedSecret(app.NostrPubkey, notifier.keys.GetNostrSecretKey())
+	walletSecretKey := notifier.keys.GetNostrSecretKey()
+	if app.WalletPubkey != nil {
+		var err error
+		walletSecretKey, err = notifier.keys.GetAppWalletKey(app.ID, app.WalletKeyIndex)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"notification": notification,
+				"appId":        app.ID,
+			}).WithError(err).Error("Failed to derive app wallet key")
+			return
+		}
+	}
+
+	ss, err := nip04.ComputeSharedSecret(app.NostrPubkey, walletSecretKey)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"notification": notification,
@@ -138,13 +342,13 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 	allTags = append(allTags, tags...)
 
 	event := &nostr.Event{
-		PubKey:    notifier.keys.GetNostrPublicKey(),
 		CreatedAt: nostr.Now(),
 		Kind:      models.NOTIFICATION_KIND,
 		Tags:      allTags,
 		Content:   msg,
 	}
-	err = event.Sign(notifier.keys.GetNostrSecretKey())
+	// also sets the pubkey of the event
+	err = event.Sign(walletSecretKey)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"notification": notification,
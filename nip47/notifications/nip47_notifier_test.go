@@ -13,6 +13,7 @@ import (
 	"github.com/getAlby/hub/nip47/permissions"
 	"github.com/getAlby/hub/tests"
 	"github.com/getAlby/hub/transactions"
+	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/stretchr/testify/assert"
 )
@@ -227,3 +228,265 @@ func TestSendNotificationNoPermission(t *testing.T) {
 
 	assert.Nil(t, relay.PublishedEvent)
 }
+
+func createNotificationsApp(t *testing.T, svc *tests.TestService, notificationTypes string, expiresAt *time.Time) (*db.App, []byte) {
+	app, ss, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	err = svc.DB.Model(app).Update("notification_types", notificationTypes).Error
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:     app.ID,
+		App:       *app,
+		Scope:     constants.NOTIFICATIONS_SCOPE,
+		ExpiresAt: expiresAt,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	return app, ss
+}
+
+func decryptNotification(t *testing.T, event *nostr.Event, ss []byte, notification interface{}) *Notification {
+	assert.NotNil(t, event)
+
+	decrypted, err := nip04.Decrypt(event.Content, ss)
+	assert.NoError(t, err)
+	unmarshalledResponse := Notification{
+		Notification: notification,
+	}
+
+	err = json.Unmarshal([]byte(decrypted), &unmarshalledResponse)
+	assert.NoError(t, err)
+	return &unmarshalledResponse
+}
+
+func TestSendNotification_PaymentFailed(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, ss := createNotificationsApp(t, svc, "payment_failed", nil)
+
+	transaction := db.Transaction{
+		Type:          constants.TRANSACTION_TYPE_OUTGOING,
+		State:         constants.TRANSACTION_STATE_FAILED,
+		PaymentHash:   tests.MockPaymentHash,
+		AmountMsat:    1000,
+		FailureReason: "no route",
+		AppId:         &app.ID,
+	}
+	err = svc.DB.Create(&transaction).Error
+	assert.NoError(t, err)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_payment_failed",
+		Properties: &transaction,
+	})
+
+	notification := decryptNotification(t, relay.PublishedEvent, ss, &PaymentFailedNotification{})
+	assert.Equal(t, PAYMENT_FAILED_NOTIFICATION, notification.NotificationType)
+	paymentFailedNotification := notification.Notification.(*PaymentFailedNotification)
+	assert.Equal(t, tests.MockPaymentHash, paymentFailedNotification.PaymentHash)
+	assert.Equal(t, int64(1000), paymentFailedNotification.Amount)
+	assert.Equal(t, "no route", paymentFailedNotification.FailureReason)
+}
+
+func TestSendNotification_NotOptedIn(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	// the app only receives the default notification types
+	app, _ := createNotificationsApp(t, svc, "", nil)
+
+	transaction := db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_FAILED,
+		PaymentHash: tests.MockPaymentHash,
+		AppId:       &app.ID,
+	}
+	err = svc.DB.Create(&transaction).Error
+	assert.NoError(t, err)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_payment_failed",
+		Properties: &transaction,
+	})
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_channel_ready",
+	})
+
+	assert.Nil(t, relay.PublishedEvent)
+}
+
+func TestSendNotification_BalanceChanged(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	_, ss := createNotificationsApp(t, svc, "balance_changed", nil)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_channel_ready",
+	})
+
+	notification := decryptNotification(t, relay.PublishedEvent, ss, &BalanceChangedNotification{})
+	assert.Equal(t, BALANCE_CHANGED_NOTIFICATION, notification.NotificationType)
+	assert.Equal(t, uint64(21000), notification.Notification.(*BalanceChangedNotification).Balance)
+}
+
+func TestSendNotification_BalanceChanged_Isolated(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, ss := createNotificationsApp(t, svc, "balance_changed", nil)
+	err = svc.DB.Model(app).Update("isolated", true).Error
+	assert.NoError(t, err)
+
+	transaction := db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		PaymentHash: tests.MockPaymentHash,
+		AmountMsat:  5000,
+		AppId:       &app.ID,
+	}
+	err = svc.DB.Create(&transaction).Error
+	assert.NoError(t, err)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+
+	// isolated apps are not affected by changes to the node balance
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_channel_ready",
+	})
+	assert.Nil(t, relay.PublishedEvent)
+
+	notifier.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_payment_received",
+		Properties: &transaction,
+	})
+
+	notification := decryptNotification(t, relay.PublishedEvent, ss, &BalanceChangedNotification{})
+	assert.Equal(t, BALANCE_CHANGED_NOTIFICATION, notification.NotificationType)
+	assert.Equal(t, uint64(5000), notification.Notification.(*BalanceChangedNotification).Balance)
+}
+
+func TestSendNotification_BudgetWarning(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, ss := createNotificationsApp(t, svc, "budget_warning", nil)
+	err = svc.DB.Create(&db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  100,
+		BudgetRenewal: constants.BUDGET_RENEWAL_MONTHLY,
+	}).Error
+	assert.NoError(t, err)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+
+	sendPayment := func(paymentHash string, amountMsat uint64) {
+		transaction := db.Transaction{
+			Type:        constants.TRANSACTION_TYPE_OUTGOING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			PaymentHash: paymentHash,
+			AmountMsat:  amountMsat,
+			AppId:       &app.ID,
+		}
+		err := svc.DB.Create(&transaction).Error
+		assert.NoError(t, err)
+
+		notifier.ConsumeEvent(ctx, &events.Event{
+			Event:      "nwc_payment_sent",
+			Properties: &transaction,
+		})
+	}
+
+	// below the threshold
+	sendPayment("hash1", 50000)
+	assert.Nil(t, relay.PublishedEvent)
+
+	sendPayment("hash2", 35000)
+	notification := decryptNotification(t, relay.PublishedEvent, ss, &BudgetWarningNotification{})
+	assert.Equal(t, BUDGET_WARNING_NOTIFICATION, notification.NotificationType)
+	budgetWarningNotification := notification.Notification.(*BudgetWarningNotification)
+	assert.Equal(t, uint64(85000), budgetWarningNotification.UsedBudget)
+	assert.Equal(t, uint64(100000), budgetWarningNotification.TotalBudget)
+	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, budgetWarningNotification.RenewalPeriod)
+	assert.NotNil(t, budgetWarningNotification.RenewsAt)
+
+	// the app was already warned
+	relay.PublishedEvent = nil
+	sendPayment("hash3", 5000)
+	assert.Nil(t, relay.PublishedEvent)
+
+	// the app is warned again in the next budget period
+	err = svc.DB.Model(&db.AppPermission{}).Where("app_id = ? AND scope = ?", app.ID, constants.PAY_INVOICE_SCOPE).
+		Update("budget_warning_notified_at", time.Now().AddDate(0, -2, 0)).Error
+	assert.NoError(t, err)
+	sendPayment("hash4", 1000)
+	assert.NotNil(t, relay.PublishedEvent)
+}
+
+func TestSendNotification_AppExpiring(t *testing.T) {
+	ctx := context.TODO()
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	expiresAt := now.Add(2 * 24 * time.Hour)
+	_, ss := createNotificationsApp(t, svc, "app_expiring", &expiresAt)
+
+	relay := tests.NewMockRelay()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	notifier := NewNip47Notifier(relay, svc.DB, svc.Cfg, svc.Keys, permissionsSvc, transactionsSvc, svc.LNClient)
+
+	// not within the notice period yet
+	notifier.NotifyExpiringApps(ctx, now)
+	assert.Nil(t, relay.PublishedEvent)
+
+	notifier.NotifyExpiringApps(ctx, now.Add(25*time.Hour))
+	notification := decryptNotification(t, relay.PublishedEvent, ss, &AppExpiringNotification{})
+	assert.Equal(t, APP_EXPIRING_NOTIFICATION, notification.NotificationType)
+	assert.Equal(t, uint64(expiresAt.Unix()), notification.Notification.(*AppExpiringNotification).ExpiresAt)
+
+	// the app is only warned once
+	relay.PublishedEvent = nil
+	notifier.NotifyExpiringApps(ctx, now.Add(26*time.Hour))
+	assert.Nil(t, relay.PublishedEvent)
+}
@@ -106,13 +106,13 @@ func scopesToRequestMethods(scopes []string) []string {
 func scopeToRequestMethods(scope string) []string {
 	switch scope {
 	case constants.PAY_INVOICE_SCOPE:
-		return []string{models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD}
+		return []string{models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD, models.SEND_CASHU_TOKEN_METHOD, models.GET_BUDGET_METHOD}
 	case constants.GET_BALANCE_SCOPE:
 		return []string{models.GET_BALANCE_METHOD}
 	case constants.GET_INFO_SCOPE:
 		return []string{models.GET_INFO_METHOD}
 	case constants.MAKE_INVOICE_SCOPE:
-		return []string{models.MAKE_INVOICE_METHOD}
+		return []string{models.MAKE_INVOICE_METHOD, models.RECEIVE_CASHU_TOKEN_METHOD}
 	case constants.LOOKUP_INVOICE_SCOPE:
 		return []string{models.LOOKUP_INVOICE_METHOD}
 	case constants.LIST_TRANSACTIONS_SCOPE:
@@ -140,13 +140,13 @@ func RequestMethodsToScopes(requestMethods []string) ([]string, error) {
 
 func RequestMethodToScope(requestMethod string) (string, error) {
 	switch requestMethod {
-	case models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD:
+	case models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD, models.SEND_CASHU_TOKEN_METHOD, models.GET_BUDGET_METHOD:
 		return constants.PAY_INVOICE_SCOPE, nil
 	case models.GET_BALANCE_METHOD:
 		return constants.GET_BALANCE_SCOPE, nil
 	case models.GET_INFO_METHOD:
 		return constants.GET_INFO_SCOPE, nil
-	case models.MAKE_INVOICE_METHOD:
+	case models.MAKE_INVOICE_METHOD, models.RECEIVE_CASHU_TOKEN_METHOD:
 		return constants.MAKE_INVOICE_SCOPE, nil
 	case models.LOOKUP_INVOICE_METHOD:
 		return constants.LOOKUP_INVOICE_SCOPE, nil
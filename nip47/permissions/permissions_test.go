@@ -110,3 +110,10 @@ func TestHasPermission_OK(t *testing.T) {
 	assert.Empty(t, code)
 	assert.Empty(t, message)
 }
+
+func TestGetBudgetMethodScope(t *testing.T) {
+	scope, err := RequestMethodToScope(models.GET_BUDGET_METHOD)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.PAY_INVOICE_SCOPE, scope)
+	assert.Contains(t, scopeToRequestMethods(constants.PAY_INVOICE_SCOPE), models.GET_BUDGET_METHOD)
+}
@@ -12,6 +12,10 @@ import (
 )
 
 func (svc *nip47Service) PublishNip47Info(ctx context.Context, relay nostrmodels.Relay, lnClient lnclient.LNClient) error {
+	return svc.publishNip47Info(ctx, relay, svc.keys.GetNostrSecretKey(), nostr.Tags{}, lnClient)
+}
+
+func (svc *nip47Service) publishNip47Info(ctx context.Context, relay nostrmodels.Relay, walletSecretKey string, tags nostr.Tags, lnClient lnclient.LNClient) error {
 	capabilities := lnClient.GetSupportedNIP47Methods()
 	if len(lnClient.GetSupportedNIP47NotificationTypes()) > 0 {
 		capabilities = append(capabilities, "notifications")
@@ -21,9 +25,10 @@ func (svc *nip47Service) PublishNip47Info(ctx context.Context, relay nostrmodels
 	ev.Kind = models.INFO_EVENT_KIND
 	ev.Content = strings.Join(capabilities, " ")
 	ev.CreatedAt = nostr.Now()
-	ev.PubKey = svc.keys.GetNostrPublicKey()
 	ev.Tags = nostr.Tags{[]string{"notifications", strings.Join(lnClient.GetSupportedNIP47NotificationTypes(), " ")}}
-	err := ev.Sign(svc.keys.GetNostrSecretKey())
+	ev.Tags = append(ev.Tags, tags...)
+	// also sets the pubkey of the event
+	err := ev.Sign(walletSecretKey)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,201 @@
+package nip47
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+var errAppRateLimited = errors.New("too many requests, please slow down")
+var errAppQueueFull = errors.New("too many pending requests, please try again later")
+
+type scheduledJob struct {
+	run     func()
+	payment bool
+}
+
+type appRequestQueue struct {
+	jobs    []scheduledJob
+	running int
+	limiter *rate.Limiter
+}
+
+// requestScheduler executes NIP-47 requests on a bounded number of workers.
+// Each app (identified by its connection pubkey) has its own queue, concurrency and rate limit,
+// and queued requests are started round-robin across apps so one app cannot starve the others.
+// Payments can wait for a long time (e.g. for hold invoices), so some workers are reserved for other requests.
+type requestScheduler struct {
+	maxWorkers        int
+	maxPaymentWorkers int
+	maxAppConcurrency int
+	maxAppQueue       int
+	// requests per second per app, 0 for no limit
+	appRateLimit float64
+
+	mutex           sync.Mutex
+	apps            map[string]*appRequestQueue
+	order           []string
+	next            int
+	running         int
+	runningPayments int
+	queued          int
+}
+
+type AppQueueStats struct {
+	AppPubkey string `json:"appPubkey"`
+	Queued    int    `json:"queued"`
+	Running   int    `json:"running"`
+}
+
+type RequestSchedulerStats struct {
+	MaxWorkers        int             `json:"maxWorkers"`
+	MaxPaymentWorkers int             `json:"maxPaymentWorkers"`
+	Running           int             `json:"running"`
+	RunningPayments   int             `json:"runningPayments"`
+	Queued            int             `json:"queued"`
+	Apps              []AppQueueStats `json:"apps"`
+}
+
+func newRequestScheduler(maxWorkers int, maxAppConcurrency int, maxAppQueue int, appRateLimit float64) *requestScheduler {
+	maxWorkers = max(maxWorkers, 1)
+	// a quarter of the workers is reserved for requests other than payments, if there is more than one
+	reservedWorkers := 0
+	if maxWorkers > 1 {
+		reservedWorkers = max(maxWorkers/4, 1)
+	}
+	return &requestScheduler{
+		maxWorkers:        maxWorkers,
+		maxPaymentWorkers: maxWorkers - reservedWorkers,
+		maxAppConcurrency: max(maxAppConcurrency, 1),
+		maxAppQueue:       maxAppQueue,
+		appRateLimit:      appRateLimit,
+		apps:              map[string]*appRequestQueue{},
+	}
+}
+
+// Schedule queues the job of an app, or returns an error if the app exceeded its limits.
+// Payment jobs only run on the workers which are not reserved for other requests.
+func (scheduler *requestScheduler) Schedule(appPubkey string, payment bool, job func()) error {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.removeIdleApps()
+
+	appQueue, ok := scheduler.apps[appPubkey]
+	if !ok {
+		appQueue = &appRequestQueue{}
+		if scheduler.appRateLimit > 0 {
+			appQueue.limiter = rate.NewLimiter(rate.Limit(scheduler.appRateLimit), max(int(scheduler.appRateLimit), 1))
+		}
+		scheduler.apps[appPubkey] = appQueue
+		scheduler.order = append(scheduler.order, appPubkey)
+	}
+
+	if appQueue.limiter != nil && !appQueue.limiter.Allow() {
+		return errAppRateLimited
+	}
+	if scheduler.maxAppQueue > 0 && len(appQueue.jobs) >= scheduler.maxAppQueue {
+		return errAppQueueFull
+	}
+
+	appQueue.jobs = append(appQueue.jobs, scheduledJob{run: job, payment: payment})
+	scheduler.queued++
+	scheduler.dispatch()
+	return nil
+}
+
+// dispatch starts queued jobs until all workers are busy. The mutex must be held.
+func (scheduler *requestScheduler) dispatch() {
+	for scheduler.running < scheduler.maxWorkers && scheduler.queued > 0 {
+		started := false
+		for i := 0; i < len(scheduler.order) && scheduler.running < scheduler.maxWorkers; i++ {
+			appPubkey := scheduler.order[(scheduler.next+i)%len(scheduler.order)]
+			appQueue := scheduler.apps[appPubkey]
+			if len(appQueue.jobs) == 0 || appQueue.running >= scheduler.maxAppConcurrency {
+				continue
+			}
+			job := appQueue.jobs[0]
+			if job.payment && scheduler.runningPayments >= scheduler.maxPaymentWorkers {
+				// requests of an app are started in order, so the app waits for a payment worker
+				continue
+			}
+
+			appQueue.jobs = appQueue.jobs[1:]
+			appQueue.running++
+			scheduler.queued--
+			scheduler.running++
+			if job.payment {
+				scheduler.runningPayments++
+			}
+			// continue with the next app, including apps added after this one
+			scheduler.next = (scheduler.next+i)%len(scheduler.order) + 1
+			started = true
+
+			go scheduler.run(appPubkey, job)
+			break
+		}
+		if !started {
+			// all apps with queued jobs are at their concurrency limit or wait for a payment worker
+			return
+		}
+	}
+}
+
+func (scheduler *requestScheduler) run(appPubkey string, job scheduledJob) {
+	defer func() {
+		scheduler.mutex.Lock()
+		defer scheduler.mutex.Unlock()
+		appQueue := scheduler.apps[appPubkey]
+		appQueue.running--
+		scheduler.running--
+		if job.payment {
+			scheduler.runningPayments--
+		}
+		scheduler.dispatch()
+	}()
+	job.run()
+}
+
+// removeIdleApps forgets apps without pending requests once their rate limit has fully recovered,
+// so the scheduler does not grow with every pubkey that ever sent a request. The mutex must be held.
+func (scheduler *requestScheduler) removeIdleApps() {
+	order := make([]string, 0, len(scheduler.order))
+	for _, appPubkey := range scheduler.order {
+		appQueue := scheduler.apps[appPubkey]
+		idle := len(appQueue.jobs) == 0 && appQueue.running == 0 &&
+			(appQueue.limiter == nil || appQueue.limiter.Tokens() >= float64(appQueue.limiter.Burst()))
+		if idle {
+			delete(scheduler.apps, appPubkey)
+			continue
+		}
+		order = append(order, appPubkey)
+	}
+	scheduler.order = order
+}
+
+func (scheduler *requestScheduler) Stats() *RequestSchedulerStats {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	stats := &RequestSchedulerStats{
+		MaxWorkers:        scheduler.maxWorkers,
+		MaxPaymentWorkers: scheduler.maxPaymentWorkers,
+		Running:           scheduler.running,
+		RunningPayments:   scheduler.runningPayments,
+		Queued:            scheduler.queued,
+		Apps:              []AppQueueStats{},
+	}
+	for _, appPubkey := range scheduler.order {
+		appQueue := scheduler.apps[appPubkey]
+		if len(appQueue.jobs) == 0 && appQueue.running == 0 {
+			continue
+		}
+		stats.Apps = append(stats.Apps, AppQueueStats{
+			AppPubkey: appPubkey,
+			Queued:    len(appQueue.jobs),
+			Running:   appQueue.running,
+		})
+	}
+	return stats
+}
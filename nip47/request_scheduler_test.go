@@ -0,0 +1,144 @@
+package nip47
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	assert.Eventually(t, condition, 5*time.Second, time.Millisecond)
+}
+
+func TestRequestScheduler_MaxWorkers(t *testing.T) {
+	scheduler := newRequestScheduler(2, 5, 0, 0)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		err := scheduler.Schedule("app1", false, func() {
+			defer wg.Done()
+			<-release
+		})
+		assert.NoError(t, err)
+	}
+
+	stats := scheduler.Stats()
+	assert.Equal(t, 2, stats.MaxWorkers)
+	assert.Equal(t, 2, stats.Running)
+	assert.Equal(t, 3, stats.Queued)
+	assert.Equal(t, []AppQueueStats{{AppPubkey: "app1", Queued: 3, Running: 2}}, stats.Apps)
+
+	close(release)
+	wg.Wait()
+
+	waitFor(t, func() bool {
+		stats := scheduler.Stats()
+		return stats.Running == 0 && stats.Queued == 0
+	})
+	assert.Empty(t, scheduler.Stats().Apps)
+}
+
+func TestRequestScheduler_AppConcurrency(t *testing.T) {
+	scheduler := newRequestScheduler(10, 2, 0, 0)
+	release := make(chan struct{})
+	defer close(release)
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, scheduler.Schedule("app1", false, func() { <-release }))
+	}
+	assert.NoError(t, scheduler.Schedule("app2", false, func() { <-release }))
+
+	stats := scheduler.Stats()
+	assert.Equal(t, 3, stats.Running)
+	assert.Equal(t, 2, stats.Queued)
+	assert.ElementsMatch(t, []AppQueueStats{
+		{AppPubkey: "app1", Queued: 2, Running: 2},
+		{AppPubkey: "app2", Queued: 0, Running: 1},
+	}, stats.Apps)
+}
+
+func TestRequestScheduler_RoundRobin(t *testing.T) {
+	scheduler := newRequestScheduler(1, 1, 0, 0)
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	started := []string{}
+	var wg sync.WaitGroup
+
+	job := func(appPubkey string) func() {
+		wg.Add(1)
+		return func() {
+			defer wg.Done()
+			mutex.Lock()
+			started = append(started, appPubkey)
+			mutex.Unlock()
+			<-release
+		}
+	}
+
+	// the first request occupies the only worker while the rest are queued
+	assert.NoError(t, scheduler.Schedule("app1", false, job("app1")))
+	assert.NoError(t, scheduler.Schedule("app1", false, job("app1")))
+	assert.NoError(t, scheduler.Schedule("app1", false, job("app1")))
+	assert.NoError(t, scheduler.Schedule("app2", false, job("app2")))
+	assert.NoError(t, scheduler.Schedule("app3", false, job("app3")))
+
+	close(release)
+	wg.Wait()
+
+	// app1 queued first but must not starve the other apps
+	assert.Equal(t, []string{"app1", "app2", "app3", "app1", "app1"}, started)
+}
+
+func TestRequestScheduler_RateLimited(t *testing.T) {
+	scheduler := newRequestScheduler(10, 10, 0, 2)
+
+	assert.NoError(t, scheduler.Schedule("app1", false, func() {}))
+	assert.NoError(t, scheduler.Schedule("app1", false, func() {}))
+	assert.ErrorIs(t, scheduler.Schedule("app1", false, func() {}), errAppRateLimited)
+
+	// other apps have their own limit
+	assert.NoError(t, scheduler.Schedule("app2", false, func() {}))
+}
+
+func TestRequestScheduler_QueueFull(t *testing.T) {
+	scheduler := newRequestScheduler(1, 1, 2, 0)
+	release := make(chan struct{})
+	defer close(release)
+
+	assert.NoError(t, scheduler.Schedule("app1", false, func() { <-release }))
+	assert.NoError(t, scheduler.Schedule("app1", false, func() {}))
+	assert.NoError(t, scheduler.Schedule("app1", false, func() {}))
+	assert.ErrorIs(t, scheduler.Schedule("app1", false, func() {}), errAppQueueFull)
+
+	stats := scheduler.Stats()
+	assert.Equal(t, 1, stats.Running)
+	assert.Equal(t, 2, stats.Queued)
+}
+
+func TestRequestScheduler_ReservedWorkers(t *testing.T) {
+	scheduler := newRequestScheduler(4, 10, 0, 0)
+	release := make(chan struct{})
+	defer close(release)
+
+	// payments cannot take all workers, e.g. while waiting for hold invoices
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, scheduler.Schedule("app1", true, func() { <-release }))
+	}
+	stats := scheduler.Stats()
+	assert.Equal(t, 3, stats.MaxPaymentWorkers)
+	assert.Equal(t, 3, stats.RunningPayments)
+	assert.Equal(t, 1, stats.Queued)
+
+	// other requests still run on the reserved worker
+	done := make(chan struct{})
+	assert.NoError(t, scheduler.Schedule("app2", false, func() { close(done) }))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not started on the reserved worker")
+	}
+}
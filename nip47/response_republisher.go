@@ -0,0 +1,115 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	responseRepublishInterval = 5 * time.Second
+	// the delay before the first retry, doubled after every retry
+	responseRepublishBackoff    = 5 * time.Second
+	responseRepublishMaxBackoff = 10 * time.Minute
+	responseRepublishMaxRetries = 10
+	// responses to older requests are no longer awaited by the client
+	responseRepublishMaxAge = time.Hour
+)
+
+// StartResponseRepublisher periodically republishes responses that could not be published,
+// e.g. because the relay connection dropped while a payment was in flight
+func (svc *nip47Service) StartResponseRepublisher(ctx context.Context, relay nostrmodels.Relay) {
+	go func() {
+		ticker := time.NewTicker(responseRepublishInterval)
+		defer ticker.Stop()
+		for {
+			// responses are retried right away on a new relay connection
+			svc.RepublishResponses(ctx, relay, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RepublishResponses retries all failed or unconfirmed responses that are due
+func (svc *nip47Service) RepublishResponses(ctx context.Context, relay nostrmodels.Relay, now time.Time) {
+	responseEvents := []db.ResponseEvent{}
+	err := svc.db.
+		Where("state IN ? AND event_data IS NOT NULL AND event_data != '' AND retry_count < ? AND created_at > ?",
+			[]string{db.RESPONSE_EVENT_STATE_PUBLISH_FAILED, db.RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED},
+			responseRepublishMaxRetries,
+			now.Add(-responseRepublishMaxAge)).
+		Order("id").
+		Find(&responseEvents).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch response events to republish")
+		return
+	}
+
+	for _, responseEvent := range responseEvents {
+		if ctx.Err() != nil {
+			return
+		}
+		if now.Before(responseEvent.UpdatedAt.Add(getResponseRepublishBackoff(responseEvent.RetryCount))) {
+			continue
+		}
+		svc.republishResponse(ctx, relay, &responseEvent)
+	}
+}
+
+func (svc *nip47Service) republishResponse(ctx context.Context, relay nostrmodels.Relay, responseEvent *db.ResponseEvent) {
+	resp := nostr.Event{}
+	err := json.Unmarshal([]byte(responseEvent.EventData), &resp)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("responseEventId", responseEvent.ID).Error("Failed to deserialize response event")
+		return
+	}
+
+	responseEvent.RetryCount++
+	err = relay.Publish(ctx, resp)
+	switch {
+	case err == nil:
+		responseEvent.State = db.RESPONSE_EVENT_STATE_PUBLISH_CONFIRMED
+		responseEvent.RepliedAt = time.Now()
+		logger.Logger.WithFields(logrus.Fields{
+			"responseEventId":      responseEvent.ID,
+			"responseNostrEventId": responseEvent.NostrId,
+			"retryCount":           responseEvent.RetryCount,
+		}).Info("Republished reply")
+	case errors.Is(err, context.DeadlineExceeded):
+		responseEvent.State = db.RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED
+	default:
+		responseEvent.State = db.RESPONSE_EVENT_STATE_PUBLISH_FAILED
+	}
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"responseEventId":      responseEvent.ID,
+			"responseNostrEventId": responseEvent.NostrId,
+			"retryCount":           responseEvent.RetryCount,
+		}).WithError(err).Error("Failed to republish reply")
+	}
+
+	err = svc.db.Save(responseEvent).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("responseEventId", responseEvent.ID).Error("Failed to update response event")
+	}
+}
+
+func getResponseRepublishBackoff(retryCount int) time.Duration {
+	backoff := responseRepublishBackoff
+	for i := 0; i < retryCount && backoff < responseRepublishMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, responseRepublishMaxBackoff)
+}
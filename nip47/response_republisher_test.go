@@ -0,0 +1,129 @@
+package nip47
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestPublishResponseEvent_Failed(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	requestEvent := &db.RequestEvent{NostrId: "request"}
+	assert.NoError(t, svc.DB.Create(requestEvent).Error)
+
+	resp := &nostr.Event{Kind: 23195, CreatedAt: nostr.Now(), Content: "response"}
+	assert.NoError(t, resp.Sign(svc.Keys.GetNostrSecretKey()))
+
+	relay := tests.NewMockRelay()
+	relay.PublishError = errors.New("connection lost")
+	err = nip47svc.publishResponseEvent(context.TODO(), relay, requestEvent, resp, nil)
+	assert.NoError(t, err)
+
+	responseEvent := db.ResponseEvent{}
+	assert.NoError(t, svc.DB.First(&responseEvent, &db.ResponseEvent{NostrId: resp.ID}).Error)
+	assert.Equal(t, db.RESPONSE_EVENT_STATE_PUBLISH_FAILED, responseEvent.State)
+
+	storedEvent := nostr.Event{}
+	assert.NoError(t, json.Unmarshal([]byte(responseEvent.EventData), &storedEvent))
+	assert.Equal(t, resp.ID, storedEvent.ID)
+	assert.Equal(t, resp.Sig, storedEvent.Sig)
+
+	// relays which do not reply in time might still have received the event
+	resp2 := &nostr.Event{Kind: 23195, CreatedAt: nostr.Now(), Content: "response2"}
+	assert.NoError(t, resp2.Sign(svc.Keys.GetNostrSecretKey()))
+	relay.PublishError = context.DeadlineExceeded
+	err = nip47svc.publishResponseEvent(context.TODO(), relay, requestEvent, resp2, nil)
+	assert.NoError(t, err)
+
+	responseEvent2 := db.ResponseEvent{}
+	assert.NoError(t, svc.DB.First(&responseEvent2, &db.ResponseEvent{NostrId: resp2.ID}).Error)
+	assert.Equal(t, db.RESPONSE_EVENT_STATE_PUBLISH_UNCONFIRMED, responseEvent2.State)
+}
+
+func TestRepublishResponses(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	requestEvent := &db.RequestEvent{NostrId: "request"}
+	assert.NoError(t, svc.DB.Create(requestEvent).Error)
+
+	resp := &nostr.Event{Kind: 23195, CreatedAt: nostr.Now(), Content: "response"}
+	assert.NoError(t, resp.Sign(svc.Keys.GetNostrSecretKey()))
+
+	relay := tests.NewMockRelay()
+	relay.PublishError = errors.New("connection lost")
+	assert.NoError(t, nip47svc.publishResponseEvent(context.TODO(), relay, requestEvent, resp, nil))
+
+	responseEvent := db.ResponseEvent{}
+	assert.NoError(t, svc.DB.First(&responseEvent, &db.ResponseEvent{NostrId: resp.ID}).Error)
+
+	// not due yet
+	nip47svc.RepublishResponses(context.TODO(), relay, responseEvent.UpdatedAt)
+	assert.NoError(t, svc.DB.First(&responseEvent, responseEvent.ID).Error)
+	assert.Equal(t, 0, responseEvent.RetryCount)
+
+	// the retry fails again
+	nip47svc.RepublishResponses(context.TODO(), relay, responseEvent.UpdatedAt.Add(responseRepublishBackoff))
+	assert.NoError(t, svc.DB.First(&responseEvent, responseEvent.ID).Error)
+	assert.Equal(t, 1, responseEvent.RetryCount)
+	assert.Equal(t, db.RESPONSE_EVENT_STATE_PUBLISH_FAILED, responseEvent.State)
+
+	// the backoff doubled
+	relay.PublishError = nil
+	nip47svc.RepublishResponses(context.TODO(), relay, responseEvent.UpdatedAt.Add(responseRepublishBackoff))
+	assert.Nil(t, relay.PublishedEvent)
+
+	nip47svc.RepublishResponses(context.TODO(), relay, responseEvent.UpdatedAt.Add(2*responseRepublishBackoff))
+	assert.NotNil(t, relay.PublishedEvent)
+	assert.Equal(t, resp.ID, relay.PublishedEvent.ID)
+
+	assert.NoError(t, svc.DB.First(&responseEvent, responseEvent.ID).Error)
+	assert.Equal(t, 2, responseEvent.RetryCount)
+	assert.Equal(t, db.RESPONSE_EVENT_STATE_PUBLISH_CONFIRMED, responseEvent.State)
+	// the event is kept to return it for requests received again over HTTP
+	assert.NotEmpty(t, responseEvent.EventData)
+	assert.False(t, responseEvent.RepliedAt.IsZero())
+}
+
+func TestRepublishResponses_RequestTooOld(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+
+	requestEvent := &db.RequestEvent{NostrId: "request"}
+	assert.NoError(t, svc.DB.Create(requestEvent).Error)
+
+	resp := &nostr.Event{Kind: 23195, CreatedAt: nostr.Now(), Content: "response"}
+	assert.NoError(t, resp.Sign(svc.Keys.GetNostrSecretKey()))
+
+	relay := tests.NewMockRelay()
+	relay.PublishError = errors.New("connection lost")
+	assert.NoError(t, nip47svc.publishResponseEvent(context.TODO(), relay, requestEvent, resp, nil))
+
+	relay.PublishError = nil
+	nip47svc.RepublishResponses(context.TODO(), relay, time.Now().Add(responseRepublishMaxAge+time.Minute))
+	assert.Nil(t, relay.PublishedEvent)
+}
+
+func TestGetResponseRepublishBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, getResponseRepublishBackoff(0))
+	assert.Equal(t, 10*time.Second, getResponseRepublishBackoff(1))
+	assert.Equal(t, 80*time.Second, getResponseRepublishBackoff(4))
+	assert.Equal(t, 10*time.Minute, getResponseRepublishBackoff(8))
+	assert.Equal(t, 10*time.Minute, getResponseRepublishBackoff(100))
+}
@@ -0,0 +1,98 @@
+package nip47
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+)
+
+const WALLET_AUTH_URI_SCHEME = "nostr+walletauth"
+
+// WalletAuthRequest is a connection requested by a client through a nostr+walletauth URI,
+// e.g. nostr+walletauth://<client pubkey>?relay=wss://relay.getalby.com/v1&name=App&required_commands=pay_invoice%20get_balance&budget=10000/monthly
+type WalletAuthRequest struct {
+	Pubkey         string
+	RelayUrl       string
+	Name           string
+	RequestMethods []string
+	// per budget period, 0 for no budget
+	MaxAmountSat  uint64
+	BudgetRenewal string
+	ExpiresAt     *time.Time
+	Isolated      bool
+	ReturnTo      string
+}
+
+func ParseWalletAuthUri(uri string) (*WalletAuthRequest, error) {
+	parsedUri, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection URI: %w", err)
+	}
+	if parsedUri.Scheme != WALLET_AUTH_URI_SCHEME {
+		return nil, fmt.Errorf("unsupported connection URI scheme: %s", parsedUri.Scheme)
+	}
+
+	// the pubkey is the host, or the opaque part for URIs without slashes
+	pubkey := parsedUri.Host
+	if pubkey == "" {
+		pubkey = parsedUri.Opaque
+	}
+	decodedPubkey, err := hex.DecodeString(pubkey)
+	if err != nil || len(decodedPubkey) != 32 {
+		return nil, fmt.Errorf("invalid public key format: %s", pubkey)
+	}
+
+	query := parsedUri.Query()
+	walletAuthRequest := &WalletAuthRequest{
+		Pubkey:        pubkey,
+		RelayUrl:      query.Get("relay"),
+		Name:          query.Get("name"),
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+		Isolated:      query.Get("isolated") == "true",
+		ReturnTo:      query.Get("return_to"),
+	}
+
+	for _, commands := range []string{query.Get("required_commands"), query.Get("optional_commands")} {
+		for _, command := range strings.Fields(commands) {
+			if !slices.Contains(walletAuthRequest.RequestMethods, command) {
+				walletAuthRequest.RequestMethods = append(walletAuthRequest.RequestMethods, command)
+			}
+		}
+	}
+	if len(walletAuthRequest.RequestMethods) == 0 {
+		return nil, errors.New("no commands requested")
+	}
+
+	// <max amount in sats>/<renewal period>, the period is optional
+	if budget := query.Get("budget"); budget != "" {
+		maxAmount, budgetRenewal, _ := strings.Cut(budget, "/")
+		walletAuthRequest.MaxAmountSat, err = strconv.ParseUint(maxAmount, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget: %s", budget)
+		}
+		if budgetRenewal != "" {
+			if !slices.Contains([]string{constants.BUDGET_RENEWAL_DAILY, constants.BUDGET_RENEWAL_WEEKLY, constants.BUDGET_RENEWAL_MONTHLY, constants.BUDGET_RENEWAL_YEARLY, constants.BUDGET_RENEWAL_NEVER}, budgetRenewal) {
+				return nil, fmt.Errorf("invalid budget renewal: %s", budgetRenewal)
+			}
+			walletAuthRequest.BudgetRenewal = budgetRenewal
+		}
+	}
+
+	if expiresAt := query.Get("expires_at"); expiresAt != "" {
+		expiresAtUnix, err := strconv.ParseInt(expiresAt, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at: %s", expiresAt)
+		}
+		expiresAtTime := time.Unix(expiresAtUnix, 0)
+		walletAuthRequest.ExpiresAt = &expiresAtTime
+	}
+
+	return walletAuthRequest, nil
+}
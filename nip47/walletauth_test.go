@@ -0,0 +1,62 @@
+package nip47
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/constants"
+)
+
+const walletAuthPubkey = "b889ff5b1513b641e2a139f661a661364979c5beee91842f8f0ef42ab558e9d4"
+
+func TestParseWalletAuthUri(t *testing.T) {
+	walletAuthRequest, err := ParseWalletAuthUri("nostr+walletauth://" + walletAuthPubkey +
+		"?relay=wss%3A%2F%2Frelay.getalby.com%2Fv1&name=Test%20App&required_commands=pay_invoice%20get_balance" +
+		"&optional_commands=list_transactions%20pay_invoice&budget=10000%2Fdaily&expires_at=1735689600&isolated=true&return_to=https%3A%2F%2Fexample.com")
+	assert.NoError(t, err)
+	assert.Equal(t, &WalletAuthRequest{
+		Pubkey:         walletAuthPubkey,
+		RelayUrl:       "wss://relay.getalby.com/v1",
+		Name:           "Test App",
+		RequestMethods: []string{"pay_invoice", "get_balance", "list_transactions"},
+		MaxAmountSat:   10000,
+		BudgetRenewal:  constants.BUDGET_RENEWAL_DAILY,
+		ExpiresAt:      walletAuthRequest.ExpiresAt,
+		Isolated:       true,
+		ReturnTo:       "https://example.com",
+	}, walletAuthRequest)
+	assert.Equal(t, time.Unix(1735689600, 0), *walletAuthRequest.ExpiresAt)
+}
+
+func TestParseWalletAuthUri_Minimal(t *testing.T) {
+	for _, uri := range []string{
+		"nostr+walletauth://" + walletAuthPubkey + "?required_commands=get_info",
+		"nostr+walletauth:" + walletAuthPubkey + "?required_commands=get_info",
+	} {
+		walletAuthRequest, err := ParseWalletAuthUri(uri)
+		assert.NoError(t, err)
+		assert.Equal(t, walletAuthPubkey, walletAuthRequest.Pubkey)
+		assert.Equal(t, []string{"get_info"}, walletAuthRequest.RequestMethods)
+		assert.Equal(t, constants.BUDGET_RENEWAL_NEVER, walletAuthRequest.BudgetRenewal)
+		assert.Zero(t, walletAuthRequest.MaxAmountSat)
+		assert.Nil(t, walletAuthRequest.ExpiresAt)
+		assert.False(t, walletAuthRequest.Isolated)
+	}
+}
+
+func TestParseWalletAuthUri_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"nostr+walletconnect://" + walletAuthPubkey + "?required_commands=get_info":                  "unsupported connection URI scheme: nostr+walletconnect",
+		"nostr+walletauth://abc?required_commands=get_info":                                          "invalid public key format: abc",
+		"nostr+walletauth://" + walletAuthPubkey:                                                     "no commands requested",
+		"nostr+walletauth://" + walletAuthPubkey + "?required_commands=get_info&budget=abc/daily":    "invalid budget: abc/daily",
+		"nostr+walletauth://" + walletAuthPubkey + "?required_commands=get_info&budget=1000/hourly":  "invalid budget renewal: hourly",
+		"nostr+walletauth://" + walletAuthPubkey + "?required_commands=get_info&expires_at=tomorrow": "invalid expires_at: tomorrow",
+	}
+	for uri, expectedError := range testCases {
+		_, err := ParseWalletAuthUri(uri)
+		assert.EqualError(t, err, expectedError, uri)
+	}
+}
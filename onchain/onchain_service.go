@@ -0,0 +1,160 @@
+package onchain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const checkInterval = 1 * time.Minute
+
+type onchainService struct {
+	db            *gorm.DB
+	lnClient      lnclient.LNClient
+	lnClientMutex sync.RWMutex
+}
+
+type OnchainService interface {
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+	// looks up the transaction IDs and states of pending onchain sends made via a swap
+	ResolveSwaps(ctx context.Context) error
+	// marks pending onchain sends as settled once the backend sees them confirmed
+	UpdateOnchainTransactions(ctx context.Context) error
+}
+
+func NewOnchainService(db *gorm.DB) *onchainService {
+	return &onchainService{
+		db: db,
+	}
+}
+
+func (svc *onchainService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	svc.setLNClient(lnClient)
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				svc.setLNClient(nil)
+				return
+			case <-ticker.C:
+				err := svc.ResolveSwaps(ctx)
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to resolve onchain swaps")
+				}
+				err = svc.UpdateOnchainTransactions(ctx)
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to update onchain transactions")
+				}
+			}
+		}
+	}()
+}
+
+func (svc *onchainService) ResolveSwaps(ctx context.Context) error {
+	swapSender, ok := svc.getLNClient().(lnclient.OnchainSwapSender)
+	if !ok {
+		return nil
+	}
+
+	pendingOnchainTransactions := []db.OnchainTransaction{}
+	err := svc.db.Where("swap_id != '' AND state = ?", constants.TRANSACTION_STATE_PENDING).Find(&pendingOnchainTransactions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, onchainTransaction := range pendingOnchainTransactions {
+		onchainSwap, err := swapSender.GetOnchainSwap(ctx, onchainTransaction.SwapId)
+		if err != nil {
+			// retried on the next check
+			logger.Logger.WithError(err).WithField("swapId", onchainTransaction.SwapId).Error("Failed to get onchain swap")
+			continue
+		}
+
+		state := constants.TRANSACTION_STATE_PENDING
+		switch {
+		case onchainSwap.Failed:
+			state = constants.TRANSACTION_STATE_FAILED
+		case onchainSwap.Confirmed:
+			state = constants.TRANSACTION_STATE_SETTLED
+		}
+		if state == onchainTransaction.State && onchainSwap.TxId == onchainTransaction.TxId {
+			continue
+		}
+
+		err = svc.db.Model(&onchainTransaction).Updates(map[string]interface{}{
+			"tx_id": onchainSwap.TxId,
+			"state": state,
+		}).Error
+		if err != nil {
+			logger.Logger.WithError(err).WithField("swapId", onchainTransaction.SwapId).Error("Failed to update onchain transaction")
+			continue
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"swapId": onchainTransaction.SwapId,
+			"txId":   onchainSwap.TxId,
+			"state":  state,
+		}).Info("Updated onchain swap transaction")
+	}
+	return nil
+}
+
+func (svc *onchainService) UpdateOnchainTransactions(ctx context.Context) error {
+	lnClient := svc.getLNClient()
+	if lnClient == nil {
+		return nil
+	}
+
+	pendingOnchainTransactions := []db.OnchainTransaction{}
+	err := svc.db.Where("tx_id != '' AND state = ?", constants.TRANSACTION_STATE_PENDING).Find(&pendingOnchainTransactions).Error
+	if err != nil {
+		return err
+	}
+	if len(pendingOnchainTransactions) == 0 {
+		return nil
+	}
+
+	backendTransactions, err := lnClient.ListOnchainTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	confirmedTxIds := map[string]bool{}
+	for _, backendTransaction := range backendTransactions {
+		if backendTransaction.Confirmations > 0 {
+			confirmedTxIds[backendTransaction.TxId] = true
+		}
+	}
+
+	for _, onchainTransaction := range pendingOnchainTransactions {
+		if !confirmedTxIds[onchainTransaction.TxId] {
+			continue
+		}
+		err = svc.db.Model(&onchainTransaction).Update("state", constants.TRANSACTION_STATE_SETTLED).Error
+		if err != nil {
+			logger.Logger.WithError(err).WithField("txId", onchainTransaction.TxId).Error("Failed to update onchain transaction state")
+		}
+	}
+	return nil
+}
+
+func (svc *onchainService) getLNClient() lnclient.LNClient {
+	svc.lnClientMutex.RLock()
+	defer svc.lnClientMutex.RUnlock()
+	return svc.lnClient
+}
+
+func (svc *onchainService) setLNClient(lnClient lnclient.LNClient) {
+	svc.lnClientMutex.Lock()
+	defer svc.lnClientMutex.Unlock()
+	svc.lnClient = lnClient
+}
@@ -0,0 +1,189 @@
+package onchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+const mockTxId = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+type mockSwapSender struct {
+	lnclient.LNClient
+	swaps map[string]*lnclient.OnchainSwap
+}
+
+func (mock *mockSwapSender) PayOnchainViaSwap(ctx context.Context, toAddress string, amount uint64) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (mock *mockSwapSender) GetOnchainSwap(ctx context.Context, swapId string) (*lnclient.OnchainSwap, error) {
+	onchainSwap, ok := mock.swaps[swapId]
+	if !ok {
+		return nil, errors.New("swap not found")
+	}
+	return onchainSwap, nil
+}
+
+func TestResolveSwaps(t *testing.T) {
+	testCases := []struct {
+		name          string
+		onchainSwap   *lnclient.OnchainSwap
+		expectedTxId  string
+		expectedState string
+	}{
+		{
+			name:          "in progress",
+			onchainSwap:   &lnclient.OnchainSwap{},
+			expectedTxId:  "",
+			expectedState: constants.TRANSACTION_STATE_PENDING,
+		},
+		{
+			name:          "claim transaction broadcast",
+			onchainSwap:   &lnclient.OnchainSwap{TxId: mockTxId},
+			expectedTxId:  mockTxId,
+			expectedState: constants.TRANSACTION_STATE_PENDING,
+		},
+		{
+			name:          "claim transaction confirmed",
+			onchainSwap:   &lnclient.OnchainSwap{TxId: mockTxId, Confirmed: true},
+			expectedTxId:  mockTxId,
+			expectedState: constants.TRANSACTION_STATE_SETTLED,
+		},
+		{
+			name:          "cancelled",
+			onchainSwap:   &lnclient.OnchainSwap{Failed: true},
+			expectedTxId:  "",
+			expectedState: constants.TRANSACTION_STATE_FAILED,
+		},
+		{
+			name:          "unknown swap",
+			expectedTxId:  "",
+			expectedState: constants.TRANSACTION_STATE_PENDING,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer tests.RemoveTestService()
+			svc, err := tests.CreateTestService()
+			require.NoError(t, err)
+
+			swapSender := &mockSwapSender{LNClient: svc.LNClient, swaps: map[string]*lnclient.OnchainSwap{}}
+			if tc.onchainSwap != nil {
+				swapSender.swaps["swap-1"] = tc.onchainSwap
+			}
+			onchainSvc := NewOnchainService(svc.DB)
+			onchainSvc.setLNClient(swapSender)
+
+			onchainTransaction := &db.OnchainTransaction{
+				SwapId:    "swap-1",
+				Type:      constants.TRANSACTION_TYPE_OUTGOING,
+				State:     constants.TRANSACTION_STATE_PENDING,
+				Address:   "bc1qtest",
+				AmountSat: 50_000,
+			}
+			require.NoError(t, svc.DB.Create(onchainTransaction).Error)
+
+			err = onchainSvc.ResolveSwaps(context.TODO())
+			assert.NoError(t, err)
+
+			require.NoError(t, svc.DB.First(onchainTransaction, onchainTransaction.ID).Error)
+			assert.Equal(t, tc.expectedTxId, onchainTransaction.TxId)
+			assert.Equal(t, tc.expectedState, onchainTransaction.State)
+		})
+	}
+}
+
+func TestResolveSwaps_IgnoresSendsWithoutSwap(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	swapSender := &mockSwapSender{LNClient: svc.LNClient, swaps: map[string]*lnclient.OnchainSwap{}}
+	onchainSvc := NewOnchainService(svc.DB)
+	onchainSvc.setLNClient(swapSender)
+
+	onchainTransaction := &db.OnchainTransaction{
+		TxId:  mockTxId,
+		Type:  constants.TRANSACTION_TYPE_OUTGOING,
+		State: constants.TRANSACTION_STATE_PENDING,
+	}
+	require.NoError(t, svc.DB.Create(onchainTransaction).Error)
+
+	err = onchainSvc.ResolveSwaps(context.TODO())
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.DB.First(onchainTransaction, onchainTransaction.ID).Error)
+	assert.Equal(t, mockTxId, onchainTransaction.TxId)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, onchainTransaction.State)
+}
+
+func TestResolveSwaps_NoSwapSender(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	onchainSvc := NewOnchainService(svc.DB)
+	onchainSvc.setLNClient(svc.LNClient)
+
+	err = onchainSvc.ResolveSwaps(context.TODO())
+	assert.NoError(t, err)
+}
+
+func TestUpdateOnchainTransactions(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	svc.LNClient.(*tests.MockLn).OnchainTransactions = []lnclient.OnchainTransaction{
+		{TxId: "confirmed", Type: constants.TRANSACTION_TYPE_OUTGOING, Confirmations: 1},
+		{TxId: "unconfirmed", Type: constants.TRANSACTION_TYPE_OUTGOING},
+	}
+	onchainSvc := NewOnchainService(svc.DB)
+	onchainSvc.setLNClient(svc.LNClient)
+
+	for _, txId := range []string{"confirmed", "unconfirmed", "unknown"} {
+		require.NoError(t, svc.DB.Create(&db.OnchainTransaction{
+			TxId:  txId,
+			Type:  constants.TRANSACTION_TYPE_OUTGOING,
+			State: constants.TRANSACTION_STATE_PENDING,
+		}).Error)
+	}
+
+	err = onchainSvc.UpdateOnchainTransactions(context.TODO())
+	assert.NoError(t, err)
+
+	onchainTransactions := []db.OnchainTransaction{}
+	require.NoError(t, svc.DB.Order("id").Find(&onchainTransactions).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, onchainTransactions[0].State)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, onchainTransactions[1].State)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, onchainTransactions[2].State)
+}
+
+func TestUpdateOnchainTransactions_BackendError(t *testing.T) {
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	svc.LNClient.(*tests.MockLn).OnchainTransactionsError = errors.New("backend unavailable")
+	onchainSvc := NewOnchainService(svc.DB)
+	onchainSvc.setLNClient(svc.LNClient)
+
+	require.NoError(t, svc.DB.Create(&db.OnchainTransaction{
+		TxId:  mockTxId,
+		Type:  constants.TRANSACTION_TYPE_OUTGOING,
+		State: constants.TRANSACTION_STATE_PENDING,
+	}).Error)
+
+	err = onchainSvc.UpdateOnchainTransactions(context.TODO())
+	assert.EqualError(t, err, "backend unavailable")
+}
@@ -1,6 +1,13 @@
 package keys
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/logger"
 	"github.com/nbd-wtf/go-nostr"
@@ -12,6 +19,8 @@ type Keys interface {
 	GetNostrPublicKey() string
 	// Wallet Service Nostr secret key
 	GetNostrSecretKey() string
+	// Wallet Service Nostr secret key of a connection with its own wallet service key
+	GetAppWalletKey(appId uint, keyIndex uint) (string, error)
 }
 
 type keys struct {
@@ -53,3 +62,23 @@ func (keys *keys) GetNostrSecretKey() string {
 	}
 	return keys.nostrSecretKey
 }
+
+// GetAppWalletKey derives a unique wallet service key for an app from the hub's nostr secret key,
+// so the key does not have to be stored. App IDs are never reused, so the key of a deleted app
+// is never handed out again. The key index is incremented when the key of an app is rotated.
+func (keys *keys) GetAppWalletKey(appId uint, keyIndex uint) (string, error) {
+	mac := hmac.New(sha256.New, []byte(keys.GetNostrSecretKey()))
+	mac.Write([]byte("app-wallet-key/"))
+	mac.Write([]byte(strconv.FormatUint(uint64(appId), 10)))
+	if keyIndex > 0 {
+		mac.Write([]byte("/" + strconv.FormatUint(uint64(keyIndex), 10)))
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(mac.Sum(nil))
+	appWalletKey := hex.EncodeToString(privateKey.Serialize())
+
+	_, err := nostr.GetPublicKey(appWalletKey)
+	if err != nil {
+		return "", err
+	}
+	return appWalletKey, nil
+}
@@ -4,8 +4,13 @@ import (
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/forwards"
+	"github.com/getAlby/hub/liquidity"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/nip47"
 	"github.com/getAlby/hub/service/keys"
+	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/transactions"
 	"gorm.io/gorm"
 )
@@ -20,7 +25,13 @@ type Service interface {
 	GetEventPublisher() events.EventPublisher
 	GetLNClient() lnclient.LNClient
 	GetTransactionsService() transactions.TransactionsService
+	GetForwardsService() forwards.ForwardsService
+	GetLSPService() lsp.LSPService
+	GetLiquidityService() liquidity.LiquidityService
+	GetSwapsService() swaps.SwapsService
+	GetNip47Service() nip47.Nip47Service
 	GetDB() *gorm.DB
 	GetConfig() config.Config
 	GetKeys() keys.Keys
+	GetRelayStatus() RelayStatus
 }
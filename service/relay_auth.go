@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/logger"
+)
+
+var errRelayRejectedSubscription = errors.New("relay rejected subscription")
+
+// NIP-42 prefix of CLOSED and OK messages of relays that require authentication
+const relayAuthRequiredPrefix = "auth-required:"
+
+// a subscription is only resubscribed this many times after authenticating, with an exponential backoff
+const maxRelayAuthAttempts = 3
+
+var relayAuthRetryDelay = 2 * time.Second
+
+// RelayStatus describes the connection to the relay the hub receives requests from
+type RelayStatus struct {
+	Url           string
+	Connected     bool
+	AuthRequired  bool
+	Authenticated bool
+	AuthError     string
+	UpdatedAt     time.Time
+}
+
+func isRelayAuthRequired(reason string) bool {
+	// publish errors wrap the reason
+	return strings.Contains(reason, relayAuthRequiredPrefix)
+}
+
+// authenticateRelay answers the NIP-42 challenge of the relay, signed with the wallet service key
+func (svc *service) authenticateRelay(ctx context.Context, relay *nostr.Relay) error {
+	logger.Logger.WithField("relay_url", relay.URL).Info("Authenticating to relay")
+	err := relay.Auth(ctx, func(event *nostr.Event) error {
+		return event.Sign(svc.keys.GetNostrSecretKey())
+	})
+
+	svc.updateRelayStatus(func(relayStatus *RelayStatus) {
+		relayStatus.AuthRequired = true
+		relayStatus.Authenticated = err == nil
+		relayStatus.AuthError = ""
+		if err != nil {
+			relayStatus.AuthError = err.Error()
+		}
+	})
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"relay_url": relay.URL,
+		}).WithError(err).Error("Failed to authenticate to relay")
+		return err
+	}
+	logger.Logger.WithField("relay_url", relay.URL).Info("Authenticated to relay")
+	return nil
+}
+
+func (svc *service) GetRelayStatus() RelayStatus {
+	svc.relayStatusMutex.Lock()
+	defer svc.relayStatusMutex.Unlock()
+	return svc.relayStatus
+}
+
+func (svc *service) updateRelayStatus(update func(relayStatus *RelayStatus)) {
+	svc.relayStatusMutex.Lock()
+	defer svc.relayStatusMutex.Unlock()
+	update(&svc.relayStatus)
+	svc.relayStatus.UpdatedAt = time.Now()
+}
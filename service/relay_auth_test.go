@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/nip47"
+	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/tests"
+)
+
+// authRelay is a relay which closes subscriptions until the client authenticated (NIP-42)
+type authRelay struct {
+	// keep closing subscriptions after the client authenticated
+	alwaysRequireAuth bool
+
+	mutex         sync.Mutex
+	authenticated bool
+	authCount     int
+	reqCount      int
+}
+
+func (relay *authRelay) handle(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var writeMutex sync.Mutex
+	write := func(message ...interface{}) {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.WriteJSON(message)
+	}
+	write("AUTH", "challenge")
+
+	for {
+		var message []json.RawMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+		var label string
+		json.Unmarshal(message[0], &label)
+
+		relay.mutex.Lock()
+		switch label {
+		case "AUTH":
+			var event nostr.Event
+			json.Unmarshal(message[1], &event)
+			relay.authCount++
+			relay.authenticated = true
+			write("OK", event.ID, true, "")
+		case "REQ":
+			var subscriptionId string
+			json.Unmarshal(message[1], &subscriptionId)
+			relay.reqCount++
+			if relay.authenticated && !relay.alwaysRequireAuth {
+				write("EOSE", subscriptionId)
+			} else {
+				write("CLOSED", subscriptionId, "auth-required: authenticate to subscribe")
+			}
+		}
+		relay.mutex.Unlock()
+	}
+}
+
+func (relay *authRelay) counts() (int, int) {
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	return relay.authCount, relay.reqCount
+}
+
+type mockNip47Service struct {
+	nip47.Nip47Service
+	appWalletKeySubscriptions chan struct{}
+}
+
+func (svc *mockNip47Service) StartNotifier(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient) {
+}
+
+func (svc *mockNip47Service) StartResponseRepublisher(ctx context.Context, relay nostrmodels.Relay) {
+}
+
+func (svc *mockNip47Service) StartAppWalletKeySubscriptions(ctx context.Context, relay *nostr.Relay, lnClient lnclient.LNClient) {
+	svc.appWalletKeySubscriptions <- struct{}{}
+}
+
+func (svc *mockNip47Service) ScheduleEvent(ctx context.Context, relay nostrmodels.Relay, event *nostr.Event, lnClient lnclient.LNClient) {
+}
+
+func startAuthRelayTest(t *testing.T, relay *authRelay) (*service, *mockNip47Service, *nostr.Subscription) {
+	testSvc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(relay.handle))
+	t.Cleanup(server.Close)
+
+	nip47Svc := &mockNip47Service{appWalletKeySubscriptions: make(chan struct{}, 1)}
+	svc := &service{keys: testSvc.Keys, nip47Service: nip47Svc}
+
+	nostrRelay, err := nostr.RelayConnect(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { nostrRelay.Close() })
+
+	sub, err := nostrRelay.Subscribe(nostrRelay.Context(), svc.createFilters(svc.keys.GetNostrPublicKey()))
+	assert.NoError(t, err)
+	return svc, nip47Svc, sub
+}
+
+func TestIsRelayAuthRequired(t *testing.T) {
+	assert.True(t, isRelayAuthRequired("auth-required: authenticate to subscribe"))
+	// publish errors wrap the reason of the relay
+	assert.True(t, isRelayAuthRequired("msg: auth-required: authenticate to publish"))
+	assert.False(t, isRelayAuthRequired("restricted: not allowed"))
+	assert.False(t, isRelayAuthRequired(""))
+}
+
+func TestStartSubscription_AuthenticatesAndResubscribes(t *testing.T) {
+	defer tests.RemoveTestService()
+	relay := &authRelay{}
+	svc, nip47Svc, sub := startAuthRelayTest(t, relay)
+
+	ctx, cancel := context.WithCancel(sub.Relay.Context())
+	result := make(chan error, 1)
+	go func() {
+		result <- svc.StartSubscription(ctx, sub)
+	}()
+
+	// the replaced subscription received EOSE
+	select {
+	case <-nip47Svc.appWalletKeySubscriptions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription was not replaced after authenticating")
+	}
+	authCount, reqCount := relay.counts()
+	assert.Equal(t, 1, authCount)
+	assert.Equal(t, 2, reqCount)
+	assert.True(t, svc.GetRelayStatus().Authenticated)
+
+	cancel()
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not end")
+	}
+}
+
+func TestStartSubscription_MaxAuthAttempts(t *testing.T) {
+	defer tests.RemoveTestService()
+	relayAuthRetryDelay = time.Millisecond
+	defer func() { relayAuthRetryDelay = 2 * time.Second }()
+
+	relay := &authRelay{alwaysRequireAuth: true}
+	svc, _, sub := startAuthRelayTest(t, relay)
+
+	err := svc.StartSubscription(sub.Relay.Context(), sub)
+	assert.ErrorIs(t, err, errRelayRejectedSubscription)
+
+	authCount, reqCount := relay.counts()
+	assert.Equal(t, maxRelayAuthAttempts, authCount)
+	assert.Equal(t, maxRelayAuthAttempts+1, reqCount)
+}
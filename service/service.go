@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"os"
@@ -18,8 +19,13 @@ import (
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/forwards"
+	"github.com/getAlby/hub/liquidity"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/lsp"
+	"github.com/getAlby/hub/onchain"
 	"github.com/getAlby/hub/service/keys"
+	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/transactions"
 	"github.com/getAlby/hub/version"
 
@@ -36,6 +42,11 @@ type service struct {
 	db                  *gorm.DB
 	lnClient            lnclient.LNClient
 	transactionsService transactions.TransactionsService
+	forwardsService     forwards.ForwardsService
+	lspService          lsp.LSPService
+	liquidityService    liquidity.LiquidityService
+	swapsService        swaps.SwapsService
+	onchainService      onchain.OnchainService
 	albyOAuthSvc        alby.AlbyOAuthService
 	eventPublisher      events.EventPublisher
 	ctx                 context.Context
@@ -43,6 +54,8 @@ type service struct {
 	nip47Service        nip47.Nip47Service
 	appCancelFn         context.CancelFunc
 	keys                keys.Keys
+	relayStatus         RelayStatus
+	relayStatusMutex    sync.Mutex
 }
 
 func NewService(ctx context.Context) (*service, error) {
@@ -91,22 +104,33 @@ func NewService(ctx context.Context) (*service, error) {
 
 	keys := keys.NewKeys()
 
+	albyOAuthSvc := alby.NewAlbyOAuthService(gormDB, cfg, keys, eventPublisher)
+	transactionsService := transactions.NewTransactionsService(gormDB, eventPublisher)
+	lspService := lsp.NewLSPService(gormDB, cfg, eventPublisher)
+
 	var wg sync.WaitGroup
 	svc := &service{
 		cfg:                 cfg,
 		ctx:                 ctx,
 		wg:                  &wg,
 		eventPublisher:      eventPublisher,
-		albyOAuthSvc:        alby.NewAlbyOAuthService(gormDB, cfg, keys, eventPublisher),
+		albyOAuthSvc:        albyOAuthSvc,
 		nip47Service:        nip47.NewNip47Service(gormDB, cfg, keys, eventPublisher),
-		transactionsService: transactions.NewTransactionsService(gormDB, eventPublisher),
+		transactionsService: transactionsService,
+		forwardsService:     forwards.NewForwardsService(gormDB),
+		lspService:          lspService,
+		liquidityService:    liquidity.NewLiquidityService(gormDB, cfg, eventPublisher, lspService, albyOAuthSvc, transactionsService),
+		swapsService:        swaps.NewSwapsService(gormDB, keys, eventPublisher, transactionsService, swaps.NewBoltzSwapProvider(appConfig.SwapServiceUrl), appConfig.LDKEsploraServer),
+		onchainService:      onchain.NewOnchainService(gormDB),
 		db:                  gormDB,
 		keys:                keys,
 	}
 
 	eventPublisher.RegisterSubscriber(svc.transactionsService)
+	eventPublisher.RegisterSubscriber(svc.forwardsService)
 	eventPublisher.RegisterSubscriber(svc.nip47Service)
 	eventPublisher.RegisterSubscriber(svc.albyOAuthSvc)
+	eventPublisher.RegisterSubscriber(svc.liquidityService)
 
 	eventPublisher.Publish(&events.Event{
 		Event: "nwc_started",
@@ -142,22 +166,74 @@ func (svc *service) noticeHandler(notice string) {
 	logger.Logger.Infof("Received a notice %s", notice)
 }
 
+// StartSubscription handles the requests of the subscription until ctx, the context of the relay connection, ends.
+// Subscriptions closed because the relay requires authentication are replaced after authenticating.
 func (svc *service) StartSubscription(ctx context.Context, sub *nostr.Subscription) error {
 	svc.nip47Service.StartNotifier(ctx, sub.Relay, svc.lnClient)
+	svc.nip47Service.StartResponseRepublisher(ctx, sub.Relay)
+
+	authAttempts := 0
+	var appWalletKeySubscriptionsOnce sync.Once
+	for {
+		go func(sub *nostr.Subscription) {
+			// block till EOS is received
+			select {
+			case <-sub.EndOfStoredEvents:
+			case <-sub.Context.Done():
+				// the subscription was closed by the relay
+				return
+			}
+			logger.Logger.Debug("Received EOS")
+
+			// the relay accepted the subscription, so it also accepts the ones of app wallet keys
+			appWalletKeySubscriptionsOnce.Do(func() {
+				svc.nip47Service.StartAppWalletKeySubscriptions(ctx, sub.Relay, svc.lnClient)
+			})
+
+			// loop through incoming events
+			for event := range sub.Events {
+				svc.nip47Service.ScheduleEvent(ctx, sub.Relay, event, svc.lnClient)
+			}
+			logger.Logger.Debug("Relay subscription events channel ended")
+		}(sub)
+
+		var closedReason string
+		select {
+		case <-ctx.Done():
+		case closedReason = <-sub.ClosedReason:
+		}
+		if closedReason == "" {
+			break
+		}
 
-	go func() {
-		// block till EOS is received
-		<-sub.EndOfStoredEvents
-		logger.Logger.Debug("Received EOS")
+		// the relay already closed the subscription, this only stops the events loop
+		sub.Unsub()
 
-		// loop through incoming events
-		for event := range sub.Events {
-			go svc.nip47Service.HandleEvent(ctx, sub.Relay, event, svc.lnClient)
+		if !isRelayAuthRequired(closedReason) {
+			return fmt.Errorf("%w: %s", errRelayRejectedSubscription, closedReason)
+		}
+		if authAttempts >= maxRelayAuthAttempts {
+			return fmt.Errorf("%w: still requires authentication after %d attempts: %s", errRelayRejectedSubscription, authAttempts, closedReason)
 		}
-		logger.Logger.Debug("Relay subscription events channel ended")
-	}()
+		if authAttempts > 0 {
+			// the relay rejected the previous authentication, e.g. because it was not processed yet
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(relayAuthRetryDelay * time.Duration(1<<(authAttempts-1))):
+			}
+		}
+		authAttempts++
 
-	<-ctx.Done()
+		err := svc.authenticateRelay(ctx, sub.Relay)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errRelayRejectedSubscription, err)
+		}
+		sub, err = sub.Relay.Subscribe(ctx, sub.Filters)
+		if err != nil {
+			return err
+		}
+	}
 
 	if sub.Relay.ConnectionError != nil {
 		logger.Logger.WithField("connectionError", sub.Relay.ConnectionError).Error("Relay error")
@@ -244,6 +320,22 @@ func (svc *service) GetTransactionsService() transactions.TransactionsService {
 	return svc.transactionsService
 }
 
+func (svc *service) GetLSPService() lsp.LSPService {
+	return svc.lspService
+}
+
+func (svc *service) GetLiquidityService() liquidity.LiquidityService {
+	return svc.liquidityService
+}
+
+func (svc *service) GetSwapsService() swaps.SwapsService {
+	return svc.swapsService
+}
+
+func (svc *service) GetForwardsService() forwards.ForwardsService {
+	return svc.forwardsService
+}
+
 func (svc *service) GetKeys() keys.Keys {
 	return svc.keys
 }
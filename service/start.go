@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"path"
 	"strconv"
@@ -74,6 +75,10 @@ func (svc *service) startNostr(ctx context.Context, encryptionKey string) error
 				"iteration": i,
 			}).Info("Connecting to the relay")
 
+			svc.updateRelayStatus(func(relayStatus *RelayStatus) {
+				*relayStatus = RelayStatus{Url: relayUrl}
+			})
+
 			relay, err = nostr.RelayConnect(ctx, relayUrl, nostr.WithNoticeHandler(svc.noticeHandler))
 			if err != nil {
 				// exponential backoff from 2 - 60 seconds
@@ -88,9 +93,19 @@ func (svc *service) startNostr(ctx context.Context, encryptionKey string) error
 			}
 
 			waitToReconnectSeconds = 0
+			svc.updateRelayStatus(func(relayStatus *RelayStatus) {
+				relayStatus.Connected = true
+			})
 
 			//publish event with NIP-47 info
 			err = svc.nip47Service.PublishNip47Info(ctx, relay, svc.lnClient)
+			if err != nil && isRelayAuthRequired(err.Error()) {
+				// relays requiring NIP-42 auth reject events until the client is authenticated
+				err = svc.authenticateRelay(ctx, relay)
+				if err == nil {
+					err = svc.nip47Service.PublishNip47Info(ctx, relay, svc.lnClient)
+				}
+			}
 			if err != nil {
 				logger.Logger.WithError(err).Error("Could not publish NIP47 info")
 			}
@@ -101,10 +116,18 @@ func (svc *service) startNostr(ctx context.Context, encryptionKey string) error
 				logger.Logger.WithError(err).Error("Failed to subscribe to events")
 				continue
 			}
-			err = svc.StartSubscription(sub.Context, sub)
+			// subscriptions can be replaced, so the subscription is handled until the connection ends
+			err = svc.StartSubscription(relay.Context(), sub)
+			svc.updateRelayStatus(func(relayStatus *RelayStatus) {
+				relayStatus.Connected = false
+			})
 			if err != nil {
 				//err being non-nil means that we have an error on the websocket error channel. In this case we just try to reconnect.
 				logger.Logger.WithError(err).Error("Got an error from the relay while listening to subscription.")
+				if errors.Is(err, errRelayRejectedSubscription) {
+					// reconnecting right away would be rejected again
+					waitToReconnectSeconds = 30
+				}
 				continue
 			}
 			//err being nil means that the context was canceled and we should exit the program.
@@ -143,6 +166,13 @@ func (svc *service) StartApp(encryptionKey string) error {
 		return err
 	}
 
+	svc.lspService.StartOrderPolling(ctx)
+	svc.nip47Service.StartEventPruning(ctx)
+	svc.nip47Service.StartAppExpiryChecks(ctx)
+	svc.liquidityService.Start(ctx, svc.lnClient)
+	svc.swapsService.Start(ctx, svc.lnClient)
+	svc.onchainService.Start(ctx, svc.lnClient)
+
 	svc.appCancelFn = cancelFn
 
 	return nil
@@ -179,7 +209,18 @@ func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) e
 		Mnemonic, _ := svc.cfg.Get("Mnemonic", encryptionKey)
 		LDKWorkdir := path.Join(svc.cfg.GetEnv().Workdir, "ldk")
 
-		lnClient, err = ldk.NewLDKService(ctx, svc.cfg, svc.eventPublisher, Mnemonic, LDKWorkdir, svc.cfg.GetEnv().LDKNetwork)
+		// registered LSPs are trusted to open zero-conf channels
+		lsps, lspsErr := svc.lspService.ListLSPs()
+		if lspsErr != nil {
+			logger.Logger.WithError(lspsErr).Error("Failed to list LSPs")
+			return lspsErr
+		}
+		trustedPeers := make([]string, 0, len(lsps))
+		for _, lsp := range lsps {
+			trustedPeers = append(trustedPeers, lsp.Pubkey)
+		}
+
+		lnClient, err = ldk.NewLDKService(ctx, svc.cfg, svc.eventPublisher, Mnemonic, LDKWorkdir, svc.cfg.GetEnv().LDKNetwork, trustedPeers)
 	case config.GreenlightBackendType:
 		Mnemonic, _ := svc.cfg.Get("Mnemonic", encryptionKey)
 		GreenlightInviteCode, _ := svc.cfg.Get("GreenlightInviteCode", encryptionKey)
@@ -197,12 +238,31 @@ func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) e
 		PhoenixdAddress, _ := svc.cfg.Get("PhoenixdAddress", encryptionKey)
 		PhoenixdAuthorization, _ := svc.cfg.Get("PhoenixdAuthorization", encryptionKey)
 
-		lnClient, err = phoenixd.NewPhoenixService(PhoenixdAddress, PhoenixdAuthorization)
+		lnClient, err = phoenixd.NewPhoenixService(ctx, svc.eventPublisher, PhoenixdAddress, PhoenixdAuthorization, svc.cfg.GetEnv().MempoolApi)
 	case config.CashuBackendType:
+		Mnemonic, _ := svc.cfg.Get("Mnemonic", encryptionKey)
 		cashuMintUrl, _ := svc.cfg.Get("CashuMintUrl", encryptionKey)
 		cashuWorkdir := path.Join(svc.cfg.GetEnv().Workdir, "cashu")
 
-		lnClient, err = cashu.NewCashuService(cashuWorkdir, cashuMintUrl)
+		// mints other than the default mint are needed to restore the wallet from the mnemonic
+		var cashuTrustedMintUrls []string
+		cashuTrustedMintUrlsJson, _ := svc.cfg.Get("CashuTrustedMintUrls", "")
+		if cashuTrustedMintUrlsJson != "" {
+			err = json.Unmarshal([]byte(cashuTrustedMintUrlsJson), &cashuTrustedMintUrls)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to parse trusted cashu mints")
+			}
+		}
+		saveCashuTrustedMints := func(mintUrls []string) {
+			mintUrlsJson, err := json.Marshal(mintUrls)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to serialize trusted cashu mints")
+				return
+			}
+			svc.cfg.SetUpdate("CashuTrustedMintUrls", string(mintUrlsJson), "")
+		}
+
+		lnClient, err = cashu.NewCashuService(cashuWorkdir, cashuMintUrl, cashuTrustedMintUrls, Mnemonic, saveCashuTrustedMints)
 	default:
 		logger.Logger.Fatalf("Unsupported LNBackendType: %v", lnBackend)
 	}
@@ -0,0 +1,206 @@
+package swaps
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getAlby/hub/utils"
+)
+
+const boltzCurrency = "BTC"
+
+type boltzLimits struct {
+	Maximal uint64 `json:"maximal"`
+	Minimal uint64 `json:"minimal"`
+}
+
+// boltzSubmarinePairs and boltzReversePairs are indexed by the currency sent and the currency received
+type boltzSubmarinePairs map[string]map[string]struct {
+	Limits boltzLimits `json:"limits"`
+	Fees   struct {
+		Percentage float64 `json:"percentage"`
+		MinerFees  uint64  `json:"minerFees"`
+	} `json:"fees"`
+}
+
+type boltzReversePairs map[string]map[string]struct {
+	Limits boltzLimits `json:"limits"`
+	Fees   struct {
+		Percentage float64 `json:"percentage"`
+		MinerFees  struct {
+			Lockup uint64 `json:"lockup"`
+			Claim  uint64 `json:"claim"`
+		} `json:"minerFees"`
+	} `json:"fees"`
+}
+
+type boltzSwapTree struct {
+	ClaimLeaf struct {
+		Output string `json:"output"`
+	} `json:"claimLeaf"`
+	RefundLeaf struct {
+		Output string `json:"output"`
+	} `json:"refundLeaf"`
+}
+
+type boltzCreateSubmarineSwapRequest struct {
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Invoice         string `json:"invoice"`
+	RefundPublicKey string `json:"refundPublicKey"`
+}
+
+type boltzCreateSubmarineSwapResponse struct {
+	Id                 string        `json:"id"`
+	Address            string        `json:"address"`
+	ExpectedAmount     uint64        `json:"expectedAmount"`
+	ClaimPublicKey     string        `json:"claimPublicKey"`
+	SwapTree           boltzSwapTree `json:"swapTree"`
+	TimeoutBlockHeight uint32        `json:"timeoutBlockHeight"`
+}
+
+type boltzCreateReverseSwapRequest struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	InvoiceAmount  uint64 `json:"invoiceAmount"`
+	PreimageHash   string `json:"preimageHash"`
+	ClaimPublicKey string `json:"claimPublicKey"`
+}
+
+type boltzCreateReverseSwapResponse struct {
+	Id                 string        `json:"id"`
+	Invoice            string        `json:"invoice"`
+	LockupAddress      string        `json:"lockupAddress"`
+	OnchainAmount      uint64        `json:"onchainAmount"`
+	RefundPublicKey    string        `json:"refundPublicKey"`
+	SwapTree           boltzSwapTree `json:"swapTree"`
+	TimeoutBlockHeight uint32        `json:"timeoutBlockHeight"`
+}
+
+type boltzSwapStatusResponse struct {
+	Status        string `json:"status"`
+	FailureReason string `json:"failureReason"`
+	Transaction   *struct {
+		Id string `json:"id"`
+	} `json:"transaction"`
+}
+
+// boltzSwapProvider swaps with Boltz (API v2) using taproot HTLCs
+type boltzSwapProvider struct {
+	url string
+}
+
+func NewBoltzSwapProvider(url string) *boltzSwapProvider {
+	return &boltzSwapProvider{
+		url: strings.TrimSuffix(url, "/"),
+	}
+}
+
+func (provider *boltzSwapProvider) GetInfo(ctx context.Context) (*SwapInfo, error) {
+	var submarinePairs boltzSubmarinePairs
+	err := provider.request(ctx, http.MethodGet, "/v2/swap/submarine", nil, &submarinePairs)
+	if err != nil {
+		return nil, err
+	}
+	var reversePairs boltzReversePairs
+	err = provider.request(ctx, http.MethodGet, "/v2/swap/reverse", nil, &reversePairs)
+	if err != nil {
+		return nil, err
+	}
+
+	submarinePair, ok := submarinePairs[boltzCurrency][boltzCurrency]
+	if !ok {
+		return nil, errors.New("swap provider does not support swaps in")
+	}
+	reversePair, ok := reversePairs[boltzCurrency][boltzCurrency]
+	if !ok {
+		return nil, errors.New("swap provider does not support swaps out")
+	}
+
+	return &SwapInfo{
+		MinAmountSat:         max(submarinePair.Limits.Minimal, reversePair.Limits.Minimal),
+		MaxAmountSat:         min(submarinePair.Limits.Maximal, reversePair.Limits.Maximal),
+		SwapInFeePercentage:  submarinePair.Fees.Percentage,
+		SwapOutFeePercentage: reversePair.Fees.Percentage,
+		SwapOutLockupFeeSat:  reversePair.Fees.MinerFees.Lockup,
+		SwapInClaimFeeSat:    submarinePair.Fees.MinerFees,
+	}, nil
+}
+
+func (provider *boltzSwapProvider) CreateSwapIn(ctx context.Context, request *CreateSwapInRequest) (*CreateSwapInResponse, error) {
+	var createSwapResponse boltzCreateSubmarineSwapResponse
+	err := provider.request(ctx, http.MethodPost, "/v2/swap/submarine", &boltzCreateSubmarineSwapRequest{
+		From:            boltzCurrency,
+		To:              boltzCurrency,
+		Invoice:         request.Invoice,
+		RefundPublicKey: request.RefundPubkey,
+	}, &createSwapResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateSwapInResponse{
+		Id:                 createSwapResponse.Id,
+		Address:            createSwapResponse.Address,
+		ExpectedAmountSat:  createSwapResponse.ExpectedAmount,
+		ClaimPubkey:        createSwapResponse.ClaimPublicKey,
+		ClaimLeaf:          createSwapResponse.SwapTree.ClaimLeaf.Output,
+		RefundLeaf:         createSwapResponse.SwapTree.RefundLeaf.Output,
+		TimeoutBlockHeight: createSwapResponse.TimeoutBlockHeight,
+	}, nil
+}
+
+func (provider *boltzSwapProvider) CreateSwapOut(ctx context.Context, request *CreateSwapOutRequest) (*CreateSwapOutResponse, error) {
+	var createSwapResponse boltzCreateReverseSwapResponse
+	err := provider.request(ctx, http.MethodPost, "/v2/swap/reverse", &boltzCreateReverseSwapRequest{
+		From:           boltzCurrency,
+		To:             boltzCurrency,
+		InvoiceAmount:  request.InvoiceAmountSat,
+		PreimageHash:   request.PaymentHash,
+		ClaimPublicKey: request.ClaimPubkey,
+	}, &createSwapResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateSwapOutResponse{
+		Id:                 createSwapResponse.Id,
+		Invoice:            createSwapResponse.Invoice,
+		LockupAddress:      createSwapResponse.LockupAddress,
+		OnchainAmountSat:   createSwapResponse.OnchainAmount,
+		RefundPubkey:       createSwapResponse.RefundPublicKey,
+		ClaimLeaf:          createSwapResponse.SwapTree.ClaimLeaf.Output,
+		RefundLeaf:         createSwapResponse.SwapTree.RefundLeaf.Output,
+		TimeoutBlockHeight: createSwapResponse.TimeoutBlockHeight,
+	}, nil
+}
+
+func (provider *boltzSwapProvider) GetSwapStatus(ctx context.Context, id string) (*SwapStatus, error) {
+	var swapStatusResponse boltzSwapStatusResponse
+	err := provider.request(ctx, http.MethodGet, "/v2/swap/"+url.PathEscape(id), nil, &swapStatusResponse)
+	if err != nil {
+		return nil, err
+	}
+	swapStatus := &SwapStatus{
+		Status:        swapStatusResponse.Status,
+		FailureReason: swapStatusResponse.FailureReason,
+	}
+	if swapStatusResponse.Transaction != nil {
+		swapStatus.LockupTxId = swapStatusResponse.Transaction.Id
+	}
+	return swapStatus, nil
+}
+
+func (provider *boltzSwapProvider) request(ctx context.Context, method string, path string, payload interface{}, result interface{}) error {
+	if provider.url == "" {
+		return errors.New("no swap service is available on the network of the node")
+	}
+	return utils.JsonRequest(ctx, method, provider.url+path, payload, result)
+}
+
+// swapFee returns the service fee of a swap of amount with the percentage fee of the provider
+func swapFee(amount uint64, percentage float64) uint64 {
+	return uint64(math.Ceil(float64(amount) * percentage / 100))
+}
@@ -0,0 +1,107 @@
+package swaps
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/getAlby/hub/utils"
+)
+
+// fee rate in sat/vB used if the Esplora server does not return estimates
+const defaultFeeRate = 2
+
+// the claim and refund transactions should confirm within this number of blocks
+const feeEstimateTarget = "6"
+
+type esploraUtxo struct {
+	TxId  string `json:"txid"`
+	Vout  uint32 `json:"vout"`
+	Value int64  `json:"value"`
+}
+
+type esploraTx struct {
+	TxId string `json:"txid"`
+	Vin  []struct {
+		TxId    string `json:"txid"`
+		Vout    uint32 `json:"vout"`
+		Prevout struct {
+			Value int64 `json:"value"`
+		} `json:"prevout"`
+	} `json:"vin"`
+	Status struct {
+		Confirmed bool `json:"confirmed"`
+	} `json:"status"`
+}
+
+// esploraClient broadcasts the claim and refund transactions via the Esplora server used by the node
+type esploraClient struct {
+	url string
+}
+
+func newEsploraClient(url string) *esploraClient {
+	return &esploraClient{
+		url: strings.TrimSuffix(url, "/"),
+	}
+}
+
+func (client *esploraClient) GetTipHeight(ctx context.Context) (uint32, error) {
+	body, err := utils.HttpRequest(ctx, http.MethodGet, client.url+"/blocks/tip/height", nil, "")
+	if err != nil {
+		return 0, err
+	}
+	height, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(height), nil
+}
+
+func (client *esploraClient) GetFeeRate(ctx context.Context) uint64 {
+	feeEstimates := map[string]float64{}
+	err := utils.JsonRequest(ctx, http.MethodGet, client.url+"/fee-estimates", nil, &feeEstimates)
+	if err != nil || feeEstimates[feeEstimateTarget] == 0 {
+		return defaultFeeRate
+	}
+	return uint64(math.Ceil(feeEstimates[feeEstimateTarget]))
+}
+
+// GetUtxos returns the unspent outputs of an address, including unconfirmed ones
+func (client *esploraClient) GetUtxos(ctx context.Context, address string) ([]esploraUtxo, error) {
+	utxos := []esploraUtxo{}
+	err := utils.JsonRequest(ctx, http.MethodGet, client.url+"/address/"+address+"/utxo", nil, &utxos)
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// GetTx returns the inputs and the confirmation status of a transaction
+func (client *esploraClient) GetTx(ctx context.Context, txId string) (*esploraTx, error) {
+	var tx esploraTx
+	err := utils.JsonRequest(ctx, http.MethodGet, client.url+"/tx/"+txId, nil, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (client *esploraClient) Broadcast(ctx context.Context, tx *wire.MsgTx) (string, error) {
+	var buf bytes.Buffer
+	err := tx.Serialize(&buf)
+	if err != nil {
+		return "", err
+	}
+	body, err := utils.HttpRequest(ctx, http.MethodPost, client.url+"/tx", []byte(hex.EncodeToString(buf.Bytes())), "text/plain")
+	if err != nil {
+		return "", fmt.Errorf("failed to broadcast transaction: %w", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
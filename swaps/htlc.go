@@ -0,0 +1,257 @@
+package swaps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/ripemd160" //nolint:staticcheck
+)
+
+// virtual size of the P2WPKH/P2TR output of a claim or refund transaction
+const htlcSpendTxOutputVsize = 43
+
+const dustLimitSat = 546
+
+// claim and refund transactions signal replaceability (BIP125) so their fee can be bumped.
+// The lock time of refund transactions is only enforced if the sequence is not final.
+const htlcSpendInputSequence = wire.MaxTxInSequenceNum - 2
+
+// SwapTree is the taproot script tree of a swap. The key path is a MuSig2 aggregate of the keys
+// of the provider and the hub, which is not used: the hub always spends via the script path,
+// so swaps never depend on the cooperation of the provider.
+type SwapTree struct {
+	ClaimLeaf  txscript.TapLeaf
+	RefundLeaf txscript.TapLeaf
+}
+
+// HTLC is the P2TR output the funds of a swap are locked in
+type HTLC struct {
+	Tree        *SwapTree
+	InternalKey *btcec.PublicKey
+	Address     btcutil.Address
+	PkScript    []byte
+	scriptTree  *txscript.IndexedTapScriptTree
+}
+
+func hash160(paymentHash []byte) []byte {
+	hasher := ripemd160.New()
+	hasher.Write(paymentHash)
+	return hasher.Sum(nil)
+}
+
+func refundLeaf(refundPubkey *btcec.PublicKey, timeoutBlockHeight uint32) (txscript.TapLeaf, error) {
+	script, err := txscript.NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(refundPubkey)).
+		AddOp(txscript.OP_CHECKSIGVERIFY).
+		AddInt64(int64(timeoutBlockHeight)).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		Script()
+	if err != nil {
+		return txscript.TapLeaf{}, err
+	}
+	return txscript.NewBaseTapLeaf(script), nil
+}
+
+// SwapInTree is the script tree of a swap in (submarine swap): the provider claims the funds with
+// the preimage of the invoice it paid, otherwise the user can refund them after the timeout
+//
+//	claim:  OP_HASH160 <ripemd160(paymentHash)> OP_EQUALVERIFY <claimPubkey> OP_CHECKSIG
+//	refund: <refundPubkey> OP_CHECKSIGVERIFY <timeout> OP_CHECKLOCKTIMEVERIFY
+func SwapInTree(paymentHash []byte, claimPubkey *btcec.PublicKey, refundPubkey *btcec.PublicKey, timeoutBlockHeight uint32) (*SwapTree, error) {
+	claimScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(hash160(paymentHash)).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddData(schnorr.SerializePubKey(claimPubkey)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return nil, err
+	}
+	refundLeaf, err := refundLeaf(refundPubkey, timeoutBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &SwapTree{
+		ClaimLeaf:  txscript.NewBaseTapLeaf(claimScript),
+		RefundLeaf: refundLeaf,
+	}, nil
+}
+
+// SwapOutTree is the script tree of a swap out (reverse submarine swap): the user claims the funds with
+// the preimage of the invoice they paid, otherwise the provider can refund them after the timeout
+//
+//	claim:  OP_SIZE 32 OP_EQUALVERIFY OP_HASH160 <ripemd160(paymentHash)> OP_EQUALVERIFY <claimPubkey> OP_CHECKSIG
+//	refund: <refundPubkey> OP_CHECKSIGVERIFY <timeout> OP_CHECKLOCKTIMEVERIFY
+func SwapOutTree(paymentHash []byte, claimPubkey *btcec.PublicKey, refundPubkey *btcec.PublicKey, timeoutBlockHeight uint32) (*SwapTree, error) {
+	claimScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_SIZE).
+		AddInt64(32).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_HASH160).
+		AddData(hash160(paymentHash)).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddData(schnorr.SerializePubKey(claimPubkey)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return nil, err
+	}
+	refundLeaf, err := refundLeaf(refundPubkey, timeoutBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &SwapTree{
+		ClaimLeaf:  txscript.NewBaseTapLeaf(claimScript),
+		RefundLeaf: refundLeaf,
+	}, nil
+}
+
+// NewHTLC creates the output of a script tree. The internal key aggregates the key of the provider first.
+func NewHTLC(tree *SwapTree, providerPubkey *btcec.PublicKey, pubkey *btcec.PublicKey, params *chaincfg.Params) (*HTLC, error) {
+	aggregateKey, _, _, err := musig2.AggregateKeys([]*btcec.PublicKey{providerPubkey, pubkey}, false)
+	if err != nil {
+		return nil, err
+	}
+	internalKey := aggregateKey.PreTweakedKey
+
+	scriptTree := txscript.AssembleTaprootScriptTree(tree.ClaimLeaf, tree.RefundLeaf)
+	rootHash := scriptTree.RootNode.TapHash()
+	outputKey := txscript.ComputeTaprootOutputKey(internalKey, rootHash[:])
+
+	address, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, err
+	}
+	return &HTLC{
+		Tree:        tree,
+		InternalKey: internalKey,
+		Address:     address,
+		PkScript:    pkScript,
+		scriptTree:  scriptTree,
+	}, nil
+}
+
+// VerifyHTLC checks that the leaves returned by the provider match the expected swap tree
+// and that the lockup address commits to them
+func VerifyHTLC(claimLeaf []byte, refundLeaf []byte, address string, expectedTree *SwapTree, providerPubkey *btcec.PublicKey, pubkey *btcec.PublicKey, params *chaincfg.Params) (*HTLC, error) {
+	if !bytes.Equal(claimLeaf, expectedTree.ClaimLeaf.Script) || !bytes.Equal(refundLeaf, expectedTree.RefundLeaf.Script) {
+		return nil, errors.New("swap tree does not match the swap parameters")
+	}
+	htlc, err := NewHTLC(expectedTree, providerPubkey, pubkey, params)
+	if err != nil {
+		return nil, err
+	}
+	if htlc.Address.EncodeAddress() != address {
+		return nil, fmt.Errorf("lockup address %s does not match the swap tree", address)
+	}
+	return htlc, nil
+}
+
+func (htlc *HTLC) controlBlock(leaf txscript.TapLeaf) ([]byte, error) {
+	proofIndex, ok := htlc.scriptTree.LeafProofIndex[leaf.TapHash()]
+	if !ok {
+		return nil, errors.New("leaf is not part of the swap tree")
+	}
+	controlBlock := htlc.scriptTree.LeafMerkleProofs[proofIndex].ToControlBlock(htlc.InternalKey)
+	return controlBlock.ToBytes()
+}
+
+// HTLCOutput is an unspent output locked in the HTLC
+type HTLCOutput struct {
+	OutPoint wire.OutPoint
+	Value    int64
+}
+
+// NewHTLCSpendTx creates a transaction which sends all outputs locked in the HTLC to the destination address
+// via the script path of the leaf. The witness of each input is created by witnessFn from the signature.
+// Claim transactions use a lock time of 0, refund transactions the timeout of the HTLC.
+func NewHTLCSpendTx(htlc *HTLC, leaf txscript.TapLeaf, outputs []HTLCOutput, destination string, feeRate uint64, lockTime uint32, privateKey *btcec.PrivateKey, witnessFn func(signature []byte, controlBlock []byte) wire.TxWitness, params *chaincfg.Params) (*wire.MsgTx, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("no HTLC outputs to spend")
+	}
+
+	destinationAddress, err := btcutil.DecodeAddress(destination, params)
+	if err != nil {
+		return nil, err
+	}
+	destinationScript, err := txscript.PayToAddrScript(destinationAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	controlBlock, err := htlc.controlBlock(leaf)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.LockTime = lockTime
+	prevOutputFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	var total int64
+	for _, output := range outputs {
+		txIn := wire.NewTxIn(&output.OutPoint, nil, nil)
+		txIn.Sequence = htlcSpendInputSequence
+		// a schnorr signature is 64 bytes, which allows to calculate the size of the transaction before signing
+		txIn.Witness = witnessFn(make([]byte, schnorr.SignatureSize), controlBlock)
+		tx.AddTxIn(txIn)
+		prevOutputFetcher.AddPrevOut(output.OutPoint, wire.NewTxOut(output.Value, htlc.PkScript))
+		total += output.Value
+	}
+
+	weight := tx.SerializeSizeStripped()*3 + tx.SerializeSize()
+	vsize := (weight+3)/4 + htlcSpendTxOutputVsize
+	fee := int64(feeRate) * int64(vsize)
+	if total-fee < dustLimitSat {
+		return nil, fmt.Errorf("HTLC amount of %d sats does not cover the fee of %d sats", total, fee)
+	}
+	tx.AddTxOut(wire.NewTxOut(total-fee, destinationScript))
+
+	sigHashes := txscript.NewTxSigHashes(tx, prevOutputFetcher)
+	for i, output := range outputs {
+		signature, err := txscript.RawTxInTapscriptSignature(tx, sigHashes, i, output.Value, htlc.PkScript, leaf, txscript.SigHashDefault, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		tx.TxIn[i].Witness = witnessFn(signature, controlBlock)
+	}
+
+	return tx, nil
+}
+
+// NewClaimTx spends the HTLC of a swap out with the preimage
+func NewClaimTx(htlc *HTLC, outputs []HTLCOutput, destination string, feeRate uint64, preimage []byte, privateKey *btcec.PrivateKey, params *chaincfg.Params) (*wire.MsgTx, error) {
+	leaf := htlc.Tree.ClaimLeaf
+	return NewHTLCSpendTx(htlc, leaf, outputs, destination, feeRate, 0, privateKey, func(signature []byte, controlBlock []byte) wire.TxWitness {
+		return wire.TxWitness{signature, preimage, leaf.Script, controlBlock}
+	}, params)
+}
+
+// NewRefundTx spends the HTLC of a swap in after the timeout
+func NewRefundTx(htlc *HTLC, outputs []HTLCOutput, destination string, feeRate uint64, timeoutBlockHeight uint32, privateKey *btcec.PrivateKey, params *chaincfg.Params) (*wire.MsgTx, error) {
+	leaf := htlc.Tree.RefundLeaf
+	return NewHTLCSpendTx(htlc, leaf, outputs, destination, feeRate, timeoutBlockHeight, privateKey, func(signature []byte, controlBlock []byte) wire.TxWitness {
+		return wire.TxWitness{signature, leaf.Script, controlBlock}
+	}, params)
+}
+
+func parseOutPoint(txId string, vout uint32) (*wire.OutPoint, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewOutPoint(hash, vout), nil
+}
@@ -0,0 +1,98 @@
+package swaps
+
+import (
+	"context"
+)
+
+const (
+	// onchain to Lightning (submarine swap)
+	SWAP_TYPE_IN = "in"
+	// Lightning to onchain (reverse submarine swap)
+	SWAP_TYPE_OUT = "out"
+)
+
+const (
+	SWAP_STATE_PENDING   = "pending"
+	SWAP_STATE_COMPLETED = "completed"
+	SWAP_STATE_FAILED    = "failed"
+	SWAP_STATE_REFUNDED  = "refunded"
+)
+
+// provider swap statuses
+const (
+	SWAP_STATUS_CREATED               = "swap.created"
+	SWAP_STATUS_EXPIRED               = "swap.expired"
+	SWAP_STATUS_INVOICE_SET           = "invoice.set"
+	SWAP_STATUS_INVOICE_PENDING       = "invoice.pending"
+	SWAP_STATUS_INVOICE_PAID          = "invoice.paid"
+	SWAP_STATUS_INVOICE_SETTLED       = "invoice.settled"
+	SWAP_STATUS_INVOICE_EXPIRED       = "invoice.expired"
+	SWAP_STATUS_INVOICE_FAILED_TO_PAY = "invoice.failedToPay"
+	SWAP_STATUS_TX_MEMPOOL            = "transaction.mempool"
+	SWAP_STATUS_TX_CONFIRMED          = "transaction.confirmed"
+	SWAP_STATUS_TX_CLAIM_PENDING      = "transaction.claim.pending"
+	SWAP_STATUS_TX_CLAIMED            = "transaction.claimed"
+	SWAP_STATUS_TX_FAILED             = "transaction.failed"
+	SWAP_STATUS_TX_REFUNDED           = "transaction.refunded"
+	SWAP_STATUS_TX_LOCKUP_FAILED      = "transaction.lockupFailed"
+)
+
+type SwapInfo struct {
+	MinAmountSat uint64 `json:"minAmountSat"`
+	MaxAmountSat uint64 `json:"maxAmountSat"`
+	// service fee of swaps in and out in percent
+	SwapInFeePercentage  float64 `json:"swapInFeePercentage"`
+	SwapOutFeePercentage float64 `json:"swapOutFeePercentage"`
+	// miner fees paid by the provider to lock the funds of a swap out, charged to the user
+	SwapOutLockupFeeSat uint64 `json:"swapOutLockupFeeSat"`
+	// miner fees paid by the provider to claim the funds of a swap in, charged to the user
+	SwapInClaimFeeSat uint64 `json:"swapInClaimFeeSat"`
+}
+
+type CreateSwapInRequest struct {
+	Invoice      string
+	RefundPubkey string
+}
+
+// leaves of the swap tree are hex encoded scripts, pubkeys hex encoded compressed keys
+type CreateSwapInResponse struct {
+	Id                 string
+	Address            string
+	ExpectedAmountSat  uint64
+	ClaimPubkey        string
+	ClaimLeaf          string
+	RefundLeaf         string
+	TimeoutBlockHeight uint32
+}
+
+type CreateSwapOutRequest struct {
+	InvoiceAmountSat uint64
+	PaymentHash      string
+	ClaimPubkey      string
+}
+
+type CreateSwapOutResponse struct {
+	Id                 string
+	Invoice            string
+	LockupAddress      string
+	OnchainAmountSat   uint64
+	RefundPubkey       string
+	ClaimLeaf          string
+	RefundLeaf         string
+	TimeoutBlockHeight uint32
+}
+
+type SwapStatus struct {
+	Status        string
+	FailureReason string
+	// lockup transaction of a swap out, once the provider sent it
+	LockupTxId string
+}
+
+// SwapProvider is a swap service the hub swaps with, e.g. Boltz
+type SwapProvider interface {
+	GetInfo(ctx context.Context) (*SwapInfo, error)
+	CreateSwapIn(ctx context.Context, request *CreateSwapInRequest) (*CreateSwapInResponse, error)
+	CreateSwapOut(ctx context.Context, request *CreateSwapOutRequest) (*CreateSwapOutResponse, error)
+	GetSwapStatus(ctx context.Context, id string) (*SwapStatus, error)
+}
@@ -0,0 +1,829 @@
+package swaps
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/service/keys"
+	"github.com/getAlby/hub/transactions"
+)
+
+const swapPollingInterval = 30 * time.Second
+
+type swapsService struct {
+	db                  *gorm.DB
+	keys                keys.Keys
+	eventPublisher      events.EventPublisher
+	transactionsService transactions.TransactionsService
+	provider            SwapProvider
+	esplora             *esploraClient
+	// swaps are processed one at a time so funds are not claimed or refunded twice
+	processMutex sync.Mutex
+	// swaps out whose hold invoice is being paid
+	payingSwaps      map[string]bool
+	payingSwapsMutex sync.Mutex
+}
+
+type SwapsService interface {
+	GetInfo(ctx context.Context) (*SwapInfo, error)
+	// sends amount onchain to the swap provider which pays an invoice of the node
+	SwapIn(ctx context.Context, lnClient lnclient.LNClient, amount uint64) (*db.Swap, error)
+	// pays a swap provider invoice of amount and claims the onchain funds to the destination
+	// address, or a new address of the onchain wallet if no destination is given
+	SwapOut(ctx context.Context, lnClient lnclient.LNClient, amount uint64, destination string) (*db.Swap, error)
+	ListSwaps(limit, offset uint64) ([]db.Swap, error)
+	GetSwap(swapId string) (*db.Swap, error)
+	// polls the provider for the status of all pending swaps, pays the invoices of swaps out,
+	// claims or refunds their funds and bumps the fee of unconfirmed claim and refund transactions
+	ProcessSwaps(ctx context.Context, lnClient lnclient.LNClient)
+	Start(ctx context.Context, lnClient lnclient.LNClient)
+}
+
+func NewSwapsService(db *gorm.DB, keys keys.Keys, eventPublisher events.EventPublisher, transactionsService transactions.TransactionsService, provider SwapProvider, esploraUrl string) *swapsService {
+	return &swapsService{
+		db:                  db,
+		keys:                keys,
+		eventPublisher:      eventPublisher,
+		transactionsService: transactionsService,
+		provider:            provider,
+		esplora:             newEsploraClient(esploraUrl),
+		payingSwaps:         map[string]bool{},
+	}
+}
+
+func (svc *swapsService) GetInfo(ctx context.Context) (*SwapInfo, error) {
+	return svc.provider.GetInfo(ctx)
+}
+
+func (svc *swapsService) SwapIn(ctx context.Context, lnClient lnclient.LNClient, amount uint64) (*db.Swap, error) {
+	swapInfo, err := svc.getSwapInfo(ctx, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := getNetworkParams(ctx, lnClient)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := svc.transactionsService.MakeInvoice(ctx, int64(amount*1000), "Swap in", "", 0, nil, lnClient, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	paymentHash, err := hex.DecodeString(transaction.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	refundKey := svc.getSwapKey(paymentHash)
+
+	swapInResponse, err := svc.provider.CreateSwapIn(ctx, &CreateSwapInRequest{
+		Invoice:      transaction.PaymentRequest,
+		RefundPubkey: hex.EncodeToString(refundKey.PubKey().SerializeCompressed()),
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create swap in")
+		return nil, err
+	}
+
+	providerPubkey, err := parsePubkey(swapInResponse.ClaimPubkey)
+	if err != nil {
+		return nil, err
+	}
+	expectedTree, err := SwapInTree(paymentHash, providerPubkey, refundKey.PubKey(), swapInResponse.TimeoutBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyHTLC(swapInResponse.ClaimLeaf, swapInResponse.RefundLeaf, swapInResponse.Address, expectedTree, providerPubkey, refundKey.PubKey(), params)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swapInResponse.Id).Error("Invalid swap in HTLC")
+		return nil, err
+	}
+
+	maxExpectedAmount := amount + swapFee(amount, swapInfo.SwapInFeePercentage) + swapInfo.SwapInClaimFeeSat
+	if swapInResponse.ExpectedAmountSat > maxExpectedAmount {
+		return nil, fmt.Errorf("swap provider expects %d sats which is more than the quoted %d sats", swapInResponse.ExpectedAmountSat, maxExpectedAmount)
+	}
+
+	swap := &db.Swap{
+		SwapId:             swapInResponse.Id,
+		Type:               SWAP_TYPE_IN,
+		State:              SWAP_STATE_PENDING,
+		ProviderStatus:     SWAP_STATUS_CREATED,
+		AmountSat:          amount,
+		OnchainAmountSat:   swapInResponse.ExpectedAmountSat,
+		PaymentHash:        transaction.PaymentHash,
+		Invoice:            transaction.PaymentRequest,
+		LockupAddress:      swapInResponse.Address,
+		ProviderPubkey:     swapInResponse.ClaimPubkey,
+		ClaimLeaf:          swapInResponse.ClaimLeaf,
+		RefundLeaf:         swapInResponse.RefundLeaf,
+		TimeoutBlockHeight: swapInResponse.TimeoutBlockHeight,
+	}
+	err = svc.db.Create(swap).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lockupTxId, err := lnClient.RedeemOnchainFunds(ctx, swap.LockupAddress, swap.OnchainAmountSat, false)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swap.SwapId).Error("Failed to send swap in lockup transaction")
+		svc.markSwapFailed(swap, fmt.Sprintf("failed to send lockup transaction: %v", err))
+		return nil, err
+	}
+
+	swap.LockupTxId = lockupTxId
+	err = svc.db.Model(swap).Update("lockup_tx_id", lockupTxId).Error
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":      swap.SwapId,
+		"amount":       swap.AmountSat,
+		"lockup_tx_id": lockupTxId,
+	}).Info("Created swap in")
+
+	return swap, nil
+}
+
+func (svc *swapsService) SwapOut(ctx context.Context, lnClient lnclient.LNClient, amount uint64, destination string) (*db.Swap, error) {
+	swapInfo, err := svc.getSwapInfo(ctx, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := getNetworkParams(ctx, lnClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if destination == "" {
+		destination, err = lnClient.GetNewOnchainAddress(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = btcutil.DecodeAddress(destination, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination address: %v", err)
+	}
+
+	preimage := make([]byte, 32)
+	_, err = rand.Read(preimage)
+	if err != nil {
+		return nil, err
+	}
+	paymentHash := sha256.Sum256(preimage)
+
+	claimKey := svc.getSwapKey(paymentHash[:])
+
+	swapOutResponse, err := svc.provider.CreateSwapOut(ctx, &CreateSwapOutRequest{
+		InvoiceAmountSat: amount,
+		PaymentHash:      hex.EncodeToString(paymentHash[:]),
+		ClaimPubkey:      hex.EncodeToString(claimKey.PubKey().SerializeCompressed()),
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create swap out")
+		return nil, err
+	}
+
+	paymentRequest, err := decodepay.Decodepay(swapOutResponse.Invoice)
+	if err != nil {
+		return nil, err
+	}
+	if paymentRequest.PaymentHash != hex.EncodeToString(paymentHash[:]) {
+		return nil, errors.New("swap out invoice does not match the payment hash")
+	}
+	if uint64(paymentRequest.MSatoshi) != amount*1000 {
+		return nil, fmt.Errorf("swap out invoice amount %d msat does not match the requested amount", paymentRequest.MSatoshi)
+	}
+
+	providerPubkey, err := parsePubkey(swapOutResponse.RefundPubkey)
+	if err != nil {
+		return nil, err
+	}
+	expectedTree, err := SwapOutTree(paymentHash[:], claimKey.PubKey(), providerPubkey, swapOutResponse.TimeoutBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyHTLC(swapOutResponse.ClaimLeaf, swapOutResponse.RefundLeaf, swapOutResponse.LockupAddress, expectedTree, providerPubkey, claimKey.PubKey(), params)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swapOutResponse.Id).Error("Invalid swap out HTLC")
+		return nil, err
+	}
+
+	minOnchainAmount := int64(amount) - int64(swapFee(amount, swapInfo.SwapOutFeePercentage)) - int64(swapInfo.SwapOutLockupFeeSat)
+	if int64(swapOutResponse.OnchainAmountSat) < minOnchainAmount {
+		return nil, fmt.Errorf("swap provider locks %d sats which is less than the quoted %d sats", swapOutResponse.OnchainAmountSat, minOnchainAmount)
+	}
+
+	swap := &db.Swap{
+		SwapId:             swapOutResponse.Id,
+		Type:               SWAP_TYPE_OUT,
+		State:              SWAP_STATE_PENDING,
+		ProviderStatus:     SWAP_STATUS_CREATED,
+		AmountSat:          amount,
+		OnchainAmountSat:   swapOutResponse.OnchainAmountSat,
+		PaymentHash:        hex.EncodeToString(paymentHash[:]),
+		Preimage:           hex.EncodeToString(preimage),
+		Invoice:            swapOutResponse.Invoice,
+		LockupAddress:      swapOutResponse.LockupAddress,
+		ProviderPubkey:     swapOutResponse.RefundPubkey,
+		ClaimLeaf:          swapOutResponse.ClaimLeaf,
+		RefundLeaf:         swapOutResponse.RefundLeaf,
+		TimeoutBlockHeight: swapOutResponse.TimeoutBlockHeight,
+		DestinationAddress: destination,
+	}
+	err = svc.db.Create(swap).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// the payment is resumed by ProcessSwaps if the hub stops before it is made
+	err = svc.paySwapOutInvoice(lnClient, swap)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":     swap.SwapId,
+		"amount":      swap.AmountSat,
+		"destination": destination,
+	}).Info("Created swap out")
+
+	return swap, nil
+}
+
+func (svc *swapsService) ListSwaps(limit, offset uint64) ([]db.Swap, error) {
+	swaps := []db.Swap{}
+	query := svc.db.Order("created_at desc")
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if offset > 0 {
+		query = query.Offset(int(offset))
+	}
+	err := query.Find(&swaps).Error
+	if err != nil {
+		return nil, err
+	}
+	return swaps, nil
+}
+
+func (svc *swapsService) GetSwap(swapId string) (*db.Swap, error) {
+	var swap db.Swap
+	result := svc.db.Limit(1).Find(&swap, &db.Swap{SwapId: swapId})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("swap not found")
+	}
+	return &swap, nil
+}
+
+func (svc *swapsService) Start(ctx context.Context, lnClient lnclient.LNClient) {
+	go func() {
+		// resume swaps which were pending when the hub stopped
+		svc.ProcessSwaps(ctx, lnClient)
+
+		ticker := time.NewTicker(swapPollingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.ProcessSwaps(ctx, lnClient)
+			}
+		}
+	}()
+}
+
+func (svc *swapsService) ProcessSwaps(ctx context.Context, lnClient lnclient.LNClient) {
+	svc.processMutex.Lock()
+	defer svc.processMutex.Unlock()
+
+	swaps := []db.Swap{}
+	err := svc.db.Where(&db.Swap{State: SWAP_STATE_PENDING}).Find(&swaps).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch pending swaps")
+		return
+	}
+	if len(swaps) == 0 {
+		return
+	}
+
+	params, err := getNetworkParams(ctx, lnClient)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get network for swaps")
+		return
+	}
+
+	for _, swap := range swaps {
+		err := svc.processSwap(ctx, lnClient, &swap, params)
+		if err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"swap_id": swap.SwapId,
+				"type":    swap.Type,
+			}).Error("Failed to process swap")
+		}
+	}
+}
+
+func (svc *swapsService) processSwap(ctx context.Context, lnClient lnclient.LNClient, swap *db.Swap, params *chaincfg.Params) error {
+	swapStatus, err := svc.provider.GetSwapStatus(ctx, swap.SwapId)
+	if err != nil {
+		return err
+	}
+
+	if swapStatus.Status != swap.ProviderStatus {
+		logger.Logger.WithFields(logrus.Fields{
+			"swap_id": swap.SwapId,
+			"status":  swapStatus.Status,
+		}).Info("Swap status updated")
+		swap.ProviderStatus = swapStatus.Status
+		err = svc.db.Model(swap).Update("provider_status", swap.ProviderStatus).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	switch swap.Type {
+	case SWAP_TYPE_OUT:
+		if swapStatus.LockupTxId != "" && swap.LockupTxId == "" {
+			swap.LockupTxId = swapStatus.LockupTxId
+			err = svc.db.Model(swap).Update("lockup_tx_id", swap.LockupTxId).Error
+			if err != nil {
+				return err
+			}
+		}
+		if swap.ClaimTxId != "" {
+			// the swap completes once the claim transaction confirms
+			return svc.claimSwapOut(ctx, swap, params)
+		}
+		switch swapStatus.Status {
+		case SWAP_STATUS_CREATED:
+			return svc.checkSwapOutPayment(lnClient, swap)
+		case SWAP_STATUS_TX_MEMPOOL, SWAP_STATUS_TX_CONFIRMED:
+			return svc.claimSwapOut(ctx, swap, params)
+		case SWAP_STATUS_EXPIRED, SWAP_STATUS_INVOICE_EXPIRED, SWAP_STATUS_TX_FAILED, SWAP_STATUS_TX_REFUNDED:
+			svc.markSwapFailed(swap, failureReason(swapStatus))
+		}
+	case SWAP_TYPE_IN:
+		if swap.ClaimTxId != "" {
+			// the swap is refunded once the refund transaction confirms
+			return svc.refundSwapIn(ctx, lnClient, swap, swap.FailureReason, params)
+		}
+		switch swapStatus.Status {
+		case SWAP_STATUS_INVOICE_PAID, SWAP_STATUS_TX_CLAIM_PENDING, SWAP_STATUS_TX_CLAIMED:
+			svc.markSwapCompleted(swap)
+		case SWAP_STATUS_INVOICE_FAILED_TO_PAY, SWAP_STATUS_TX_LOCKUP_FAILED, SWAP_STATUS_EXPIRED:
+			return svc.refundSwapIn(ctx, lnClient, swap, failureReason(swapStatus), params)
+		}
+	}
+	return nil
+}
+
+// checkSwapOutPayment fails a swap out whose invoice could not be paid,
+// and pays the invoice if no payment was made yet (e.g. the hub stopped before paying it)
+func (svc *swapsService) checkSwapOutPayment(lnClient lnclient.LNClient, swap *db.Swap) error {
+	svc.payingSwapsMutex.Lock()
+	paying := svc.payingSwaps[swap.SwapId]
+	svc.payingSwapsMutex.Unlock()
+	if paying {
+		return nil
+	}
+
+	var transaction db.Transaction
+	result := svc.db.Limit(1).Order("created_at desc").Find(&transaction, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash: swap.PaymentHash,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return svc.paySwapOutInvoice(lnClient, swap)
+	}
+	if transaction.State == constants.TRANSACTION_STATE_FAILED {
+		svc.markSwapFailed(swap, fmt.Sprintf("failed to pay swap invoice: %s", transaction.FailureReason))
+	}
+	return nil
+}
+
+// paySwapOutInvoice pays the hold invoice of a swap out, which only settles once the onchain funds are claimed
+func (svc *swapsService) paySwapOutInvoice(lnClient lnclient.LNClient, swap *db.Swap) error {
+	svc.payingSwapsMutex.Lock()
+	defer svc.payingSwapsMutex.Unlock()
+	if svc.payingSwaps[swap.SwapId] {
+		return nil
+	}
+	svc.payingSwaps[swap.SwapId] = true
+
+	go func() {
+		defer func() {
+			svc.payingSwapsMutex.Lock()
+			defer svc.payingSwapsMutex.Unlock()
+			delete(svc.payingSwaps, swap.SwapId)
+		}()
+		_, err := svc.transactionsService.SendPaymentSync(context.Background(), swap.Invoice, lnClient, nil, nil)
+		if err != nil {
+			// the swap is failed by the next run of ProcessSwaps
+			logger.Logger.WithError(err).WithField("swap_id", swap.SwapId).Error("Failed to pay swap out invoice")
+		}
+	}()
+	return nil
+}
+
+func (svc *swapsService) claimSwapOut(ctx context.Context, swap *db.Swap, params *chaincfg.Params) error {
+	htlc, err := svc.getHTLC(swap, params)
+	if err != nil {
+		return err
+	}
+	preimage, err := hex.DecodeString(swap.Preimage)
+	if err != nil {
+		return err
+	}
+	paymentHash, err := hex.DecodeString(swap.PaymentHash)
+	if err != nil {
+		return err
+	}
+	newClaimTx := func(outputs []HTLCOutput, feeRate uint64) (*wire.MsgTx, error) {
+		return NewClaimTx(htlc, outputs, swap.DestinationAddress, feeRate, preimage, svc.getSwapKey(paymentHash), params)
+	}
+
+	if swap.ClaimTxId != "" {
+		confirmed, err := svc.confirmHTLCSpend(ctx, swap, newClaimTx)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			svc.markSwapCompleted(swap)
+		}
+		return nil
+	}
+
+	outputs, err := svc.getHTLCOutputs(ctx, swap)
+	if err != nil {
+		return err
+	}
+	if len(outputs) == 0 {
+		// the lockup transaction is not visible to the Esplora server yet
+		return nil
+	}
+
+	// the claim transaction reveals the preimage which settles the hold invoice,
+	// so it is only sent once the provider locked the quoted amount
+	var lockedAmountSat int64
+	for _, output := range outputs {
+		lockedAmountSat += output.Value
+	}
+	if lockedAmountSat < int64(swap.OnchainAmountSat) {
+		logger.Logger.WithFields(logrus.Fields{
+			"swap_id":         swap.SwapId,
+			"locked_amount":   lockedAmountSat,
+			"expected_amount": swap.OnchainAmountSat,
+		}).Warn("Swap provider locked less than the quoted amount, not claiming swap out")
+		return nil
+	}
+
+	err = svc.broadcastHTLCSpend(ctx, swap, outputs, newClaimTx)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":     swap.SwapId,
+		"claim_tx_id": swap.ClaimTxId,
+	}).Info("Claimed swap out")
+	return nil
+}
+
+func (svc *swapsService) refundSwapIn(ctx context.Context, lnClient lnclient.LNClient, swap *db.Swap, reason string, params *chaincfg.Params) error {
+	if swap.FailureReason != reason {
+		swap.FailureReason = reason
+		err := svc.db.Model(swap).Update("failure_reason", reason).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	htlc, err := svc.getHTLC(swap, params)
+	if err != nil {
+		return err
+	}
+	paymentHash, err := hex.DecodeString(swap.PaymentHash)
+	if err != nil {
+		return err
+	}
+	newRefundTx := func(outputs []HTLCOutput, feeRate uint64) (*wire.MsgTx, error) {
+		return NewRefundTx(htlc, outputs, swap.DestinationAddress, feeRate, swap.TimeoutBlockHeight, svc.getSwapKey(paymentHash), params)
+	}
+
+	if swap.ClaimTxId != "" {
+		confirmed, err := svc.confirmHTLCSpend(ctx, swap, newRefundTx)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			svc.markSwapRefunded(swap)
+		}
+		return nil
+	}
+
+	tipHeight, err := svc.esplora.GetTipHeight(ctx)
+	if err != nil {
+		return err
+	}
+	if tipHeight < swap.TimeoutBlockHeight {
+		// the funds can only be refunded after the timeout
+		return nil
+	}
+
+	outputs, err := svc.getHTLCOutputs(ctx, swap)
+	if err != nil {
+		return err
+	}
+	if len(outputs) == 0 {
+		// nothing was locked, or the funds were already refunded
+		svc.markSwapFailed(swap, reason)
+		return nil
+	}
+
+	if swap.DestinationAddress == "" {
+		swap.DestinationAddress, err = lnClient.GetNewOnchainAddress(ctx)
+		if err != nil {
+			return err
+		}
+		err = svc.db.Model(swap).Update("destination_address", swap.DestinationAddress).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err = svc.broadcastHTLCSpend(ctx, swap, outputs, newRefundTx)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":      swap.SwapId,
+		"refund_tx_id": swap.ClaimTxId,
+	}).Info("Sent swap in refund transaction")
+	return nil
+}
+
+// broadcastHTLCSpend sends the claim or refund transaction of a swap with the current fee estimate
+func (svc *swapsService) broadcastHTLCSpend(ctx context.Context, swap *db.Swap, outputs []HTLCOutput, newSpendTx func(outputs []HTLCOutput, feeRate uint64) (*wire.MsgTx, error)) error {
+	feeRate := svc.esplora.GetFeeRate(ctx)
+	spendTx, err := newSpendTx(outputs, feeRate)
+	if err != nil {
+		return err
+	}
+
+	spendTxId, err := svc.esplora.Broadcast(ctx, spendTx)
+	if err != nil {
+		return err
+	}
+
+	swap.ClaimTxId = spendTxId
+	swap.ClaimFeeRate = feeRate
+	return svc.db.Model(swap).Updates(map[string]interface{}{
+		"claim_tx_id":    spendTxId,
+		"claim_fee_rate": feeRate,
+	}).Error
+}
+
+// confirmHTLCSpend returns whether the claim or refund transaction of a swap confirmed.
+// An unconfirmed transaction is replaced (RBF) if the fee estimate rose since it was sent.
+func (svc *swapsService) confirmHTLCSpend(ctx context.Context, swap *db.Swap, newSpendTx func(outputs []HTLCOutput, feeRate uint64) (*wire.MsgTx, error)) (bool, error) {
+	spendTx, err := svc.esplora.GetTx(ctx, swap.ClaimTxId)
+	if err != nil {
+		return false, err
+	}
+	if spendTx.Status.Confirmed {
+		return true, nil
+	}
+
+	feeRate := svc.esplora.GetFeeRate(ctx)
+	if feeRate <= swap.ClaimFeeRate {
+		return false, nil
+	}
+
+	// the replacement spends the same outputs
+	outputs := []HTLCOutput{}
+	for _, input := range spendTx.Vin {
+		outPoint, err := parseOutPoint(input.TxId, input.Vout)
+		if err != nil {
+			return false, err
+		}
+		outputs = append(outputs, HTLCOutput{
+			OutPoint: *outPoint,
+			Value:    input.Prevout.Value,
+		})
+	}
+
+	previousTxId := swap.ClaimTxId
+	err = svc.broadcastHTLCSpend(ctx, swap, outputs, newSpendTx)
+	if err != nil {
+		return false, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":        swap.SwapId,
+		"previous_tx_id": previousTxId,
+		"tx_id":          swap.ClaimTxId,
+		"fee_rate":       feeRate,
+	}).Info("Bumped fee of swap transaction")
+	return false, nil
+}
+
+// getHTLC recreates the HTLC of a swap from the leaves verified when the swap was created
+func (svc *swapsService) getHTLC(swap *db.Swap, params *chaincfg.Params) (*HTLC, error) {
+	paymentHash, err := hex.DecodeString(swap.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+	providerPubkey, err := parsePubkey(swap.ProviderPubkey)
+	if err != nil {
+		return nil, err
+	}
+	claimLeaf, err := hex.DecodeString(swap.ClaimLeaf)
+	if err != nil {
+		return nil, err
+	}
+	refundLeaf, err := hex.DecodeString(swap.RefundLeaf)
+	if err != nil {
+		return nil, err
+	}
+	tree := &SwapTree{
+		ClaimLeaf:  txscript.NewBaseTapLeaf(claimLeaf),
+		RefundLeaf: txscript.NewBaseTapLeaf(refundLeaf),
+	}
+	return NewHTLC(tree, providerPubkey, svc.getSwapKey(paymentHash).PubKey(), params)
+}
+
+func (svc *swapsService) getHTLCOutputs(ctx context.Context, swap *db.Swap) ([]HTLCOutput, error) {
+	utxos, err := svc.esplora.GetUtxos(ctx, swap.LockupAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []HTLCOutput{}
+	for _, utxo := range utxos {
+		outPoint, err := parseOutPoint(utxo.TxId, utxo.Vout)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, HTLCOutput{
+			OutPoint: *outPoint,
+			Value:    utxo.Value,
+		})
+	}
+	return outputs, nil
+}
+
+func (svc *swapsService) markSwapCompleted(swap *db.Swap) {
+	swap.State = SWAP_STATE_COMPLETED
+	err := svc.db.Model(swap).Update("state", SWAP_STATE_COMPLETED).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swap.SwapId).Error("Failed to mark swap completed")
+		return
+	}
+	logger.Logger.WithField("swap_id", swap.SwapId).Info("Swap completed")
+	svc.publishSwapEvent("nwc_swap_succeeded", swap)
+}
+
+func (svc *swapsService) markSwapRefunded(swap *db.Swap) {
+	swap.State = SWAP_STATE_REFUNDED
+	err := svc.db.Model(swap).Update("state", SWAP_STATE_REFUNDED).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swap.SwapId).Error("Failed to mark swap refunded")
+		return
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id":      swap.SwapId,
+		"refund_tx_id": swap.ClaimTxId,
+	}).Info("Refunded swap in")
+	svc.publishSwapEvent("nwc_swap_refunded", swap)
+}
+
+func (svc *swapsService) markSwapFailed(swap *db.Swap, reason string) {
+	swap.State = SWAP_STATE_FAILED
+	swap.FailureReason = reason
+	err := svc.db.Model(swap).Updates(map[string]interface{}{
+		"state":          SWAP_STATE_FAILED,
+		"failure_reason": reason,
+	}).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swap_id", swap.SwapId).Error("Failed to mark swap failed")
+		return
+	}
+	logger.Logger.WithFields(logrus.Fields{
+		"swap_id": swap.SwapId,
+		"reason":  reason,
+	}).Warn("Swap failed")
+	svc.publishSwapEvent("nwc_swap_failed", swap)
+}
+
+func (svc *swapsService) publishSwapEvent(event string, swap *db.Swap) {
+	svc.eventPublisher.Publish(&events.Event{
+		Event: event,
+		Properties: map[string]interface{}{
+			"swap_id": swap.SwapId,
+			"type":    swap.Type,
+			"amount":  swap.AmountSat,
+		},
+	})
+}
+
+func (svc *swapsService) getSwapInfo(ctx context.Context, amount uint64) (*SwapInfo, error) {
+	swapInfo, err := svc.provider.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if amount < swapInfo.MinAmountSat || amount > swapInfo.MaxAmountSat {
+		return nil, fmt.Errorf("swap amount must be between %d and %d sats", swapInfo.MinAmountSat, swapInfo.MaxAmountSat)
+	}
+	return swapInfo, nil
+}
+
+// getSwapKey derives the claim or refund key of a swap from the payment hash,
+// so swaps can be resumed after a restart without storing private keys
+func (svc *swapsService) getSwapKey(paymentHash []byte) *btcec.PrivateKey {
+	mac := hmac.New(sha256.New, []byte(svc.keys.GetNostrSecretKey()))
+	mac.Write([]byte("swap/"))
+	mac.Write(paymentHash)
+	privateKey, _ := btcec.PrivKeyFromBytes(mac.Sum(nil))
+	return privateKey
+}
+
+func parsePubkey(pubkey string) (*btcec.PublicKey, error) {
+	pubkeyBytes, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return btcec.ParsePubKey(pubkeyBytes)
+}
+
+// verifyHTLC checks the hex encoded leaves returned by the provider
+func verifyHTLC(claimLeaf string, refundLeaf string, address string, expectedTree *SwapTree, providerPubkey *btcec.PublicKey, pubkey *btcec.PublicKey, params *chaincfg.Params) error {
+	claimLeafBytes, err := hex.DecodeString(claimLeaf)
+	if err != nil {
+		return err
+	}
+	refundLeafBytes, err := hex.DecodeString(refundLeaf)
+	if err != nil {
+		return err
+	}
+	_, err = VerifyHTLC(claimLeafBytes, refundLeafBytes, address, expectedTree, providerPubkey, pubkey, params)
+	return err
+}
+
+func failureReason(swapStatus *SwapStatus) string {
+	if swapStatus.FailureReason != "" {
+		return swapStatus.FailureReason
+	}
+	return swapStatus.Status
+}
+
+func getNetworkParams(ctx context.Context, lnClient lnclient.LNClient) (*chaincfg.Params, error) {
+	nodeInfo, err := lnClient.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch nodeInfo.Network {
+	case "bitcoin", "mainnet":
+		return &chaincfg.MainNetParams, nil
+	case "testnet":
+		return &chaincfg.TestNet3Params, nil
+	case "signet", "mutinynet":
+		return &chaincfg.SigNetParams, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, fmt.Errorf("unsupported network: %s", nodeInfo.Network)
+}
@@ -0,0 +1,575 @@
+package swaps
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+const mockLockupTxId = "8e1ad7f5b6a47bbc1b2f7e0a7b5b4b6c1e2c5f5d4b0a3e0f6f9d3b2c1a0f9e8d"
+const mockTimeoutBlockHeight = 1000
+
+// mockSwapServer is a stand-in Boltz API v2 which also serves the Esplora endpoints
+type mockSwapServer struct {
+	*httptest.Server
+	providerKey       *btcec.PrivateKey
+	status            string
+	tipHeight         uint32
+	feeRate           float64
+	expectedAmountSat uint64
+	lockupAddress     string
+	lockupValue       int64
+	funded            bool
+	confirmed         bool
+	// the swap tree returned to the hub does not match the swap parameters
+	invalidSwapTree bool
+	broadcastTxs    []*wire.MsgTx
+	mutex           sync.Mutex
+}
+
+func newMockSwapServer(t *testing.T) *mockSwapServer {
+	providerKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	mockServer := &mockSwapServer{
+		providerKey: providerKey,
+		status:      SWAP_STATUS_CREATED,
+		tipHeight:   900,
+		feeRate:     3.2,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/swap/submarine", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"BTC":{"BTC":{"hash":"mock","rate":1,"limits":{"maximal":1000000,"minimal":10000},"fees":{"percentage":0.1,"minerFees":300}}}}`))
+	})
+	mux.HandleFunc("GET /v2/swap/reverse", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"BTC":{"BTC":{"hash":"mock","rate":1,"limits":{"maximal":2000000,"minimal":5000},"fees":{"percentage":0.5,"minerFees":{"lockup":250,"claim":200}}}}}`))
+	})
+	mux.HandleFunc("POST /v2/swap/submarine", func(w http.ResponseWriter, r *http.Request) {
+		var createSwapRequest boltzCreateSubmarineSwapRequest
+		err := json.NewDecoder(r.Body).Decode(&createSwapRequest)
+		require.NoError(t, err)
+		assert.Equal(t, "BTC", createSwapRequest.From)
+
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+
+		paymentRequest, err := decodepay.Decodepay(createSwapRequest.Invoice)
+		require.NoError(t, err)
+		paymentHash, err := hex.DecodeString(paymentRequest.PaymentHash)
+		require.NoError(t, err)
+		refundPubkey, err := parsePubkey(createSwapRequest.RefundPublicKey)
+		require.NoError(t, err)
+		tree, err := SwapInTree(paymentHash, providerKey.PubKey(), refundPubkey, mockTimeoutBlockHeight)
+		require.NoError(t, err)
+		htlc, err := NewHTLC(tree, providerKey.PubKey(), refundPubkey, &chaincfg.TestNet3Params)
+		require.NoError(t, err)
+
+		response := boltzCreateSubmarineSwapResponse{
+			Id:                 "mock-swap-in",
+			Address:            htlc.Address.EncodeAddress(),
+			ExpectedAmount:     mockServer.expectedAmountSat,
+			ClaimPublicKey:     hex.EncodeToString(providerKey.PubKey().SerializeCompressed()),
+			SwapTree:           mockServer.swapTree(tree),
+			TimeoutBlockHeight: mockTimeoutBlockHeight,
+		}
+		mockServer.lockupAddress = response.Address
+		mockServer.lockupValue = int64(response.ExpectedAmount)
+		json.NewEncoder(w).Encode(&response)
+	})
+	mux.HandleFunc("POST /v2/swap/reverse", func(w http.ResponseWriter, r *http.Request) {
+		var createSwapRequest boltzCreateReverseSwapRequest
+		err := json.NewDecoder(r.Body).Decode(&createSwapRequest)
+		require.NoError(t, err)
+
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+
+		paymentHash, err := hex.DecodeString(createSwapRequest.PreimageHash)
+		require.NoError(t, err)
+		claimPubkey, err := parsePubkey(createSwapRequest.ClaimPublicKey)
+		require.NoError(t, err)
+		tree, err := SwapOutTree(paymentHash, claimPubkey, providerKey.PubKey(), mockTimeoutBlockHeight)
+		require.NoError(t, err)
+		htlc, err := NewHTLC(tree, providerKey.PubKey(), claimPubkey, &chaincfg.TestNet3Params)
+		require.NoError(t, err)
+
+		response := boltzCreateReverseSwapResponse{
+			Id:                 "mock-swap-out",
+			Invoice:            mockServer.makeHoldInvoice(t, paymentHash, createSwapRequest.InvoiceAmount),
+			LockupAddress:      htlc.Address.EncodeAddress(),
+			OnchainAmount:      createSwapRequest.InvoiceAmount - swapFee(createSwapRequest.InvoiceAmount, 0.5) - 250,
+			RefundPublicKey:    hex.EncodeToString(providerKey.PubKey().SerializeCompressed()),
+			SwapTree:           mockServer.swapTree(tree),
+			TimeoutBlockHeight: mockTimeoutBlockHeight,
+		}
+		mockServer.lockupAddress = response.LockupAddress
+		mockServer.lockupValue = int64(response.OnchainAmount)
+		json.NewEncoder(w).Encode(&response)
+	})
+	mux.HandleFunc("GET /v2/swap/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		response := boltzSwapStatusResponse{
+			Status: mockServer.status,
+		}
+		if mockServer.funded {
+			response.Transaction = &struct {
+				Id string `json:"id"`
+			}{Id: mockLockupTxId}
+		}
+		json.NewEncoder(w).Encode(&response)
+	})
+	mux.HandleFunc("GET /blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		w.Write([]byte(strconv.Itoa(int(mockServer.tipHeight))))
+	})
+	mux.HandleFunc("GET /fee-estimates", func(w http.ResponseWriter, r *http.Request) {
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		json.NewEncoder(w).Encode(map[string]float64{"1": 10.5, "6": mockServer.feeRate, "144": 1})
+	})
+	mux.HandleFunc("GET /address/{address}/utxo", func(w http.ResponseWriter, r *http.Request) {
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		utxos := []esploraUtxo{}
+		// the HTLC output is spent once a claim or refund transaction was broadcast
+		if mockServer.funded && len(mockServer.broadcastTxs) == 0 && r.PathValue("address") == mockServer.lockupAddress {
+			utxos = append(utxos, esploraUtxo{
+				TxId:  mockLockupTxId,
+				Vout:  0,
+				Value: mockServer.lockupValue,
+			})
+		}
+		json.NewEncoder(w).Encode(utxos)
+	})
+	mux.HandleFunc("GET /tx/{txid}", func(w http.ResponseWriter, r *http.Request) {
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		for _, tx := range mockServer.broadcastTxs {
+			if tx.TxHash().String() != r.PathValue("txid") {
+				continue
+			}
+			var response esploraTx
+			response.TxId = tx.TxHash().String()
+			response.Status.Confirmed = mockServer.confirmed
+			for _, txIn := range tx.TxIn {
+				response.Vin = append(response.Vin, struct {
+					TxId    string `json:"txid"`
+					Vout    uint32 `json:"vout"`
+					Prevout struct {
+						Value int64 `json:"value"`
+					} `json:"prevout"`
+				}{
+					TxId: txIn.PreviousOutPoint.Hash.String(),
+					Vout: txIn.PreviousOutPoint.Index,
+				})
+				response.Vin[len(response.Vin)-1].Prevout.Value = mockServer.lockupValue
+			}
+			json.NewEncoder(w).Encode(&response)
+			return
+		}
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /tx", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		txBytes, err := hex.DecodeString(string(body))
+		require.NoError(t, err)
+		tx := wire.NewMsgTx(2)
+		err = tx.Deserialize(bytes.NewReader(txBytes))
+		require.NoError(t, err)
+
+		mockServer.mutex.Lock()
+		defer mockServer.mutex.Unlock()
+		mockServer.broadcastTxs = append(mockServer.broadcastTxs, tx)
+		w.Write([]byte(tx.TxHash().String()))
+	})
+	mockServer.Server = httptest.NewServer(mux)
+	return mockServer
+}
+
+func (mockServer *mockSwapServer) swapTree(tree *SwapTree) boltzSwapTree {
+	var swapTree boltzSwapTree
+	swapTree.ClaimLeaf.Output = hex.EncodeToString(tree.ClaimLeaf.Script)
+	swapTree.RefundLeaf.Output = hex.EncodeToString(tree.RefundLeaf.Script)
+	if mockServer.invalidSwapTree {
+		// a refund leaf without a timeout would allow the provider to take the funds at any time
+		refundLeaf, _ := txscript.NewScriptBuilder().
+			AddData(schnorr.SerializePubKey(mockServer.providerKey.PubKey())).
+			AddOp(txscript.OP_CHECKSIG).
+			Script()
+		swapTree.RefundLeaf.Output = hex.EncodeToString(refundLeaf)
+	}
+	return swapTree
+}
+
+func (mockServer *mockSwapServer) makeHoldInvoice(t *testing.T, paymentHash []byte, amount uint64) string {
+	var hash [32]byte
+	copy(hash[:], paymentHash)
+	invoice, err := zpay32.NewInvoice(&chaincfg.TestNet3Params, hash, time.Now(),
+		zpay32.Amount(lnwire.MilliSatoshi(amount*1000)),
+		zpay32.Description("Swap out"),
+	)
+	require.NoError(t, err)
+	paymentRequest, err := invoice.Encode(zpay32.MessageSigner{
+		SignCompact: func(msg []byte) ([]byte, error) {
+			return ecdsa.SignCompact(mockServer.providerKey, chainhash.HashB(msg), true)
+		},
+	})
+	require.NoError(t, err)
+	return paymentRequest
+}
+
+func (mockServer *mockSwapServer) update(fn func()) {
+	mockServer.mutex.Lock()
+	defer mockServer.mutex.Unlock()
+	fn()
+}
+
+func (mockServer *mockSwapServer) getBroadcastTxs() []*wire.MsgTx {
+	mockServer.mutex.Lock()
+	defer mockServer.mutex.Unlock()
+	return mockServer.broadcastTxs
+}
+
+func newTestAddress(t *testing.T) string {
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(privateKey.PubKey().SerializeCompressed()), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	return address.EncodeAddress()
+}
+
+// assertValidHTLCSpend executes the script path spend to check the transaction can spend the locked funds
+func assertValidHTLCSpend(t *testing.T, swapsSvc *swapsService, tx *wire.MsgTx, swap *db.Swap, value int64) {
+	htlc, err := swapsSvc.getHTLC(swap, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+
+	prevOutputFetcher := txscript.NewCannedPrevOutputFetcher(htlc.PkScript, value)
+	engine, err := txscript.NewEngine(htlc.PkScript, tx, 0, txscript.StandardVerifyFlags, nil, txscript.NewTxSigHashes(tx, prevOutputFetcher), value, prevOutputFetcher)
+	require.NoError(t, err)
+	assert.NoError(t, engine.Execute())
+}
+
+func setupSwapsService(t *testing.T) (*swapsService, *tests.TestService, *mockSwapServer) {
+	svc, err := tests.CreateTestService()
+	require.NoError(t, err)
+
+	server := newMockSwapServer(t)
+	swapsSvc := NewSwapsService(svc.DB, svc.Keys, svc.EventPublisher, transactions.NewTransactionsService(svc.DB, svc.EventPublisher), NewBoltzSwapProvider(server.URL), server.URL)
+	return swapsSvc, svc, server
+}
+
+func TestSwapIn_Completed(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	server.expectedAmountSat = 50_350
+
+	swap, err := swapsSvc.SwapIn(context.TODO(), svc.LNClient, 50_000)
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_TYPE_IN, swap.Type)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+	assert.Equal(t, tests.MockPaymentHash, swap.PaymentHash)
+	assert.Equal(t, uint64(50_350), swap.OnchainAmountSat)
+	assert.Equal(t, server.lockupAddress, swap.LockupAddress)
+
+	server.update(func() {
+		server.status = SWAP_STATUS_INVOICE_PAID
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	swap, err = swapsSvc.GetSwap("mock-swap-in")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_COMPLETED, swap.State)
+	assert.Equal(t, SWAP_STATUS_INVOICE_PAID, swap.ProviderStatus)
+}
+
+func TestSwapIn_ExpectedAmountTooHigh(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	server.expectedAmountSat = 51_000
+
+	swap, err := swapsSvc.SwapIn(context.TODO(), svc.LNClient, 50_000)
+	assert.Error(t, err)
+	assert.Nil(t, swap)
+
+	swaps, err := swapsSvc.ListSwaps(20, 0)
+	require.NoError(t, err)
+	assert.Empty(t, swaps)
+}
+
+func TestSwapIn_Refund(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	server.expectedAmountSat = 50_350
+	refundAddress := newTestAddress(t)
+	svc.LNClient.(*tests.MockLn).OnchainAddress = refundAddress
+
+	_, err := swapsSvc.SwapIn(context.TODO(), svc.LNClient, 50_000)
+	require.NoError(t, err)
+
+	server.update(func() {
+		server.funded = true
+		server.status = SWAP_STATUS_INVOICE_FAILED_TO_PAY
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	// the funds cannot be refunded before the timeout
+	swap, err := swapsSvc.GetSwap("mock-swap-in")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+	assert.Equal(t, SWAP_STATUS_INVOICE_FAILED_TO_PAY, swap.FailureReason)
+	assert.Empty(t, server.getBroadcastTxs())
+
+	server.update(func() {
+		server.tipHeight = mockTimeoutBlockHeight
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	// the swap is refunded once the refund transaction confirms
+	swap, err = swapsSvc.GetSwap("mock-swap-in")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+	assert.Equal(t, refundAddress, swap.DestinationAddress)
+
+	broadcastTxs := server.getBroadcastTxs()
+	require.Len(t, broadcastTxs, 1)
+	refundTx := broadcastTxs[0]
+	assert.Equal(t, refundTx.TxHash().String(), swap.ClaimTxId)
+	assert.Equal(t, uint64(4), swap.ClaimFeeRate)
+	assert.Equal(t, uint32(mockTimeoutBlockHeight), refundTx.LockTime)
+	require.Len(t, refundTx.TxOut, 1)
+	assert.Less(t, refundTx.TxOut[0].Value, int64(50_350))
+	assertValidHTLCSpend(t, swapsSvc, refundTx, swap, 50_350)
+
+	server.update(func() {
+		server.confirmed = true
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	swap, err = swapsSvc.GetSwap("mock-swap-in")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_REFUNDED, swap.State)
+	assert.Len(t, server.getBroadcastTxs(), 1)
+}
+
+func TestSwapOut_Claim(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	destination := newTestAddress(t)
+
+	swap, err := swapsSvc.SwapOut(context.TODO(), svc.LNClient, 50_000, destination)
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_TYPE_OUT, swap.Type)
+	assert.Equal(t, uint64(49_500), swap.OnchainAmountSat)
+	assert.Equal(t, destination, swap.DestinationAddress)
+
+	// wait for the hold invoice payment
+	assert.Eventually(t, func() bool {
+		var count int64
+		svc.DB.Model(&db.Transaction{}).Where("payment_hash = ? AND state = ?", swap.PaymentHash, constants.TRANSACTION_STATE_SETTLED).Count(&count)
+		return count == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	server.update(func() {
+		server.funded = true
+		server.status = SWAP_STATUS_TX_MEMPOOL
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	swap, err = swapsSvc.GetSwap("mock-swap-out")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+	assert.Equal(t, mockLockupTxId, swap.LockupTxId)
+
+	broadcastTxs := server.getBroadcastTxs()
+	require.Len(t, broadcastTxs, 1)
+	claimTx := broadcastTxs[0]
+	assert.Equal(t, claimTx.TxHash().String(), swap.ClaimTxId)
+	assert.Equal(t, uint64(4), swap.ClaimFeeRate)
+	assertValidHTLCSpend(t, swapsSvc, claimTx, swap, 49_500)
+
+	// the claim is not broadcast again while the fee estimate does not rise
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+	assert.Len(t, server.getBroadcastTxs(), 1)
+
+	// the claim transaction is replaced with a higher fee
+	server.update(func() {
+		server.status = SWAP_STATUS_INVOICE_SETTLED
+		server.feeRate = 12
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	swap, err = swapsSvc.GetSwap("mock-swap-out")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+
+	broadcastTxs = server.getBroadcastTxs()
+	require.Len(t, broadcastTxs, 2)
+	replacementTx := broadcastTxs[1]
+	assert.Equal(t, replacementTx.TxHash().String(), swap.ClaimTxId)
+	assert.Equal(t, uint64(12), swap.ClaimFeeRate)
+	assert.Equal(t, claimTx.TxIn[0].PreviousOutPoint, replacementTx.TxIn[0].PreviousOutPoint)
+	assert.Less(t, replacementTx.TxIn[0].Sequence, uint32(wire.MaxTxInSequenceNum-1))
+	assert.Less(t, replacementTx.TxOut[0].Value, claimTx.TxOut[0].Value)
+	assertValidHTLCSpend(t, swapsSvc, replacementTx, swap, 49_500)
+
+	server.update(func() {
+		server.confirmed = true
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	swap, err = swapsSvc.GetSwap("mock-swap-out")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_COMPLETED, swap.State)
+}
+
+func TestSwapOut_LockedLessThanQuoted(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+
+	swap, err := swapsSvc.SwapOut(context.TODO(), svc.LNClient, 50_000, newTestAddress(t))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(49_500), swap.OnchainAmountSat)
+
+	assert.Eventually(t, func() bool {
+		var count int64
+		svc.DB.Model(&db.Transaction{}).Where("payment_hash = ? AND state = ?", swap.PaymentHash, constants.TRANSACTION_STATE_SETTLED).Count(&count)
+		return count == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the provider locks less than it quoted
+	server.update(func() {
+		server.funded = true
+		server.lockupValue = 1
+		server.status = SWAP_STATUS_TX_MEMPOOL
+	})
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	// the preimage is not revealed
+	swap, err = swapsSvc.GetSwap("mock-swap-out")
+	require.NoError(t, err)
+	assert.Equal(t, SWAP_STATE_PENDING, swap.State)
+	assert.Empty(t, swap.ClaimTxId)
+	assert.Empty(t, server.getBroadcastTxs())
+}
+
+func TestSwapOut_ResumePayment(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+
+	// the hub stopped before the invoice of the swap was paid
+	paymentHash, err := hex.DecodeString(tests.MockPaymentHash)
+	require.NoError(t, err)
+	swap := &db.Swap{
+		SwapId:         "mock-swap-out",
+		Type:           SWAP_TYPE_OUT,
+		State:          SWAP_STATE_PENDING,
+		ProviderStatus: SWAP_STATUS_CREATED,
+		AmountSat:      50_000,
+		PaymentHash:    tests.MockPaymentHash,
+		Invoice:        server.makeHoldInvoice(t, paymentHash, 50_000),
+	}
+	require.NoError(t, svc.DB.Create(swap).Error)
+
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+
+	assert.Eventually(t, func() bool {
+		var count int64
+		svc.DB.Model(&db.Transaction{}).Where("payment_hash = ? AND state = ?", swap.PaymentHash, constants.TRANSACTION_STATE_SETTLED).Count(&count)
+		return count == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the invoice is not paid twice
+	swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+	var count int64
+	svc.DB.Model(&db.Transaction{}).Where("payment_hash = ?", swap.PaymentHash).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSwapOut_PaymentFailed(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	mockLn := svc.LNClient.(*tests.MockLn)
+	mockLn.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{nil}
+	mockLn.PayInvoiceErrors = []error{errors.New("no route")}
+
+	swap, err := swapsSvc.SwapOut(context.TODO(), svc.LNClient, 50_000, newTestAddress(t))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		var count int64
+		svc.DB.Model(&db.Transaction{}).Where("payment_hash = ? AND state = ?", swap.PaymentHash, constants.TRANSACTION_STATE_FAILED).Count(&count)
+		return count == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		swapsSvc.ProcessSwaps(context.TODO(), svc.LNClient)
+		swap, err = swapsSvc.GetSwap("mock-swap-out")
+		require.NoError(t, err)
+		return swap.State == SWAP_STATE_FAILED
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Contains(t, swap.FailureReason, "failed to pay swap invoice")
+	assert.Empty(t, server.getBroadcastTxs())
+}
+
+func TestSwapOut_InvalidSwapTree(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+	server.invalidSwapTree = true
+
+	swap, err := swapsSvc.SwapOut(context.TODO(), svc.LNClient, 50_000, newTestAddress(t))
+	assert.EqualError(t, err, "swap tree does not match the swap parameters")
+	assert.Nil(t, swap)
+
+	swaps, err := swapsSvc.ListSwaps(20, 0)
+	require.NoError(t, err)
+	assert.Empty(t, swaps)
+}
+
+func TestSwapOut_AmountOutOfRange(t *testing.T) {
+	defer tests.RemoveTestService()
+	swapsSvc, svc, server := setupSwapsService(t)
+	defer server.Close()
+
+	_, err := swapsSvc.SwapOut(context.TODO(), svc.LNClient, 5_000, newTestAddress(t))
+	assert.EqualError(t, err, "swap amount must be between 10000 and 1000000 sats")
+}
@@ -9,6 +9,8 @@ import (
 
 type mockRelay struct {
 	PublishedEvent *nostr.Event
+	// if set, publishing fails with this error
+	PublishError error
 }
 
 func NewMockRelay() *mockRelay {
@@ -16,6 +18,9 @@ func NewMockRelay() *mockRelay {
 }
 
 func (relay *mockRelay) Publish(ctx context.Context, event nostr.Event) error {
+	if relay.PublishError != nil {
+		return relay.PublishError
+	}
 	logger.Logger.WithField("event", event).Info("Mock Publishing event")
 	relay.PublishedEvent = &event
 	return nil
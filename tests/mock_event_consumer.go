@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/getAlby/hub/events"
@@ -9,6 +10,7 @@ import (
 
 type mockEventConsumer struct {
 	consumedEvents []*events.Event
+	mutex          sync.Mutex
 }
 
 func NewMockEventConsumer() *mockEventConsumer {
@@ -18,11 +20,15 @@ func NewMockEventConsumer() *mockEventConsumer {
 }
 
 func (e *mockEventConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	e.consumedEvents = append(e.consumedEvents, event)
 }
 
 func (e *mockEventConsumer) GetConsumeEvents() []*events.Event {
 	// events are consumed async - give it a bit of time for tests
 	time.Sleep(1 * time.Millisecond)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 	return e.consumedEvents
 }
@@ -14,6 +14,11 @@ const MockPaymentHash500 = "be8ad5d0b82071d538dcd160e3a3af444bd890de68388a4d771b
 const MockInvoice = "lntb1230n1pjypux0pp5xgxzcks5jtx06k784f9dndjh664wc08ucrganpqn52d0ftrh9n8sdqyw3jscqzpgxqyz5vqsp5rkx7cq252p3frx8ytjpzc55rkgyx2mfkzzraa272dqvr2j6leurs9qyyssqhutxa24r5hqxstchz5fxlslawprqjnarjujp5sm3xj7ex73s32sn54fthv2aqlhp76qmvrlvxppx9skd3r5ut5xutgrup8zuc6ay73gqmra29m"
 const MockPaymentHash = "320c2c5a1492ccfd5bc7aa4ad9b657d6aaec3cfcc0d1d98413a29af4ac772ccf" // for the above invoice
 
+const MockEcashToken = "cashuBpGF0gaJhaUgArSaMTR9YJmFwgaNhYQFhc3hAOWE2ZGJiODQ3YmQyMzJiYTc2ZGIwZGYxOTcyMTZiMjlkM2I4Y2MxNDU1M2NkMjc4MjdmYzFjYzk0MmZlZGI0ZWFjWCEDhhhUP_trhpXfStS6vN6So0qWvc2X3O4NfM-Y1HISZ5JhZGlUaGFuayB5b3VhbXVodHRwOi8vbG9jYWxob3N0OjMzMzhhdWNzYXQ="
+const MockMintUrl = "http://localhost:3338"
+
+const MockLSPPubkey = "02f7029c14f3d805843e065d42e9bdc57f5f414249f335906bbe282ff99b2be17a"
+
 var MockNodeInfo = lnclient.NodeInfo{
 	Alias:       "bob",
 	Color:       "#3399FF",
@@ -62,6 +67,20 @@ type MockLn struct {
 	Pubkey                     string
 	MockTransaction            *lnclient.Transaction
 	SupportedNotificationTypes *[]string
+	Channels                   []lnclient.Channel
+	JitChannelsSupported       bool
+	JitChannelInvoiceError     error
+	JitChannelInvoiceRequests  []MockJitChannelInvoiceRequest
+	Balances                   *lnclient.BalancesResponse
+	OnchainAddress             string
+	OnchainTransactions        []lnclient.OnchainTransaction
+	OnchainTransactionsError   error
+}
+
+type MockJitChannelInvoiceRequest struct {
+	Amount            int64
+	MaxOpeningFeeMsat uint64
+	MaxOpeningFeePpm  uint64
 }
 
 func NewMockLn() (*MockLn, error) {
@@ -82,6 +101,10 @@ func (mln *MockLn) SendPaymentSync(ctx context.Context, payReq string) (*lnclien
 	}, nil
 }
 
+func (mln *MockLn) SendRebalancePayment(ctx context.Context, payReq string, outgoingChannelId string, incomingChannelId string, maxFeeMsat uint64) (*lnclient.PayInvoiceResponse, error) {
+	return mln.SendPaymentSync(ctx, payReq)
+}
+
 func (mln *MockLn) SendKeysend(ctx context.Context, amount uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	return &lnclient.PayKeysendResponse{
 		Fee: 1,
@@ -100,6 +123,31 @@ func (mln *MockLn) MakeInvoice(ctx context.Context, amount int64, description st
 	return MockLNClientTransaction, nil
 }
 
+func (mln *MockLn) SupportsJitChannels() bool {
+	return mln.JitChannelsSupported
+}
+
+func (mln *MockLn) MakeJitChannelInvoice(ctx context.Context, amount int64, description string, expiry int64, maxOpeningFeeMsat uint64) (transaction *lnclient.Transaction, err error) {
+	mln.JitChannelInvoiceRequests = append(mln.JitChannelInvoiceRequests, MockJitChannelInvoiceRequest{
+		Amount:            amount,
+		MaxOpeningFeeMsat: maxOpeningFeeMsat,
+	})
+	if mln.JitChannelInvoiceError != nil {
+		return nil, mln.JitChannelInvoiceError
+	}
+	return MockLNClientTransaction, nil
+}
+
+func (mln *MockLn) MakeVariableAmountJitChannelInvoice(ctx context.Context, description string, expiry int64, maxOpeningFeePpm uint64) (transaction *lnclient.Transaction, err error) {
+	mln.JitChannelInvoiceRequests = append(mln.JitChannelInvoiceRequests, MockJitChannelInvoiceRequest{
+		MaxOpeningFeePpm: maxOpeningFeePpm,
+	})
+	if mln.JitChannelInvoiceError != nil {
+		return nil, mln.JitChannelInvoiceError
+	}
+	return MockLNClientTransaction, nil
+}
+
 func (mln *MockLn) LookupInvoice(ctx context.Context, paymentHash string) (transaction *lnclient.Transaction, err error) {
 	if mln.MockTransaction != nil {
 		return mln.MockTransaction, nil
@@ -115,6 +163,9 @@ func (mln *MockLn) Shutdown() error {
 }
 
 func (mln *MockLn) ListChannels(ctx context.Context) (channels []lnclient.Channel, err error) {
+	if mln.Channels != nil {
+		return mln.Channels, nil
+	}
 	return []lnclient.Channel{}, nil
 }
 func (mln *MockLn) GetNodeConnectionInfo(ctx context.Context) (nodeConnectionInfo *lnclient.NodeConnectionInfo, err error) {
@@ -130,10 +181,10 @@ func (mln *MockLn) CloseChannel(ctx context.Context, closeChannelRequest *lnclie
 	return nil, nil
 }
 func (mln *MockLn) GetNewOnchainAddress(ctx context.Context) (string, error) {
-	return "", nil
+	return mln.OnchainAddress, nil
 }
 func (mln *MockLn) GetBalances(ctx context.Context) (*lnclient.BalancesResponse, error) {
-	return nil, nil
+	return mln.Balances, nil
 }
 func (mln *MockLn) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainBalanceResponse, error) {
 	return nil, nil
@@ -150,6 +201,16 @@ func (mln *MockLn) SendPaymentProbes(ctx context.Context, invoice string) error
 func (mln *MockLn) SendSpontaneousPaymentProbes(ctx context.Context, amountMsat uint64, nodeId string) error {
 	return nil
 }
+func (mln *MockLn) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	if mln.OnchainTransactionsError != nil {
+		return nil, mln.OnchainTransactionsError
+	}
+	if mln.OnchainTransactions != nil {
+		return mln.OnchainTransactions, nil
+	}
+	return []lnclient.OnchainTransaction{}, nil
+}
+
 func (mln *MockLn) ListPeers(ctx context.Context) ([]lnclient.PeerDetails, error) {
 	return nil, nil
 }
@@ -179,15 +240,31 @@ func (mln *MockLn) UpdateChannel(ctx context.Context, updateChannelRequest *lncl
 	return nil
 }
 
+func (mln *MockLn) SendToken(ctx context.Context, amount uint64, mintUrl string) (*lnclient.EcashToken, error) {
+	return &lnclient.EcashToken{
+		Token:   MockEcashToken,
+		MintUrl: MockMintUrl,
+		Amount:  amount + 1000,
+	}, nil
+}
+
+func (mln *MockLn) ReceiveToken(ctx context.Context, token string, swapToDefaultMint bool) (uint64, error) {
+	return 1000, nil
+}
+
+func (mln *MockLn) SwapToDefaultMint(ctx context.Context, fromMintUrl string, amount uint64) error {
+	return nil
+}
+
 func (mln *MockLn) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message"}
+	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "send_cashu_token", "receive_cashu_token", "get_budget"}
 }
 func (mln *MockLn) GetSupportedNIP47NotificationTypes() []string {
 	if mln.SupportedNotificationTypes != nil {
 		return *mln.SupportedNotificationTypes
 	}
 
-	return []string{"payment_received", "payment_sent"}
+	return []string{"payment_received", "payment_sent", "payment_failed", "balance_changed", "budget_warning", "app_expiring"}
 }
 func (mln *MockLn) GetPubkey() string {
 	if mln.Pubkey != "" {
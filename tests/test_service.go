@@ -30,7 +30,8 @@ func CreateTestService() (svc *TestService, err error) {
 	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
 
 	appConfig := &config.AppConfig{
-		Workdir: ".test",
+		Workdir:    ".test",
+		LDKNetwork: "bitcoin",
 	}
 
 	cfg := config.NewConfig(
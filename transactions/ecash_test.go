@@ -0,0 +1,102 @@
+package transactions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendEcashToken(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendEcashToken(ctx, uint64(21000), "", svc.LNClient, nil, nil)
+	assert.NoError(t, err)
+
+	tokenHash := sha256.Sum256([]byte(tests.MockEcashToken))
+	assert.Equal(t, hex.EncodeToString(tokenHash[:]), transaction.PaymentHash)
+	assert.Equal(t, tests.MockEcashToken, *transaction.EcashToken)
+	// the token is not shared with apps as the preimage
+	assert.Nil(t, transaction.Preimage)
+	assert.Equal(t, uint64(21000), transaction.AmountMsat)
+	assert.Equal(t, uint64(1000), transaction.FeeMsat)
+	assert.Zero(t, transaction.FeeReserveMsat)
+	assert.Equal(t, constants.TRANSACTION_TYPE_OUTGOING, transaction.Type)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.JSONEq(t, `{"mint_url":"`+tests.MockMintUrl+`"}`, string(transaction.Metadata))
+
+	assert.Equal(t, 1, len(mockEventConsumer.GetConsumeEvents()))
+	assert.Equal(t, "nwc_payment_sent", mockEventConsumer.GetConsumeEvents()[0].Event)
+}
+
+func TestSendEcashToken_IsolatedApp_NoBalance(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	svc.DB.Save(&app)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendEcashToken(ctx, uint64(21000), "", svc.LNClient, &app.ID, nil)
+
+	assert.ErrorIs(t, err, NewInsufficientBalanceError())
+	assert.Nil(t, transaction)
+}
+
+func TestReceiveEcashToken_IsolatedApp(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+	app.Isolated = true
+	svc.DB.Save(&app)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.ReceiveEcashToken(ctx, tests.MockEcashToken, false, svc.LNClient, &app.ID, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint64(1000), transaction.AmountMsat)
+	assert.Equal(t, constants.TRANSACTION_TYPE_INCOMING, transaction.Type)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, &app.ID, transaction.AppId)
+	assert.Equal(t, tests.MockEcashToken, *transaction.EcashToken)
+	assert.Nil(t, transaction.Preimage)
+	assert.Equal(t, uint64(1000), queries.GetIsolatedBalance(svc.DB, app.ID))
+
+	assert.Equal(t, 1, len(mockEventConsumer.GetConsumeEvents()))
+	assert.Equal(t, "nwc_payment_received", mockEventConsumer.GetConsumeEvents()[0].Event)
+}
@@ -0,0 +1,75 @@
+package transactions
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// limits the opening fee of JIT channel invoices to 5% of the received amount
+const maxJitChannelOpeningFeePpm = 50_000
+
+// makeLNClientInvoice creates the invoice through an LSPS2 just-in-time channel
+// when the active channels cannot receive the amount
+func (svc *transactionsService) makeLNClientInvoice(ctx context.Context, amount int64, description string, descriptionHash string, expiry int64, lnClient lnclient.LNClient) (*lnclient.Transaction, error) {
+	jitChannelInvoiceMaker, ok := lnClient.(lnclient.JitChannelInvoiceMaker)
+	// JIT channel invoices cannot commit to a description hash
+	if !ok || !jitChannelInvoiceMaker.SupportsJitChannels() || descriptionHash != "" {
+		return lnClient.MakeInvoice(ctx, amount, description, descriptionHash, expiry)
+	}
+
+	receivableMsat, err := getReceivableMsat(ctx, lnClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction *lnclient.Transaction
+	if amount > 0 {
+		if uint64(amount) <= receivableMsat {
+			return lnClient.MakeInvoice(ctx, amount, description, descriptionHash, expiry)
+		}
+		// the opening fee is deducted from the payment. If the LSP charges more than the limit,
+		// no JIT channel invoice is created and a regular invoice is returned instead
+		maxOpeningFeeMsat := uint64(amount) * maxJitChannelOpeningFeePpm / 1_000_000
+		transaction, err = jitChannelInvoiceMaker.MakeJitChannelInvoice(ctx, amount, description, expiry, maxOpeningFeeMsat)
+	} else {
+		// any amount can be paid, so a JIT channel is only used if nothing can be received otherwise
+		if receivableMsat > 0 {
+			return lnClient.MakeInvoice(ctx, amount, description, descriptionHash, expiry)
+		}
+		transaction, err = jitChannelInvoiceMaker.MakeVariableAmountJitChannelInvoice(ctx, description, expiry, maxJitChannelOpeningFeePpm)
+	}
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"amount":          amount,
+			"receivable_msat": receivableMsat,
+		}).Warn("Failed to create JIT channel invoice, falling back to a regular invoice")
+		return lnClient.MakeInvoice(ctx, amount, description, descriptionHash, expiry)
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"payment_hash":    transaction.PaymentHash,
+		"amount":          amount,
+		"receivable_msat": receivableMsat,
+	}).Info("Created JIT channel invoice")
+
+	return transaction, nil
+}
+
+// getReceivableMsat returns the inbound liquidity of all active channels
+func getReceivableMsat(ctx context.Context, lnClient lnclient.LNClient) (uint64, error) {
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var receivableMsat uint64
+	for _, channel := range channels {
+		if channel.Active && channel.RemoteBalance > 0 {
+			receivableMsat += uint64(channel.RemoteBalance)
+		}
+	}
+	return receivableMsat, nil
+}
@@ -0,0 +1,120 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestMakeInvoice_JitChannel(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		amount                    int64
+		descriptionHash           string
+		jitChannelsSupported      bool
+		jitChannelInvoiceError    error
+		channels                  []lnclient.Channel
+		expectedJitChannelInvoice *tests.MockJitChannelInvoiceRequest
+	}{
+		{
+			name:                 "not enough inbound liquidity",
+			amount:               100_000_000,
+			jitChannelsSupported: true,
+			channels:             []lnclient.Channel{{Active: true, RemoteBalance: 50_000_000}},
+			expectedJitChannelInvoice: &tests.MockJitChannelInvoiceRequest{
+				Amount:            100_000_000,
+				MaxOpeningFeeMsat: 5_000_000,
+			},
+		},
+		{
+			name:                 "enough inbound liquidity",
+			amount:               100_000_000,
+			jitChannelsSupported: true,
+			channels:             []lnclient.Channel{{Active: true, RemoteBalance: 200_000_000}},
+		},
+		{
+			name:                 "inactive channels are not counted",
+			amount:               100_000_000,
+			jitChannelsSupported: true,
+			channels:             []lnclient.Channel{{Active: false, RemoteBalance: 200_000_000}},
+			expectedJitChannelInvoice: &tests.MockJitChannelInvoiceRequest{
+				Amount:            100_000_000,
+				MaxOpeningFeeMsat: 5_000_000,
+			},
+		},
+		{
+			name:                 "variable amount without inbound liquidity",
+			jitChannelsSupported: true,
+			expectedJitChannelInvoice: &tests.MockJitChannelInvoiceRequest{
+				MaxOpeningFeePpm: maxJitChannelOpeningFeePpm,
+			},
+		},
+		{
+			name:                 "variable amount with inbound liquidity",
+			jitChannelsSupported: true,
+			channels:             []lnclient.Channel{{Active: true, RemoteBalance: 1_000}},
+		},
+		{
+			name:   "no LSPS2 LSP configured",
+			amount: 100_000_000,
+		},
+		{
+			name:                 "description hash",
+			amount:               100_000_000,
+			descriptionHash:      "ebf2fdd8dfe4ee0d6c0bb69cc7b4aa1cba9cf0c23ec1b9e37d75bba1dca8e94e",
+			jitChannelsSupported: true,
+		},
+		{
+			name:                   "opening fee above the limit",
+			amount:                 1_000_000,
+			jitChannelsSupported:   true,
+			jitChannelInvoiceError: errors.New("opening fee exceeds the maximum"),
+			expectedJitChannelInvoice: &tests.MockJitChannelInvoiceRequest{
+				Amount:            1_000_000,
+				MaxOpeningFeeMsat: 50_000,
+			},
+		},
+		{
+			name:                   "falls back to a regular invoice",
+			amount:                 100_000_000,
+			jitChannelsSupported:   true,
+			jitChannelInvoiceError: errors.New("LSP unavailable"),
+			expectedJitChannelInvoice: &tests.MockJitChannelInvoiceRequest{
+				Amount:            100_000_000,
+				MaxOpeningFeeMsat: 5_000_000,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+
+			defer tests.RemoveTestService()
+			svc, err := tests.CreateTestService()
+			require.NoError(t, err)
+
+			mockLn := svc.LNClient.(*tests.MockLn)
+			mockLn.JitChannelsSupported = tc.jitChannelsSupported
+			mockLn.JitChannelInvoiceError = tc.jitChannelInvoiceError
+			mockLn.Channels = tc.channels
+
+			transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+			transaction, err := transactionsService.MakeInvoice(ctx, tc.amount, "Hello world", tc.descriptionHash, 0, nil, svc.LNClient, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tests.MockLNClientTransaction.PaymentHash, transaction.PaymentHash)
+
+			if tc.expectedJitChannelInvoice == nil {
+				assert.Empty(t, mockLn.JitChannelInvoiceRequests)
+				return
+			}
+			assert.Equal(t, []tests.MockJitChannelInvoiceRequest{*tc.expectedJitChannelInvoice}, mockLn.JitChannelInvoiceRequests)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebalance(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	svc.LNClient.(*tests.MockLn).PayInvoiceResponses = []*lnclient.PayInvoiceResponse{{
+		Preimage: "123preimage",
+		Fee:      2000,
+	}}
+	svc.LNClient.(*tests.MockLn).PayInvoiceErrors = []error{nil}
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.Rebalance(ctx, "1", "2", 1000000, 5000, svc.LNClient)
+	assert.NoError(t, err)
+
+	assert.Equal(t, tests.MockLNClientTransaction.PaymentHash, transaction.PaymentHash)
+	assert.Equal(t, "123preimage", *transaction.Preimage)
+	assert.Equal(t, uint64(1000000), transaction.AmountMsat)
+	assert.Equal(t, uint64(2000), transaction.FeeMsat)
+	assert.Zero(t, transaction.FeeReserveMsat)
+	assert.Nil(t, transaction.AppId)
+	assert.Equal(t, constants.TRANSACTION_TYPE_REBALANCE, transaction.Type)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.JSONEq(t, `{"outgoing_channel_id":"1","incoming_channel_id":"2"}`, string(transaction.Metadata))
+
+	assert.Equal(t, 1, len(mockEventConsumer.GetConsumeEvents()))
+	assert.Equal(t, "nwc_rebalance_succeeded", mockEventConsumer.GetConsumeEvents()[0].Event)
+
+	// rebalances are not part of the regular transaction history
+	transactions, err := transactionsService.ListTransactions(ctx, 0, 0, 0, 0, false, nil, svc.LNClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(transactions))
+
+	transactionType := constants.TRANSACTION_TYPE_REBALANCE
+	transactions, err = transactionsService.ListTransactions(ctx, 0, 0, 0, 0, false, &transactionType, svc.LNClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+}
+
+func TestRebalance_Failed(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	svc.LNClient.(*tests.MockLn).PayInvoiceResponses = []*lnclient.PayInvoiceResponse{nil}
+	svc.LNClient.(*tests.MockLn).PayInvoiceErrors = []error{errors.New("no route")}
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.Rebalance(ctx, "1", "2", 1000000, 5000, svc.LNClient)
+	assert.EqualError(t, err, "no route")
+	assert.Nil(t, transaction)
+
+	transactionType := constants.TRANSACTION_TYPE_REBALANCE
+	transactions, err := transactionsService.ListTransactions(ctx, 0, 0, 0, 0, true, &transactionType, svc.LNClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, transactions[0].State)
+	assert.Equal(t, "no route", transactions[0].FailureReason)
+
+	assert.Equal(t, 1, len(mockEventConsumer.GetConsumeEvents()))
+	assert.Equal(t, "nwc_rebalance_failed", mockEventConsumer.GetConsumeEvents()[0].Event)
+}
+
+func TestRebalance_IgnoresLNClientEvents(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.Rebalance(ctx, "1", "2", 1000000, 5000, svc.LNClient)
+	assert.NoError(t, err)
+
+	// the backend also notifies about the received invoice
+	transactionsService.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_lnclient_payment_received",
+		Properties: tests.MockLNClientTransaction,
+	}, map[string]interface{}{})
+
+	transactions, err := transactionsService.ListTransactions(ctx, 0, 0, 0, 0, true, nil, svc.LNClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(transactions))
+}
+
+type mockNonRebalancerLn struct {
+	lnclient.LNClient
+}
+
+func TestRebalance_NotSupported(t *testing.T) {
+	ctx := context.TODO()
+
+	defer tests.RemoveTestService()
+	svc, err := tests.CreateTestService()
+	assert.NoError(t, err)
+
+	// e.g. LDK, which cannot choose the channels a payment is routed through
+	lnClient := &mockNonRebalancerLn{LNClient: svc.LNClient}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.Rebalance(ctx, "1", "2", 1000000, 5000, lnClient)
+	assert.EqualError(t, err, "rebalancing is not supported by this backend")
+	assert.Nil(t, transaction)
+
+	transactionType := constants.TRANSACTION_TYPE_REBALANCE
+	transactions, err := transactionsService.ListTransactions(ctx, 0, 0, 0, 0, true, &transactionType, svc.LNClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(transactions))
+}
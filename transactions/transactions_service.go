@@ -38,6 +38,9 @@ type TransactionsService interface {
 	ListTransactions(ctx context.Context, from, until, limit, offset uint64, unpaid bool, transactionType *string, lnClient lnclient.LNClient, appId *uint) (transactions []Transaction, err error)
 	SendPaymentSync(ctx context.Context, payReq string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
 	SendKeysend(ctx context.Context, amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	SendEcashToken(ctx context.Context, amount uint64, mintUrl string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	ReceiveEcashToken(ctx context.Context, token string, swapToDefaultMint bool, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	Rebalance(ctx context.Context, outgoingChannelId string, incomingChannelId string, amountMsat uint64, maxFeeMsat uint64, lnClient lnclient.LNClient) (*Transaction, error)
 }
 
 const (
@@ -119,7 +122,7 @@ func (svc *transactionsService) MakeInvoice(ctx context.Context, amount int64, d
 		}
 	}
 
-	lnClientTransaction, err := lnClient.MakeInvoice(ctx, amount, description, descriptionHash, expiry)
+	lnClientTransaction, err := svc.makeLNClientInvoice(ctx, amount, description, descriptionHash, expiry, lnClient)
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to create transaction")
 		return nil, err
@@ -425,6 +428,217 @@ func (svc *transactionsService) SendKeysend(ctx context.Context, amount uint64,
 	return settledTransaction, nil
 }
 
+// ecash tokens have no payment hash, so the hash of the token is used instead.
+// The token itself is saved as the preimage, which allows unclaimed sent tokens to be recovered.
+func (svc *transactionsService) SendEcashToken(ctx context.Context, amount uint64, mintUrl string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+	ecashClient, ok := lnClient.(lnclient.EcashClient)
+	if !ok {
+		return nil, errors.New("this wallet does not support ecash tokens")
+	}
+
+	var dbTransaction db.Transaction
+
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		err := svc.validateCanPay(tx, appId, amount)
+		if err != nil {
+			return err
+		}
+
+		dbTransaction = db.Transaction{
+			AppId:          appId,
+			RequestEventId: requestEventId,
+			Type:           constants.TRANSACTION_TYPE_OUTGOING,
+			State:          constants.TRANSACTION_STATE_PENDING,
+			FeeReserveMsat: svc.calculateFeeReserveMsat(amount),
+			AmountMsat:     amount,
+		}
+		return tx.Create(&dbTransaction).Error
+	})
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"mint_url": mintUrl,
+			"amount":   amount,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	ecashToken, err := ecashClient.SendToken(ctx, amount, mintUrl)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"mint_url": mintUrl,
+			"amount":   amount,
+		}).WithError(err).Error("Failed to send ecash token")
+
+		svc.db.Transaction(func(tx *gorm.DB) error {
+			return svc.markPaymentFailed(tx, &dbTransaction, err.Error())
+		})
+		return nil, err
+	}
+
+	metadataBytes, err := json.Marshal(map[string]interface{}{
+		"mint_url": ecashToken.MintUrl,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to serialize transaction metadata")
+		return nil, err
+	}
+
+	// the token includes the fees the receiver needs to pay to swap it
+	var fee uint64
+	if ecashToken.Amount > amount {
+		fee = ecashToken.Amount - amount
+	}
+
+	var settledTransaction *db.Transaction
+	err = svc.db.Transaction(func(tx *gorm.DB) error {
+		dbTransaction.PaymentHash = hashEcashToken(ecashToken.Token)
+		dbTransaction.EcashToken = &ecashToken.Token
+		dbTransaction.Metadata = datatypes.JSON(metadataBytes)
+		err := tx.Model(&dbTransaction).Updates(&db.Transaction{
+			PaymentHash: dbTransaction.PaymentHash,
+			EcashToken:  dbTransaction.EcashToken,
+			Metadata:    dbTransaction.Metadata,
+		}).Error
+		if err != nil {
+			return err
+		}
+		settledTransaction, err = svc.markTransactionSettled(tx, &dbTransaction, "", fee, false)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return settledTransaction, nil
+}
+
+func (svc *transactionsService) ReceiveEcashToken(ctx context.Context, token string, swapToDefaultMint bool, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+	ecashClient, ok := lnClient.(lnclient.EcashClient)
+	if !ok {
+		return nil, errors.New("this wallet does not support ecash tokens")
+	}
+
+	amount, err := ecashClient.ReceiveToken(ctx, token, swapToDefaultMint)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to receive ecash token")
+		return nil, err
+	}
+
+	var settledTransaction *db.Transaction
+	err = svc.db.Transaction(func(tx *gorm.DB) error {
+		dbTransaction := db.Transaction{
+			AppId:          appId,
+			RequestEventId: requestEventId,
+			Type:           constants.TRANSACTION_TYPE_INCOMING,
+			State:          constants.TRANSACTION_STATE_PENDING,
+			AmountMsat:     amount,
+			PaymentHash:    hashEcashToken(token),
+			EcashToken:     &token,
+		}
+		err := tx.Create(&dbTransaction).Error
+		if err != nil {
+			return err
+		}
+		settledTransaction, err = svc.markTransactionSettled(tx, &dbTransaction, "", 0, false)
+		return err
+	})
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"amount": amount,
+		}).WithError(err).Error("Failed to save received ecash token")
+		return nil, err
+	}
+
+	return settledTransaction, nil
+}
+
+// Rebalance moves amountMsat of outbound liquidity from the outgoing channel to the incoming channel
+// by paying an invoice to ourselves. The payment is stored as a rebalance transaction
+// which does not belong to any app.
+func (svc *transactionsService) Rebalance(ctx context.Context, outgoingChannelId string, incomingChannelId string, amountMsat uint64, maxFeeMsat uint64, lnClient lnclient.LNClient) (*Transaction, error) {
+	rebalancer, ok := lnClient.(lnclient.Rebalancer)
+	if !ok {
+		return nil, errors.New("rebalancing is not supported by this backend")
+	}
+
+	if amountMsat == 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	lnClientTransaction, err := lnClient.MakeInvoice(ctx, int64(amountMsat), "Rebalance", "", 0)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create rebalance invoice")
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if lnClientTransaction.ExpiresAt != nil {
+		expiresAtValue := time.Unix(*lnClientTransaction.ExpiresAt, 0)
+		expiresAt = &expiresAtValue
+	}
+
+	metadataBytes, err := json.Marshal(map[string]interface{}{
+		"outgoing_channel_id": outgoingChannelId,
+		"incoming_channel_id": incomingChannelId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the transaction must exist before paying so the incoming payment is not recorded as a regular invoice
+	dbTransaction := db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_REBALANCE,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		AmountMsat:     amountMsat,
+		FeeReserveMsat: maxFeeMsat,
+		PaymentRequest: lnClientTransaction.Invoice,
+		PaymentHash:    lnClientTransaction.PaymentHash,
+		Description:    lnClientTransaction.Description,
+		ExpiresAt:      expiresAt,
+		Metadata:       datatypes.JSON(metadataBytes),
+	}
+	err = svc.db.Create(&dbTransaction).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"payment_hash": lnClientTransaction.PaymentHash,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	response, err := rebalancer.SendRebalancePayment(ctx, lnClientTransaction.Invoice, outgoingChannelId, incomingChannelId, maxFeeMsat)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"payment_hash":        lnClientTransaction.PaymentHash,
+			"outgoing_channel_id": outgoingChannelId,
+			"incoming_channel_id": incomingChannelId,
+		}).WithError(err).Error("Failed to send rebalance payment")
+
+		if errors.Is(err, lnclient.NewTimeoutError()) {
+			// the payment might still succeed
+			return nil, err
+		}
+
+		svc.db.Transaction(func(tx *gorm.DB) error {
+			return svc.markPaymentFailed(tx, &dbTransaction, err.Error())
+		})
+		return nil, err
+	}
+
+	var settledTransaction *db.Transaction
+	err = svc.db.Transaction(func(tx *gorm.DB) error {
+		settledTransaction, err = svc.markTransactionSettled(tx, &dbTransaction, response.Preimage, response.Fee, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settledTransaction, nil
+}
+
 func (svc *transactionsService) LookupTransaction(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Transaction, error) {
 	transaction := db.Transaction{}
 
@@ -488,6 +702,9 @@ func (svc *transactionsService) ListTransactions(ctx context.Context, from, unti
 
 	if transactionType != nil {
 		tx = tx.Where("type == ?", *transactionType)
+	} else {
+		// rebalances move funds between the node's own channels and are only listed explicitly
+		tx = tx.Where("type != ?", constants.TRANSACTION_TYPE_REBALANCE)
 	}
 
 	if from > 0 {
@@ -581,6 +798,10 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 			return
 		}
 
+		if svc.isRebalance(lnClientTransaction.PaymentHash) {
+			return
+		}
+
 		var dbTransaction db.Transaction
 		err := svc.db.Transaction(func(tx *gorm.DB) error {
 
@@ -655,6 +876,10 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 			return
 		}
 
+		if svc.isRebalance(lnClientTransaction.PaymentHash) {
+			return
+		}
+
 		var dbTransaction db.Transaction
 		err := svc.db.Transaction(func(tx *gorm.DB) error {
 			result := tx.Limit(1).Find(&dbTransaction, &db.Transaction{
@@ -709,6 +934,14 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 	}
 }
 
+// rebalance payments are both sent and received by the node, and are settled by Rebalance
+func (svc *transactionsService) isRebalance(paymentHash string) bool {
+	return svc.db.Limit(1).Find(&db.Transaction{}, &db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_REBALANCE,
+		PaymentHash: paymentHash,
+	}).RowsAffected > 0
+}
+
 func (svc *transactionsService) interceptSelfPayment(paymentHash string) (*lnclient.PayInvoiceResponse, error) {
 	logger.Logger.WithField("payment_hash", paymentHash).Debug("Intercepting self payment")
 	incomingTransaction := db.Transaction{}
@@ -806,6 +1039,11 @@ func (svc *transactionsService) calculateFeeReserveMsat(amount uint64) uint64 {
 	return uint64(math.Max(math.Ceil(float64(amount)*0.01), 10000))
 }
 
+func hashEcashToken(token string) string {
+	tokenHash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(tokenHash[:])
+}
+
 func makePreimageHex() ([]byte, error) {
 	bytes := make([]byte, 32) // 32 bytes * 8 bits/byte = 256 bits
 	_, err := rand.Read(bytes)
@@ -896,19 +1134,23 @@ func (svc *transactionsService) markTransactionSettled(tx *gorm.DB, dbTransactio
 		return &existingSettledTransaction, nil
 	}
 
-	if preimage == "" {
+	// ecash payments have no preimage, the token is stored separately
+	if preimage == "" && dbTransaction.EcashToken == nil {
 		return nil, errors.New("no preimage in payment")
 	}
 
 	now := time.Now()
-	err := tx.Model(dbTransaction).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"State":          constants.TRANSACTION_STATE_SETTLED,
-		"Preimage":       &preimage,
 		"FeeMsat":        fee,
 		"FeeReserveMsat": 0,
 		"SettledAt":      &now,
 		"SelfPayment":    selfPayment,
-	}).Error
+	}
+	if preimage != "" {
+		updates["Preimage"] = &preimage
+	}
+	err := tx.Model(dbTransaction).Updates(updates).Error
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"payment_hash": dbTransaction.PaymentHash,
@@ -922,8 +1164,12 @@ func (svc *transactionsService) markTransactionSettled(tx *gorm.DB, dbTransactio
 	}).Info("Marked transaction as settled")
 
 	event := "nwc_payment_sent"
-	if dbTransaction.Type == constants.TRANSACTION_TYPE_INCOMING {
+	switch dbTransaction.Type {
+	case constants.TRANSACTION_TYPE_INCOMING:
 		event = "nwc_payment_received"
+	case constants.TRANSACTION_TYPE_REBALANCE:
+		// rebalances must not trigger app notifications
+		event = "nwc_rebalance_succeeded"
 	}
 
 	svc.eventPublisher.Publish(&events.Event{
@@ -963,8 +1209,13 @@ func (svc *transactionsService) markPaymentFailed(tx *gorm.DB, dbTransaction *db
 	}
 	logger.Logger.WithField("payment_hash", dbTransaction.PaymentHash).Info("Marked transaction as failed")
 
+	event := "nwc_payment_failed"
+	if dbTransaction.Type == constants.TRANSACTION_TYPE_REBALANCE {
+		event = "nwc_rebalance_failed"
+	}
+
 	svc.eventPublisher.Publish(&events.Event{
-		Event:      "nwc_payment_failed",
+		Event:      event,
 		Properties: dbTransaction,
 	})
 	return nil
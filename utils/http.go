@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/version"
+)
+
+const httpRequestTimeout = 30 * time.Second
+
+// HttpRequest sends a request to an external service and returns the response body.
+// Responses with a non-success status code are returned as an error.
+func HttpRequest(ctx context.Context, method string, url string, body []byte, contentType string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "AlbyHub/"+version.Tag)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("url", url).Error("Failed to request endpoint")
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.New("failed to read response body")
+	}
+
+	if res.StatusCode >= 300 {
+		logger.Logger.WithFields(logrus.Fields{
+			"url":        url,
+			"body":       string(responseBody),
+			"statusCode": res.StatusCode,
+		}).Error("Endpoint returned non-success code")
+		return nil, fmt.Errorf("endpoint returned non-success code: %s", string(responseBody))
+	}
+	return responseBody, nil
+}
+
+// JsonRequest sends the payload, if any, as JSON and deserializes the response into result
+func JsonRequest(ctx context.Context, method string, url string, payload interface{}, result interface{}) error {
+	var body []byte
+	var contentType string
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = payloadBytes
+		contentType = "application/json"
+	}
+
+	responseBody, err := HttpRequest(ctx, method, url, body, contentType)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(responseBody, result)
+	if err != nil {
+		return fmt.Errorf("failed to deserialize json %s %s", url, string(responseBody))
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -50,6 +51,76 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
 	}
 
+	appRequestsRegex := regexp.MustCompile(
+		`/api/apps/([0-9a-f]+)/requests`,
+	)
+
+	appRequestsMatch := appRequestsRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appRequestsMatch) == 2 && method == "GET":
+		limit := uint64(20)
+		offset := uint64(0)
+		filters := &api.ListAppRequestsFilters{}
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset|method|state|from|until)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			case "method":
+				filters.Method = match[2]
+			case "state":
+				filters.State = match[2]
+			case "from":
+				if parsedFrom, err := strconv.ParseInt(match[2], 10, 64); err == nil {
+					from := time.Unix(parsedFrom, 0)
+					filters.From = &from
+				}
+			case "until":
+				if parsedUntil, err := strconv.ParseInt(match[2], 10, 64); err == nil {
+					until := time.Unix(parsedUntil, 0)
+					filters.Until = &until
+				}
+			}
+		}
+
+		appRequests, err := app.api.ListAppRequests(appRequestsMatch[1], filters, limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: appRequests, Error: ""}
+	}
+
+	appRotateWalletKeyRegex := regexp.MustCompile(
+		`/api/apps/([0-9a-f]+)/rotate-wallet-key`,
+	)
+
+	appRotateWalletKeyMatch := appRotateWalletKeyRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(appRotateWalletKeyMatch) == 2 && method == "POST":
+		dbApp := db.App{}
+		findResult := app.db.Where("nostr_pubkey = ?", appRotateWalletKeyMatch[1]).First(&dbApp)
+
+		if findResult.RowsAffected == 0 {
+			return WailsRequestRouterResponse{Body: nil, Error: "App does not exist"}
+		}
+
+		rotatedApp, err := app.api.RotateAppWalletKey(&dbApp)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: rotatedApp, Error: ""}
+	}
+
 	appRegex := regexp.MustCompile(
 		`/api/apps/([0-9a-f]+)`,
 	)
@@ -145,6 +216,72 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 	}
 
+	fundingTransactionChannelsRegex := regexp.MustCompile(
+		`/api/channels/funding/([0-9a-f]+)`,
+	)
+
+	fundingTransactionChannelsMatch := fundingTransactionChannelsRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(fundingTransactionChannelsMatch) == 2:
+		channels, err := app.api.ListFundingTransactionChannels(ctx, fundingTransactionChannelsMatch[1])
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: channels, Error: ""}
+	}
+
+	psbtChannelRegex := regexp.MustCompile(
+		`/api/channels/psbt/([0-9a-f]+)(/verify|/finalize)?$`,
+	)
+
+	psbtChannelMatch := psbtChannelRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(psbtChannelMatch) == 3:
+		pendingChannelId := psbtChannelMatch[1]
+		switch {
+		case method == "POST" && psbtChannelMatch[2] == "/verify":
+			verifyPsbtChannelFundingRequest := &api.VerifyPsbtChannelFundingRequest{}
+			err := json.Unmarshal([]byte(body), verifyPsbtChannelFundingRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.VerifyPsbtChannelFunding(ctx, pendingChannelId, verifyPsbtChannelFundingRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		case method == "POST" && psbtChannelMatch[2] == "/finalize":
+			finalizePsbtChannelOpenRequest := &api.FinalizePsbtChannelOpenRequest{}
+			err := json.Unmarshal([]byte(body), finalizePsbtChannelOpenRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			openChannelResponse, err := app.api.FinalizePsbtChannelOpen(ctx, pendingChannelId, finalizePsbtChannelOpenRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: openChannelResponse, Error: ""}
+		case method == "DELETE" && psbtChannelMatch[2] == "":
+			err := app.api.CancelPsbtChannelOpen(ctx, pendingChannelId)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
 	peerRegex := regexp.MustCompile(
 		`/api/peers/([^/]+)`,
 	)
@@ -212,6 +349,289 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: paymentInfo, Error: ""}
 	}
 
+	forwardingStatsRegex := regexp.MustCompile(
+		`/api/forwards/stats`,
+	)
+
+	switch {
+	case forwardingStatsRegex.MatchString(route):
+		var from, until, interval uint64
+
+		paramRegex := regexp.MustCompile(`[?&](from|until|interval)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			parsedValue, err := strconv.ParseUint(match[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch match[1] {
+			case "from":
+				from = parsedValue
+			case "until":
+				until = parsedValue
+			case "interval":
+				interval = parsedValue
+			}
+		}
+
+		forwardingStats, err := app.api.GetForwardingStats(ctx, from, until, interval)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: forwardingStats, Error: ""}
+	}
+
+	historyRegex := regexp.MustCompile(
+		`/api/history`,
+	)
+
+	switch {
+	case historyRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			}
+		}
+
+		history, err := app.api.ListHistory(ctx, limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: history, Error: ""}
+	}
+
+	onchainLabelsRegex := regexp.MustCompile(
+		`/api/wallet/labels`,
+	)
+
+	switch {
+	case onchainLabelsRegex.MatchString(route):
+		switch method {
+		case "GET":
+			labelType := ""
+			typeMatch := regexp.MustCompile(`[?&]type=([^&]+)`).FindStringSubmatch(route)
+			if len(typeMatch) > 1 {
+				labelType = typeMatch[1]
+			}
+			onchainLabels, err := app.api.ListOnchainLabels(ctx, labelType)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: onchainLabels, Error: ""}
+		case "PUT":
+			setOnchainLabelRequest := &api.SetOnchainLabelRequest{}
+			err := json.Unmarshal([]byte(body), setOnchainLabelRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.SetOnchainLabel(ctx, setOnchainLabelRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	}
+
+	lspRegex := regexp.MustCompile(
+		`/api/lsps/([0-9]+)`,
+	)
+
+	lspMatch := lspRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(lspMatch) == 2 && method == "DELETE":
+		id, err := strconv.ParseUint(lspMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.DeleteLSP(uint(id))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	connectionRequestRegex := regexp.MustCompile(
+		`/api/connection-requests/([0-9]+)(/approve)?$`,
+	)
+
+	connectionRequestMatch := connectionRequestRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(connectionRequestMatch) == 3 && connectionRequestMatch[2] == "/approve" && method == "POST":
+		id, err := strconv.ParseUint(connectionRequestMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		connectedApp, err := app.api.ApproveConnectionRequest(uint(id))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: connectedApp, Error: ""}
+	case len(connectionRequestMatch) == 3 && connectionRequestMatch[2] == "" && method == "DELETE":
+		id, err := strconv.ParseUint(connectionRequestMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.RejectConnectionRequest(uint(id))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	}
+
+	listLSPOrdersRegex := regexp.MustCompile(
+		`/api/lsp-orders`,
+	)
+
+	switch {
+	case listLSPOrdersRegex.MatchString(route) && method == "GET":
+		limit := uint64(20)
+		offset := uint64(0)
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			}
+		}
+
+		orders, err := app.api.ListLSPOrders(limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: orders, Error: ""}
+	}
+
+	listLiquidityDecisionsRegex := regexp.MustCompile(
+		`/api/liquidity/decisions`,
+	)
+
+	switch {
+	case listLiquidityDecisionsRegex.MatchString(route) && method == "GET":
+		limit := uint64(20)
+		offset := uint64(0)
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			}
+		}
+
+		decisions, err := app.api.ListLiquidityDecisions(limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: decisions, Error: ""}
+	}
+
+	listSwapsRegex := regexp.MustCompile(
+		`^/api/swaps(\?.*)?$`,
+	)
+	swapRegex := regexp.MustCompile(
+		`^/api/swaps/([^/?]+)$`,
+	)
+
+	switch {
+	case listSwapsRegex.MatchString(route) && method == "GET":
+		limit := uint64(20)
+		offset := uint64(0)
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			}
+		}
+
+		swaps, err := app.api.ListSwaps(limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swaps, Error: ""}
+	// /api/swaps/info is handled below
+	case swapRegex.MatchString(route) && method == "GET" && route != "/api/swaps/info":
+		swapId := swapRegex.FindStringSubmatch(route)[1]
+		swap, err := app.api.GetSwap(swapId)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swap, Error: ""}
+	}
+
+	listRebalancesRegex := regexp.MustCompile(
+		`/api/rebalances`,
+	)
+
+	switch {
+	case listRebalancesRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+
+		paramRegex := regexp.MustCompile(`[?&](limit|offset)=([^&]+)`)
+		paramMatches := paramRegex.FindAllStringSubmatch(route, -1)
+		for _, match := range paramMatches {
+			switch match[1] {
+			case "limit":
+				if parsedLimit, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					limit = parsedLimit
+				}
+			case "offset":
+				if parsedOffset, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+					offset = parsedOffset
+				}
+			}
+		}
+
+		rebalances, err := app.api.ListRebalances(ctx, limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: rebalances, Error: ""}
+	}
+
 	listTransactionsRegex := regexp.MustCompile(
 		`/api/transactions`,
 	)
@@ -364,6 +784,38 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 		res := WailsRequestRouterResponse{Body: nil, Error: ""}
 		return res
+	case "/api/channels/batch":
+		batchOpenChannelRequest := &api.BatchOpenChannelRequest{}
+		err := json.Unmarshal([]byte(body), batchOpenChannelRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		batchOpenChannelResponse, err := app.api.OpenChannels(ctx, batchOpenChannelRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: batchOpenChannelResponse, Error: ""}
+	case "/api/channels/psbt":
+		openChannelRequest := &api.OpenChannelRequest{}
+		err := json.Unmarshal([]byte(body), openChannelRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		psbtChannelOpenResponse, err := app.api.StartPsbtChannelOpen(ctx, openChannelRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: psbtChannelOpenResponse, Error: ""}
 	case "/api/channels":
 		switch method {
 		case "GET":
@@ -389,7 +841,39 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
 			return WailsRequestRouterResponse{Body: openChannelResponse, Error: ""}
+		case "PATCH":
+			updateChannelsRequest := &api.UpdateChannelsRequest{}
+			err := json.Unmarshal([]byte(body), updateChannelsRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			updateChannelsResponse, err := app.api.UpdateChannels(ctx, updateChannelsRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: updateChannelsResponse, Error: ""}
+		}
+	case "/api/channels/rebalance":
+		rebalanceRequest := &api.RebalanceRequest{}
+		err := json.Unmarshal([]byte(body), rebalanceRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		rebalanceResponse, err := app.api.Rebalance(ctx, rebalanceRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
+		return WailsRequestRouterResponse{Body: rebalanceResponse, Error: ""}
 	case "/api/channels/suggestions":
 		suggestions, err := app.api.GetChannelPeerSuggestions(ctx)
 		if err != nil {
@@ -453,6 +937,69 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: *redeemOnchainFundsResponse, Error: ""}
+	case "/api/wallet/estimate-onchain-fees":
+		redeemOnchainFundsRequest := &api.RedeemOnchainFundsRequest{}
+		err := json.Unmarshal([]byte(body), redeemOnchainFundsRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		feeEstimate, err := app.api.EstimateOnchainFees(ctx, redeemOnchainFundsRequest.ToAddress, redeemOnchainFundsRequest.Amount, redeemOnchainFundsRequest.SendAll)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *feeEstimate, Error: ""}
+	case "/api/wallet/onchain-transactions":
+		onchainTransactions, err := app.api.ListOnchainTransactions(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: onchainTransactions, Error: ""}
+	case "/api/wallet/utxos":
+		utxos, err := app.api.ListUtxos(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: utxos, Error: ""}
+	case "/api/wallet/send-onchain":
+		sendOnchainRequest := &api.SendOnchainRequest{}
+		err := json.Unmarshal([]byte(body), sendOnchainRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		sendOnchainResponse, err := app.api.SendOnchain(ctx, sendOnchainRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *sendOnchainResponse, Error: ""}
+	case "/api/wallet/bump-fee":
+		bumpOnchainFeeRequest := &api.BumpOnchainFeeRequest{}
+		err := json.Unmarshal([]byte(body), bumpOnchainFeeRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		bumpOnchainFeeResponse, err := app.api.BumpOnchainFee(ctx, bumpOnchainFeeRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *bumpOnchainFeeResponse, Error: ""}
 	case "/api/wallet/sign-message":
 		signMessageRequest := &api.SignMessageRequest{}
 		err := json.Unmarshal([]byte(body), signMessageRequest)
@@ -464,12 +1011,167 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: *signMessageResponse, Error: ""}
+	case "/api/ecash/send":
+		sendEcashTokenRequest := &api.SendEcashTokenRequest{}
+		err := json.Unmarshal([]byte(body), sendEcashTokenRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		sendEcashTokenResponse, err := app.api.SendEcashToken(ctx, sendEcashTokenRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *sendEcashTokenResponse, Error: ""}
+	case "/api/ecash/receive":
+		receiveEcashTokenRequest := &api.ReceiveEcashTokenRequest{}
+		err := json.Unmarshal([]byte(body), receiveEcashTokenRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		receiveEcashTokenResponse, err := app.api.ReceiveEcashToken(ctx, receiveEcashTokenRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: *receiveEcashTokenResponse, Error: ""}
+	case "/api/ecash/swap":
+		swapEcashRequest := &api.SwapEcashRequest{}
+		err := json.Unmarshal([]byte(body), swapEcashRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.SwapEcashToDefaultMint(ctx, swapEcashRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
 	case "/api/wallet/capabilities":
 		capabilitiesResponse, err := app.api.GetWalletCapabilities(ctx)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: *capabilitiesResponse, Error: ""}
+	case "/api/connection-requests":
+		switch method {
+		case "GET":
+			connectionRequests, err := app.api.ListConnectionRequests()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: connectionRequests, Error: ""}
+		case "POST":
+			createConnectionRequestRequest := &api.CreateConnectionRequestRequest{}
+			err := json.Unmarshal([]byte(body), createConnectionRequestRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			connectionRequest, err := app.api.CreateConnectionRequest(createConnectionRequestRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: connectionRequest, Error: ""}
+		}
+	case "/api/relay/status":
+		relayStatus := app.api.GetRelayStatus()
+		return WailsRequestRouterResponse{Body: relayStatus, Error: ""}
+	case "/api/nip47/queue":
+		queueStats, err := app.api.GetNip47QueueStats()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: queueStats, Error: ""}
+	case "/api/swaps/info":
+		swapInfo, err := app.api.GetSwapInfo(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swapInfo, Error: ""}
+	case "/api/swaps/in":
+		swapInRequest := &api.SwapInRequest{}
+		err := json.Unmarshal([]byte(body), swapInRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		swap, err := app.api.SwapIn(ctx, swapInRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swap, Error: ""}
+	case "/api/swaps/out":
+		swapOutRequest := &api.SwapOutRequest{}
+		err := json.Unmarshal([]byte(body), swapOutRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+				"body":   body,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		swap, err := app.api.SwapOut(ctx, swapOutRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swap, Error: ""}
+	case "/api/liquidity/settings":
+		switch method {
+		case "GET":
+			settings, err := app.api.GetLiquiditySettings()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: settings, Error: ""}
+		case "PATCH":
+			settings := &api.LiquiditySettings{}
+			err := json.Unmarshal([]byte(body), settings)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.UpdateLiquiditySettings(settings)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+	case "/api/lsps":
+		switch method {
+		case "GET":
+			lsps, err := app.api.ListLSPs()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: lsps, Error: ""}
+		case "POST":
+			addLSPRequest := &api.AddLSPRequest{}
+			err := json.Unmarshal([]byte(body), addLSPRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+					"body":   body,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			lsp, err := app.api.AddLSP(addLSPRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: lsp, Error: ""}
+		}
 	case "/api/lsp-orders":
 		newInstantChannelRequest := &api.LSPOrderRequest{}
 		err := json.Unmarshal([]byte(body), newInstantChannelRequest)